@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	register("asn1", "解析任意ASN.1/DER结构并输出结构树", runAsn1)
+}
+
+// asn1Result ASN.1节点的输出结构
+type asn1Result struct {
+	Tag      int           `json:"tag"`
+	Type     string        `json:"type"`
+	Length   int           `json:"length"`
+	Value    string        `json:"value,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Children []*asn1Result `json:"children,omitempty"`
+}
+
+func runAsn1(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("asn1")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw)
+	if err != nil {
+		return err
+	}
+
+	root := helper.ParseAsn1(der)
+	result := buildAsn1Result(&root)
+	return emit(stdout, *asJSON, result, func(w io.Writer) {
+		printAsn1Result(w, result, 0)
+	})
+}
+
+func buildAsn1Result(node *helper.ASN1Node) *asn1Result {
+	result := &asn1Result{
+		Tag:    node.Tag,
+		Type:   helper.TagName(node.Tag),
+		Length: node.Length,
+		Error:  node.Error,
+	}
+	if len(node.Children) == 0 {
+		result.Value = node.Value
+	}
+	for _, child := range node.Children {
+		result.Children = append(result.Children, buildAsn1Result(child))
+	}
+	return result
+}
+
+func printAsn1Result(w io.Writer, node *asn1Result, level int) {
+	indent := strings.Repeat("  ", level)
+	line := fmt.Sprintf("%s%s (Tag:0x%02X) [%d bytes]", indent, node.Type, node.Tag, node.Length)
+	if node.Value != "" {
+		line += ": " + strings.ReplaceAll(node.Value, "\n", " ")
+	}
+	if node.Error != "" {
+		line += " ! " + node.Error
+	}
+	fmt.Fprintln(w, line)
+	for _, child := range node.Children {
+		printAsn1Result(w, child, level+1)
+	}
+}
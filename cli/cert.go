@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("cert", "解析X.509证书", runCert)
+}
+
+// certResult 证书解析结果
+type certResult struct {
+	SerialNumber       string            `json:"serialNumber"`
+	Subject            string            `json:"subject"`
+	Issuer             string            `json:"issuer"`
+	NotBefore          string            `json:"notBefore"`
+	NotAfter           string            `json:"notAfter"`
+	PublicKeyAlgorithm string            `json:"publicKeyAlgorithm"`
+	PublicKey          string            `json:"publicKey"`
+	SignatureAlgorithm string            `json:"signatureAlgorithm"`
+	KeyUsage           string            `json:"keyUsage,omitempty"`
+	Extensions         []extensionResult `json:"extensions,omitempty"`
+}
+
+type extensionResult struct {
+	OID      string      `json:"oid"`
+	Name     string      `json:"name,omitempty"`
+	Critical bool        `json:"critical"`
+	Value    string      `json:"value"`
+	Decoded  interface{} `json:"decoded,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+func runCert(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("cert")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw, "CERTIFICATE")
+	if err != nil {
+		return err
+	}
+	certificate, err := helper.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+
+	result := buildCertResult(certificate)
+	return emit(stdout, *asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "SerialNumber:       %s\n", result.SerialNumber)
+		fmt.Fprintf(w, "SubjectName:        %s\n", result.Subject)
+		fmt.Fprintf(w, "IssueName:          %s\n", result.Issuer)
+		fmt.Fprintf(w, "NotBefore:          %s\n", result.NotBefore)
+		fmt.Fprintf(w, "NotAfter:           %s\n", result.NotAfter)
+		fmt.Fprintf(w, "PublicKeyAlgorithm: %s\n", result.PublicKeyAlgorithm)
+		fmt.Fprintf(w, "PublicKey:          %s\n", result.PublicKey)
+		fmt.Fprintf(w, "SignatureAlgorithm: %s\n", result.SignatureAlgorithm)
+		if result.KeyUsage != "" {
+			fmt.Fprintf(w, "KeyUsage:           %s\n", result.KeyUsage)
+		}
+		for _, ext := range result.Extensions {
+			critical := ""
+			if ext.Critical {
+				critical = " [Critical]"
+			}
+			name := ext.OID
+			if ext.Name != "" {
+				name = fmt.Sprintf("%s (%s)", ext.Name, ext.OID)
+			}
+			raw, _ := hex.DecodeString(ext.Value)
+			fmt.Fprintf(w, "%s%s:\n  %s\n", name, critical, strings.ReplaceAll(helper.FormatExtension(ext.OID, raw), "\n", "\n  "))
+		}
+	})
+}
+
+func buildCertResult(certificate *x509.Certificate) certResult {
+	result := certResult{
+		SerialNumber:       hex.EncodeToString(certificate.SerialNumber.Bytes()),
+		Subject:            certificate.Subject.String(),
+		Issuer:             certificate.Issuer.String(),
+		NotBefore:          util.ToBeijingTime(certificate.NotBefore).Format(util.DateTime),
+		NotAfter:           util.ToBeijingTime(certificate.NotAfter).Format(util.DateTime),
+		PublicKeyAlgorithm: publicKeyAlgorithmName(certificate.PublicKeyAlgorithm),
+		PublicKey:          base64.StdEncoding.EncodeToString(certificate.RawSubjectPublicKeyInfo),
+		SignatureAlgorithm: certificate.SignatureAlgorithm.String(),
+		KeyUsage:           strings.TrimSpace(helper.ParseKeyUsage(certificate.KeyUsage)),
+	}
+	for _, ext := range certificate.Extensions {
+		oid := ext.Id.String()
+		extension := extensionResult{
+			OID:      oid,
+			Name:     helper.ExtensionNames[oid],
+			Critical: ext.Critical,
+			Value:    hex.EncodeToString(ext.Value),
+		}
+		decoded, err := helper.DecodeExtension(oid, ext.Value)
+		if err != nil {
+			extension.Error = err.Error()
+		} else if decoded != nil {
+			extension.Decoded = decoded
+		}
+		result.Extensions = append(result.Extensions, extension)
+	}
+	return result
+}
+
+func publicKeyAlgorithmName(alg x509.PublicKeyAlgorithm) string {
+	switch alg {
+	case x509.RSA:
+		return "RSA"
+	case x509.SM2:
+		return "SM2"
+	case x509.ECDSA:
+		return "ECDSA"
+	default:
+		return "Unknown"
+	}
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("chain", "从无序证书池构建并验证证书路径", runChain)
+}
+
+func runChain(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("chain")
+	leafFile := fs.String("leaf", "", "终端证书文件路径，缺省时取证书池中未签发其他证书的证书")
+	rootsFile := fs.String("roots", "", "信任锚证书文件路径，缺省时以证书池中的自签名证书作为信任锚")
+	crlFiles := fs.String("crl", "", "用于吊销检查的CRL文件路径，逗号分隔，每个文件可包含多个PEM块")
+	at := fs.String("time", "", "验证时间，格式 "+util.DateTime+"(北京时间)，缺省为当前时间")
+	policies := fs.String("policy", "", "初始策略集，逗号分隔的OID")
+	explicitPolicy := fs.Bool("explicit-policy", false, "要求显式策略")
+	inhibitMapping := fs.Bool("inhibit-mapping", false, "禁止策略映射")
+	inhibitAny := fs.Bool("inhibit-any", false, "禁止anyPolicy")
+	purposes := fs.String("purpose", "", "终端证书须具备的扩展密钥用法之一，逗号分隔的OID或简称，如 \"serverAuth\"")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	pool, err := decodeCertificates(raw)
+	if err != nil {
+		return err
+	}
+
+	opts := helper.PathOptions{
+		Intermediates:         pool,
+		InitialPolicies:       splitList(*policies),
+		RequireExplicitPolicy: *explicitPolicy,
+		InhibitPolicyMapping:  *inhibitMapping,
+		InhibitAnyPolicy:      *inhibitAny,
+	}
+	if *rootsFile != "" {
+		if opts.Roots, err = readCertificateFile(*rootsFile); err != nil {
+			return err
+		}
+	}
+	for _, path := range splitList(*crlFiles) {
+		rawCRL, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		crls, err := decodeCRLs(rawCRL)
+		if err != nil {
+			return err
+		}
+		opts.CRLs = append(opts.CRLs, crls...)
+	}
+	for _, item := range splitList(*purposes) {
+		if oid, ok := extKeyUsageAliases[strings.ToLower(item)]; ok {
+			item = oid
+		}
+		opts.KeyPurposes = append(opts.KeyPurposes, item)
+	}
+	if *at != "" {
+		if opts.Time, err = util.ParseDateTime(*at); err != nil {
+			return fmt.Errorf("验证时间格式应为 %s", util.DateTime)
+		}
+	}
+
+	leaves := helper.FindLeafCertificates(pool)
+	if *leafFile != "" {
+		if leaves, err = readCertificateFile(*leafFile); err != nil {
+			return err
+		}
+	}
+	var results []*helper.PathValidation
+	for _, leaf := range leaves {
+		results = append(results, helper.ValidateCertificatePath(leaf, opts))
+	}
+	err = emit(stdout, *asJSON, results, func(w io.Writer) {
+		for i, result := range results {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, helper.FormatPathValidation(result))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	invalid := 0
+	for _, result := range results {
+		if !result.Valid() {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return checkFailed("%d/%d 个终端证书没有通过验证的证书路径", invalid, len(results))
+	}
+	return nil
+}
+
+// readCertificateFile 读取证书文件，支持多个PEM证书及P7B
+func readCertificateFile(path string) ([]*x509.Certificate, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCertificates(raw)
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"HeTu/codec"
+	"HeTu/helper"
+	"crypto"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+// command 子命令定义
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{}
+
+// register 注册子命令，由各命令文件的init调用
+func register(name, usage string, run func(args []string, stdout io.Writer) error) {
+	commands[name] = command{name: name, usage: usage, run: run}
+}
+
+// 进程退出码: 0表示成功；1表示执行出错，或结果已输出但校验未通过；2表示用法错误
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// usageError 参数解析失败，flag包已输出错误信息及用法
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+// checkFailedError 结果已完整输出，但路径无效、签名验证失败或证书已吊销等校验未通过
+type checkFailedError struct {
+	reason string
+}
+
+func (e *checkFailedError) Error() string {
+	return "校验未通过: " + e.reason
+}
+
+// checkFailed 子命令输出结果后返回，使进程以非零退出码结束
+func checkFailed(format string, args ...interface{}) error {
+	return &checkFailedError{reason: fmt.Sprintf(format, args...)}
+}
+
+// Run 命令行入口，返回进程退出码
+func Run(args []string) int {
+	return run(args, os.Stdout, os.Stderr)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stdout)
+		return 0
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "未知命令: %s\n\n", args[0])
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	if err := cmd.run(args[1:], stdout); err != nil {
+		var usage *usageError
+		switch {
+		case err == flag.ErrHelp:
+			return 0
+		case errors.As(err, &usage):
+			return exitUsage
+		}
+		fmt.Fprintf(stderr, "hetu %s: %v\n", cmd.name, err)
+		return exitFailure
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "用法: hetu <命令> [参数]")
+	fmt.Fprintln(w, "不带参数运行时启动图形界面。")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "命令:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "使用 hetu <命令> -h 查看命令参数。")
+}
+
+// newFlagSet 创建子命令参数集，并注册公共的 -in/-json 参数
+func newFlagSet(name string) (*flag.FlagSet, *string, *bool) {
+	fs := flag.NewFlagSet("hetu "+name, flag.ContinueOnError)
+	in := fs.String("in", "", "输入文件路径，缺省时读取位置参数或标准输入")
+	asJSON := fs.Bool("json", false, "以JSON格式输出")
+	return fs, in, asJSON
+}
+
+// parseFlags 解析子命令参数，解析失败时返回usageError
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && err != flag.ErrHelp {
+		return &usageError{err: err}
+	}
+	return err
+}
+
+// readInput 按 -in 文件、位置参数、标准输入的顺序读取原始输入
+func readInput(in string, fs *flag.FlagSet) ([]byte, error) {
+	if in != "" {
+		return os.ReadFile(in)
+	}
+	if fs.NArg() > 0 {
+		return []byte(strings.Join(fs.Args(), " ")), nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("读取标准输入失败: %v", err)
+	}
+	return data, nil
+}
+
+// decodeBinary 使用codec将输入解码为二进制，PEM输入时优先取指定类型的块
+func decodeBinary(raw []byte, pemTypes ...string) ([]byte, error) {
+	result, err := codec.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(pemTypes) == 0 {
+		return result.Bytes(), nil
+	}
+	data, ok := result.FirstOfType(pemTypes...)
+	if !ok {
+		return nil, fmt.Errorf("未找到 %s 类型的PEM块，输入为 %s", strings.Join(pemTypes, "/"), result.Describe())
+	}
+	return data, nil
+}
+
+// readPrivateKeyFile 读取并解析PEM/Base64/Hex/DER编码的私钥文件
+func readPrivateKeyFile(path string) (crypto.Signer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := decodeBinary(raw, "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	return helper.ParsePrivateKey(keyData)
+}
+
+// decodeCertificates 解码证书列表，支持多个PEM证书、PKCS7块及Base64/Hex/DER编码的证书或P7B
+func decodeCertificates(raw []byte) ([]*x509.Certificate, error) {
+	result, err := codec.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for _, block := range result.AllOfType("CERTIFICATE", "PKCS7") {
+		parsed, err := helper.ParseCertificates(block)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, parsed...)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("未找到证书，输入为 %s", result.Describe())
+	}
+	return certs, nil
+}
+
+// decodeCRLs 解码CRL列表，支持多个PEM块及Base64/Hex/DER编码的CRL
+func decodeCRLs(raw []byte) ([]*pkix.CertificateList, error) {
+	result, err := codec.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	var crls []*pkix.CertificateList
+	for _, block := range result.AllOfType("X509 CRL", "CRL") {
+		crl, err := x509.ParseCRL(block)
+		if err != nil {
+			return nil, fmt.Errorf("解析CRL失败: %v", err)
+		}
+		crls = append(crls, crl)
+	}
+	if len(crls) == 0 {
+		return nil, fmt.Errorf("未找到CRL，输入为 %s", result.Describe())
+	}
+	return crls, nil
+}
+
+// emit 按输出模式打印结果
+func emit(w io.Writer, asJSON bool, v interface{}, text func(w io.Writer)) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(v)
+	}
+	text(w)
+	return nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"HeTu/codec"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+func init() {
+	register("coder", "Base64/Hex互转（自动识别PEM/Hex/Base64，普通字符串兜底）", runCoder)
+}
+
+// coderResult 编码转换结果
+type coderResult struct {
+	Format string `json:"format"`
+	Output string `json:"output"`
+	Length int    `json:"length"`
+}
+
+func runCoder(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("coder")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+
+	decoded := codec.DecodeOrText(string(raw))
+	decodedData := decoded.Bytes()
+	result := coderResult{Format: decoded.Describe()}
+	if decoded.Encoding == codec.EncodingHex {
+		result.Output = base64.StdEncoding.EncodeToString(decodedData)
+	} else {
+		result.Output = hex.EncodeToString(decodedData)
+	}
+	result.Length = len(decodedData)
+
+	return emit(stdout, *asJSON, result, func(w io.Writer) {
+		fmt.Fprintln(w, result.Output)
+		fmt.Fprintf(w, "数据长度为:%d字节(输入格式: %s)\n", result.Length, result.Format)
+	})
+}
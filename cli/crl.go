@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("crl", "解析CRL，可通过 -issuer 验证签名、-serial/-serials 批量检查证书是否被吊销", runCrl)
+}
+
+// crlResult CRL解析结果
+type crlResult struct {
+	Version                  int                              `json:"version"`
+	Issuer                   string                           `json:"issuer"`
+	ThisUpdate               string                           `json:"thisUpdate"`
+	NextUpdate               string                           `json:"nextUpdate,omitempty"`
+	SignatureAlgorithm       string                           `json:"signatureAlgorithm"`
+	SignatureStatus          string                           `json:"signatureStatus"`
+	SignatureError           string                           `json:"signatureError,omitempty"`
+	CRLNumber                string                           `json:"crlNumber,omitempty"`
+	DeltaCRLIndicator        string                           `json:"deltaCRLIndicator,omitempty"`
+	IssuingDistributionPoint *helper.IssuingDistributionPoint `json:"issuingDistributionPoint,omitempty"`
+	AuthorityKeyIdentifier   *helper.AuthorityKeyIdentifier   `json:"authorityKeyIdentifier,omitempty"`
+	FreshestCRL              []helper.DistributionPoint       `json:"freshestCRL,omitempty"`
+	Stale                    bool                             `json:"stale"`
+	Problems                 []string                         `json:"problems,omitempty"`
+	TotalRevoked             int                              `json:"totalRevoked"`
+	RevokedCerts             []revokedResult                  `json:"revokedCerts,omitempty"`
+	Page                     int                              `json:"page,omitempty"`
+	Pages                    int                              `json:"pages,omitempty"`
+	Check                    *checkResult                     `json:"check,omitempty"`
+	Checks                   []checkResult                    `json:"checks,omitempty"`
+}
+
+type revokedResult struct {
+	SerialNumber      string               `json:"serialNumber"`
+	RevocationTime    string               `json:"revocationTime"`
+	Reason            string               `json:"reason"`
+	InvalidityDate    string               `json:"invalidityDate,omitempty"`
+	CertificateIssuer []helper.GeneralName `json:"certificateIssuer,omitempty"`
+}
+
+type checkResult struct {
+	SerialNumber string         `json:"serialNumber"`
+	Revoked      bool           `json:"revoked"`
+	Entry        *revokedResult `json:"entry,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+func runCrl(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("crl")
+	serial := fs.String("serial", "", "待检查的证书序列号(Hex)，多个以逗号分隔")
+	serialsFile := fs.String("serials", "", "待检查的序列号文件，每行一个")
+	list := fs.Bool("list", false, "输出被吊销证书，可配合 -page 分页")
+	page := fs.Int("page", 0, "配合 -list 仅输出第几页(从1开始)，0表示全部")
+	size := fs.Int("size", 100, "分页大小")
+	issuer := fs.String("issuer", "", "CRL颁发者证书文件，用于验证CRL签名")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	// 颁发者证书在读取CRL之前加载，大型CRL的SM2签名摘要需要在读取时计算
+	var issuers []*x509.Certificate
+	var err error
+	if *issuer != "" {
+		if issuers, err = readCertificateFile(*issuer); err != nil {
+			return fmt.Errorf("读取颁发者证书失败: %v", err)
+		}
+	}
+	crlInfo, err := readCRLInput(*in, fs, issuers...)
+	if err != nil {
+		return err
+	}
+
+	var verifyErr error
+	if issuers != nil {
+		// 签名验证失败记录在结果中，不中断输出
+		verifyErr = helper.VerifyCRLWithCertificates(crlInfo, issuers)
+	}
+
+	now := time.Now()
+	result := crlResult{
+		Version:                  crlInfo.Version,
+		Issuer:                   crlInfo.Issuer,
+		ThisUpdate:               crlInfo.ThisUpdate.Format(util.DateTime),
+		SignatureAlgorithm:       crlInfo.SignatureAlgorithm,
+		SignatureStatus:          crlInfo.SignatureStatus,
+		SignatureError:           crlInfo.SignatureError,
+		IssuingDistributionPoint: crlInfo.IssuingDistributionPoint,
+		AuthorityKeyIdentifier:   crlInfo.AuthorityKeyIdentifier,
+		FreshestCRL:              crlInfo.FreshestCRL,
+		Stale:                    crlInfo.IsStale(now),
+		Problems:                 crlInfo.CheckFreshness(now),
+		TotalRevoked:             crlInfo.TotalRevoked,
+	}
+	if !crlInfo.NextUpdate.IsZero() {
+		result.NextUpdate = crlInfo.NextUpdate.Format(util.DateTime)
+	}
+	if crlInfo.CRLNumber != nil {
+		result.CRLNumber = crlInfo.CRLNumber.String()
+	}
+	if crlInfo.DeltaCRLIndicator != nil {
+		result.DeltaCRLIndicator = crlInfo.DeltaCRLIndicator.String()
+	}
+	if *list {
+		entries := crlInfo.RevokedCerts
+		if *page > 0 {
+			entries, result.Page, result.Pages = crlInfo.RevokedPage(*page, *size)
+		}
+		for _, revoked := range entries {
+			result.RevokedCerts = append(result.RevokedCerts, toRevokedResult(&revoked))
+		}
+	}
+
+	serials := helper.SplitSerialNumbers(*serial)
+	if *serialsFile != "" {
+		content, err := os.ReadFile(*serialsFile)
+		if err != nil {
+			return err
+		}
+		serials = append(serials, helper.SplitSerialNumbers(string(content))...)
+	}
+	for _, lookup := range crlInfo.LookupSerials(serials) {
+		check := checkResult{SerialNumber: lookup.SerialNumber, Revoked: lookup.Revoked, Error: lookup.Error}
+		if lookup.Entry != nil {
+			entry := toRevokedResult(lookup.Entry)
+			check.Entry = &entry
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	// 单个序列号保持原有的check字段
+	if len(result.Checks) == 1 {
+		result.Check = &result.Checks[0]
+		result.Checks = nil
+	}
+
+	err = emit(stdout, *asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "版本: V%d\n", result.Version)
+		fmt.Fprintf(w, "颁发者: %s\n", result.Issuer)
+		fmt.Fprintf(w, "本次更新时间: %s\n", result.ThisUpdate)
+		if result.NextUpdate == "" {
+			fmt.Fprintf(w, "下次更新时间: 未设置\n")
+		} else {
+			fmt.Fprintf(w, "下次更新时间: %s\n", result.NextUpdate)
+		}
+		fmt.Fprintf(w, "签名算法: %s\n", result.SignatureAlgorithm)
+		fmt.Fprintf(w, "签名状态: %s\n", result.SignatureStatus)
+		if result.CRLNumber != "" {
+			fmt.Fprintf(w, "CRL序号: %s\n", result.CRLNumber)
+		}
+		if result.DeltaCRLIndicator != "" {
+			fmt.Fprintf(w, "增量CRL，基础CRL序号: %s\n", result.DeltaCRLIndicator)
+		}
+		for _, ext := range crlInfo.Extensions {
+			switch ext.Id.String() {
+			case "2.5.29.20", "2.5.29.27":
+				continue
+			}
+			fmt.Fprintf(w, "%s:\n  %s\n", extensionLabel(ext.Id.String(), ext.Critical),
+				strings.ReplaceAll(strings.TrimSpace(helper.FormatExtension(ext.Id.String(), ext.Value)), "\n", "\n  "))
+		}
+		for _, problem := range result.Problems {
+			fmt.Fprintf(w, "警告: %s\n", problem)
+		}
+		fmt.Fprintf(w, "被吊销证书总数: %d\n", result.TotalRevoked)
+		first := 1
+		if result.Page > 0 {
+			fmt.Fprintf(w, "第 %d/%d 页:\n", result.Page, result.Pages)
+			first = (result.Page-1)**size + 1
+		}
+		for i, revoked := range result.RevokedCerts {
+			fmt.Fprintf(w, "%d. 序列号: %s, 吊销时间: %s, 原因: %s", first+i, revoked.SerialNumber, revoked.RevocationTime, revoked.Reason)
+			if revoked.InvalidityDate != "" {
+				fmt.Fprintf(w, ", 失效日期: %s", revoked.InvalidityDate)
+			}
+			for _, name := range revoked.CertificateIssuer {
+				fmt.Fprintf(w, ", 证书颁发者: %s", name)
+			}
+			fmt.Fprintln(w)
+		}
+		if result.Check != nil {
+			printCheckResult(w, result.Check)
+		}
+		revokedCount := 0
+		for i := range result.Checks {
+			printCheckResult(w, &result.Checks[i])
+			if result.Checks[i].Revoked {
+				revokedCount++
+			}
+		}
+		if len(result.Checks) > 0 {
+			fmt.Fprintf(w, "共查询 %d 个序列号，已吊销 %d 个\n", len(result.Checks), revokedCount)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return crlCheckError(verifyErr, result)
+}
+
+// crlCheckError CRL签名验证失败、序列号已吊销或查询失败时返回checkFailed
+func crlCheckError(verifyErr error, result crlResult) error {
+	if verifyErr != nil {
+		return checkFailed("CRL签名验证失败，%v", verifyErr)
+	}
+	checks := result.Checks
+	if result.Check != nil {
+		checks = []checkResult{*result.Check}
+	}
+	revoked, failed := 0, 0
+	for _, check := range checks {
+		switch {
+		case check.Error != "":
+			failed++
+		case check.Revoked:
+			revoked++
+		}
+	}
+	switch {
+	case revoked > 0 && failed > 0:
+		return checkFailed("%d 个序列号已吊销，%d 个查询失败", revoked, failed)
+	case revoked > 0:
+		return checkFailed("%d 个序列号已吊销", revoked)
+	case failed > 0:
+		return checkFailed("%d 个序列号查询失败", failed)
+	}
+	return nil
+}
+
+func printCheckResult(w io.Writer, check *checkResult) {
+	switch {
+	case check.Error != "":
+		fmt.Fprintf(w, "证书 %s 查询失败: %s\n", check.SerialNumber, check.Error)
+	case check.Revoked:
+		fmt.Fprintf(w, "证书 %s 已被吊销，吊销时间: %s，原因: %s\n", check.SerialNumber, check.Entry.RevocationTime, check.Entry.Reason)
+	default:
+		fmt.Fprintf(w, "证书 %s 未被吊销\n", check.SerialNumber)
+	}
+}
+
+// readCRLInput 读取CRL，-in指定的DER/PEM文件流式读取，其余输入按编码识别后解析。
+// issuers为候选颁发者证书，大型SM2 CRL需在读取时提供才能验证签名
+func readCRLInput(in string, fs *flag.FlagSet, issuers ...*x509.Certificate) (*helper.CRLInfo, error) {
+	if in != "" {
+		if crlInfo, err := helper.ReadCRLFile(in, issuers, nil); err == nil {
+			return crlInfo, nil
+		}
+	}
+	raw, err := readInput(in, fs)
+	if err != nil {
+		return nil, err
+	}
+	der, err := decodeBinary(raw, "X509 CRL")
+	if err != nil {
+		return nil, err
+	}
+	return helper.ReadCRL(bytes.NewReader(der), issuers, nil)
+}
+
+func toRevokedResult(revoked *helper.RevokedCertificate) revokedResult {
+	result := revokedResult{
+		SerialNumber:      revoked.SerialNumber,
+		RevocationTime:    revoked.RevocationTime.Format(util.DateTime),
+		Reason:            revoked.Reason,
+		CertificateIssuer: revoked.CertificateIssuer,
+	}
+	if !revoked.InvalidityDate.IsZero() {
+		result.InvalidityDate = revoked.InvalidityDate.Format(util.DateTime)
+	}
+	return result
+}
+
+// extensionLabel 返回扩展项名称，未知OID直接显示OID
+func extensionLabel(oid string, critical bool) string {
+	name := helper.ExtensionNames[oid]
+	if name == "" {
+		name = oid
+	}
+	if critical {
+		name += " (关键)"
+	}
+	return name
+}
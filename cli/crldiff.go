@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+func init() {
+	register("crldiff", "比较同一颁发者的新旧两个CRL，输出新增/移除/变更的吊销条目", runCrlDiff)
+}
+
+func runCrlDiff(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("crldiff")
+	newFile := fs.String("new", "", "新CRL文件路径，-in 指定旧CRL")
+	asCSV := fs.Bool("csv", false, "以CSV格式输出变更条目")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *newFile == "" {
+		return fmt.Errorf("必须通过 -new 指定新CRL文件")
+	}
+	oldCRL, err := readCRLInput(*in, fs)
+	if err != nil {
+		return fmt.Errorf("读取旧CRL失败: %v", err)
+	}
+	newCRL, err := readCRLInput(*newFile, fs)
+	if err != nil {
+		return fmt.Errorf("读取新CRL失败: %v", err)
+	}
+	diff, err := helper.DiffCRL(oldCRL, newCRL)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *asJSON:
+		return diff.WriteJSON(stdout)
+	case *asCSV:
+		return diff.WriteCSV(stdout)
+	}
+	for _, line := range diff.Summary() {
+		fmt.Fprintln(stdout, line)
+	}
+	for _, warning := range diff.Warnings {
+		fmt.Fprintf(stdout, "警告: %s\n", warning)
+	}
+	if len(diff.Entries) == 0 {
+		fmt.Fprintln(stdout, "吊销条目没有变化")
+		return nil
+	}
+	fmt.Fprintln(stdout)
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	writeRow := func(row []string) {
+		for i, cell := range row {
+			if cell == "" {
+				cell = "-"
+			}
+			if i > 0 {
+				fmt.Fprint(table, "\t")
+			}
+			fmt.Fprint(table, cell)
+		}
+		fmt.Fprintln(table)
+	}
+	writeRow(helper.CRLDiffColumns)
+	for _, entry := range diff.Entries {
+		writeRow(entry.Row())
+	}
+	return table.Flush()
+}
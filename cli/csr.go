@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	register("csr", "解析PKCS#10证书请求并验证自签名", runCsr)
+}
+
+func runCsr(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("csr")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw, "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST")
+	if err != nil {
+		return err
+	}
+	info, err := helper.ParseCSR(der)
+	if err != nil {
+		return err
+	}
+
+	err = emit(stdout, *asJSON, info, func(w io.Writer) {
+		fmt.Fprintf(w, "SubjectName:        %s\n", info.Subject)
+		fmt.Fprintf(w, "PublicKeyAlgorithm: %s (%d bits)\n", info.PublicKeyAlgorithm, info.PublicKeySize)
+		fmt.Fprintf(w, "PublicKey:          %s\n", info.PublicKey)
+		fmt.Fprintf(w, "SignatureAlgorithm: %s\n", info.SignatureAlgorithm)
+		if info.SignatureValid {
+			fmt.Fprintln(w, "Signature:          验证通过")
+		} else {
+			fmt.Fprintf(w, "Signature:          %s\n", info.SignatureError)
+		}
+		if info.ChallengePassword != "" {
+			fmt.Fprintf(w, "ChallengePassword:  %s\n", info.ChallengePassword)
+		}
+		for _, attr := range info.Attributes {
+			fmt.Fprintf(w, "Attribute %s (%s): %s\n", attr.Name, attr.OID, strings.Join(attr.Values, ", "))
+		}
+		for _, ext := range info.Extensions {
+			oid := ext.Id.String()
+			name := oid
+			if n, ok := helper.ExtensionNames[oid]; ok {
+				name = fmt.Sprintf("%s (%s)", n, oid)
+			}
+			fmt.Fprintf(w, "%s:\n  %s\n", name, strings.ReplaceAll(helper.FormatExtension(oid, ext.Value), "\n", "\n  "))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !info.SignatureValid {
+		return checkFailed("自签名验证失败，%s", info.SignatureError)
+	}
+	return nil
+}
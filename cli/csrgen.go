@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register("csrgen", "由SM2/RSA私钥生成PKCS#10证书请求", runCsrGen)
+}
+
+// extKeyUsageAliases 常用扩展密钥用法简称
+var extKeyUsageAliases = map[string]string{
+	"any":             "2.5.29.37.0",
+	"serverauth":      "1.3.6.1.5.5.7.3.1",
+	"clientauth":      "1.3.6.1.5.5.7.3.2",
+	"codesigning":     "1.3.6.1.5.5.7.3.3",
+	"emailprotection": "1.3.6.1.5.5.7.3.4",
+	"timestamping":    "1.3.6.1.5.5.7.3.8",
+	"ocspsigning":     "1.3.6.1.5.5.7.3.9",
+}
+
+func runCsrGen(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("csrgen")
+	subject := fs.String("subject", "", "主题名称，如 \"CN=test,O=HeTu,C=CN\"")
+	san := fs.String("san", "", "主题备用名称，逗号分隔，如 \"DNS:a.com,IP:127.0.0.1\"")
+	keyUsage := fs.String("ku", "", "密钥用法，逗号分隔的位序号或名称，如 \"digitalSignature,keyEncipherment\"")
+	extKeyUsage := fs.String("eku", "", "扩展密钥用法，逗号分隔的OID或简称，如 \"serverAuth,clientAuth\"")
+	challenge := fs.String("challenge", "", "challengePassword属性")
+	der := fs.Bool("der", false, "输出Base64编码的DER而非PEM")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	keyData, err := decodeBinary(raw, "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+	if err != nil {
+		return err
+	}
+	key, err := helper.ParsePrivateKey(keyData)
+	if err != nil {
+		return err
+	}
+
+	template := &helper.CSRTemplate{ChallengePassword: *challenge}
+	if template.Subject, err = helper.ParseDistinguishedName(*subject); err != nil {
+		return err
+	}
+	template.SubjectAltNames = splitList(*san)
+	for _, item := range splitList(*keyUsage) {
+		bit, err := parseKeyUsageBit(item)
+		if err != nil {
+			return err
+		}
+		template.KeyUsage = append(template.KeyUsage, bit)
+	}
+	for _, item := range splitList(*extKeyUsage) {
+		if oid, ok := extKeyUsageAliases[strings.ToLower(item)]; ok {
+			item = oid
+		}
+		template.ExtKeyUsage = append(template.ExtKeyUsage, item)
+	}
+
+	csr, err := helper.CreateCSR(template, key)
+	if err != nil {
+		return err
+	}
+	var encoded string
+	if *der {
+		encoded = base64.StdEncoding.EncodeToString(csr)
+	} else {
+		encoded = strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})))
+	}
+	return emit(stdout, *asJSON, map[string]string{"csr": encoded}, func(w io.Writer) {
+		fmt.Fprintln(w, encoded)
+	})
+}
+
+// splitList 拆分逗号分隔的参数并去除空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// keyUsageAliases RFC 5280中KeyUsage各位的名称，下标即位序号
+var keyUsageAliases = []string{
+	"digitalsignature", "nonrepudiation", "keyencipherment", "dataencipherment",
+	"keyagreement", "keycertsign", "crlsign", "encipheronly", "decipheronly",
+}
+
+// parseKeyUsageBit 将位序号或RFC 5280名称(忽略大小写)转换为KeyUsage位序号
+func parseKeyUsageBit(item string) (int, error) {
+	if bit, err := strconv.Atoi(item); err == nil {
+		return bit, nil
+	}
+	for bit, name := range keyUsageAliases {
+		if strings.EqualFold(item, name) {
+			return bit, nil
+		}
+	}
+	return 0, fmt.Errorf("无法识别的密钥用法: %s", item)
+}
@@ -0,0 +1,323 @@
+package cli
+
+import (
+	"HeTu/gm"
+	"HeTu/helper"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("envelope", "解析GM/T 0009 SM2数字信封、GM/T 0016 SKF ENVELOPEDKEYBLOB或CMS EnvelopedData(SM2/RSA接收者)，提供 -key 时解密", runEnvelope)
+}
+
+// envelopeResult 信封解析结果，SKF输入先转换为SM2EnvelopedKey再按同样字段输出
+type envelopeResult struct {
+	Format              string                  `json:"format"`
+	SKF                 *envelopedKeyBlobResult `json:"skf,omitempty"`
+	Converted           string                  `json:"converted,omitempty"`
+	SymAlgID            string                  `json:"symAlgId"`
+	CipherX             string                  `json:"cipherX"`
+	CipherY             string                  `json:"cipherY"`
+	CipherHash          string                  `json:"cipherHash"`
+	CipherText          string                  `json:"cipherText"`
+	PublicKey           string                  `json:"publicKey"`
+	EncryptedPrivateKey string                  `json:"encryptedPrivateKey"`
+	SymKey              string                  `json:"symKey,omitempty"`
+	PrivateKey          string                  `json:"privateKey,omitempty"`
+	// PublicKeyMatch 私钥推导的公钥是否与信封中的公钥一致，仅解密时输出
+	PublicKeyMatch *bool  `json:"publicKeyMatch,omitempty"`
+	Warning        string `json:"warning,omitempty"`
+}
+
+// envelopedKeyBlobResult SKF ENVELOPEDKEYBLOB各字段
+type envelopedKeyBlobResult struct {
+	Version         uint32 `json:"version"`
+	SymmAlgID       string `json:"symmAlgId"`
+	Bits            uint32 `json:"bits"`
+	EncryptedPriKey string `json:"encryptedPriKey"`
+	PubKeyBitLen    uint32 `json:"pubKeyBitLen"`
+	PubKeyX         string `json:"pubKeyX"`
+	PubKeyY         string `json:"pubKeyY"`
+	CipherX         string `json:"cipherX"`
+	CipherY         string `json:"cipherY"`
+	CipherHash      string `json:"cipherHash"`
+	CipherLen       uint32 `json:"cipherLen"`
+	Cipher          string `json:"cipher"`
+	Trailing        int    `json:"trailing,omitempty"`
+}
+
+// cmsEnvelopeResult CMS数字信封解析及解密结果
+type cmsEnvelopeResult struct {
+	ContentType                string            `json:"contentType"`
+	Version                    int               `json:"version"`
+	EncryptedContentType       string            `json:"encryptedContentType"`
+	ContentEncryptionAlgorithm string            `json:"contentEncryptionAlgorithm"`
+	EncryptedContentSize       int               `json:"encryptedContentSize"`
+	MAC                        string            `json:"mac,omitempty"`
+	Recipients                 []recipientResult `json:"recipients"`
+	DecryptedBy                int               `json:"decryptedBy,omitempty"`
+	Content                    string            `json:"content,omitempty"`
+}
+
+type recipientResult struct {
+	Type                   string `json:"type"`
+	Version                int    `json:"version"`
+	Identifier             string `json:"identifier,omitempty"`
+	KeyEncryptionAlgorithm string `json:"keyEncryptionAlgorithm,omitempty"`
+}
+
+func runEnvelope(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("envelope")
+	key := fs.String("key", "", "用于解密的私钥(PEM/Base64/Hex)，GM/T 0009信封为SM2签名私钥，CMS信封为接收者SM2或RSA私钥")
+	certFile := fs.String("cert", "", "CMS信封接收者证书文件，用于在多个接收者中选择")
+	out := fs.String("out", "", "将CMS信封解密后的明文写入文件")
+	convert := fs.String("convert", "", "将GM/T 0009 SM2EnvelopedKey与SKF ENVELOPEDKEYBLOB互相转换，结果写入文件")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw, "PKCS7", "CMS")
+	if err != nil {
+		return err
+	}
+	if info, err := helper.ParseEnvelopedData(der); err == nil {
+		return emitCMSEnvelope(stdout, *asJSON, info, *key, *certFile, *out)
+	}
+	format, skf := envelopeFormatGM0009, (*envelopedKeyBlobResult)(nil)
+	env, err := gm.ParseSM2EnvelopedKey(der)
+	if err != nil {
+		blob, blobErr := gm.ParseEnvelopedKeyBlob(der)
+		if blobErr != nil {
+			return fmt.Errorf("信封结构解析失败: %v; 按SKF ENVELOPEDKEYBLOB解析失败: %v", err, blobErr)
+		}
+		if env, err = gm.EnvelopedKeyBlobToSM2EnvelopedKey(blob); err != nil {
+			return err
+		}
+		format, skf = envelopeFormatSKF, newEnvelopedKeyBlobResult(blob)
+	}
+
+	result := envelopeResult{
+		Format:              format,
+		SKF:                 skf,
+		SymAlgID:            env.SymAlgID.Algorithm.String(),
+		CipherX:             fmt.Sprintf("%064x", env.Sm2cipher.X),
+		CipherY:             fmt.Sprintf("%064x", env.Sm2cipher.Y),
+		CipherHash:          hex.EncodeToString(env.Sm2cipher.Hash),
+		CipherText:          hex.EncodeToString(env.Sm2cipher.CipherText),
+		PublicKey:           hex.EncodeToString(env.PublicKey.Bytes),
+		EncryptedPrivateKey: hex.EncodeToString(env.Sm2EncryptedPrivateKey.Bytes),
+	}
+
+	// 转换失败(如AES算法无GM/T 0006标识)时仅在指定 -convert 时报错
+	converted, err := convertEnvelopedKey(env, format)
+	if err != nil && *convert != "" {
+		return err
+	}
+	if converted != nil {
+		result.Converted = base64.StdEncoding.EncodeToString(converted)
+		if *convert != "" {
+			if err := os.WriteFile(*convert, converted, 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *key != "" {
+		keyBytes, err := decodeBinary([]byte(*key), "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+		if err != nil {
+			return fmt.Errorf("私钥解码失败: %v", err)
+		}
+		signPrivateKey, err := helper.ParseSM2PrivateKey(keyBytes)
+		if err != nil {
+			return fmt.Errorf("私钥解析失败: %v", err)
+		}
+		symKey, privateKey, err := gm.DecryptSM2EnvelopedKey(env, signPrivateKey)
+		match := err == nil
+		if errors.Is(err, gm.ErrPublicKeyMismatch) {
+			result.Warning = err.Error()
+		} else if err != nil {
+			return err
+		}
+		result.SymKey = hex.EncodeToString(symKey)
+		result.PrivateKey = hex.EncodeToString(privateKey)
+		result.PublicKeyMatch = &match
+	}
+
+	return emit(stdout, *asJSON, result, func(w io.Writer) {
+		if blob := result.SKF; blob != nil {
+			fmt.Fprintln(w, "格式: SKF ENVELOPEDKEYBLOB")
+			fmt.Fprintf(w, "Version: %d\n", blob.Version)
+			fmt.Fprintf(w, "ulSymmAlgID: %s\n", blob.SymmAlgID)
+			fmt.Fprintf(w, "ulBits: %d\n", blob.Bits)
+			fmt.Fprintf(w, "cbEncryptedPriKey: %s\n", blob.EncryptedPriKey)
+			fmt.Fprintf(w, "PubKey.BitLen: %d\n", blob.PubKeyBitLen)
+			fmt.Fprintf(w, "PubKey.XCoordinate: %s\n", blob.PubKeyX)
+			fmt.Fprintf(w, "PubKey.YCoordinate: %s\n", blob.PubKeyY)
+			fmt.Fprintf(w, "ECCCipherBlob.XCoordinate: %s\n", blob.CipherX)
+			fmt.Fprintf(w, "ECCCipherBlob.YCoordinate: %s\n", blob.CipherY)
+			fmt.Fprintf(w, "ECCCipherBlob.HASH: %s\n", blob.CipherHash)
+			fmt.Fprintf(w, "ECCCipherBlob.CipherLen: %d\n", blob.CipherLen)
+			fmt.Fprintf(w, "ECCCipherBlob.Cipher: %s\n", blob.Cipher)
+			if blob.Trailing > 0 {
+				fmt.Fprintf(w, "Cipher之后多余数据: %d 字节\n", blob.Trailing)
+			}
+			fmt.Fprintln(w, "--- 转换为 GM/T 0009 SM2EnvelopedKey ---")
+		}
+		fmt.Fprintf(w, "对称算法 OID: %s\n", result.SymAlgID)
+		fmt.Fprintf(w, "SM2Cipher.X: %s\n", result.CipherX)
+		fmt.Fprintf(w, "SM2Cipher.Y: %s\n", result.CipherY)
+		fmt.Fprintf(w, "SM2Cipher.Hash: %s\n", result.CipherHash)
+		fmt.Fprintf(w, "SM2Cipher.CipherText: %s\n", result.CipherText)
+		fmt.Fprintf(w, "SM2 公钥: %s\n", result.PublicKey)
+		fmt.Fprintf(w, "加密的私钥: %s\n", result.EncryptedPrivateKey)
+		if result.Converted != "" {
+			if result.SKF != nil {
+				fmt.Fprintf(w, "SM2EnvelopedKey (Base64): %s\n", result.Converted)
+			} else {
+				fmt.Fprintf(w, "SKF ENVELOPEDKEYBLOB (Base64): %s\n", result.Converted)
+			}
+		}
+		if result.PrivateKey != "" {
+			privateKey, _ := hex.DecodeString(result.PrivateKey)
+			fmt.Fprintf(w, "对称密钥 (Hex): %s\n", result.SymKey)
+			fmt.Fprintf(w, "私钥明文 (Hex): %s\n", result.PrivateKey)
+			fmt.Fprintf(w, "私钥明文 (Base64): %s\n", base64.StdEncoding.EncodeToString(privateKey))
+			if *result.PublicKeyMatch {
+				fmt.Fprintln(w, "公钥校验: 一致")
+			} else {
+				fmt.Fprintf(w, "公钥校验: 警告，%s\n", result.Warning)
+			}
+		}
+	})
+}
+
+// convertEnvelopedKey 转换为与输入相对的另一种信封格式：SKF输入输出SM2EnvelopedKey DER，反之输出ENVELOPEDKEYBLOB
+func convertEnvelopedKey(env *gm.SM2EnvelopedKey, format string) ([]byte, error) {
+	if format == envelopeFormatSKF {
+		return gm.MarshalSM2EnvelopedKey(env)
+	}
+	blob, err := gm.SM2EnvelopedKeyToEnvelopedKeyBlob(env)
+	if err != nil {
+		return nil, err
+	}
+	return gm.MarshalEnvelopedKeyBlob(blob), nil
+}
+
+func newEnvelopedKeyBlobResult(blob *gm.EnvelopedKeyBlob) *envelopedKeyBlobResult {
+	algID := fmt.Sprintf("0x%08X", blob.SymmAlgID)
+	if name, ok := gm.SGDAlgorithmNames[blob.SymmAlgID]; ok {
+		algID = fmt.Sprintf("%s (%s)", algID, name)
+	}
+	cipherBlob := blob.ECCCipherBlob
+	return &envelopedKeyBlobResult{
+		Version:         blob.Version,
+		SymmAlgID:       algID,
+		Bits:            blob.Bits,
+		EncryptedPriKey: hex.EncodeToString(blob.EncryptedPriKey[:]),
+		PubKeyBitLen:    blob.PubKey.BitLen,
+		PubKeyX:         hex.EncodeToString(blob.PubKey.XCoordinate[:]),
+		PubKeyY:         hex.EncodeToString(blob.PubKey.YCoordinate[:]),
+		CipherX:         hex.EncodeToString(cipherBlob.XCoordinate[:]),
+		CipherY:         hex.EncodeToString(cipherBlob.YCoordinate[:]),
+		CipherHash:      hex.EncodeToString(cipherBlob.Hash[:]),
+		CipherLen:       cipherBlob.CipherLen,
+		Cipher:          hex.EncodeToString(cipherBlob.Cipher),
+		Trailing:        blob.Trailing,
+	}
+}
+
+// emitCMSEnvelope 输出CMS数字信封结构，提供私钥时解密
+func emitCMSEnvelope(w io.Writer, asJSON bool, info *helper.EnvelopedDataInfo, key, certFile, out string) error {
+	result := cmsEnvelopeResult{
+		ContentType:                info.ContentType,
+		Version:                    info.Version,
+		EncryptedContentType:       info.EncryptedContentType,
+		ContentEncryptionAlgorithm: info.ContentEncryptionAlgorithm,
+		EncryptedContentSize:       len(info.EncryptedContent),
+	}
+	if info.MAC != nil {
+		result.MAC = hex.EncodeToString(info.MAC)
+	}
+	for _, recipient := range info.Recipients {
+		item := recipientResult{Type: recipient.Type, Version: recipient.Version, KeyEncryptionAlgorithm: recipient.KeyEncryptionAlgorithm}
+		if recipient.KeyEncryptionAlgorithm != "" {
+			item.Identifier = recipient.Identifier()
+		}
+		result.Recipients = append(result.Recipients, item)
+	}
+
+	var content []byte
+	if key != "" {
+		keyBytes, err := decodeBinary([]byte(key), "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+		if err != nil {
+			return fmt.Errorf("私钥解码失败: %v", err)
+		}
+		privateKey, err := helper.ParsePrivateKey(keyBytes)
+		if err != nil {
+			return fmt.Errorf("私钥解析失败: %v", err)
+		}
+		var cert *x509.Certificate
+		if certFile != "" {
+			certs, err := readCertificateFile(certFile)
+			if err != nil {
+				return fmt.Errorf("读取接收者证书失败: %v", err)
+			}
+			cert = certs[0]
+		}
+		var recipient *helper.CMSRecipientInfo
+		if content, recipient, err = helper.DecryptEnvelopedData(info, privateKey, cert); err != nil {
+			return err
+		}
+		for i := range info.Recipients {
+			if info.Recipients[i] == recipient {
+				result.DecryptedBy = i + 1
+			}
+		}
+		if out != "" {
+			if err := os.WriteFile(out, content, 0644); err != nil {
+				return err
+			}
+		}
+		result.Content = base64.StdEncoding.EncodeToString(content)
+	}
+
+	return emit(w, asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "内容类型: %s\n", result.ContentType)
+		fmt.Fprintf(w, "版本: %d\n", result.Version)
+		fmt.Fprintf(w, "加密内容类型: %s\n", result.EncryptedContentType)
+		fmt.Fprintf(w, "内容加密算法: %s\n", result.ContentEncryptionAlgorithm)
+		fmt.Fprintf(w, "密文长度: %d 字节\n", result.EncryptedContentSize)
+		if result.MAC != "" {
+			fmt.Fprintf(w, "认证码: %s\n", result.MAC)
+		}
+		for i, recipient := range result.Recipients {
+			fmt.Fprintf(w, "接收者 #%d: %s\n", i+1, recipient.Type)
+			if recipient.KeyEncryptionAlgorithm != "" {
+				fmt.Fprintf(w, "  标识: %s\n", recipient.Identifier)
+				fmt.Fprintf(w, "  密钥加密算法: %s\n", recipient.KeyEncryptionAlgorithm)
+			}
+		}
+		if content == nil {
+			return
+		}
+		fmt.Fprintf(w, "解密成功(接收者 #%d)，明文 %d 字节\n", result.DecryptedBy, len(content))
+		if out == "" {
+			if utf8.Valid(content) {
+				fmt.Fprintln(w, string(content))
+			} else {
+				fmt.Fprintf(w, "明文 (Hex): %s\n", hex.EncodeToString(content))
+			}
+		}
+	})
+}
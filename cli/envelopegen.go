@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"HeTu/gm"
+	"HeTu/helper"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zaneway/cain-go/sm2"
+)
+
+func init() {
+	register("envelopegen", "为SM2/RSA接收者证书生成CMS数字信封(EnvelopedData)，或以 -format gm0009/skf 为签名公钥生成GM/T 0009 SM2加密密钥信封或SKF ENVELOPEDKEYBLOB", runEnvelopeGen)
+}
+
+// 数字信封格式
+const (
+	envelopeFormatCMS    = "cms"
+	envelopeFormatGM0009 = "gm0009"
+	// envelopeFormatSKF GM/T 0016 SKF ENVELOPEDKEYBLOB
+	envelopeFormatSKF = "skf"
+)
+
+// sm2EnvelopedKeyResult GM/T 0009信封生成结果，附带加密密钥对及对称密钥供联调核对
+type sm2EnvelopedKeyResult struct {
+	PrivateKey string `json:"privateKey"`
+	PublicKey  string `json:"publicKey"`
+	SymKey     string `json:"symKey"`
+	Base64     string `json:"base64"`
+	Hex        string `json:"hex"`
+}
+
+func runEnvelopeGen(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("envelopegen")
+	format := fs.String("format", envelopeFormatCMS, "信封格式: cms、gm0009、skf")
+	certFile := fs.String("cert", "", "cms: 接收者证书文件，可包含多个证书，每个证书对应一个接收者；gm0009/skf: 接收方签名证书或SM2签名公钥文件")
+	cipherName := fs.String("cipher", "", "cms: 内容加密算法: "+strings.Join(helper.EnvelopeCiphers, "、")+"，缺省时SM2接收者使用SM4-CBC、RSA接收者使用AES-256-CBC")
+	gmOIDs := fs.Bool("gm", false, "cms: 使用GM/T 0010的envelopedData及data OID")
+	oaep := fs.Bool("oaep", false, "cms: RSA接收者使用RSAES-OAEP(SHA256)，缺省为PKCS#1 v1.5")
+	encKeyFile := fs.String("enckey", "", "gm0009/skf: 被封装的SM2加密私钥文件，缺省时随机生成")
+	out := fs.String("out", "", "将DER编码的数字信封写入文件")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *certFile == "" {
+		return fmt.Errorf("必须通过 -cert 指定接收者证书")
+	}
+
+	switch *format {
+	case envelopeFormatGM0009, envelopeFormatSKF:
+		return createSM2EnvelopedKey(stdout, *asJSON, *certFile, *encKeyFile, *out, *format == envelopeFormatSKF)
+	case envelopeFormatCMS:
+	default:
+		return fmt.Errorf("不支持的信封格式: %s", *format)
+	}
+
+	content, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	recipients, err := readCertificateFile(*certFile)
+	if err != nil {
+		return fmt.Errorf("读取接收者证书失败: %v", err)
+	}
+	envelope, err := helper.CreateEnvelopedData(content, recipients, helper.EnvelopedDataOptions{
+		Cipher:  strings.ToUpper(*cipherName),
+		GMOIDs:  *gmOIDs,
+		RSAOAEP: *oaep,
+	})
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, envelope, 0644)
+	}
+	fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(envelope))
+	return nil
+}
+
+// createSM2EnvelopedKey 以签名公钥封装加密私钥，生成GM/T 0009 SM2EnvelopedKey，skf为true时输出SKF ENVELOPEDKEYBLOB
+func createSM2EnvelopedKey(w io.Writer, asJSON bool, signFile, encKeyFile, out string, skf bool) error {
+	raw, err := os.ReadFile(signFile)
+	if err != nil {
+		return err
+	}
+	signData, err := decodeBinary(raw, "CERTIFICATE", "PUBLIC KEY")
+	if err != nil {
+		return err
+	}
+	signPublicKey, err := helper.ParseSM2PublicKey(signData)
+	if err != nil {
+		return fmt.Errorf("解析签名公钥失败: %v", err)
+	}
+
+	var encPrivateKey *sm2.PrivateKey
+	if encKeyFile != "" {
+		keyRaw, err := os.ReadFile(encKeyFile)
+		if err != nil {
+			return err
+		}
+		keyData, err := decodeBinary(keyRaw, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+		if err != nil {
+			return err
+		}
+		if encPrivateKey, err = helper.ParseSM2PrivateKey(keyData); err != nil {
+			return fmt.Errorf("解析加密私钥失败: %v", err)
+		}
+	} else if encPrivateKey, err = sm2.GenerateKey(rand.Reader); err != nil {
+		return err
+	}
+
+	env, symKey, err := gm.CreateSM2EnvelopedKey(encPrivateKey, signPublicKey)
+	if err != nil {
+		return err
+	}
+	der, err := gm.MarshalSM2EnvelopedKey(env)
+	if err != nil {
+		return err
+	}
+	if skf {
+		blob, err := gm.SM2EnvelopedKeyToEnvelopedKeyBlob(env)
+		if err != nil {
+			return err
+		}
+		der = gm.MarshalEnvelopedKeyBlob(blob)
+	}
+	if out != "" {
+		if err := os.WriteFile(out, der, 0644); err != nil {
+			return err
+		}
+	}
+	result := sm2EnvelopedKeyResult{
+		PrivateKey: hex.EncodeToString(encPrivateKey.D.FillBytes(make([]byte, 32))),
+		PublicKey:  hex.EncodeToString(env.PublicKey.Bytes),
+		SymKey:     hex.EncodeToString(symKey),
+		Base64:     base64.StdEncoding.EncodeToString(der),
+		Hex:        hex.EncodeToString(der),
+	}
+	return emit(w, asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "加密私钥 (Hex): %s\n", result.PrivateKey)
+		fmt.Fprintf(w, "加密公钥 (Hex): %s\n", result.PublicKey)
+		fmt.Fprintf(w, "对称密钥 (Hex): %s\n", result.SymKey)
+		fmt.Fprintf(w, "信封 (Base64): %s\n", result.Base64)
+		fmt.Fprintf(w, "信封 (Hex): %s\n", result.Hex)
+	})
+}
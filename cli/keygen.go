@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/security"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	register("keygen", "按security包中的规格生成对称或非对称密钥", runKeygen)
+}
+
+func runKeygen(args []string, stdout io.Writer) error {
+	fs, _, asJSON := newFlagSet("keygen")
+	specs := append(append([]string{}, security.ALL_ASYM_KEYS...), security.ALL_SYM_KEYS...)
+	spec := fs.String("alg", security.SM2_256, "密钥规格: "+strings.Join(specs, "/"))
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	key, err := helper.GenerateKey(strings.ToUpper(*spec))
+	if err != nil {
+		return err
+	}
+
+	return emit(stdout, *asJSON, key, func(w io.Writer) {
+		fmt.Fprintf(w, "Algorithm: %s (%d bits)\n", key.Algorithm, key.Bits)
+		label := "私钥"
+		if len(key.Public) == 0 {
+			label = "密钥"
+		}
+		printKeyEncodings(w, label, key.Private)
+		printKeyEncodings(w, "公钥", key.Public)
+	})
+}
+
+// printKeyEncodings 裸密钥以Hex输出，其余以Base64及PEM输出
+func printKeyEncodings(w io.Writer, label string, encodings []helper.KeyEncoding) {
+	for _, e := range encodings {
+		if e.PEMType == "" {
+			fmt.Fprintf(w, "%s %s (Hex): %s\n", label, e.Name, hex.EncodeToString(e.Data))
+			continue
+		}
+		fmt.Fprintf(w, "%s %s (Base64): %s\n", label, e.Name, base64.StdEncoding.EncodeToString(e.Data))
+		fmt.Fprint(w, e.PEM())
+	}
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("ocsp", "解析OCSP响应，显示证书状态并验证响应签名(支持委托响应者及SM2)", runOcsp)
+}
+
+type ocspResult struct {
+	ResponseStatus     string              `json:"responseStatus"`
+	ResponseType       string              `json:"responseType,omitempty"`
+	ResponderID        string              `json:"responderId,omitempty"`
+	ProducedAt         string              `json:"producedAt,omitempty"`
+	Nonce              string              `json:"nonce,omitempty"`
+	SignatureAlgorithm string              `json:"signatureAlgorithm,omitempty"`
+	SignatureStatus    string              `json:"signatureStatus"`
+	SignatureError     string              `json:"signatureError,omitempty"`
+	Signer             string              `json:"signer,omitempty"`
+	Delegated          bool                `json:"delegated,omitempty"`
+	Certificates       []string            `json:"certificates,omitempty"`
+	Responses          []ocspSingleResult  `json:"responses,omitempty"`
+	Problems           []string            `json:"problems,omitempty"`
+	Request            *ocspRequestSummary `json:"request,omitempty"`
+}
+
+type ocspSingleResult struct {
+	SerialNumber     string `json:"serialNumber"`
+	HashAlgorithm    string `json:"hashAlgorithm"`
+	IssuerNameHash   string `json:"issuerNameHash"`
+	IssuerKeyHash    string `json:"issuerKeyHash"`
+	Status           string `json:"status"`
+	RevocationTime   string `json:"revocationTime,omitempty"`
+	RevocationReason string `json:"revocationReason,omitempty"`
+	ThisUpdate       string `json:"thisUpdate"`
+	NextUpdate       string `json:"nextUpdate,omitempty"`
+}
+
+type ocspRequestSummary struct {
+	Nonce        string   `json:"nonce,omitempty"`
+	SerialNumber []string `json:"serialNumbers"`
+	Base64       string   `json:"base64,omitempty"`
+}
+
+func runOcsp(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("ocsp")
+	issuerFile := fs.String("issuer", "", "被查询证书的颁发者证书文件，用于验证响应签名及响应者授权")
+	reqFile := fs.String("req", "", "对应的OCSP请求文件，用于检查nonce及CertID")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw)
+	if err != nil {
+		return err
+	}
+	info, err := helper.ParseOCSPResponse(der)
+	if err != nil {
+		return err
+	}
+	issuer, err := readOptionalIssuer(*issuerFile)
+	if err != nil {
+		return err
+	}
+	var req *helper.OCSPRequestInfo
+	if *reqFile != "" {
+		reqRaw, err := os.ReadFile(*reqFile)
+		if err != nil {
+			return err
+		}
+		reqDER, err := decodeBinary(reqRaw)
+		if err != nil {
+			return err
+		}
+		if req, err = helper.ParseOCSPRequest(reqDER); err != nil {
+			return err
+		}
+	}
+	return emitOCSPResponse(stdout, *asJSON, info, issuer, req, nil)
+}
+
+// readOptionalIssuer 读取颁发者证书文件，未指定时返回nil
+func readOptionalIssuer(path string) (*x509.Certificate, error) {
+	if path == "" {
+		return nil, nil
+	}
+	certs, err := readCertificateFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取颁发者证书失败: %v", err)
+	}
+	return certs[0], nil
+}
+
+// emitOCSPResponse 验证并输出OCSP响应，req不为空时检查响应与请求是否对应
+func emitOCSPResponse(w io.Writer, asJSON bool, info *helper.OCSPResponseInfo, issuer *x509.Certificate, req *helper.OCSPRequestInfo, summary *ocspRequestSummary) error {
+	result := ocspResult{
+		ResponseStatus:  info.ResponseStatusText(),
+		SignatureStatus: info.SignatureStatus,
+		Request:         summary,
+	}
+	var verifyErr error
+	if info.ResponseStatus == 0 {
+		// 签名验证失败记录在结果中，不中断输出
+		verifyErr = helper.VerifyOCSPResponse(info, issuer)
+		result.ResponseType = info.ResponseType
+		result.ResponderID = info.ResponderID()
+		result.ProducedAt = info.ProducedAt.Format(util.DateTime)
+		result.Nonce = hex.EncodeToString(info.Nonce)
+		result.SignatureAlgorithm = info.SignatureAlgorithm
+		result.SignatureStatus = info.SignatureStatus
+		result.SignatureError = info.SignatureError
+		result.Delegated = info.Delegated
+		if info.Signer != nil {
+			result.Signer = info.Signer.Subject.String()
+		}
+		for _, cert := range info.Certificates {
+			result.Certificates = append(result.Certificates, cert.Subject.String())
+		}
+		for _, response := range info.Responses {
+			result.Responses = append(result.Responses, toOCSPSingleResult(response))
+		}
+		result.Problems = info.CheckFreshness(time.Now())
+		if req != nil {
+			result.Problems = append(result.Problems, info.CheckRequest(req)...)
+		}
+	}
+
+	err := emit(w, asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "响应状态: %s\n", result.ResponseStatus)
+		if info.ResponseStatus != 0 {
+			return
+		}
+		fmt.Fprintf(w, "响应类型: %s\n", result.ResponseType)
+		fmt.Fprintf(w, "响应者: %s\n", result.ResponderID)
+		fmt.Fprintf(w, "产生时间: %s\n", result.ProducedAt)
+		if result.Nonce != "" {
+			fmt.Fprintf(w, "Nonce: %s\n", result.Nonce)
+		}
+		fmt.Fprintf(w, "签名算法: %s\n", result.SignatureAlgorithm)
+		fmt.Fprintf(w, "签名状态: %s\n", result.SignatureStatus)
+		if result.Signer != "" {
+			if result.Delegated {
+				fmt.Fprintf(w, "签名证书: %s (委托响应者)\n", result.Signer)
+			} else {
+				fmt.Fprintf(w, "签名证书: %s\n", result.Signer)
+			}
+		}
+		for _, subject := range result.Certificates {
+			fmt.Fprintf(w, "附带证书: %s\n", subject)
+		}
+		for i, response := range result.Responses {
+			fmt.Fprintf(w, "%d. 序列号: %s, 状态: %s", i+1, response.SerialNumber, response.Status)
+			if response.RevocationTime != "" {
+				fmt.Fprintf(w, ", 吊销时间: %s", response.RevocationTime)
+			}
+			if response.RevocationReason != "" {
+				fmt.Fprintf(w, ", 原因: %s", response.RevocationReason)
+			}
+			fmt.Fprintf(w, "\n   CertID: %s, 颁发者名称摘要: %s, 颁发者公钥摘要: %s\n", response.HashAlgorithm, response.IssuerNameHash, response.IssuerKeyHash)
+			if response.NextUpdate != "" {
+				fmt.Fprintf(w, "   本次更新时间: %s, 下次更新时间: %s\n", response.ThisUpdate, response.NextUpdate)
+			} else {
+				fmt.Fprintf(w, "   本次更新时间: %s, 下次更新时间: 未设置\n", response.ThisUpdate)
+			}
+		}
+		for _, problem := range result.Problems {
+			fmt.Fprintf(w, "警告: %s\n", problem)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.ResponseStatus != 0:
+		return checkFailed("响应状态为%s", result.ResponseStatus)
+	case verifyErr != nil:
+		return checkFailed("响应签名验证失败，%v", verifyErr)
+	}
+	for _, response := range info.Responses {
+		if response.Status == helper.OCSPStatusRevoked {
+			return checkFailed("证书 %X 已被吊销", response.CertID.SerialNumber)
+		}
+	}
+	return nil
+}
+
+func toOCSPSingleResult(response helper.OCSPSingleResponse) ocspSingleResult {
+	result := ocspSingleResult{
+		SerialNumber:   fmt.Sprintf("%X", response.CertID.SerialNumber),
+		HashAlgorithm:  response.CertID.HashAlgorithm,
+		IssuerNameHash: hex.EncodeToString(response.CertID.IssuerNameHash),
+		IssuerKeyHash:  hex.EncodeToString(response.CertID.IssuerKeyHash),
+		Status:         response.StatusText(),
+		ThisUpdate:     response.ThisUpdate.Format(util.DateTime),
+	}
+	if response.Status == helper.OCSPStatusRevoked {
+		result.RevocationTime = response.RevocationTime.Format(util.DateTime)
+		if response.ReasonCode != helper.CRLReasonOmitted {
+			result.RevocationReason = helper.RevocationReasonText(response.ReasonCode)
+		}
+	}
+	if !response.NextUpdate.IsZero() {
+		result.NextUpdate = response.NextUpdate.Format(util.DateTime)
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	register("ocspreq", "由证书及颁发者构造OCSP请求(SHA1/SM3 CertID、nonce)，可发送至OCSP服务并验证响应", runOcspReq)
+}
+
+func runOcspReq(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("ocspreq")
+	issuerFile := fs.String("issuer", "", "颁发者证书文件")
+	serial := fs.String("serial", "", "被查询证书序列号(Hex)，多个以逗号分隔，指定后可不提供证书")
+	hashAlgorithm := fs.String("hash", helper.OCSPHashSHA1, "CertID摘要算法: SHA1、SM3或SHA256")
+	noNonce := fs.Bool("no-nonce", false, "请求不包含nonce扩展")
+	out := fs.String("out", "", "将DER编码的请求写入文件")
+	url := fs.String("url", "", "OCSP服务地址，指定后发送请求并验证响应")
+	send := fs.Bool("send", false, "发送至证书AIA中的OCSP地址，-url优先")
+	respOut := fs.String("resp", "", "将DER编码的响应写入文件")
+	timeout := fs.Duration("timeout", 15*time.Second, "发送请求的超时时间")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *issuerFile == "" {
+		return fmt.Errorf("必须通过 -issuer 指定颁发者证书")
+	}
+	issuer, err := readOptionalIssuer(*issuerFile)
+	if err != nil {
+		return err
+	}
+
+	template := &helper.OCSPRequestTemplate{HashAlgorithm: strings.ToUpper(*hashAlgorithm)}
+	var ocspURLs []string
+	for _, s := range helper.SplitSerialNumbers(*serial) {
+		n, err := helper.ConvertSerialNumberToBigInt(s)
+		if err != nil {
+			return err
+		}
+		template.SerialNumbers = append(template.SerialNumbers, n)
+	}
+	if len(template.SerialNumbers) == 0 {
+		raw, err := readInput(*in, fs)
+		if err != nil {
+			return err
+		}
+		certs, err := decodeCertificates(raw)
+		if err != nil {
+			return err
+		}
+		for _, cert := range certs {
+			template.SerialNumbers = append(template.SerialNumbers, cert.SerialNumber)
+			ocspURLs = append(ocspURLs, cert.OCSPServer...)
+		}
+	}
+	if !*noNonce {
+		if template.Nonce, err = helper.NewOCSPNonce(); err != nil {
+			return err
+		}
+	}
+	request, err := helper.CreateOCSPRequest(template, issuer)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		if err := os.WriteFile(*out, request, 0644); err != nil {
+			return err
+		}
+	}
+
+	summary := &ocspRequestSummary{Nonce: hex.EncodeToString(template.Nonce)}
+	for _, n := range template.SerialNumbers {
+		summary.SerialNumber = append(summary.SerialNumber, fmt.Sprintf("%X", n))
+	}
+	target := *url
+	if target == "" && *send {
+		if len(ocspURLs) == 0 {
+			return fmt.Errorf("证书未包含AIA OCSP地址，请通过 -url 指定")
+		}
+		target = ocspURLs[0]
+	}
+	if target == "" {
+		summary.Base64 = base64.StdEncoding.EncodeToString(request)
+		return emit(stdout, *asJSON, summary, func(w io.Writer) {
+			fmt.Fprintf(w, "序列号: %s\n", strings.Join(summary.SerialNumber, ", "))
+			if summary.Nonce != "" {
+				fmt.Fprintf(w, "Nonce: %s\n", summary.Nonce)
+			}
+			for _, u := range ocspURLs {
+				fmt.Fprintf(w, "OCSP地址: %s\n", u)
+			}
+			fmt.Fprintln(w, summary.Base64)
+		})
+	}
+
+	response, err := helper.QueryOCSP(target, request, *timeout)
+	if err != nil {
+		return err
+	}
+	if *respOut != "" {
+		if err := os.WriteFile(*respOut, response, 0644); err != nil {
+			return err
+		}
+	}
+	info, err := helper.ParseOCSPResponse(response)
+	if err != nil {
+		return err
+	}
+	req, err := helper.ParseOCSPRequest(request)
+	if err != nil {
+		return err
+	}
+	return emitOCSPResponse(stdout, *asJSON, info, issuer, req, summary)
+}
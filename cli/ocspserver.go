@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func init() {
+	register("ocspserver", "在本地启动OCSP响应服务，按CRL或手工状态表应答并记录每个请求", runOcspServer)
+}
+
+type ocspLogResult struct {
+	Time           string   `json:"time"`
+	RemoteAddr     string   `json:"remoteAddr"`
+	Method         string   `json:"method"`
+	SerialNumbers  []string `json:"serialNumbers,omitempty"`
+	Statuses       []string `json:"statuses,omitempty"`
+	Nonce          string   `json:"nonce,omitempty"`
+	ResponseStatus int      `json:"responseStatus"`
+	Error          string   `json:"error,omitempty"`
+}
+
+func runOcspServer(args []string, stdout io.Writer) error {
+	fs, _, asJSON := newFlagSet("ocspserver")
+	issuerFile := fs.String("issuer", "", "被查询证书的颁发者证书文件")
+	certFile := fs.String("cert", "", "委托响应者证书文件，缺省时由颁发者直接签名")
+	keyFile := fs.String("key", "", "响应签名私钥文件(SM2或RSA)")
+	crlFile := fs.String("crl", "", "作为状态来源的完整CRL文件，须由 -issuer 签发且签名有效，未列入的证书返回good")
+	statusFile := fs.String("status", "", "手工状态表文件，每行\"序列号,good|revoked|unknown[,吊销原因[,吊销时间]]\"，优先于CRL")
+	addr := fs.String("addr", helper.DefaultOCSPResponderAddr, "监听地址")
+	validity := fs.Duration("validity", time.Hour, "手工状态响应的有效期，0表示不包含nextUpdate")
+	byKey := fs.Bool("bykey", false, "以公钥摘要标识响应者(默认使用主题名称)")
+	requests := fs.Int("n", 0, "处理指定数量的请求后退出，0表示持续运行直至中断")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *issuerFile == "" || *keyFile == "" {
+		return fmt.Errorf("必须通过 -issuer 及 -key 指定颁发者证书和签名私钥")
+	}
+	if *crlFile == "" && *statusFile == "" {
+		return fmt.Errorf("必须通过 -crl 或 -status 指定状态来源")
+	}
+
+	config := helper.OCSPResponderConfig{Validity: *validity, ResponderIDByKey: *byKey}
+	var err error
+	if config.Issuer, err = readOptionalIssuer(*issuerFile); err != nil {
+		return err
+	}
+	if *certFile != "" {
+		certs, err := readCertificateFile(*certFile)
+		if err != nil {
+			return fmt.Errorf("读取响应者证书失败: %v", err)
+		}
+		config.Certificate = certs[0]
+	}
+	if config.Key, err = readPrivateKeyFile(*keyFile); err != nil {
+		return fmt.Errorf("读取签名私钥失败: %v", err)
+	}
+	if *crlFile != "" {
+		if config.CRL, err = readCRLInput(*crlFile, fs, config.Issuer); err != nil {
+			return fmt.Errorf("读取CRL失败: %v", err)
+		}
+	}
+	if *statusFile != "" {
+		text, err := os.ReadFile(*statusFile)
+		if err != nil {
+			return err
+		}
+		if config.Statuses, err = helper.ParseOCSPStatusTable(string(text)); err != nil {
+			return err
+		}
+	}
+	responder, err := helper.NewOCSPResponder(config)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	handled := 0
+	responder.Log = func(log helper.OCSPRequestLog) {
+		if *asJSON {
+			result := ocspLogResult{
+				Time:           util.ToBeijingTime(log.Time).Format(util.DateTime),
+				RemoteAddr:     log.RemoteAddr,
+				Method:         log.Method,
+				SerialNumbers:  log.SerialNumbers,
+				Statuses:       log.Statuses,
+				Nonce:          hex.EncodeToString(log.Nonce),
+				ResponseStatus: log.ResponseStatus,
+				Error:          log.Error,
+			}
+			json.NewEncoder(stdout).Encode(result)
+		} else {
+			fmt.Fprintln(stdout, log.String())
+		}
+		handled++
+		if handled == *requests {
+			close(done)
+		}
+	}
+	server, url, err := responder.Listen(*addr)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	// 启动信息输出到标准错误，-json时标准输出只包含请求日志
+	for _, warning := range responder.Warnings {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", warning)
+	}
+	fmt.Fprintf(os.Stderr, "OCSP服务已启动: %s，按Ctrl+C停止\n", url)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	select {
+	case <-done:
+		// 等待最后一个响应写出
+		time.Sleep(100 * time.Millisecond)
+	case <-interrupt:
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("p7b", "解析PKCS#7证书链及签名消息，验证签名者的签名(支持分离式签名、SM2及副署签名)", runP7b)
+}
+
+// p7bResult P7B解析结果
+type p7bResult struct {
+	Certificates []certResult      `json:"certificates"`
+	CRLCount     int               `json:"crlCount"`
+	ContentSize  int               `json:"contentSize"`
+	SignedData   *signedDataResult `json:"signedData,omitempty"`
+}
+
+// signedDataResult 签名消息的签名者及验证结果
+type signedDataResult struct {
+	Version          int            `json:"version"`
+	ContentType      string         `json:"contentType"`
+	DigestAlgorithms []string       `json:"digestAlgorithms"`
+	Detached         bool           `json:"detached"`
+	Signers          []signerResult `json:"signers"`
+}
+
+type signerResult struct {
+	Version            int               `json:"version"`
+	Identifier         string            `json:"identifier"`
+	DigestAlgorithm    string            `json:"digestAlgorithm"`
+	SignatureAlgorithm string            `json:"signatureAlgorithm"`
+	Certificate        string            `json:"certificate,omitempty"`
+	SignatureStatus    string            `json:"signatureStatus"`
+	SignatureError     string            `json:"signatureError,omitempty"`
+	SignedAttributes   []attributeResult `json:"signedAttributes,omitempty"`
+	UnsignedAttributes []attributeResult `json:"unsignedAttributes,omitempty"`
+	Countersignatures  []signerResult    `json:"countersignatures,omitempty"`
+	Warnings           []string          `json:"warnings,omitempty"`
+}
+
+type attributeResult struct {
+	Type   string   `json:"type"`
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+func runP7b(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("p7b")
+	contentFile := fs.String("content", "", "分离式签名的原文文件")
+	certFile := fs.String("cert", "", "签名消息未附带签名者证书时使用的证书文件")
+	userID := fs.String("userid", "", "验证SM2签名使用的用户ID，缺省为 "+string(helper.SM2DefaultUserID))
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw, "PKCS7", "CMS")
+	if err != nil {
+		return err
+	}
+	// 签名消息另行解析签名者信息，仅含证书的P7B没有签名者
+	signedData, _ := helper.ParseSignedData(der)
+	p7b, err := x509.ParsePKCS7(der)
+	if err != nil {
+		// cain-go无法解析其中的证书(如含关键名称约束)或GM/T 0010签名消息时仅提取证书
+		if certs, certErr := helper.ParseCertificates(der); certErr == nil {
+			p7b, err = &x509.PKCS7{Certificates: certs}, nil
+		} else if signedData != nil {
+			p7b, err = &x509.PKCS7{Certificates: signedData.Certificates, Content: signedData.Content}, nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("P7B证书链解析失败: %v", err)
+	}
+
+	result := p7bResult{CRLCount: len(p7b.CRLs), ContentSize: len(p7b.Content)}
+	for _, certificate := range p7b.Certificates {
+		result.Certificates = append(result.Certificates, buildCertResult(certificate))
+	}
+	// 签名验证失败时仍完整输出结果，最后以非零退出码结束
+	var verifyErr error
+	if signedData != nil && len(signedData.Signers) > 0 {
+		certs, err := readOptionalCertificates(*certFile)
+		if err != nil {
+			return err
+		}
+		var content []byte
+		if *contentFile != "" {
+			if content, err = os.ReadFile(*contentFile); err != nil {
+				return err
+			}
+		}
+		if signedData.Detached && content == nil {
+			fmt.Fprintln(os.Stderr, "警告: 分离式签名未通过 -content 提供原文，未验证签名")
+		} else {
+			// 各签名者的结果记录在SignatureStatus中
+			signedData.SM2UserID = []byte(*userID)
+			verifyErr = helper.VerifySignedData(signedData, content, certs)
+		}
+		result.SignedData = buildSignedDataResult(signedData)
+	}
+	err = emit(stdout, *asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "证书数量: %d\n", len(result.Certificates))
+		fmt.Fprintf(w, "CRL数量: %d\n", result.CRLCount)
+		if result.ContentSize > 0 {
+			fmt.Fprintf(w, "内容长度: %d 字节\n", result.ContentSize)
+		}
+		for i, certificate := range result.Certificates {
+			fmt.Fprintf(w, "证书 #%d\n", i+1)
+			fmt.Fprintf(w, "  SerialNumber: %s\n", certificate.SerialNumber)
+			fmt.Fprintf(w, "  SubjectName:  %s\n", certificate.Subject)
+			fmt.Fprintf(w, "  IssueName:    %s\n", certificate.Issuer)
+			fmt.Fprintf(w, "  NotBefore:    %s\n", certificate.NotBefore)
+			fmt.Fprintf(w, "  NotAfter:     %s\n", certificate.NotAfter)
+		}
+		if sd := result.SignedData; sd != nil {
+			fmt.Fprintf(w, "内容类型: %s\n", sd.ContentType)
+			fmt.Fprintf(w, "摘要算法: %s\n", strings.Join(sd.DigestAlgorithms, ", "))
+			if sd.Detached {
+				fmt.Fprintln(w, "分离式签名: 是")
+			}
+			for i, signer := range sd.Signers {
+				fmt.Fprintf(w, "签名者 #%d\n", i+1)
+				writeSignerResult(w, signer, "  ")
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if verifyErr != nil {
+		return checkFailed("签名验证失败，%v", verifyErr)
+	}
+	return nil
+}
+
+func buildSignedDataResult(info *helper.SignedDataInfo) *signedDataResult {
+	result := &signedDataResult{
+		Version:          info.Version,
+		ContentType:      info.ContentType,
+		DigestAlgorithms: info.DigestAlgorithms,
+		Detached:         info.Detached,
+	}
+	for _, signer := range info.Signers {
+		result.Signers = append(result.Signers, buildSignerResult(signer))
+	}
+	return result
+}
+
+func buildSignerResult(signer *helper.CMSSignerInfo) signerResult {
+	result := signerResult{
+		Version:            signer.Version,
+		Identifier:         signer.Identifier(),
+		DigestAlgorithm:    signer.DigestAlgorithm,
+		SignatureAlgorithm: signer.SignatureAlgorithm,
+		SignatureStatus:    signer.SignatureStatus,
+		SignatureError:     signer.SignatureError,
+		Warnings:           signer.Warnings,
+	}
+	if signer.Certificate != nil {
+		result.Certificate = signer.Certificate.Subject.String()
+	}
+	for _, attr := range signer.SignedAttributes {
+		result.SignedAttributes = append(result.SignedAttributes, attributeResult{Type: attr.Type, Name: attr.Name, Values: attr.FormatValues()})
+	}
+	for _, attr := range signer.UnsignedAttributes {
+		result.UnsignedAttributes = append(result.UnsignedAttributes, attributeResult{Type: attr.Type, Name: attr.Name, Values: attr.FormatValues()})
+	}
+	for _, countersignature := range signer.Countersignatures {
+		result.Countersignatures = append(result.Countersignatures, buildSignerResult(countersignature))
+	}
+	return result
+}
+
+func writeSignerResult(w io.Writer, signer signerResult, indent string) {
+	fmt.Fprintf(w, "%s标识: %s\n", indent, signer.Identifier)
+	fmt.Fprintf(w, "%s摘要算法: %s, 签名算法: %s\n", indent, signer.DigestAlgorithm, signer.SignatureAlgorithm)
+	if signer.Certificate != "" {
+		fmt.Fprintf(w, "%s签名证书: %s\n", indent, signer.Certificate)
+	}
+	fmt.Fprintf(w, "%s签名状态: %s\n", indent, signer.SignatureStatus)
+	if signer.SignatureError != "" {
+		fmt.Fprintf(w, "%s签名错误: %s\n", indent, signer.SignatureError)
+	}
+	for _, attr := range signer.SignedAttributes {
+		fmt.Fprintf(w, "%s签名属性 %s: %s\n", indent, attr.Name, strings.Join(attr.Values, "; "))
+	}
+	for _, attr := range signer.UnsignedAttributes {
+		fmt.Fprintf(w, "%s非签名属性 %s: %s\n", indent, attr.Name, strings.Join(attr.Values, "; "))
+	}
+	for i, countersignature := range signer.Countersignatures {
+		fmt.Fprintf(w, "%s副署签名 #%d\n", indent, i+1)
+		writeSignerResult(w, countersignature, indent+"  ")
+	}
+	for _, warning := range signer.Warnings {
+		fmt.Fprintf(w, "%s警告: %s\n", indent, warning)
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	register("p7bsign", "以证书及SM2/RSA私钥生成PKCS#7/CMS签名消息，支持分离式签名、GM/T 0010 OID及自定义SM2用户ID", runP7bSign)
+}
+
+func runP7bSign(args []string, stdout io.Writer) error {
+	fs, in, _ := newFlagSet("p7bsign")
+	certFile := fs.String("cert", "", "签名证书文件，可包含上级证书，附带证书时一同附带")
+	keyFile := fs.String("key", "", "签名私钥文件(SM2或RSA)")
+	hashAlgorithm := fs.String("hash", "", "摘要算法，缺省时SM2使用SM3、RSA使用SHA256")
+	detached := fs.Bool("detached", false, "生成分离式签名，不封装原文")
+	noAttributes := fs.Bool("noattr", false, "直接对原文签名，不包含签名属性")
+	noCerts := fs.Bool("nocerts", false, "不在签名消息中附带证书")
+	gm := fs.Bool("gm", false, "使用GM/T 0010的data及signedData OID")
+	userID := fs.String("userid", "", "SM2签名使用的用户ID，缺省为 "+string(helper.SM2DefaultUserID))
+	signingTime := fs.String("time", "", "signingTime属性，格式 "+util.DateTime+"(北京时间)，缺省为当前时间")
+	noTime := fs.Bool("notime", false, "不包含signingTime属性")
+	out := fs.String("out", "", "将DER编码的签名消息写入文件")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *certFile == "" || *keyFile == "" {
+		return fmt.Errorf("必须通过 -cert 及 -key 指定签名证书和私钥")
+	}
+
+	content, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	certs, err := readCertificateFile(*certFile)
+	if err != nil {
+		return fmt.Errorf("读取签名证书失败: %v", err)
+	}
+	key, err := readPrivateKeyFile(*keyFile)
+	if err != nil {
+		return fmt.Errorf("读取签名私钥失败: %v", err)
+	}
+
+	opts := helper.SignedDataOptions{
+		HashAlgorithm:      strings.ToUpper(*hashAlgorithm),
+		Detached:           *detached,
+		NoSignedAttributes: *noAttributes,
+		GMOIDs:             *gm,
+		SM2UserID:          []byte(*userID),
+	}
+	if !*noCerts {
+		opts.Certificates = certs
+	}
+	if !*noTime && !*noAttributes {
+		if opts.SigningTime, err = util.ParseDateTime(*signingTime); err != nil {
+			return fmt.Errorf("签名时间格式应为 %s", util.DateTime)
+		}
+		if opts.SigningTime.IsZero() {
+			opts.SigningTime = time.Now()
+		}
+	}
+	signed, err := helper.CreateSignedData(key, certs[0], content, opts)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, signed, 0644)
+	}
+	fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(signed))
+	return nil
+}
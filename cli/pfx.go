@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	register("pfx", "使用口令解析PKCS#12/PFX(含SM2)，可导出私钥与证书链", runPfx)
+}
+
+func runPfx(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("pfx")
+	password := fs.String("pass", "", "PFX口令")
+	export := fs.String("export", "", "导出私钥与证书链: pem 或 der(Base64)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw, "PKCS12")
+	if err != nil {
+		return err
+	}
+	info, err := helper.ParsePFX(der, *password)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(*export) {
+	case "":
+	case "pem":
+		fmt.Fprint(stdout, info.ExportPEM())
+		return nil
+	case "der":
+		if info.PrivateKeyDER != nil {
+			fmt.Fprintf(stdout, "PrivateKey: %s\n", base64.StdEncoding.EncodeToString(info.PrivateKeyDER))
+		}
+		for i, cert := range info.Certificates {
+			fmt.Fprintf(stdout, "Certificate #%d: %s\n", i+1, base64.StdEncoding.EncodeToString(cert.Raw))
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", *export)
+	}
+
+	return emit(stdout, *asJSON, info, func(w io.Writer) {
+		if info.MACAlgorithm != "" {
+			fmt.Fprintf(w, "MAC: %s, %d次迭代, Salt %s\n", info.MACAlgorithm, info.MACIterations, info.MACSalt)
+		} else {
+			fmt.Fprintln(w, "MAC: 无")
+		}
+		for i, safe := range info.Safes {
+			fmt.Fprintf(w, "ContentInfo #%d: %s", i+1, safe.ContentType)
+			if safe.EncryptionAlgorithm != "" {
+				fmt.Fprintf(w, " [%s]", safe.EncryptionAlgorithm)
+			}
+			fmt.Fprintln(w)
+			if safe.Error != "" {
+				fmt.Fprintf(w, "  错误: %s\n", safe.Error)
+			}
+			for _, bag := range safe.Bags {
+				printPFXBag(w, bag)
+			}
+		}
+	})
+}
+
+// printPFXBag 打印单个SafeBag
+func printPFXBag(w io.Writer, bag *helper.PFXBag) {
+	fmt.Fprintf(w, "  %s\n", bag.TypeName)
+	if bag.EncryptionAlgorithm != "" {
+		fmt.Fprintf(w, "    加密算法:     %s\n", bag.EncryptionAlgorithm)
+	}
+	if bag.KeyAlgorithm != "" {
+		fmt.Fprintf(w, "    密钥算法:     %s (%d bits)\n", bag.KeyAlgorithm, bag.KeySize)
+	}
+	if bag.ValueType != "" {
+		fmt.Fprintf(w, "    值类型:       %s\n", bag.ValueType)
+	}
+	if bag.Subject != "" {
+		fmt.Fprintf(w, "    SubjectName:  %s\n", bag.Subject)
+		fmt.Fprintf(w, "    IssueName:    %s\n", bag.Issuer)
+		fmt.Fprintf(w, "    SerialNumber: %s\n", bag.SerialNumber)
+	}
+	for _, attr := range bag.Attributes {
+		fmt.Fprintf(w, "    %s: %s\n", attr.Name, strings.Join(attr.Values, ", "))
+	}
+	if bag.Error != "" {
+		fmt.Fprintf(w, "    错误: %s\n", bag.Error)
+	}
+}
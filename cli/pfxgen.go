@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("pfxgen", "由证书、私钥及CA证书链生成PKCS#12/PFX，可选择加密方案", runPfxGen)
+}
+
+func runPfxGen(args []string, stdout io.Writer) error {
+	fs, in, _ := newFlagSet("pfxgen")
+	keyFile := fs.String("key", "", "私钥文件路径")
+	chainFile := fs.String("chain", "", "CA证书链文件路径，支持多个PEM证书或P7B")
+	password := fs.String("pass", "", "PFX口令")
+	friendlyName := fs.String("name", "", "friendlyName属性")
+	keyID := fs.String("keyid", "", "localKeyId属性(Hex)，缺省为证书SHA-1指纹")
+	encryption := fs.String("alg", helper.PFXEncryptionAES256, "加密方案: "+strings.Join(helper.PFXEncryptions, ", "))
+	iterations := fs.Int("iter", 2048, "口令加密迭代次数")
+	macIterations := fs.Int("maciter", 2048, "MAC迭代次数")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return fmt.Errorf("必须通过 -key 指定私钥文件")
+	}
+
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	certData, err := decodeBinary(raw, "CERTIFICATE")
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(certData)
+	if err != nil {
+		return fmt.Errorf("解析证书失败: %v", err)
+	}
+
+	key, err := readPrivateKeyFile(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	var chain []*x509.Certificate
+	if *chainFile != "" {
+		rawChain, err := os.ReadFile(*chainFile)
+		if err != nil {
+			return err
+		}
+		if chain, err = decodeCertificates(rawChain); err != nil {
+			return err
+		}
+	}
+
+	opts := helper.PFXOptions{
+		Password:      *password,
+		FriendlyName:  *friendlyName,
+		Encryption:    *encryption,
+		Iterations:    *iterations,
+		MACIterations: *macIterations,
+	}
+	if *keyID != "" {
+		if opts.LocalKeyID, err = hex.DecodeString(*keyID); err != nil {
+			return fmt.Errorf("localKeyId不是有效的Hex: %v", err)
+		}
+	}
+	pfx, err := helper.CreatePFX(key, cert, chain, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(pfx))
+	return nil
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/corvus-ch/shamir"
+)
+
+func init() {
+	register("shamir", "Shamir门限秘密拆分，指定 -combine 时恢复", runShamir)
+}
+
+// shamirResult Shamir拆分/恢复结果
+type shamirResult struct {
+	Shares map[string]string `json:"shares,omitempty"`
+	Secret string            `json:"secret,omitempty"`
+}
+
+func runShamir(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("shamir")
+	combine := fs.Bool("combine", false, "恢复模式，输入为每行一个 Index:ShareHex")
+	parts := fs.Int("n", 5, "总份数 (N)")
+	threshold := fs.Int("k", 3, "恢复所需份数 (K)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+
+	var result shamirResult
+	if *combine {
+		shares := parseShares(string(raw))
+		if len(shares) < 2 {
+			return fmt.Errorf("有效分片数量不足，请检查输入格式")
+		}
+		secret, err := shamir.Combine(shares)
+		if err != nil {
+			return fmt.Errorf("恢复失败: %v", err)
+		}
+		result.Secret = string(secret)
+	} else {
+		if *parts < 2 || *parts > 255 {
+			return fmt.Errorf("总份数必须在 2-255 之间")
+		}
+		if *threshold < 2 || *threshold > *parts {
+			return fmt.Errorf("阈值必须在 2 到总份数之间")
+		}
+		secret := strings.TrimRight(string(raw), "\r\n")
+		if secret == "" {
+			return fmt.Errorf("请输入要拆分的秘密")
+		}
+		shares, err := shamir.Split([]byte(secret), *parts, *threshold)
+		if err != nil {
+			return fmt.Errorf("拆分失败: %v", err)
+		}
+		result.Shares = make(map[string]string, len(shares))
+		for k, v := range shares {
+			result.Shares[strconv.Itoa(int(k))] = hex.EncodeToString(v)
+		}
+	}
+
+	return emit(stdout, *asJSON, result, func(w io.Writer) {
+		if *combine {
+			fmt.Fprintln(w, result.Secret)
+			return
+		}
+		indexes := make([]int, 0, len(result.Shares))
+		for k := range result.Shares {
+			idx, _ := strconv.Atoi(k)
+			indexes = append(indexes, idx)
+		}
+		sort.Ints(indexes)
+		for _, idx := range indexes {
+			fmt.Fprintf(w, "Index: %d, Share: %s\n", idx, result.Shares[strconv.Itoa(idx)])
+		}
+	})
+}
+
+// parseShares 解析 "Index:ShareHex" 或 "Index: 1, Share: xx" 格式的分片
+func parseShares(input string) map[byte][]byte {
+	shares := make(map[byte][]byte)
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		line = strings.Replace(line, ", Share", "", 1)
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		indexStr := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(fields[0]), "Index"))
+		shareHex := strings.TrimSpace(fields[1])
+		if i := strings.Index(shareHex, ":"); i >= 0 {
+			indexStr = strings.TrimSpace(shareHex[:i])
+			shareHex = strings.TrimSpace(shareHex[i+1:])
+		}
+
+		idx, err := strconv.Atoi(indexStr)
+		if err != nil || idx < 0 || idx > 255 {
+			continue
+		}
+		shareData, err := hex.DecodeString(shareHex)
+		if err != nil {
+			continue
+		}
+		shares[byte(idx)] = shareData
+	}
+	return shares
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"HeTu/gm"
+	"HeTu/helper"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/zaneway/cain-go/sm2"
+)
+
+func init() {
+	register("sm2kx", "GM/T 0003.3 SM2密钥交换，输出Z_A/Z_B、U/V、共享密钥K及确认值S1/S2/S_A/S_B等全部中间值", runSM2KeyExchange)
+}
+
+// keyExchangePartyResult 密钥交换一方的标识及密钥，私钥未知时为空
+type keyExchangePartyResult struct {
+	ID                  string `json:"id"`
+	StaticPrivateKey    string `json:"staticPrivateKey,omitempty"`
+	StaticPublicKey     string `json:"staticPublicKey"`
+	EphemeralPrivateKey string `json:"ephemeralPrivateKey,omitempty"`
+	EphemeralPublicKey  string `json:"ephemeralPublicKey"`
+}
+
+// keyExchangeResult 密钥交换中间值，A方或B方私钥缺失时对应视角的字段为空
+type keyExchangeResult struct {
+	KLen  int                    `json:"klen"`
+	A     keyExchangePartyResult `json:"a"`
+	B     keyExchangePartyResult `json:"b"`
+	ZA    string                 `json:"za"`
+	ZB    string                 `json:"zb"`
+	X1Bar string                 `json:"x1Bar"`
+	X2Bar string                 `json:"x2Bar"`
+	TA    string                 `json:"tA,omitempty"`
+	U     string                 `json:"u,omitempty"`
+	KA    string                 `json:"kA,omitempty"`
+	HashU string                 `json:"hashU,omitempty"`
+	S1    string                 `json:"s1,omitempty"`
+	SA    string                 `json:"sA,omitempty"`
+	TB    string                 `json:"tB,omitempty"`
+	V     string                 `json:"v,omitempty"`
+	KB    string                 `json:"kB,omitempty"`
+	HashV string                 `json:"hashV,omitempty"`
+	SB    string                 `json:"sB,omitempty"`
+	S2    string                 `json:"s2,omitempty"`
+	// 双方均已计算时的比对结果: KA = KB，S1 = SB(A确认B)，S2 = SA(B确认A)
+	KeyMatch *bool `json:"keyMatch,omitempty"`
+	S1MatchB *bool `json:"s1MatchSB,omitempty"`
+	S2MatchA *bool `json:"s2MatchSA,omitempty"`
+}
+
+func runSM2KeyExchange(args []string, stdout io.Writer) error {
+	fs, _, asJSON := newFlagSet("sm2kx")
+	ida := fs.String("ida", string(helper.SM2DefaultUserID), "发起方A的标识ID_A")
+	idb := fs.String("idb", string(helper.SM2DefaultUserID), "响应方B的标识ID_B")
+	aKey := fs.String("a-key", "", "A方静态私钥d_A(PEM/Base64/Hex)")
+	aPub := fs.String("a-pub", "", "A方静态公钥P_A(裸公钥/SubjectPublicKeyInfo/证书)")
+	aTmpKey := fs.String("a-tmpkey", "", "A方临时私钥r_A")
+	aTmpPub := fs.String("a-tmppub", "", "A方临时公钥R_A")
+	bKey := fs.String("b-key", "", "B方静态私钥d_B")
+	bPub := fs.String("b-pub", "", "B方静态公钥P_B")
+	bTmpKey := fs.String("b-tmpkey", "", "B方临时私钥r_B")
+	bTmpPub := fs.String("b-tmppub", "", "B方临时公钥R_B")
+	klen := fs.Int("klen", 16, "共享密钥K的字节数")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	a := &gm.SM2KeyExchangeParty{ID: []byte(*ida)}
+	b := &gm.SM2KeyExchangeParty{ID: []byte(*idb)}
+	// 私钥与公钥均未提供的密钥随机生成
+	slots := []struct {
+		name               string
+		keyValue, pubValue string
+		key                **sm2.PrivateKey
+		pub                **sm2.PublicKey
+	}{
+		{"A方静态", *aKey, *aPub, &a.StaticPrivateKey, &a.StaticPublicKey},
+		{"A方临时", *aTmpKey, *aTmpPub, &a.EphemeralPrivateKey, &a.EphemeralPublicKey},
+		{"B方静态", *bKey, *bPub, &b.StaticPrivateKey, &b.StaticPublicKey},
+		{"B方临时", *bTmpKey, *bTmpPub, &b.EphemeralPrivateKey, &b.EphemeralPublicKey},
+	}
+	for _, slot := range slots {
+		var err error
+		if slot.keyValue == "" && slot.pubValue == "" {
+			if *slot.key, err = sm2.GenerateKey(rand.Reader); err != nil {
+				return err
+			}
+			continue
+		}
+		if slot.keyValue != "" {
+			if *slot.key, err = parseSM2PrivateKeyValue(slot.keyValue); err != nil {
+				return fmt.Errorf("%s私钥: %v", slot.name, err)
+			}
+		}
+		if slot.pubValue != "" {
+			if *slot.pub, err = parseSM2PublicKeyValue(slot.pubValue); err != nil {
+				return fmt.Errorf("%s公钥: %v", slot.name, err)
+			}
+		}
+	}
+
+	exchange, err := gm.SM2KeyExchange(a, b, *klen)
+	if err != nil {
+		return err
+	}
+	result := keyExchangeResult{
+		KLen:  *klen,
+		A:     newKeyExchangePartyResult(a),
+		B:     newKeyExchangePartyResult(b),
+		ZA:    hex.EncodeToString(exchange.ZA),
+		ZB:    hex.EncodeToString(exchange.ZB),
+		X1Bar: hex.EncodeToString(exchange.X1Bar),
+		X2Bar: hex.EncodeToString(exchange.X2Bar),
+		TA:    hex.EncodeToString(exchange.TA),
+		U:     hex.EncodeToString(exchange.U),
+		KA:    hex.EncodeToString(exchange.KA),
+		HashU: hex.EncodeToString(exchange.HashU),
+		S1:    hex.EncodeToString(exchange.S1),
+		SA:    hex.EncodeToString(exchange.SA),
+		TB:    hex.EncodeToString(exchange.TB),
+		V:     hex.EncodeToString(exchange.V),
+		KB:    hex.EncodeToString(exchange.KB),
+		HashV: hex.EncodeToString(exchange.HashV),
+		SB:    hex.EncodeToString(exchange.SB),
+		S2:    hex.EncodeToString(exchange.S2),
+	}
+	if exchange.KA != nil && exchange.KB != nil {
+		keyMatch := exchange.KeyMatch()
+		s1Match := bytes.Equal(exchange.S1, exchange.SB)
+		s2Match := bytes.Equal(exchange.S2, exchange.SA)
+		result.KeyMatch, result.S1MatchB, result.S2MatchA = &keyMatch, &s1Match, &s2Match
+	}
+
+	return emit(stdout, *asJSON, result, func(w io.Writer) {
+		for _, party := range []struct {
+			name string
+			keys keyExchangePartyResult
+		}{{"A", result.A}, {"B", result.B}} {
+			fmt.Fprintf(w, "ID_%s: %s\n", party.name, party.keys.ID)
+			if party.keys.StaticPrivateKey != "" {
+				fmt.Fprintf(w, "d_%s: %s\n", party.name, party.keys.StaticPrivateKey)
+			}
+			fmt.Fprintf(w, "P_%s: %s\n", party.name, party.keys.StaticPublicKey)
+			if party.keys.EphemeralPrivateKey != "" {
+				fmt.Fprintf(w, "r_%s: %s\n", party.name, party.keys.EphemeralPrivateKey)
+			}
+			fmt.Fprintf(w, "R_%s: %s\n", party.name, party.keys.EphemeralPublicKey)
+		}
+		fmt.Fprintf(w, "Z_A: %s\n", result.ZA)
+		fmt.Fprintf(w, "Z_B: %s\n", result.ZB)
+		fmt.Fprintf(w, "x̄1: %s\n", result.X1Bar)
+		fmt.Fprintf(w, "x̄2: %s\n", result.X2Bar)
+		if result.KA != "" {
+			fmt.Fprintln(w, "--- A方 ---")
+			fmt.Fprintf(w, "t_A: %s\n", result.TA)
+			fmt.Fprintf(w, "U: %s\n", result.U)
+			fmt.Fprintf(w, "K_A (%d 字节): %s\n", result.KLen, result.KA)
+			fmt.Fprintf(w, "Hash(x_U||Z_A||Z_B||x1||y1||x2||y2): %s\n", result.HashU)
+			fmt.Fprintf(w, "S1: %s\n", result.S1)
+			fmt.Fprintf(w, "S_A: %s\n", result.SA)
+		}
+		if result.KB != "" {
+			fmt.Fprintln(w, "--- B方 ---")
+			fmt.Fprintf(w, "t_B: %s\n", result.TB)
+			fmt.Fprintf(w, "V: %s\n", result.V)
+			fmt.Fprintf(w, "K_B (%d 字节): %s\n", result.KLen, result.KB)
+			fmt.Fprintf(w, "Hash(x_V||Z_A||Z_B||x1||y1||x2||y2): %s\n", result.HashV)
+			fmt.Fprintf(w, "S_B: %s\n", result.SB)
+			fmt.Fprintf(w, "S2: %s\n", result.S2)
+		}
+		if result.KeyMatch != nil {
+			fmt.Fprintln(w, "--- 校验 ---")
+			fmt.Fprintf(w, "K_A = K_B: %s\n", matchText(*result.KeyMatch))
+			fmt.Fprintf(w, "S1 = S_B: %s\n", matchText(*result.S1MatchB))
+			fmt.Fprintf(w, "S2 = S_A: %s\n", matchText(*result.S2MatchA))
+		}
+	})
+}
+
+func newKeyExchangePartyResult(party *gm.SM2KeyExchangeParty) keyExchangePartyResult {
+	result := keyExchangePartyResult{
+		ID:                 string(party.ID),
+		StaticPublicKey:    hex.EncodeToString(helper.MarshalSM2Point(party.StaticPublicKey)),
+		EphemeralPublicKey: hex.EncodeToString(helper.MarshalSM2Point(party.EphemeralPublicKey)),
+	}
+	if party.StaticPrivateKey != nil {
+		result.StaticPrivateKey = hex.EncodeToString(party.StaticPrivateKey.D.FillBytes(make([]byte, 32)))
+	}
+	if party.EphemeralPrivateKey != nil {
+		result.EphemeralPrivateKey = hex.EncodeToString(party.EphemeralPrivateKey.D.FillBytes(make([]byte, 32)))
+	}
+	return result
+}
+
+func parseSM2PrivateKeyValue(value string) (*sm2.PrivateKey, error) {
+	data, err := decodeBinary([]byte(value), "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	return helper.ParseSM2PrivateKey(data)
+}
+
+func parseSM2PublicKeyValue(value string) (*sm2.PublicKey, error) {
+	data, err := decodeBinary([]byte(value), "PUBLIC KEY", "CERTIFICATE")
+	if err != nil {
+		return nil, err
+	}
+	return helper.ParseSM2PublicKey(data)
+}
+
+func matchText(match bool) string {
+	if match {
+		return "一致"
+	}
+	return "不一致"
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/zaneway/otp/totp"
+)
+
+func init() {
+	register("totp", "根据密钥生成基于时间的一次性密码", runTotp)
+}
+
+// totpResult TOTP生成结果
+type totpResult struct {
+	Code      string `json:"code"`
+	Remaining int64  `json:"remaining"`
+}
+
+func runTotp(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("totp")
+	period := fs.Int("period", 30, "时间步长(秒)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	secret := strings.TrimSpace(string(raw))
+	if secret == "" {
+		return fmt.Errorf("请输入TOTP密钥")
+	}
+	if *period <= 0 {
+		return fmt.Errorf("时间步长必须大于0")
+	}
+
+	code, err := totp.Generate(secret, *period)
+	if err != nil {
+		return err
+	}
+	result := totpResult{Code: code, Remaining: int64(*period) - time.Now().Unix()%int64(*period)}
+	return emit(stdout, *asJSON, result, func(w io.Writer) {
+		fmt.Fprintln(w, result.Code)
+		fmt.Fprintf(w, "更新倒计时: %d 秒\n", result.Remaining)
+	})
+}
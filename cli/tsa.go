@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+func init() {
+	register("tsa", "本地时间戳服务，以SM2/RSA TSA证书签发RFC 3161令牌，可直接应答请求文件或启动HTTP服务", runTsa)
+}
+
+type tsaLogResult struct {
+	Time          string `json:"time"`
+	RemoteAddr    string `json:"remoteAddr,omitempty"`
+	HashAlgorithm string `json:"hashAlgorithm,omitempty"`
+	HashedMessage string `json:"hashedMessage,omitempty"`
+	Nonce         string `json:"nonce,omitempty"`
+	SerialNumber  string `json:"serialNumber,omitempty"`
+	GenTime       string `json:"genTime,omitempty"`
+	Status        int    `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+func runTsa(args []string, stdout io.Writer) error {
+	fs, _, asJSON := newFlagSet("tsa")
+	certFile := fs.String("cert", "", "TSA证书文件，可包含上级证书，请求要求附带证书时一同附带")
+	keyFile := fs.String("key", "", "TSA签名私钥文件(SM2或RSA)")
+	policy := fs.String("policy", "", "TSA策略OID")
+	hashAlgorithm := fs.String("hash", "", "令牌签名摘要算法，缺省时SM2使用SM3、RSA使用SHA256")
+	accuracy := fs.Duration("accuracy", time.Second, "genTime精度，如 1s、500ms，0表示不包含")
+	ordering := fs.Bool("ordering", false, "令牌中设置ordering")
+	tsaName := fs.Bool("tsaname", false, "以TSA证书主题作为令牌中的tsa名称")
+	genTime := fs.String("gentime", "", "固定的签发时间，格式 "+util.DateTime+"(北京时间)，缺省为当前时间")
+	reqFile := fs.String("req", "", "时间戳请求文件，指定后直接签发响应而不启动服务")
+	out := fs.String("out", "", "将DER编码的响应写入文件，与 -req 配合使用")
+	addr := fs.String("addr", helper.DefaultTSAAddr, "监听地址")
+	requests := fs.Int("n", 0, "处理指定数量的请求后退出，0表示持续运行直至中断")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *certFile == "" || *keyFile == "" || *policy == "" {
+		return fmt.Errorf("必须通过 -cert、-key 及 -policy 指定TSA证书、签名私钥和策略OID")
+	}
+
+	config := helper.TSAConfig{
+		Policy:         *policy,
+		HashAlgorithm:  strings.ToUpper(*hashAlgorithm),
+		Accuracy:       helper.NewTimeStampAccuracy(*accuracy),
+		Ordering:       *ordering,
+		IncludeTSAName: *tsaName,
+	}
+	certs, err := readCertificateFile(*certFile)
+	if err != nil {
+		return fmt.Errorf("读取TSA证书失败: %v", err)
+	}
+	config.Certificate, config.Chain = certs[0], certs[1:]
+	if config.Key, err = readPrivateKeyFile(*keyFile); err != nil {
+		return fmt.Errorf("读取签名私钥失败: %v", err)
+	}
+	if config.GenTime, err = util.ParseDateTime(*genTime); err != nil {
+		return fmt.Errorf("签发时间格式应为 %s", util.DateTime)
+	}
+	tsa, err := helper.NewTimeStampAuthority(config)
+	if err != nil {
+		return err
+	}
+	for _, warning := range tsa.Warnings {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", warning)
+	}
+
+	writeLog := func(log helper.TSARequestLog) {
+		if !*asJSON {
+			fmt.Fprintln(stdout, log.String())
+			return
+		}
+		result := tsaLogResult{
+			Time:          util.ToBeijingTime(log.Time).Format(util.DateTime),
+			RemoteAddr:    log.RemoteAddr,
+			HashAlgorithm: log.HashAlgorithm,
+			HashedMessage: fmt.Sprintf("%X", log.HashedMessage),
+			Status:        log.Status,
+			Error:         log.Error,
+		}
+		if log.Nonce != nil {
+			result.Nonce = fmt.Sprintf("%X", log.Nonce)
+		}
+		if log.SerialNumber != nil {
+			result.SerialNumber = fmt.Sprintf("%X", log.SerialNumber)
+			result.GenTime = util.ToBeijingTime(log.GenTime).Format(util.DateTime)
+		}
+		json.NewEncoder(stdout).Encode(result)
+	}
+
+	if *reqFile != "" {
+		raw, err := os.ReadFile(*reqFile)
+		if err != nil {
+			return err
+		}
+		request, err := decodeBinary(raw)
+		if err != nil {
+			return err
+		}
+		response, log := tsa.Issue(request)
+		writeLog(log)
+		if *out == "" {
+			return nil
+		}
+		return os.WriteFile(*out, response, 0644)
+	}
+
+	done := make(chan struct{})
+	handled := 0
+	tsa.Log = func(log helper.TSARequestLog) {
+		writeLog(log)
+		handled++
+		if handled == *requests {
+			close(done)
+		}
+	}
+	server, url, err := tsa.Listen(*addr)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	// 启动信息输出到标准错误，-json时标准输出只包含请求日志
+	fmt.Fprintf(os.Stderr, "时间戳服务已启动: %s，按Ctrl+C停止\n", url)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	select {
+	case <-done:
+		// 等待最后一个响应写出
+		time.Sleep(100 * time.Millisecond)
+	case <-interrupt:
+	}
+	return nil
+}
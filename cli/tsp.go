@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+func init() {
+	register("tsp", "解析RFC 3161时间戳响应或令牌，验证令牌签名(支持SM2)及数据摘要", runTsp)
+}
+
+type tspResult struct {
+	Status             string             `json:"status"`
+	StatusStrings      []string           `json:"statusStrings,omitempty"`
+	FailureInfo        []string           `json:"failureInfo,omitempty"`
+	Version            int                `json:"version,omitempty"`
+	Policy             string             `json:"policy,omitempty"`
+	HashAlgorithm      string             `json:"hashAlgorithm,omitempty"`
+	HashedMessage      string             `json:"hashedMessage,omitempty"`
+	SerialNumber       string             `json:"serialNumber,omitempty"`
+	GenTime            string             `json:"genTime,omitempty"`
+	Accuracy           string             `json:"accuracy,omitempty"`
+	Ordering           bool               `json:"ordering,omitempty"`
+	Nonce              string             `json:"nonce,omitempty"`
+	TSAName            string             `json:"tsaName,omitempty"`
+	SignatureAlgorithm string             `json:"signatureAlgorithm,omitempty"`
+	SignatureStatus    string             `json:"signatureStatus,omitempty"`
+	SignatureError     string             `json:"signatureError,omitempty"`
+	Signer             string             `json:"signer,omitempty"`
+	Certificates       []string           `json:"certificates,omitempty"`
+	ImprintStatus      string             `json:"imprintStatus,omitempty"`
+	Problems           []string           `json:"problems,omitempty"`
+	Request            *tspRequestSummary `json:"request,omitempty"`
+}
+
+type tspRequestSummary struct {
+	HashAlgorithm string `json:"hashAlgorithm"`
+	HashedMessage string `json:"hashedMessage"`
+	Nonce         string `json:"nonce,omitempty"`
+	Policy        string `json:"policy,omitempty"`
+	CertReq       bool   `json:"certReq"`
+	Base64        string `json:"base64,omitempty"`
+}
+
+func runTsp(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("tsp")
+	dataFile := fs.String("data", "", "被加盖时间戳的数据文件，用于验证消息摘要")
+	certFile := fs.String("tsacert", "", "令牌未附带TSA证书时使用的证书文件")
+	reqFile := fs.String("req", "", "对应的时间戳请求文件，用于检查摘要、nonce及策略")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := readInput(*in, fs)
+	if err != nil {
+		return err
+	}
+	der, err := decodeBinary(raw)
+	if err != nil {
+		return err
+	}
+	info, err := helper.ParseTimeStampResponse(der)
+	if err != nil {
+		return err
+	}
+	certs, err := readOptionalCertificates(*certFile)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if *dataFile != "" {
+		if data, err = os.ReadFile(*dataFile); err != nil {
+			return err
+		}
+	}
+	var req *helper.TimeStampRequestInfo
+	if *reqFile != "" {
+		reqRaw, err := os.ReadFile(*reqFile)
+		if err != nil {
+			return err
+		}
+		reqDER, err := decodeBinary(reqRaw)
+		if err != nil {
+			return err
+		}
+		if req, err = helper.ParseTimeStampRequest(reqDER); err != nil {
+			return err
+		}
+	}
+	return emitTimeStampResponse(stdout, *asJSON, info, certs, data, req, nil)
+}
+
+// readOptionalCertificates 读取补充的TSA证书文件，未指定时返回nil
+func readOptionalCertificates(path string) ([]*x509.Certificate, error) {
+	if path == "" {
+		return nil, nil
+	}
+	certs, err := readCertificateFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取TSA证书失败: %v", err)
+	}
+	return certs, nil
+}
+
+// emitTimeStampResponse 验证并输出时间戳响应，data不为nil时验证消息摘要，req不为空时检查响应与请求是否对应
+func emitTimeStampResponse(w io.Writer, asJSON bool, info *helper.TimeStampResponseInfo, certs []*x509.Certificate, data []byte, req *helper.TimeStampRequestInfo, summary *tspRequestSummary) error {
+	result := tspResult{
+		Status:        info.StatusText(),
+		StatusStrings: info.StatusStrings,
+		FailureInfo:   info.FailureInfo,
+		Request:       summary,
+	}
+	var verifyErr, imprintErr error
+	if tst := info.TSTInfo; tst != nil {
+		// 签名验证失败记录在结果中，不中断输出
+		verifyErr = helper.VerifyTimeStampResponse(info, certs)
+		result.Version = tst.Version
+		result.Policy = tst.Policy
+		result.HashAlgorithm = tst.HashAlgorithm
+		result.HashedMessage = hex.EncodeToString(tst.HashedMessage)
+		result.SerialNumber = fmt.Sprintf("%X", tst.SerialNumber)
+		result.GenTime = tst.GenTime.Format(util.DateTime)
+		result.Accuracy = tst.Accuracy.String()
+		result.Ordering = tst.Ordering
+		if tst.Nonce != nil {
+			result.Nonce = fmt.Sprintf("%X", tst.Nonce)
+		}
+		result.TSAName = tst.TSAName
+		if len(info.SignedData.Signers) > 0 {
+			result.SignatureAlgorithm = info.SignedData.Signers[0].SignatureAlgorithm
+		}
+		result.SignatureStatus = info.SignatureStatus
+		result.SignatureError = info.SignatureError
+		if info.Signer != nil {
+			result.Signer = info.Signer.Subject.String()
+		}
+		for _, cert := range info.SignedData.Certificates {
+			result.Certificates = append(result.Certificates, cert.Subject.String())
+		}
+		if data != nil {
+			if imprintErr = info.VerifyMessageImprint(data); imprintErr != nil {
+				result.ImprintStatus = imprintErr.Error()
+			} else {
+				result.ImprintStatus = "一致"
+			}
+		}
+		result.Problems = append(result.Problems, info.Warnings...)
+		if req != nil {
+			result.Problems = append(result.Problems, info.CheckRequest(req)...)
+		}
+	}
+
+	err := emit(w, asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "响应状态: %s\n", result.Status)
+		for _, text := range result.StatusStrings {
+			fmt.Fprintf(w, "状态说明: %s\n", text)
+		}
+		for _, text := range result.FailureInfo {
+			fmt.Fprintf(w, "失败原因: %s\n", text)
+		}
+		if info.TSTInfo == nil {
+			return
+		}
+		fmt.Fprintf(w, "策略: %s\n", result.Policy)
+		fmt.Fprintf(w, "消息摘要: %s %s\n", result.HashAlgorithm, result.HashedMessage)
+		fmt.Fprintf(w, "序列号: %s\n", result.SerialNumber)
+		fmt.Fprintf(w, "时间: %s\n", result.GenTime)
+		fmt.Fprintf(w, "精度: %s\n", result.Accuracy)
+		fmt.Fprintf(w, "排序: %v\n", result.Ordering)
+		if result.Nonce != "" {
+			fmt.Fprintf(w, "Nonce: %s\n", result.Nonce)
+		}
+		if result.TSAName != "" {
+			fmt.Fprintf(w, "TSA名称: %s\n", result.TSAName)
+		}
+		fmt.Fprintf(w, "签名算法: %s\n", result.SignatureAlgorithm)
+		fmt.Fprintf(w, "签名状态: %s\n", result.SignatureStatus)
+		if result.SignatureError != "" {
+			fmt.Fprintf(w, "签名错误: %s\n", result.SignatureError)
+		}
+		if result.Signer != "" {
+			fmt.Fprintf(w, "TSA证书: %s\n", result.Signer)
+		}
+		for _, subject := range result.Certificates {
+			fmt.Fprintf(w, "附带证书: %s\n", subject)
+		}
+		if result.ImprintStatus != "" {
+			fmt.Fprintf(w, "数据摘要: %s\n", result.ImprintStatus)
+		}
+		for _, problem := range result.Problems {
+			fmt.Fprintf(w, "警告: %s\n", problem)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.TSTInfo == nil:
+		return checkFailed("响应状态为%s，未包含时间戳令牌", result.Status)
+	case verifyErr != nil:
+		return checkFailed("令牌签名验证失败，%v", verifyErr)
+	case imprintErr != nil:
+		return checkFailed("数据摘要验证失败，%v", imprintErr)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"HeTu/helper"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	register("tspreq", "构造RFC 3161时间戳请求(SHA2/SM3摘要、nonce、certReq)，可发送至TSA并验证响应", runTspReq)
+}
+
+func runTspReq(args []string, stdout io.Writer) error {
+	fs, in, asJSON := newFlagSet("tspreq")
+	hashAlgorithm := fs.String("hash", helper.TSPHashSHA256, "摘要算法: "+strings.Join(helper.TSPHashAlgorithms, "、"))
+	digest := fs.String("digest", "", "直接指定数据摘要(Hex)，指定后不读取数据")
+	policy := fs.String("policy", "", "请求的TSA策略OID")
+	noNonce := fs.Bool("no-nonce", false, "请求不包含nonce")
+	certReq := fs.Bool("cert", false, "要求TSA在令牌中附带签名证书")
+	out := fs.String("out", "", "将DER编码的请求写入文件")
+	url := fs.String("url", "", "时间戳服务地址，指定后发送请求并验证响应")
+	respOut := fs.String("resp", "", "将DER编码的响应写入文件")
+	certFile := fs.String("tsacert", "", "令牌未附带TSA证书时使用的证书文件")
+	timeout := fs.Duration("timeout", 15*time.Second, "发送请求的超时时间")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	template := &helper.TimeStampRequestTemplate{
+		HashAlgorithm: strings.ToUpper(*hashAlgorithm),
+		Policy:        *policy,
+		CertReq:       *certReq,
+	}
+	var data []byte
+	var err error
+	if *digest != "" {
+		if template.HashedMessage, err = hex.DecodeString(strings.TrimSpace(*digest)); err != nil {
+			return fmt.Errorf("摘要不是有效的Hex: %v", err)
+		}
+	} else {
+		if data, err = readInput(*in, fs); err != nil {
+			return err
+		}
+		if template.HashedMessage, err = helper.ComputeMessageImprint(template.HashAlgorithm, data); err != nil {
+			return err
+		}
+	}
+	if !*noNonce {
+		if template.Nonce, err = helper.NewTimeStampNonce(); err != nil {
+			return err
+		}
+	}
+	request, err := helper.CreateTimeStampRequest(template)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		if err := os.WriteFile(*out, request, 0644); err != nil {
+			return err
+		}
+	}
+
+	summary := &tspRequestSummary{
+		HashAlgorithm: template.HashAlgorithm,
+		HashedMessage: hex.EncodeToString(template.HashedMessage),
+		Policy:        template.Policy,
+		CertReq:       template.CertReq,
+	}
+	if template.Nonce != nil {
+		summary.Nonce = fmt.Sprintf("%X", template.Nonce)
+	}
+	if *url == "" {
+		summary.Base64 = base64.StdEncoding.EncodeToString(request)
+		return emit(stdout, *asJSON, summary, func(w io.Writer) {
+			fmt.Fprintf(w, "消息摘要: %s %s\n", summary.HashAlgorithm, summary.HashedMessage)
+			if summary.Nonce != "" {
+				fmt.Fprintf(w, "Nonce: %s\n", summary.Nonce)
+			}
+			if summary.Policy != "" {
+				fmt.Fprintf(w, "策略: %s\n", summary.Policy)
+			}
+			fmt.Fprintf(w, "要求证书: %v\n", summary.CertReq)
+			fmt.Fprintln(w, summary.Base64)
+		})
+	}
+
+	response, err := helper.QueryTSA(*url, request, *timeout)
+	if err != nil {
+		return err
+	}
+	if *respOut != "" {
+		if err := os.WriteFile(*respOut, response, 0644); err != nil {
+			return err
+		}
+	}
+	info, err := helper.ParseTimeStampResponse(response)
+	if err != nil {
+		return err
+	}
+	req, err := helper.ParseTimeStampRequest(request)
+	if err != nil {
+		return err
+	}
+	certs, err := readOptionalCertificates(*certFile)
+	if err != nil {
+		return err
+	}
+	return emitTimeStampResponse(stdout, *asJSON, info, certs, data, req, summary)
+}
@@ -0,0 +1,317 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoding 输入数据被识别出的编码方式
+type Encoding string
+
+const (
+	EncodingPEM       Encoding = "PEM"
+	EncodingDataURL   Encoding = "DataURL"
+	EncodingBase64    Encoding = "Base64"
+	EncodingBase64URL Encoding = "Base64URL"
+	EncodingHex       Encoding = "Hex"
+	EncodingDER       Encoding = "DER"
+	EncodingText      Encoding = "Text"
+)
+
+// Block 解码得到的一段二进制数据，PEM输入时对应每个PEM块
+type Block struct {
+	Type    string
+	Headers map[string]string
+	Bytes   []byte
+}
+
+// Result 解码结果，记录选择的解释方式
+type Result struct {
+	Encoding Encoding
+	Blocks   []Block
+	// Notes 记录解码过程中的兜底处理，例如补齐填充、去除分隔符
+	Notes []string
+}
+
+// Bytes 返回第一个数据块
+func (r *Result) Bytes() []byte {
+	if len(r.Blocks) == 0 {
+		return nil
+	}
+	return r.Blocks[0].Bytes
+}
+
+// FirstOfType 返回第一个类型匹配的PEM块，非PEM输入直接返回唯一的数据块
+func (r *Result) FirstOfType(types ...string) ([]byte, bool) {
+	if r.Encoding != EncodingPEM {
+		return r.Bytes(), len(r.Blocks) > 0
+	}
+	for _, block := range r.Blocks {
+		for _, t := range types {
+			if block.Type == t {
+				return block.Bytes, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// AllOfType 返回所有类型匹配的PEM块，非PEM输入返回唯一的数据块
+func (r *Result) AllOfType(types ...string) [][]byte {
+	var out [][]byte
+	for _, block := range r.Blocks {
+		if r.Encoding != EncodingPEM {
+			out = append(out, block.Bytes)
+			continue
+		}
+		for _, t := range types {
+			if block.Type == t {
+				out = append(out, block.Bytes)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Describe 返回对所选解释方式的简短说明
+func (r *Result) Describe() string {
+	desc := string(r.Encoding)
+	if r.Encoding == EncodingPEM {
+		var types []string
+		for _, block := range r.Blocks {
+			types = append(types, block.Type)
+		}
+		desc = fmt.Sprintf("PEM (%d个块: %s)", len(r.Blocks), strings.Join(types, ", "))
+	}
+	if len(r.Notes) > 0 {
+		desc += "，" + strings.Join(r.Notes, "，")
+	}
+	return desc
+}
+
+// DecodeString 解码文本输入
+func DecodeString(input string) (*Result, error) {
+	return Decode([]byte(input))
+}
+
+// Decode 自动识别并解码输入，支持多块PEM、Data URL、Base64（含URL-safe及缺失填充）、Hex（含0x前缀与分隔符）和原始DER
+func Decode(input []byte) (*Result, error) {
+	if isBinary(input) {
+		return &Result{Encoding: EncodingDER, Blocks: []Block{{Bytes: input}}}, nil
+	}
+
+	text := strings.TrimSpace(strings.TrimPrefix(string(input), "\ufeff"))
+	if text == "" {
+		return nil, fmt.Errorf("输入数据为空")
+	}
+
+	if strings.HasPrefix(strings.ToLower(text), "data:") {
+		return decodeDataURL(text)
+	}
+
+	if strings.Contains(text, "-----BEGIN") {
+		return decodePEM(text)
+	}
+
+	return decodeText(text)
+}
+
+// DecodeOrText 解码失败时按普通字符串处理，用于可能是明文的输入
+func DecodeOrText(input string) *Result {
+	result, err := DecodeString(input)
+	if err != nil {
+		return &Result{Encoding: EncodingText, Blocks: []Block{{Bytes: []byte(strings.TrimSpace(input))}}}
+	}
+	return result
+}
+
+// decodeText 解码Hex或Base64文本
+func decodeText(text string) (*Result, error) {
+	compact := stripWhitespace(text)
+
+	hexData, hexNotes, hexErr := decodeHex(compact)
+	b64Data, b64Encoding, b64Notes, b64Err := decodeBase64(compact)
+
+	switch {
+	case hexErr == nil && b64Err == nil:
+		// 同时是合法Hex和Base64时，优先选择能解析为完整DER结构的一方，否则按Hex处理
+		if !isCompleteDER(hexData) && isCompleteDER(b64Data) {
+			notes := append(b64Notes, "同时是合法Hex，按Base64解析得到完整DER结构")
+			return &Result{Encoding: b64Encoding, Blocks: []Block{{Bytes: b64Data}}, Notes: notes}, nil
+		}
+		hexNotes = append(hexNotes, "同时是合法Base64，按Hex解析")
+		return &Result{Encoding: EncodingHex, Blocks: []Block{{Bytes: hexData}}, Notes: hexNotes}, nil
+	case hexErr == nil:
+		return &Result{Encoding: EncodingHex, Blocks: []Block{{Bytes: hexData}}, Notes: hexNotes}, nil
+	case b64Err == nil:
+		return &Result{Encoding: b64Encoding, Blocks: []Block{{Bytes: b64Data}}, Notes: b64Notes}, nil
+	}
+	return nil, fmt.Errorf("无法识别的编码格式，请使用 PEM、Base64、Hex 或 DER\nBase64错误: %v\nHex错误: %v", b64Err, hexErr)
+}
+
+// decodePEM 解析全部PEM块，标准解析失败时按行剥离头尾后兜底
+func decodePEM(text string) (*Result, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	result := &Result{Encoding: EncodingPEM}
+
+	rest := []byte(text)
+	for {
+		block, remaining := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		result.Blocks = append(result.Blocks, Block{Type: block.Type, Headers: block.Headers, Bytes: block.Bytes})
+		rest = remaining
+	}
+	if len(result.Blocks) > 0 {
+		return result, nil
+	}
+
+	blocks, err := decodeLoosePEM(text)
+	if err != nil {
+		return nil, err
+	}
+	result.Blocks = blocks
+	result.Notes = append(result.Notes, "PEM格式不规范，已逐行提取Base64内容")
+	return result, nil
+}
+
+// decodeLoosePEM 处理缩进、行内空格等不规范的PEM
+func decodeLoosePEM(text string) ([]Block, error) {
+	var blocks []Block
+	var current *Block
+	var body strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "-----BEGIN "):
+			current = &Block{Type: strings.TrimSuffix(strings.TrimPrefix(line, "-----BEGIN "), "-----")}
+			body.Reset()
+		case strings.HasPrefix(line, "-----END "):
+			if current == nil {
+				continue
+			}
+			data, _, _, err := decodeBase64(stripWhitespace(body.String()))
+			if err != nil {
+				return nil, fmt.Errorf("PEM块 %s 的Base64内容无效: %v", current.Type, err)
+			}
+			current.Bytes = data
+			blocks = append(blocks, *current)
+			current = nil
+		case current != nil && !strings.Contains(line, ":"):
+			body.WriteString(line)
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("无法解析PEM数据，请检查格式是否正确")
+	}
+	return blocks, nil
+}
+
+// decodeDataURL 解析 data:[<mediatype>][;base64],<data> 格式
+func decodeDataURL(text string) (*Result, error) {
+	comma := strings.Index(text, ",")
+	if comma < 0 {
+		return nil, fmt.Errorf("Data URL 缺少数据部分")
+	}
+	meta, payload := text[len("data:"):comma], text[comma+1:]
+
+	var data []byte
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		decoded, _, _, err := decodeBase64(stripWhitespace(payload))
+		if err != nil {
+			return nil, fmt.Errorf("Data URL 的Base64内容无效: %v", err)
+		}
+		data = decoded
+	} else {
+		unescaped, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("Data URL 内容无效: %v", err)
+		}
+		data = []byte(unescaped)
+	}
+
+	// Data URL 中可能嵌套PEM或Base64文本
+	if !isBinary(data) {
+		if inner, err := Decode(data); err == nil {
+			inner.Notes = append(inner.Notes, "来自Data URL("+meta+")")
+			return inner, nil
+		}
+	}
+	return &Result{Encoding: EncodingDataURL, Blocks: []Block{{Type: meta, Bytes: data}}}, nil
+}
+
+// decodeHex 解码Hex，允许0x前缀以及冒号、连字符分隔
+func decodeHex(s string) ([]byte, []string, error) {
+	var notes []string
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+		notes = append(notes, "去除0x前缀")
+	}
+	if strings.ContainsAny(s, ":-") {
+		s = strings.NewReplacer(":", "", "-", "").Replace(s)
+		notes = append(notes, "去除Hex分隔符")
+	}
+	if s == "" {
+		return nil, nil, fmt.Errorf("Hex数据为空")
+	}
+	data, err := hex.DecodeString(s)
+	return data, notes, err
+}
+
+// decodeBase64 依次尝试标准、URL-safe以及无填充的Base64
+func decodeBase64(s string) ([]byte, Encoding, []string, error) {
+	if data, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return data, EncodingBase64, nil, nil
+	}
+	if data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "=")); err == nil {
+		return data, EncodingBase64, []string{"补齐Base64填充"}, nil
+	}
+	if data, err := base64.URLEncoding.DecodeString(s); err == nil {
+		return data, EncodingBase64URL, nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err == nil {
+		return data, EncodingBase64URL, []string{"补齐Base64填充"}, nil
+	}
+	return nil, "", nil, err
+}
+
+// stripWhitespace 移除所有空白字符
+func stripWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\n', '\r', '\t', '\v', '\f':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// isBinary 判断输入是否为非文本的二进制数据
+func isBinary(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if !utf8.Valid(data) {
+		return true
+	}
+	return bytes.ContainsFunc(data, func(r rune) bool {
+		return r < 0x20 && r != '\n' && r != '\r' && r != '\t'
+	})
+}
+
+// isCompleteDER 判断数据是否恰好是一个完整的DER结构
+func isCompleteDER(data []byte) bool {
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(data, &raw)
+	return err == nil && len(rest) == 0
+}
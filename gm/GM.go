@@ -1,12 +1,58 @@
 package gm
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"encoding/asn1"
+	"errors"
+	"fmt"
 	"github.com/zaneway/cain-go/sm2"
 	"github.com/zaneway/cain-go/sm4"
 	"math/big"
 )
 
+// 信封SymAlgID可能出现的对称算法OID
+var (
+	// OidSM4ECB SGD_SM4_ECB对应的算法OID
+	OidSM4ECB = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 1}
+	OidSM4CBC = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 2}
+	// OidSM4 未区分模式的SM4 OID，部分实现以此表示SM4-ECB
+	OidSM4 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104}
+	// OidSM1 SM1算法OID前缀，1.2.156.10197.1.102.x
+	OidSM1 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 102}
+
+	OidAES128ECB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 1}
+	OidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	OidAES192ECB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 21}
+	OidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	OidAES256ECB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 41}
+	OidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// ErrPublicKeyMismatch 解密得到的私钥与信封中的公钥不匹配，此时仍返回解密结果供核对
+var ErrPublicKeyMismatch = errors.New("私钥与信封中的公钥不匹配")
+
+// envelopeSymAlg 信封对称算法：分组密码构造函数、密钥长度及是否为CBC模式
+type envelopeSymAlg struct {
+	newCipher func(key []byte) (cipher.Block, error)
+	keySize   int
+	cbc       bool
+}
+
+var envelopeSymAlgs = map[string]envelopeSymAlg{
+	OidSM4.String():       {sm4.NewCipher, 16, false},
+	OidSM4ECB.String():    {sm4.NewCipher, 16, false},
+	OidSM4CBC.String():    {sm4.NewCipher, 16, true},
+	OidAES128ECB.String(): {aes.NewCipher, 16, false},
+	OidAES128CBC.String(): {aes.NewCipher, 16, true},
+	OidAES192ECB.String(): {aes.NewCipher, 24, false},
+	OidAES192CBC.String(): {aes.NewCipher, 24, true},
+	OidAES256ECB.String(): {aes.NewCipher, 32, false},
+	OidAES256CBC.String(): {aes.NewCipher, 32, true},
+}
+
 type SM2Cipher struct {
 	X          *big.Int `asn1:"integer"`
 	Y          *big.Int `asn1:"integer"`
@@ -33,3 +79,145 @@ func DecryptDataUsePrivateKey(data []byte, privateKey *sm2.PrivateKey) ([]byte,
 func DecryptDataUseSm4Key(data []byte, key []byte) (out []byte, err error) {
 	return sm4.Sm4EcbNoPaddingCipher(key, data, false)
 }
+
+// EncryptDataUsePublicKey DecryptDataUsePrivateKey的逆运算，返回C1C3C2格式密文
+func EncryptDataUsePublicKey(data []byte, publicKey *sm2.PublicKey) ([]byte, error) {
+	return sm2.Encrypt(publicKey, data, rand.Reader, sm2.C1C3C2)
+}
+
+// EncryptDataUseSm4Key DecryptDataUseSm4Key的逆运算，SM4-ECB无填充，数据长度须为16的整数倍
+func EncryptDataUseSm4Key(data []byte, key []byte) (out []byte, err error) {
+	return sm4.Sm4EcbNoPaddingCipher(key, data, true)
+}
+
+// ParseSM2EnvelopedKey 解析GM/T 0009 SM2EnvelopedKey结构
+func ParseSM2EnvelopedKey(data []byte) (*SM2EnvelopedKey, error) {
+	var sm2EnvelopedKey SM2EnvelopedKey
+	_, err := asn1.Unmarshal(data, &sm2EnvelopedKey)
+	return &sm2EnvelopedKey, err
+}
+
+// DecryptSM2EnvelopedKey 使用签名私钥解开对称密钥，按SymAlgID解密加密私钥，
+// 并校验私钥推导出的公钥与信封中的PublicKey一致。
+// 不一致时返回解密结果及包装ErrPublicKeyMismatch的错误
+func DecryptSM2EnvelopedKey(env *SM2EnvelopedKey, signPrivateKey *sm2.PrivateKey) (symKey []byte, privateKey []byte, err error) {
+	sm2CipherBytes, err := asn1.Marshal(env.Sm2cipher)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SM2Cipher编码失败: %v", err)
+	}
+	symKey, err = signPrivateKey.DecryptAsn1(sm2CipherBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("对称密钥解密失败: %v", err)
+	}
+
+	plain, err := decryptWithSymAlg(env.SymAlgID, symKey, env.Sm2EncryptedPrivateKey.Bytes)
+	if err != nil {
+		return symKey, nil, fmt.Errorf("私钥解密失败: %v", err)
+	}
+	privateKey, err = normalizeSM2PrivateKey(plain)
+	if err != nil {
+		return symKey, plain, err
+	}
+	return symKey, privateKey, checkSM2PublicKey(privateKey, env.PublicKey.Bytes)
+}
+
+// decryptWithSymAlg 按算法标识解密无填充的分组数据，CBC模式的IV取自参数中的OCTET STRING，缺省为全零
+func decryptWithSymAlg(algorithm AlgorithmIdentifier, key, data []byte) ([]byte, error) {
+	oid := algorithm.Algorithm
+	if len(oid) == len(OidSM1)+1 && oid[:len(OidSM1)].Equal(OidSM1) {
+		return nil, fmt.Errorf("SM1算法未公开，仅能在密码设备内解密 (%s)", oid)
+	}
+	alg, ok := envelopeSymAlgs[oid.String()]
+	if !ok {
+		return nil, fmt.Errorf("不支持的对称算法: %s", oid)
+	}
+	if len(key) != alg.keySize {
+		return nil, fmt.Errorf("对称密钥长度%d字节，算法%s要求%d字节", len(key), oid, alg.keySize)
+	}
+	block, err := alg.newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := block.BlockSize()
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, fmt.Errorf("密文长度%d字节，不是分组长度%d的整数倍", len(data), blockSize)
+	}
+
+	out := make([]byte, len(data))
+	if alg.cbc {
+		iv := make([]byte, blockSize)
+		if len(algorithm.Parameters.FullBytes) > 0 && algorithm.Parameters.Tag != asn1.TagNull {
+			if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &iv); err != nil || len(iv) != blockSize {
+				return nil, fmt.Errorf("CBC模式IV参数无效")
+			}
+		}
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+		return out, nil
+	}
+	for i := 0; i < len(data); i += blockSize {
+		block.Decrypt(out[i:i+blockSize], data[i:i+blockSize])
+	}
+	return out, nil
+}
+
+// normalizeSM2PrivateKey 兼容32字节私钥及前补32字节零的64字节私钥
+func normalizeSM2PrivateKey(plain []byte) ([]byte, error) {
+	switch {
+	case len(plain) == 32:
+		return plain, nil
+	case len(plain) == 64 && bytes.Equal(plain[:32], make([]byte, 32)):
+		return plain[32:], nil
+	}
+	return nil, fmt.Errorf("私钥明文长度%d字节，应为32字节或前补零的64字节", len(plain))
+}
+
+// checkSM2PublicKey 校验私钥推导的公钥与 04||X||Y 或 X||Y 形式的公钥一致
+func checkSM2PublicKey(privateKey, publicKey []byte) error {
+	curve := sm2.P256Sm2()
+	x, y := curve.ScalarBaseMult(privateKey)
+	derived := make([]byte, 64)
+	x.FillBytes(derived[:32])
+	y.FillBytes(derived[32:])
+	if len(publicKey) == 65 && publicKey[0] == 0x04 {
+		publicKey = publicKey[1:]
+	}
+	if !bytes.Equal(derived, publicKey) {
+		return fmt.Errorf("%w: 私钥推导公钥为 04%X，信封中为 %X", ErrPublicKeyMismatch, derived, publicKey)
+	}
+	return nil
+}
+
+// CreateSM2EnvelopedKey DecryptSM2EnvelopedKey的逆运算：生成随机SM4密钥并以签名公钥加密，
+// 再以SM4-ECB加密32字节的加密私钥，PublicKey填写加密公钥 04||X||Y
+func CreateSM2EnvelopedKey(encPrivateKey *sm2.PrivateKey, signPublicKey *sm2.PublicKey) (env *SM2EnvelopedKey, symKey []byte, err error) {
+	symKey = make([]byte, 16)
+	if _, err = rand.Read(symKey); err != nil {
+		return nil, nil, err
+	}
+	sm2CipherBytes, err := signPublicKey.EncryptAsn1(symKey, rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("对称密钥加密失败: %v", err)
+	}
+	env = &SM2EnvelopedKey{SymAlgID: AlgorithmIdentifier{Algorithm: OidSM4ECB}}
+	if _, err = asn1.Unmarshal(sm2CipherBytes, &env.Sm2cipher); err != nil {
+		return nil, nil, fmt.Errorf("SM2Cipher解析失败: %v", err)
+	}
+
+	privateKey := encPrivateKey.D.FillBytes(make([]byte, 32))
+	encrypted, err := EncryptDataUseSm4Key(privateKey, symKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("私钥加密失败: %v", err)
+	}
+	publicKey := make([]byte, 65)
+	publicKey[0] = 0x04
+	encPrivateKey.PublicKey.X.FillBytes(publicKey[1:33])
+	encPrivateKey.PublicKey.Y.FillBytes(publicKey[33:])
+	env.PublicKey = asn1.BitString{Bytes: publicKey, BitLength: 8 * len(publicKey)}
+	env.Sm2EncryptedPrivateKey = asn1.BitString{Bytes: encrypted, BitLength: 8 * len(encrypted)}
+	return env, symKey, nil
+}
+
+// MarshalSM2EnvelopedKey 编码SM2EnvelopedKey为DER
+func MarshalSM2EnvelopedKey(env *SM2EnvelopedKey) ([]byte, error) {
+	return asn1.Marshal(*env)
+}
@@ -0,0 +1,199 @@
+package gm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/zaneway/cain-go/sm2"
+	"github.com/zaneway/cain-go/sm3"
+)
+
+// SM2KeyExchangeParty GM/T 0003.3 密钥交换一方的标识及静态、临时密钥。
+// 私钥可为空，仅提供公钥时不计算该方视角的结果；同时提供私钥和公钥时校验二者一致
+type SM2KeyExchangeParty struct {
+	ID                  []byte
+	StaticPrivateKey    *sm2.PrivateKey
+	StaticPublicKey     *sm2.PublicKey
+	EphemeralPrivateKey *sm2.PrivateKey
+	EphemeralPublicKey  *sm2.PublicKey
+}
+
+// SM2KeyExchangeResult 密钥交换的中间值及结果，A为发起方、B为响应方，
+// 点以 04||X||Y 表示，未能计算的一方对应字段为空
+type SM2KeyExchangeResult struct {
+	ZA, ZB []byte
+	// X1Bar、X2Bar 为 x̄1 = 2^w + (x1 & (2^w-1))、x̄2，w = 127
+	X1Bar, X2Bar []byte
+
+	// A方视角: tA = (dA + x̄1·rA) mod n，U = [h·tA](PB + [x̄2]RB)，KA = KDF(xU||yU||ZA||ZB, klen)
+	TA, U, KA []byte
+	// HashU = Hash(xU||ZA||ZB||x1||y1||x2||y2)，S1 = Hash(0x02||yU||HashU)，SA = Hash(0x03||yU||HashU)
+	HashU, S1, SA []byte
+
+	// B方视角: tB = (dB + x̄2·rB) mod n，V = [h·tB](PA + [x̄1]RA)，KB = KDF(xV||yV||ZA||ZB, klen)
+	TB, V, KB []byte
+	// HashV = Hash(xV||ZA||ZB||x1||y1||x2||y2)，SB = Hash(0x02||yV||HashV)，S2 = Hash(0x03||yV||HashV)
+	HashV, SB, S2 []byte
+}
+
+// KeyMatch 双方均已计算时，KA与KB是否一致
+func (r *SM2KeyExchangeResult) KeyMatch() bool {
+	return r.KA != nil && r.KB != nil && bytes.Equal(r.KA, r.KB)
+}
+
+// sm2KeyExchangeW w = ⌈⌈log2(n)⌉/2⌉ - 1，SM2推荐曲线为127
+const sm2KeyExchangeW = 127
+
+// SM2KeyExchange 按GM/T 0003.3计算密钥交换的全部中间值，klen为共享密钥字节数。
+// 任一方提供了静态及临时私钥即计算该方视角的结果，至少需要一方；由私钥推导的公钥回填到a、b中
+func SM2KeyExchange(a, b *SM2KeyExchangeParty, klen int) (*SM2KeyExchangeResult, error) {
+	if klen <= 0 {
+		return nil, fmt.Errorf("共享密钥长度必须大于0")
+	}
+	if err := a.complete("A"); err != nil {
+		return nil, err
+	}
+	if err := b.complete("B"); err != nil {
+		return nil, err
+	}
+	canA, canB := a.hasPrivateKeys(), b.hasPrivateKeys()
+	if !canA && !canB {
+		return nil, fmt.Errorf("至少需要一方的静态私钥及临时私钥")
+	}
+
+	result := new(SM2KeyExchangeResult)
+	var err error
+	if result.ZA, err = sm2.ZA(a.StaticPublicKey, a.ID); err != nil {
+		return nil, fmt.Errorf("计算Z_A失败: %v", err)
+	}
+	if result.ZB, err = sm2.ZA(b.StaticPublicKey, b.ID); err != nil {
+		return nil, fmt.Errorf("计算Z_B失败: %v", err)
+	}
+	x1Bar := keyExchangeXBar(a.EphemeralPublicKey.X)
+	x2Bar := keyExchangeXBar(b.EphemeralPublicKey.X)
+	result.X1Bar, result.X2Bar = x1Bar.Bytes(), x2Bar.Bytes()
+
+	// x1||y1||x2||y2，两方计算确认值时共用
+	ephemeral := bytes.Join([][]byte{
+		fixedCoordinate(a.EphemeralPublicKey.X), fixedCoordinate(a.EphemeralPublicKey.Y),
+		fixedCoordinate(b.EphemeralPublicKey.X), fixedCoordinate(b.EphemeralPublicKey.Y),
+	}, nil)
+
+	if canA {
+		t, point, err := keyExchangeSharedPoint(a, x1Bar, b, x2Bar)
+		if err != nil {
+			return nil, fmt.Errorf("A方计算U失败: %v", err)
+		}
+		result.TA, result.U = t, point
+		if result.KA, err = sm3KDF(klen, point[1:], result.ZA, result.ZB); err != nil {
+			return nil, err
+		}
+		result.HashU, result.S1, result.SA = keyExchangeConfirmation(point, result.ZA, result.ZB, ephemeral)
+	}
+	if canB {
+		t, point, err := keyExchangeSharedPoint(b, x2Bar, a, x1Bar)
+		if err != nil {
+			return nil, fmt.Errorf("B方计算V失败: %v", err)
+		}
+		result.TB, result.V = t, point
+		if result.KB, err = sm3KDF(klen, point[1:], result.ZA, result.ZB); err != nil {
+			return nil, err
+		}
+		result.HashV, result.SB, result.S2 = keyExchangeConfirmation(point, result.ZA, result.ZB, ephemeral)
+	}
+	return result, nil
+}
+
+// complete 由私钥补全公钥，校验私钥与公钥一致、公钥在曲线上
+func (p *SM2KeyExchangeParty) complete(name string) error {
+	var err error
+	if p.StaticPublicKey, err = keyExchangePublicKey(p.StaticPrivateKey, p.StaticPublicKey); err != nil {
+		return fmt.Errorf("%s方静态密钥: %v", name, err)
+	}
+	if p.EphemeralPublicKey, err = keyExchangePublicKey(p.EphemeralPrivateKey, p.EphemeralPublicKey); err != nil {
+		return fmt.Errorf("%s方临时密钥: %v", name, err)
+	}
+	return nil
+}
+
+func (p *SM2KeyExchangeParty) hasPrivateKeys() bool {
+	return p.StaticPrivateKey != nil && p.EphemeralPrivateKey != nil
+}
+
+func keyExchangePublicKey(privateKey *sm2.PrivateKey, publicKey *sm2.PublicKey) (*sm2.PublicKey, error) {
+	curve := sm2.P256Sm2()
+	if privateKey != nil {
+		x, y := curve.ScalarBaseMult(privateKey.D.FillBytes(make([]byte, 32)))
+		if publicKey != nil && (publicKey.X.Cmp(x) != 0 || publicKey.Y.Cmp(y) != 0) {
+			return nil, fmt.Errorf("私钥与公钥不匹配")
+		}
+		return &sm2.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	if publicKey == nil {
+		return nil, fmt.Errorf("缺少公钥")
+	}
+	if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, fmt.Errorf("公钥不在SM2曲线上")
+	}
+	return publicKey, nil
+}
+
+// keyExchangeXBar x̄ = 2^w + (x & (2^w - 1))
+func keyExchangeXBar(x *big.Int) *big.Int {
+	w := new(big.Int).Lsh(big.NewInt(1), sm2KeyExchangeW)
+	low := new(big.Int).And(x, new(big.Int).Sub(w, big.NewInt(1)))
+	return low.Add(low, w)
+}
+
+// keyExchangeSharedPoint 计算本方 t = (d + x̄·r) mod n 及 [h·t](P + [x̄']R')，SM2推荐曲线余因子h为1
+func keyExchangeSharedPoint(self *SM2KeyExchangeParty, selfXBar *big.Int, peer *SM2KeyExchangeParty, peerXBar *big.Int) (t []byte, point []byte, err error) {
+	curve := sm2.P256Sm2()
+	n := curve.Params().N
+	tInt := new(big.Int).Mul(selfXBar, self.EphemeralPrivateKey.D)
+	tInt.Add(tInt, self.StaticPrivateKey.D)
+	tInt.Mod(tInt, n)
+
+	rx, ry := curve.ScalarMult(peer.EphemeralPublicKey.X, peer.EphemeralPublicKey.Y, peerXBar.Bytes())
+	sx, sy := curve.Add(peer.StaticPublicKey.X, peer.StaticPublicKey.Y, rx, ry)
+	x, y := curve.ScalarMult(sx, sy, tInt.FillBytes(make([]byte, 32)))
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, nil, fmt.Errorf("结果为无穷远点，协商失败")
+	}
+	point = append([]byte{0x04}, fixedCoordinate(x)...)
+	return tInt.FillBytes(make([]byte, 32)), append(point, fixedCoordinate(y)...), nil
+}
+
+// keyExchangeConfirmation 计算内层哈希及以0x02、0x03为前缀的两个确认值
+func keyExchangeConfirmation(point, za, zb, ephemeral []byte) (inner, s02, s03 []byte) {
+	x, y := point[1:33], point[33:]
+	inner = sm3.Sm3Sum(bytes.Join([][]byte{x, za, zb, ephemeral}, nil))
+	s02 = sm3.Sm3Sum(bytes.Join([][]byte{{0x02}, y, inner}, nil))
+	s03 = sm3.Sm3Sum(bytes.Join([][]byte{{0x03}, y, inner}, nil))
+	return inner, s02, s03
+}
+
+// sm3KDF GM/T 0003 密钥派生函数 KDF(Z, klen)，klen为字节数，结果全零时按标准返回错误
+func sm3KDF(klen int, z ...[]byte) ([]byte, error) {
+	out := make([]byte, 0, klen+32)
+	counter := make([]byte, 4)
+	for ct := uint32(1); len(out) < klen; ct++ {
+		h := sm3.New()
+		for _, part := range z {
+			h.Write(part)
+		}
+		binary.BigEndian.PutUint32(counter, ct)
+		h.Write(counter)
+		out = append(out, h.Sum(nil)...)
+	}
+	out = out[:klen]
+	if bytes.Equal(out, make([]byte, klen)) {
+		return nil, fmt.Errorf("KDF结果全为0，协商失败")
+	}
+	return out, nil
+}
+
+func fixedCoordinate(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, 32))
+}
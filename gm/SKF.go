@@ -0,0 +1,218 @@
+package gm
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// GM/T 0006 对称算法标识，用于SKF ENVELOPEDKEYBLOB的ulSymmAlgID
+const (
+	SGD_SM1_ECB   uint32 = 0x00000101
+	SGD_SM1_CBC   uint32 = 0x00000102
+	SGD_SSF33_ECB uint32 = 0x00000201
+	SGD_SSF33_CBC uint32 = 0x00000202
+	SGD_SM4_ECB   uint32 = 0x00000401
+	SGD_SM4_CBC   uint32 = 0x00000402
+)
+
+// SGDAlgorithmNames GM/T 0006 算法标识名称
+var SGDAlgorithmNames = map[uint32]string{
+	SGD_SM1_ECB:   "SGD_SM1_ECB",
+	SGD_SM1_CBC:   "SGD_SM1_CBC",
+	SGD_SSF33_ECB: "SGD_SSF33_ECB",
+	SGD_SSF33_CBC: "SGD_SSF33_CBC",
+	SGD_SM4_ECB:   "SGD_SM4_ECB",
+	SGD_SM4_CBC:   "SGD_SM4_CBC",
+}
+
+// sgdAlgorithmOIDs 算法标识与SM2EnvelopedKey中SymAlgID的对应关系，SSF33无OID
+var sgdAlgorithmOIDs = map[uint32]asn1.ObjectIdentifier{
+	SGD_SM1_ECB: {1, 2, 156, 10197, 1, 102, 1},
+	SGD_SM1_CBC: {1, 2, 156, 10197, 1, 102, 2},
+	SGD_SM4_ECB: OidSM4ECB,
+	SGD_SM4_CBC: OidSM4CBC,
+}
+
+// sgdAlgorithmID 按OID查找算法标识，未指明模式的SM4 OID(CreateSM2EnvelopedKey使用)按ECB处理
+func sgdAlgorithmID(oid asn1.ObjectIdentifier) (uint32, bool) {
+	if oid.Equal(OidSM4) {
+		return SGD_SM4_ECB, true
+	}
+	for algID, algOID := range sgdAlgorithmOIDs {
+		if algOID.Equal(oid) {
+			return algID, true
+		}
+	}
+	return 0, false
+}
+
+// SKF结构中坐标及私钥按64字节存放，256位数值右对齐
+const (
+	eccMaxCoordinateLen = 64
+	eccMaxModulusLen    = 64
+	// envelopedKeyBlobFixedLen ENVELOPEDKEYBLOB中Cipher之前的定长部分
+	envelopedKeyBlobFixedLen = 4 + 4 + 4 + eccMaxModulusLen + eccPublicKeyBlobLen + eccMaxCoordinateLen*2 + 32 + 4
+	eccPublicKeyBlobLen      = 4 + eccMaxCoordinateLen*2
+)
+
+// ECCPublicKeyBlob GM/T 0016 ECCPUBLICKEYBLOB
+type ECCPublicKeyBlob struct {
+	BitLen      uint32
+	XCoordinate [eccMaxCoordinateLen]byte
+	YCoordinate [eccMaxCoordinateLen]byte
+}
+
+// ECCCipherBlob GM/T 0016 ECCCIPHERBLOB
+type ECCCipherBlob struct {
+	XCoordinate [eccMaxCoordinateLen]byte
+	YCoordinate [eccMaxCoordinateLen]byte
+	Hash        [32]byte
+	CipherLen   uint32
+	Cipher      []byte
+}
+
+// EnvelopedKeyBlob GM/T 0016 ENVELOPEDKEYBLOB，SKF接口导入加密密钥对时使用的定长二进制信封，整数为小端序
+type EnvelopedKeyBlob struct {
+	Version         uint32
+	SymmAlgID       uint32
+	Bits            uint32
+	EncryptedPriKey [eccMaxModulusLen]byte
+	PubKey          ECCPublicKeyBlob
+	ECCCipherBlob   ECCCipherBlob
+	Trailing        int // Cipher之后多余的字节数，部分厂商按固定长度填充
+}
+
+// ParseEnvelopedKeyBlob 解析SKF ENVELOPEDKEYBLOB
+func ParseEnvelopedKeyBlob(data []byte) (*EnvelopedKeyBlob, error) {
+	if len(data) < envelopedKeyBlobFixedLen {
+		return nil, fmt.Errorf("ENVELOPEDKEYBLOB长度%d字节，至少应为%d字节", len(data), envelopedKeyBlobFixedLen)
+	}
+	blob := new(EnvelopedKeyBlob)
+	blob.Version = binary.LittleEndian.Uint32(data[0:])
+	blob.SymmAlgID = binary.LittleEndian.Uint32(data[4:])
+	blob.Bits = binary.LittleEndian.Uint32(data[8:])
+	offset := 12
+	offset += copy(blob.EncryptedPriKey[:], data[offset:])
+	blob.PubKey.BitLen = binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+	offset += copy(blob.PubKey.XCoordinate[:], data[offset:])
+	offset += copy(blob.PubKey.YCoordinate[:], data[offset:])
+	cipherBlob := &blob.ECCCipherBlob
+	offset += copy(cipherBlob.XCoordinate[:], data[offset:])
+	offset += copy(cipherBlob.YCoordinate[:], data[offset:])
+	offset += copy(cipherBlob.Hash[:], data[offset:])
+	cipherBlob.CipherLen = binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+
+	if cipherBlob.CipherLen == 0 || uint64(cipherBlob.CipherLen) > uint64(len(data)-offset) {
+		return nil, fmt.Errorf("ECCCIPHERBLOB.CipherLen为%d，剩余数据仅%d字节", cipherBlob.CipherLen, len(data)-offset)
+	}
+	if blob.PubKey.BitLen != 256 {
+		return nil, fmt.Errorf("ECCPUBLICKEYBLOB.BitLen为%d，SM2应为256", blob.PubKey.BitLen)
+	}
+	cipherBlob.Cipher = append([]byte(nil), data[offset:offset+int(cipherBlob.CipherLen)]...)
+	blob.Trailing = len(data) - offset - int(cipherBlob.CipherLen)
+	return blob, nil
+}
+
+// MarshalEnvelopedKeyBlob 编码ENVELOPEDKEYBLOB，Cipher按CipherLen输出
+func MarshalEnvelopedKeyBlob(blob *EnvelopedKeyBlob) []byte {
+	cipherBlob := &blob.ECCCipherBlob
+	out := make([]byte, 0, envelopedKeyBlobFixedLen+len(cipherBlob.Cipher))
+	out = binary.LittleEndian.AppendUint32(out, blob.Version)
+	out = binary.LittleEndian.AppendUint32(out, blob.SymmAlgID)
+	out = binary.LittleEndian.AppendUint32(out, blob.Bits)
+	out = append(out, blob.EncryptedPriKey[:]...)
+	out = binary.LittleEndian.AppendUint32(out, blob.PubKey.BitLen)
+	out = append(out, blob.PubKey.XCoordinate[:]...)
+	out = append(out, blob.PubKey.YCoordinate[:]...)
+	out = append(out, cipherBlob.XCoordinate[:]...)
+	out = append(out, cipherBlob.YCoordinate[:]...)
+	out = append(out, cipherBlob.Hash[:]...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(cipherBlob.Cipher)))
+	return append(out, cipherBlob.Cipher...)
+}
+
+// EnvelopedKeyBlobToSM2EnvelopedKey 将SKF ENVELOPEDKEYBLOB转换为GM/T 0009 SM2EnvelopedKey。
+// cbEncryptedPriKey前32字节全零时取右对齐的32字节私钥密文；部分厂商将密文左对齐存放，
+// 后32字节全零时取前32字节；否则按64字节(前补零私钥)密文转换
+func EnvelopedKeyBlobToSM2EnvelopedKey(blob *EnvelopedKeyBlob) (*SM2EnvelopedKey, error) {
+	oid, ok := sgdAlgorithmOIDs[blob.SymmAlgID]
+	if !ok {
+		return nil, fmt.Errorf("对称算法标识0x%08X没有对应的OID，无法转换", blob.SymmAlgID)
+	}
+	cipherBlob := &blob.ECCCipherBlob
+	env := &SM2EnvelopedKey{
+		SymAlgID: AlgorithmIdentifier{Algorithm: oid},
+		Sm2cipher: SM2Cipher{
+			X:          new(big.Int).SetBytes(cipherBlob.XCoordinate[:]),
+			Y:          new(big.Int).SetBytes(cipherBlob.YCoordinate[:]),
+			Hash:       append([]byte(nil), cipherBlob.Hash[:]...),
+			CipherText: append([]byte(nil), cipherBlob.Cipher...),
+		},
+	}
+
+	publicKey := make([]byte, 65)
+	publicKey[0] = 0x04
+	copy(publicKey[1:33], blob.PubKey.XCoordinate[32:])
+	copy(publicKey[33:], blob.PubKey.YCoordinate[32:])
+	env.PublicKey = asn1.BitString{Bytes: publicKey, BitLength: 8 * len(publicKey)}
+
+	encrypted := blob.EncryptedPriKey[:]
+	zero := make([]byte, 32)
+	switch {
+	case bytes.Equal(encrypted[:32], zero):
+		encrypted = encrypted[32:]
+	case bytes.Equal(encrypted[32:], zero):
+		encrypted = encrypted[:32]
+	}
+	encrypted = append([]byte(nil), encrypted...)
+	env.Sm2EncryptedPrivateKey = asn1.BitString{Bytes: encrypted, BitLength: 8 * len(encrypted)}
+	return env, nil
+}
+
+// SM2EnvelopedKeyToEnvelopedKeyBlob EnvelopedKeyBlobToSM2EnvelopedKey的逆运算，
+// 32字节私钥密文右对齐存放于cbEncryptedPriKey后32字节，CBC模式的IV参数无法在SKF结构中保留
+func SM2EnvelopedKeyToEnvelopedKeyBlob(env *SM2EnvelopedKey) (*EnvelopedKeyBlob, error) {
+	blob := &EnvelopedKeyBlob{Version: 1, Bits: 256}
+	algID, ok := sgdAlgorithmID(env.SymAlgID.Algorithm)
+	if !ok {
+		return nil, fmt.Errorf("对称算法%s没有对应的GM/T 0006算法标识，无法转换", env.SymAlgID.Algorithm)
+	}
+	blob.SymmAlgID = algID
+
+	encrypted := env.Sm2EncryptedPrivateKey.Bytes
+	if len(encrypted) != 32 && len(encrypted) != 64 {
+		return nil, fmt.Errorf("加密私钥长度%d字节，应为32或64字节", len(encrypted))
+	}
+	copy(blob.EncryptedPriKey[eccMaxModulusLen-len(encrypted):], encrypted)
+
+	publicKey := env.PublicKey.Bytes
+	if len(publicKey) == 65 && publicKey[0] == 0x04 {
+		publicKey = publicKey[1:]
+	}
+	if len(publicKey) != 64 {
+		return nil, fmt.Errorf("公钥长度%d字节，应为 04||X||Y 或 X||Y", len(env.PublicKey.Bytes))
+	}
+	blob.PubKey.BitLen = 256
+	copy(blob.PubKey.XCoordinate[32:], publicKey[:32])
+	copy(blob.PubKey.YCoordinate[32:], publicKey[32:])
+
+	cipher := env.Sm2cipher
+	if cipher.X == nil || cipher.Y == nil || cipher.X.BitLen() > 256 || cipher.Y.BitLen() > 256 {
+		return nil, fmt.Errorf("SM2Cipher坐标无效")
+	}
+	if len(cipher.Hash) != 32 {
+		return nil, fmt.Errorf("SM2Cipher.Hash长度%d字节，应为32字节", len(cipher.Hash))
+	}
+	cipherBlob := &blob.ECCCipherBlob
+	cipher.X.FillBytes(cipherBlob.XCoordinate[32:])
+	cipher.Y.FillBytes(cipherBlob.YCoordinate[32:])
+	copy(cipherBlob.Hash[:], cipher.Hash)
+	cipherBlob.Cipher = append([]byte(nil), cipher.CipherText...)
+	cipherBlob.CipherLen = uint32(len(cipherBlob.Cipher))
+	return blob, nil
+}
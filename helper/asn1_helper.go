@@ -57,6 +57,40 @@ type ASN1Node struct {
 	Depth              int    // 添加深度字段防止无限递归
 }
 
+// TagName 根据节点Tag（已叠加class与结构位）返回可读的类型名称
+func TagName(tag int) string {
+	prefix := ""
+	//32 = 0x20, ASN1中小于0x20的都是通用简单类型
+
+	//0x20 到 0x40 通用,结构类型
+	if 32 <= tag && tag < 64 {
+		//prefix = "Universal Structure "
+		tag -= 32
+	} else if 64 <= tag && tag < 96 {
+		prefix = "Application Simple "
+		tag -= 64
+	} else if 96 <= tag && tag < 128 {
+		prefix = "Application Structure "
+		tag -= 96
+	} else if 128 <= tag && tag < 160 {
+		prefix = "Context Specific Simple "
+		tag -= 128
+	} else if 160 <= tag && tag < 192 {
+		prefix = "Context Specific Structure "
+		tag -= 160
+	} else if 192 <= tag && tag < 224 {
+		prefix = "Private Simple "
+		tag -= 192
+	} else if 224 <= tag && tag < 256 {
+		prefix = "Private Structure "
+		tag -= 224
+	}
+	if len(prefix) > 0 {
+		prefix = fmt.Sprintf("%s :", prefix)
+	}
+	return prefix + TagToName[tag]
+}
+
 // ParseAsn1WithMaxDepth 带最大深度限制的ASN1解析
 func ParseAsn1(data []byte) ASN1Node {
 	return parseAsn1WithDepth(data, 0, 20) // 最大深度20层
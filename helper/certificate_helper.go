@@ -1,11 +1,34 @@
 package helper
 
 import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
 	"fmt"
 
 	gm "github.com/zaneway/cain-go/x509"
 )
 
+var (
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidGMSignedData    = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 2}
+)
+
+// certificateASN1 仅拆分到TBSCertificate一层，用于改写扩展后重新解析
+type certificateASN1 struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm asn1.RawValue
+	SignatureValue     asn1.RawValue
+}
+
+// signedDataCertificatesASN1 SignedData中证书集合之前的字段，其后的字段不解析
+type signedDataCertificatesASN1 struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+}
+
 var KeyUsages = make(map[gm.KeyUsage]string)
 
 func init() {
@@ -25,12 +48,139 @@ func ParseCertificate(cert []byte) (*gm.Certificate, error) {
 	}
 
 	certificate, err := gm.ParseCertificate(cert)
+	if errors.As(err, new(gm.UnhandledCriticalExtension)) {
+		certificate, err = parseWithNameConstraints(cert)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("解析证书失败: %v", err)
 	}
 	return certificate, nil
 }
 
+// parseWithNameConstraints cain-go遇到含排除子树或非DNS名称的关键名称约束时拒绝解析，
+// 此时去掉该扩展的关键标志后解析，再还原原始DER与关键标志，名称约束由路径验证处理
+func parseWithNameConstraints(der []byte) (*gm.Certificate, error) {
+	var cert certificateASN1
+	if err := unmarshalExact(der, &cert); err != nil {
+		return nil, err
+	}
+	var fields []asn1.RawValue
+	for rest := cert.TBSCertificate.Bytes; len(rest) > 0; {
+		var field asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	last := len(fields) - 1
+	if last < 0 || fields[last].Class != asn1.ClassContextSpecific || fields[last].Tag != 3 {
+		return nil, gm.UnhandledCriticalExtension{}
+	}
+	var extensions []pkix.Extension
+	if err := unmarshalExact(fields[last].Bytes, &extensions); err != nil {
+		return nil, err
+	}
+	patched := -1
+	for i := range extensions {
+		if extensions[i].Id.String() == "2.5.29.30" && extensions[i].Critical {
+			extensions[i].Critical = false
+			patched = i
+		}
+	}
+	if patched < 0 {
+		return nil, gm.UnhandledCriticalExtension{}
+	}
+
+	encoded, err := asn1.Marshal(extensions)
+	if err != nil {
+		return nil, err
+	}
+	fields[last] = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 3, IsCompound: true, Bytes: encoded}
+	var tbs []byte
+	for _, field := range fields {
+		encodedField, err := asn1.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		tbs = append(tbs, encodedField...)
+	}
+	tbsFull, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: tbs})
+	if err != nil {
+		return nil, err
+	}
+	modified, err := asn1.Marshal(certificateASN1{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbsFull},
+		SignatureAlgorithm: cert.SignatureAlgorithm,
+		SignatureValue:     cert.SignatureValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := gm.ParseCertificate(modified)
+	if err != nil {
+		return nil, err
+	}
+	certificate.Raw = der
+	certificate.RawTBSCertificate = cert.TBSCertificate.FullBytes
+	for i := range certificate.Extensions {
+		if certificate.Extensions[i].Id.String() == "2.5.29.30" {
+			certificate.Extensions[i].Critical = true
+		}
+	}
+	return certificate, nil
+}
+
+// ParseCertificates 解析单个DER证书、连续拼接的DER证书或P7B(含GM/T 0010)中的证书
+func ParseCertificates(der []byte) ([]*gm.Certificate, error) {
+	raws, err := pkcs7Certificates(der)
+	if err != nil {
+		return nil, err
+	}
+	if raws == nil {
+		raws = der
+	}
+	var certs []*gm.Certificate
+	for rest := raws; len(rest) > 0; {
+		var raw asn1.RawValue
+		if rest, err = asn1.Unmarshal(rest, &raw); err != nil {
+			return nil, fmt.Errorf("解析证书失败: %v", err)
+		}
+		// 跳过证书集合中的属性证书等其他类型
+		if raw.Class != asn1.ClassUniversal {
+			continue
+		}
+		cert, err := ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("未找到证书")
+	}
+	return certs, nil
+}
+
+// pkcs7Certificates 返回SignedData中证书集合的内容，输入不是SignedData时返回nil
+func pkcs7Certificates(der []byte) ([]byte, error) {
+	var info pfxContentInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, nil
+	}
+	if !info.ContentType.Equal(oidPKCS7SignedData) && !info.ContentType.Equal(oidGMSignedData) {
+		return nil, nil
+	}
+	var signedData signedDataCertificatesASN1
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("解析SignedData失败: %v", err)
+	}
+	if len(signedData.Certificates.FullBytes) == 0 {
+		return nil, fmt.Errorf("SignedData中没有证书")
+	}
+	return signedData.Certificates.Bytes, nil
+}
+
 // 根据密钥用途解析具体值
 func ParseKeyUsage(usage gm.KeyUsage) string {
 	var result string
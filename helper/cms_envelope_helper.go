@@ -0,0 +1,643 @@
+package helper
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/zaneway/cain-go/sm2"
+	"github.com/zaneway/cain-go/sm4"
+	"github.com/zaneway/cain-go/x509"
+)
+
+// 数字信封内容加密算法
+const (
+	EnvelopeCipherSM4CBC    = "SM4-CBC"
+	EnvelopeCipherAES128CBC = "AES-128-CBC"
+	EnvelopeCipherAES256CBC = "AES-256-CBC"
+	EnvelopeCipherAES128GCM = "AES-128-GCM"
+	EnvelopeCipherAES256GCM = "AES-256-GCM"
+)
+
+// EnvelopeCiphers 可选的内容加密算法，GCM模式生成RFC 5083 AuthEnvelopedData
+var EnvelopeCiphers = []string{EnvelopeCipherSM4CBC, EnvelopeCipherAES128CBC, EnvelopeCipherAES256CBC, EnvelopeCipherAES128GCM, EnvelopeCipherAES256GCM}
+
+// 数字信封相关OID
+var (
+	oidAuthEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 23}
+	oidRSAESOAEP         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}
+	oidMGF1              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+	oidPSpecified        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 9}
+	oidSM2Encryption     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 3}
+	oidAES128GCM         = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 6}
+	oidAES192GCM         = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 26}
+	oidAES256GCM         = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+)
+
+// EnvelopeAlgorithmNames 密钥加密及GCM内容加密算法OID与名称的映射，其余分组密码见PBEAlgorithmNames
+var EnvelopeAlgorithmNames = map[string]string{
+	oidPublicKeyRSA.String():  "rsaEncryption",
+	oidRSAESOAEP.String():     "RSAES-OAEP",
+	oidSM2Encryption.String(): "SM2加密",
+	oidPublicKeySM2.String():  "SM2",
+	oidAES128GCM.String():     "AES-128-GCM",
+	oidAES192GCM.String():     "AES-192-GCM",
+	oidAES256GCM.String():     "AES-256-GCM",
+}
+
+// cmsContentCipher 内容加密算法的OID、密钥长度及分组密码
+type cmsContentCipher struct {
+	oid      asn1.ObjectIdentifier
+	keyLen   int
+	newBlock func([]byte) (cipher.Block, error)
+	gcm      bool
+}
+
+var cmsContentCiphers = []cmsContentCipher{
+	{oidSM4CBC, 16, sm4.NewCipher, false},
+	{oidAES128CBC, 16, aes.NewCipher, false},
+	{oidAES192CBC, 24, aes.NewCipher, false},
+	{oidAES256CBC, 32, aes.NewCipher, false},
+	{oidDESEDE3CBC, 24, des.NewTripleDESCipher, false},
+	{oidAES128GCM, 16, aes.NewCipher, true},
+	{oidAES192GCM, 24, aes.NewCipher, true},
+	{oidAES256GCM, 32, aes.NewCipher, true},
+}
+
+// envelopeCipherOIDs 可选内容加密算法名称对应的OID
+var envelopeCipherOIDs = map[string]asn1.ObjectIdentifier{
+	EnvelopeCipherSM4CBC:    oidSM4CBC,
+	EnvelopeCipherAES128CBC: oidAES128CBC,
+	EnvelopeCipherAES256CBC: oidAES256CBC,
+	EnvelopeCipherAES128GCM: oidAES128GCM,
+	EnvelopeCipherAES256GCM: oidAES256GCM,
+}
+
+type cmsEnvelopedDataASN1 struct {
+	Version              int
+	OriginatorInfo       asn1.RawValue   `asn1:"optional,tag:0"`
+	RecipientInfos       []asn1.RawValue `asn1:"set"`
+	EncryptedContentInfo cmsEncryptedContentInfoASN1
+	UnprotectedAttrs     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// cmsAuthEnvelopedDataASN1 RFC 5083 AuthEnvelopedData，用于AES-GCM等认证加密
+type cmsAuthEnvelopedDataASN1 struct {
+	Version                  int
+	OriginatorInfo           asn1.RawValue   `asn1:"optional,tag:0"`
+	RecipientInfos           []asn1.RawValue `asn1:"set"`
+	AuthEncryptedContentInfo cmsEncryptedContentInfoASN1
+	AuthAttrs                asn1.RawValue `asn1:"optional,tag:1"`
+	MAC                      []byte
+	UnauthAttrs              asn1.RawValue `asn1:"optional,tag:2"`
+}
+
+type cmsEncryptedContentInfoASN1 struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	// EncryptedContent 为[0]隐式标签的OCTET STRING，BER编码时可能是分段的构造类型
+	EncryptedContent asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type cmsKeyTransRecipientInfoASN1 struct {
+	Version                int
+	RID                    asn1.RawValue
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type rsaOAEPParamsASN1 struct {
+	HashAlgorithm    pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:0"`
+	MaskGenAlgorithm pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:1"`
+	PSourceAlgorithm pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:2"`
+}
+
+type gcmParamsASN1 struct {
+	Nonce  []byte
+	ICVLen int `asn1:"optional,default:12"`
+}
+
+// cmsRecipientInfoTypes RecipientInfo CHOICE中隐式标签对应的类型名称
+var cmsRecipientInfoTypes = map[int]string{
+	1: "keyAgreeRecipientInfo",
+	2: "kekRecipientInfo",
+	3: "passwordRecipientInfo",
+	4: "otherRecipientInfo",
+}
+
+// EnvelopedDataInfo CMS EnvelopedData(含GM/T 0010)或AuthEnvelopedData信息
+type EnvelopedDataInfo struct {
+	Version int
+	// ContentType 外层内容类型
+	ContentType                string
+	EncryptedContentType       string
+	ContentEncryptionAlgorithm string
+	EncryptedContent           []byte
+	// MAC AuthEnvelopedData的认证码，EnvelopedData为nil
+	MAC                   []byte
+	Recipients            []*CMSRecipientInfo
+	UnprotectedAttributes []CMSAttribute
+
+	contentAlg pkix.AlgorithmIdentifier
+	// authAttrs AuthEnvelopedData认证属性的SET编码，作为GCM的附加认证数据
+	authAttrs []byte
+}
+
+// CMSRecipientInfo 接收者信息，仅keyTransRecipientInfo可解密
+type CMSRecipientInfo struct {
+	Type    string
+	Version int
+	CMSCertificateID
+	KeyEncryptionAlgorithm string
+	EncryptedKey           []byte
+
+	keyAlg pkix.AlgorithmIdentifier
+}
+
+// envelopeAlgorithmName 返回密钥加密或内容加密算法名称，未知时返回OID
+func envelopeAlgorithmName(oid asn1.ObjectIdentifier) string {
+	if name, ok := EnvelopeAlgorithmNames[oid.String()]; ok {
+		return name
+	}
+	return pbeAlgorithmName(oid)
+}
+
+// ParseEnvelopedData 解析ContentInfo封装的EnvelopedData、GM/T 0010 EnvelopedData或AuthEnvelopedData，支持BER不定长编码
+func ParseEnvelopedData(data []byte) (*EnvelopedDataInfo, error) {
+	der, err := berToDER(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析CMS结构失败: %v", err)
+	}
+	var contentInfo pfxContentInfo
+	if err := unmarshalExact(der, &contentInfo); err != nil {
+		return nil, fmt.Errorf("解析ContentInfo失败: %v", err)
+	}
+
+	info := &EnvelopedDataInfo{ContentType: oidName(CMSNames, contentInfo.ContentType)}
+	var recipientInfos []asn1.RawValue
+	var eci cmsEncryptedContentInfoASN1
+	switch {
+	case contentInfo.ContentType.Equal(oidPKCS7EnvelopedData), contentInfo.ContentType.Equal(oidGMEnvelopedData):
+		var ed cmsEnvelopedDataASN1
+		if err := unmarshalExact(contentInfo.Content.Bytes, &ed); err != nil {
+			return nil, fmt.Errorf("解析EnvelopedData失败: %v", err)
+		}
+		info.Version, recipientInfos, eci = ed.Version, ed.RecipientInfos, ed.EncryptedContentInfo
+		if len(ed.UnprotectedAttrs.FullBytes) > 0 {
+			attrs, err := parseCMSAttributes(ed.UnprotectedAttrs.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("解析非保护属性失败: %v", err)
+			}
+			info.UnprotectedAttributes = describeCMSAttributes(attrs)
+		}
+	case contentInfo.ContentType.Equal(oidAuthEnvelopedData):
+		var aed cmsAuthEnvelopedDataASN1
+		if err := unmarshalExact(contentInfo.Content.Bytes, &aed); err != nil {
+			return nil, fmt.Errorf("解析AuthEnvelopedData失败: %v", err)
+		}
+		info.Version, recipientInfos, eci = aed.Version, aed.RecipientInfos, aed.AuthEncryptedContentInfo
+		info.MAC = aed.MAC
+		if len(aed.AuthAttrs.FullBytes) > 0 {
+			// 认证属性以SET OF的DER编码参与认证，需将[1]隐式标签还原为SET
+			info.authAttrs = append([]byte{0x31}, aed.AuthAttrs.FullBytes[1:]...)
+		}
+	default:
+		return nil, fmt.Errorf("内容类型不是EnvelopedData: %s", oidName(CMSNames, contentInfo.ContentType))
+	}
+
+	info.EncryptedContentType = oidName(CMSNames, eci.ContentType)
+	info.ContentEncryptionAlgorithm = envelopeAlgorithmName(eci.ContentEncryptionAlgorithm.Algorithm)
+	info.contentAlg = eci.ContentEncryptionAlgorithm
+	if len(eci.EncryptedContent.FullBytes) == 0 {
+		return nil, fmt.Errorf("不支持密文不在信封内的EnvelopedData")
+	}
+	if info.EncryptedContent, err = cmsOctets(eci.EncryptedContent); err != nil {
+		return nil, fmt.Errorf("解析密文失败: %v", err)
+	}
+	for i, raw := range recipientInfos {
+		recipient, err := parseCMSRecipientInfo(raw)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个接收者: %v", i+1, err)
+		}
+		info.Recipients = append(info.Recipients, recipient)
+	}
+	return info, nil
+}
+
+// cmsOctets 取隐式标签OCTET STRING的内容，berToDER不会合并隐式标签下分段的OCTET STRING，此处逐段拼接
+func cmsOctets(raw asn1.RawValue) ([]byte, error) {
+	if !raw.IsCompound {
+		return raw.Bytes, nil
+	}
+	var content []byte
+	for rest := raw.Bytes; len(rest) > 0; {
+		var segment []byte
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &segment); err != nil {
+			return nil, err
+		}
+		content = append(content, segment...)
+	}
+	return content, nil
+}
+
+func parseCMSRecipientInfo(raw asn1.RawValue) (*CMSRecipientInfo, error) {
+	if raw.Class == asn1.ClassContextSpecific {
+		name, ok := cmsRecipientInfoTypes[raw.Tag]
+		if !ok {
+			return nil, fmt.Errorf("无法识别的接收者类型[%d]", raw.Tag)
+		}
+		return &CMSRecipientInfo{Type: name}, nil
+	}
+	var ktri cmsKeyTransRecipientInfoASN1
+	if err := unmarshalExact(raw.FullBytes, &ktri); err != nil {
+		return nil, fmt.Errorf("解析keyTransRecipientInfo失败: %v", err)
+	}
+	recipient := &CMSRecipientInfo{
+		Type:                   "keyTransRecipientInfo",
+		Version:                ktri.Version,
+		KeyEncryptionAlgorithm: envelopeAlgorithmName(ktri.KeyEncryptionAlgorithm.Algorithm),
+		EncryptedKey:           ktri.EncryptedKey,
+		keyAlg:                 ktri.KeyEncryptionAlgorithm,
+	}
+	var err error
+	if recipient.CMSCertificateID, err = parseCMSCertificateID(ktri.RID); err != nil {
+		return nil, fmt.Errorf("解析接收者标识失败: %v", err)
+	}
+	return recipient, nil
+}
+
+// DecryptEnvelopedData 以接收者私钥(SM2或RSA)解密，cert不为nil时按证书匹配接收者，
+// 否则依次尝试密钥算法与私钥相符的接收者；返回明文及解密所用的接收者
+func DecryptEnvelopedData(info *EnvelopedDataInfo, key crypto.Signer, cert *x509.Certificate) ([]byte, *CMSRecipientInfo, error) {
+	if cert != nil {
+		if err := checkKeyMatchesCertificate(key, cert); err != nil {
+			return nil, nil, err
+		}
+	}
+	var lastErr error
+	for _, recipient := range info.Recipients {
+		if recipient.keyAlg.Algorithm == nil || (cert != nil && !recipient.matches(cert)) {
+			continue
+		}
+		if !recipient.acceptsKey(key) {
+			continue
+		}
+		contentKey, err := recipient.decryptKey(key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		plain, err := decryptCMSContent(info.contentAlg, contentKey, info.EncryptedContent, info.MAC, info.authAttrs)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return plain, recipient, nil
+	}
+	if lastErr != nil {
+		return nil, nil, lastErr
+	}
+	if cert != nil {
+		return nil, nil, fmt.Errorf("信封中没有与证书对应的接收者")
+	}
+	return nil, nil, fmt.Errorf("信封中没有可用该私钥解密的接收者")
+}
+
+// acceptsKey 判断接收者的密钥加密算法是否与私钥类型相符
+func (recipient *CMSRecipientInfo) acceptsKey(key crypto.Signer) bool {
+	alg := recipient.keyAlg.Algorithm
+	switch key.(type) {
+	case *sm2.PrivateKey:
+		return alg.Equal(oidSM2Encryption) || alg.Equal(oidPublicKeySM2)
+	case *rsa.PrivateKey:
+		return alg.Equal(oidPublicKeyRSA) || alg.Equal(oidRSAESOAEP)
+	}
+	return false
+}
+
+// decryptKey 解密内容加密密钥，SM2密文为SM2Cipher结构(兼容C1C3C2拼接)，RSA支持PKCS#1 v1.5与OAEP
+func (recipient *CMSRecipientInfo) decryptKey(key crypto.Signer) ([]byte, error) {
+	switch priv := key.(type) {
+	case *sm2.PrivateKey:
+		encrypted := recipient.EncryptedKey
+		if len(encrypted) > 0 && encrypted[0] == 0x30 {
+			contentKey, err := priv.DecryptAsn1(encrypted)
+			if err != nil {
+				return nil, fmt.Errorf("SM2解密内容加密密钥失败: %v", err)
+			}
+			return contentKey, nil
+		}
+		if len(encrypted) > 0 && encrypted[0] != 0x04 {
+			encrypted = append([]byte{0x04}, encrypted...)
+		}
+		contentKey, err := sm2.Decrypt(priv, encrypted, sm2.C1C3C2)
+		if err != nil {
+			return nil, fmt.Errorf("SM2解密内容加密密钥失败: %v", err)
+		}
+		return contentKey, nil
+	case *rsa.PrivateKey:
+		if !recipient.keyAlg.Algorithm.Equal(oidRSAESOAEP) {
+			contentKey, err := rsa.DecryptPKCS1v15(rand.Reader, priv, recipient.EncryptedKey)
+			if err != nil {
+				return nil, fmt.Errorf("RSA解密内容加密密钥失败: %v", err)
+			}
+			return contentKey, nil
+		}
+		opts, err := parseRSAOAEPParams(recipient.keyAlg.Parameters.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		contentKey, err := priv.Decrypt(rand.Reader, recipient.EncryptedKey, opts)
+		if err != nil {
+			return nil, fmt.Errorf("RSA-OAEP解密内容加密密钥失败: %v", err)
+		}
+		return contentKey, nil
+	}
+	return nil, fmt.Errorf("不支持的私钥类型: %T，仅支持SM2与RSA", key)
+}
+
+// parseRSAOAEPParams 解析RSAES-OAEP参数，缺省时摘要及MGF1均为SHA1
+func parseRSAOAEPParams(der []byte) (*rsa.OAEPOptions, error) {
+	opts := &rsa.OAEPOptions{Hash: crypto.SHA1, MGFHash: crypto.SHA1}
+	if len(der) == 0 || bytes.Equal(der, asn1.NullBytes) {
+		return opts, nil
+	}
+	var params rsaOAEPParamsASN1
+	if err := unmarshalExact(der, &params); err != nil {
+		return nil, fmt.Errorf("解析RSAES-OAEP参数失败: %v", err)
+	}
+	if params.HashAlgorithm.Algorithm != nil {
+		hash, ok := cmsCryptoHashes[params.HashAlgorithm.Algorithm.String()]
+		if !ok {
+			return nil, fmt.Errorf("RSAES-OAEP不支持摘要算法%s", pbeAlgorithmName(params.HashAlgorithm.Algorithm))
+		}
+		opts.Hash = hash
+	}
+	if params.MaskGenAlgorithm.Algorithm != nil {
+		var mgfHash pkix.AlgorithmIdentifier
+		if !params.MaskGenAlgorithm.Algorithm.Equal(oidMGF1) || unmarshalExact(params.MaskGenAlgorithm.Parameters.FullBytes, &mgfHash) != nil {
+			return nil, fmt.Errorf("RSAES-OAEP仅支持MGF1掩码生成函数")
+		}
+		hash, ok := cmsCryptoHashes[mgfHash.Algorithm.String()]
+		if !ok {
+			return nil, fmt.Errorf("MGF1不支持摘要算法%s", pbeAlgorithmName(mgfHash.Algorithm))
+		}
+		opts.MGFHash = hash
+	}
+	if params.PSourceAlgorithm.Algorithm != nil {
+		if !params.PSourceAlgorithm.Algorithm.Equal(oidPSpecified) || unmarshalExact(params.PSourceAlgorithm.Parameters.FullBytes, &opts.Label) != nil {
+			return nil, fmt.Errorf("无法识别的RSAES-OAEP标签参数")
+		}
+	}
+	return opts, nil
+}
+
+// findContentCipher 按OID查找内容加密算法
+func findContentCipher(oid asn1.ObjectIdentifier) (cmsContentCipher, error) {
+	for _, c := range cmsContentCiphers {
+		if c.oid.Equal(oid) {
+			return c, nil
+		}
+	}
+	return cmsContentCipher{}, fmt.Errorf("不支持的内容加密算法: %s", envelopeAlgorithmName(oid))
+}
+
+// decryptCMSContent 解密内容，CBC模式去除PKCS#7填充；GCM模式mac为nil时认证标签位于密文末尾
+func decryptCMSContent(alg pkix.AlgorithmIdentifier, key, ciphertext, mac, aad []byte) ([]byte, error) {
+	spec, err := findContentCipher(alg.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != spec.keyLen {
+		return nil, fmt.Errorf("内容加密密钥长度 %d 与 %s 不匹配", len(key), envelopeAlgorithmName(alg.Algorithm))
+	}
+	block, err := spec.newBlock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.gcm {
+		var params gcmParamsASN1
+		if err := unmarshalExact(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("解析GCM参数失败: %v", err)
+		}
+		aead, err := cipher.NewGCMWithNonceSize(block, len(params.Nonce))
+		if err != nil {
+			return nil, err
+		}
+		if params.ICVLen != aead.Overhead() {
+			if aead, err = newGCMWithTagSize(block, len(params.Nonce), params.ICVLen); err != nil {
+				return nil, err
+			}
+		}
+		sealed := append(append([]byte{}, ciphertext...), mac...)
+		plain, err := aead.Open(nil, params.Nonce, sealed, aad)
+		if err != nil {
+			return nil, fmt.Errorf("解密失败，认证标签校验未通过")
+		}
+		return plain, nil
+	}
+
+	var iv []byte
+	if err := unmarshalExact(alg.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("解析IV失败: %v", err)
+	}
+	blockSize := block.BlockSize()
+	if len(iv) != blockSize {
+		return nil, fmt.Errorf("IV长度 %d 与分组长度不匹配", len(iv))
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%blockSize != 0 {
+		return nil, fmt.Errorf("密文长度 %d 不是分组长度 %d 的整数倍", len(ciphertext), blockSize)
+	}
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, ciphertext)
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > blockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("解密失败，私钥不匹配或数据已损坏")
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+// newGCMWithTagSize 标准库不能同时指定nonce及标签长度，仅支持12字节nonce的非默认标签长度
+func newGCMWithTagSize(block cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if nonceSize != 12 {
+		return nil, fmt.Errorf("不支持 %d 字节nonce与 %d 字节认证标签的组合", nonceSize, tagSize)
+	}
+	return cipher.NewGCMWithTagSize(block, tagSize)
+}
+
+// EnvelopedDataOptions 生成数字信封的选项
+type EnvelopedDataOptions struct {
+	// Cipher 内容加密算法，为空时GM/T 0010信封或首个接收者为SM2证书时使用SM4-CBC，否则使用AES-256-CBC；
+	// GCM模式生成AuthEnvelopedData
+	Cipher string
+	// GMOIDs 使用GM/T 0010的envelopedData及data内容类型OID
+	GMOIDs bool
+	// RSAOAEP RSA接收者使用RSAES-OAEP(SHA256)加密内容加密密钥，否则使用PKCS#1 v1.5
+	RSAOAEP bool
+}
+
+// CreateEnvelopedData 为一个或多个接收者证书(SM2或RSA)生成ContentInfo封装的数字信封
+func CreateEnvelopedData(content []byte, recipients []*x509.Certificate, opts EnvelopedDataOptions) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("至少需要一个接收者证书")
+	}
+	publicKeys := make([]crypto.PublicKey, len(recipients))
+	for i, cert := range recipients {
+		pub, err := CertificatePublicKey(cert)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个接收者证书: 解析公钥失败: %v", i+1, err)
+		}
+		publicKeys[i] = pub
+	}
+	if opts.Cipher == "" {
+		opts.Cipher = EnvelopeCipherAES256CBC
+		if _, err := toSM2PublicKey(publicKeys[0]); opts.GMOIDs || err == nil {
+			opts.Cipher = EnvelopeCipherSM4CBC
+		}
+	}
+	oid, ok := envelopeCipherOIDs[opts.Cipher]
+	if !ok {
+		return nil, fmt.Errorf("不支持的内容加密算法: %s", opts.Cipher)
+	}
+	spec, _ := findContentCipher(oid)
+	if spec.gcm && opts.GMOIDs {
+		return nil, fmt.Errorf("GM/T 0010数字信封不支持GCM模式")
+	}
+
+	contentKey := make([]byte, spec.keyLen)
+	if _, err := rand.Read(contentKey); err != nil {
+		return nil, err
+	}
+	var recipientInfos []asn1.RawValue
+	for i, cert := range recipients {
+		ktri, err := newKeyTransRecipientInfo(cert, publicKeys[i], contentKey, opts.RSAOAEP)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个接收者证书: %v", i+1, err)
+		}
+		der, err := asn1.Marshal(ktri)
+		if err != nil {
+			return nil, err
+		}
+		recipientInfos = append(recipientInfos, asn1.RawValue{FullBytes: der})
+	}
+
+	contentType, envelopeType := oidPKCS7Data, oidPKCS7EnvelopedData
+	if opts.GMOIDs {
+		contentType, envelopeType = oidGMData, oidGMEnvelopedData
+	}
+	eci, mac, err := encryptCMSContent(spec, contentKey, content)
+	if err != nil {
+		return nil, err
+	}
+	eci.ContentType = contentType
+
+	var envelope interface{}
+	if spec.gcm {
+		envelopeType = oidAuthEnvelopedData
+		envelope = cmsAuthEnvelopedDataASN1{RecipientInfos: recipientInfos, AuthEncryptedContentInfo: eci, MAC: mac}
+	} else {
+		envelope = cmsEnvelopedDataASN1{RecipientInfos: recipientInfos, EncryptedContentInfo: eci}
+	}
+	envelopeDER, err := asn1.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("编码数字信封失败: %v", err)
+	}
+	return asn1.Marshal(pfxContentInfo{ContentType: envelopeType, Content: explicitTag0(envelopeDER)})
+}
+
+// newKeyTransRecipientInfo 以接收者公钥加密内容加密密钥，以issuerAndSerialNumber标识接收者
+func newKeyTransRecipientInfo(cert *x509.Certificate, pub crypto.PublicKey, contentKey []byte, oaep bool) (cmsKeyTransRecipientInfoASN1, error) {
+	rid, err := asn1.Marshal(cmsIssuerAndSerialASN1{
+		Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	})
+	if err != nil {
+		return cmsKeyTransRecipientInfoASN1{}, err
+	}
+	ktri := cmsKeyTransRecipientInfoASN1{RID: asn1.RawValue{FullBytes: rid}}
+
+	if rsaPub, ok := pub.(*rsa.PublicKey); ok {
+		if !oaep {
+			ktri.KeyEncryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyRSA, Parameters: asn1.NullRawValue}
+			ktri.EncryptedKey, err = rsa.EncryptPKCS1v15(rand.Reader, rsaPub, contentKey)
+			return ktri, err
+		}
+		sha256Alg := pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA256, Parameters: asn1.NullRawValue}
+		mgfParams, err := asn1.Marshal(sha256Alg)
+		if err != nil {
+			return ktri, err
+		}
+		params, err := asn1.Marshal(rsaOAEPParamsASN1{
+			HashAlgorithm:    sha256Alg,
+			MaskGenAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidMGF1, Parameters: asn1.RawValue{FullBytes: mgfParams}},
+		})
+		if err != nil {
+			return ktri, err
+		}
+		ktri.KeyEncryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSAESOAEP, Parameters: asn1.RawValue{FullBytes: params}}
+		ktri.EncryptedKey, err = rsa.EncryptOAEP(crypto.SHA256.New(), rand.Reader, rsaPub, contentKey, nil)
+		return ktri, err
+	}
+
+	sm2Pub, err := toSM2PublicKey(pub)
+	if err != nil {
+		return ktri, fmt.Errorf("仅支持SM2与RSA接收者，%v", err)
+	}
+	ktri.KeyEncryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidSM2Encryption}
+	if ktri.EncryptedKey, err = sm2Pub.EncryptAsn1(contentKey, rand.Reader); err != nil {
+		return ktri, fmt.Errorf("SM2加密内容加密密钥失败: %v", err)
+	}
+	return ktri, nil
+}
+
+// encryptCMSContent 以随机IV(CBC，PKCS#7填充)或随机nonce(GCM，16字节认证标签)加密内容，GCM模式另行返回认证标签
+func encryptCMSContent(spec cmsContentCipher, key, content []byte) (cmsEncryptedContentInfoASN1, []byte, error) {
+	eci := cmsEncryptedContentInfoASN1{ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: spec.oid}}
+	block, err := spec.newBlock(key)
+	if err != nil {
+		return eci, nil, err
+	}
+
+	var ciphertext, mac []byte
+	if spec.gcm {
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return eci, nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return eci, nil, err
+		}
+		params, err := asn1.Marshal(gcmParamsASN1{Nonce: nonce, ICVLen: aead.Overhead()})
+		if err != nil {
+			return eci, nil, err
+		}
+		eci.ContentEncryptionAlgorithm.Parameters = asn1.RawValue{FullBytes: params}
+		sealed := aead.Seal(nil, nonce, content, nil)
+		ciphertext, mac = sealed[:len(content)], sealed[len(content):]
+	} else {
+		iv := make([]byte, block.BlockSize())
+		if _, err := rand.Read(iv); err != nil {
+			return eci, nil, err
+		}
+		params, err := asn1.Marshal(iv)
+		if err != nil {
+			return eci, nil, err
+		}
+		eci.ContentEncryptionAlgorithm.Parameters = asn1.RawValue{FullBytes: params}
+		padding := block.BlockSize() - len(content)%block.BlockSize()
+		ciphertext = append(append([]byte{}, content...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	}
+	eci.EncryptedContent = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: ciphertext}
+	return eci, mac, nil
+}
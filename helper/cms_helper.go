@@ -0,0 +1,939 @@
+package helper
+
+import (
+	"HeTu/util"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/zaneway/cain-go/sm2"
+	"github.com/zaneway/cain-go/x509"
+)
+
+// CMS签名验证状态
+const (
+	CMSSignatureUnverified = "未验证"
+	CMSSignatureValid      = "验证通过"
+	CMSSignatureInvalid    = "验证失败"
+)
+
+// CMS内容类型及签名属性OID
+var (
+	oidCMSContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidCMSMessageDigest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidCMSSigningTime          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidCMSCountersignature     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 6}
+	oidSigningCertificate      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 12}
+	oidSigningCertificateV2    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidSignatureTimeStampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidTSTInfo                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidSignatureSM2            = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 1}
+)
+
+// CMSNames CMS内容类型及属性OID与名称的映射
+var CMSNames = map[string]string{
+	oidPKCS7Data.String():               "data",
+	oidPKCS7SignedData.String():         "signedData",
+	oidPKCS7EnvelopedData.String():      "envelopedData",
+	oidPKCS7EncryptedData.String():      "encryptedData",
+	oidGMData.String():                  "data (GM/T 0010)",
+	oidGMSignedData.String():            "signedData (GM/T 0010)",
+	oidGMEnvelopedData.String():         "envelopedData (GM/T 0010)",
+	oidAuthEnvelopedData.String():       "authEnvelopedData",
+	oidTSTInfo.String():                 "id-ct-TSTInfo",
+	oidCMSContentType.String():          "contentType",
+	oidCMSMessageDigest.String():        "messageDigest",
+	oidCMSSigningTime.String():          "signingTime",
+	oidCMSCountersignature.String():     "countersignature",
+	oidSigningCertificate.String():      "signingCertificate",
+	oidSigningCertificateV2.String():    "signingCertificateV2",
+	oidSignatureTimeStampToken.String(): "signatureTimeStampToken",
+	"1.2.840.113549.1.9.15":             "smimeCapabilities",
+}
+
+// rsaSignatureOIDs 摘要算法对应的RSA PKCS#1 v1.5签名算法，SignerInfo使用rsaEncryption时据此组合
+var rsaSignatureOIDs = map[string]asn1.ObjectIdentifier{
+	oidDigestSHA1.String():   {1, 2, 840, 113549, 1, 1, 5},
+	oidDigestSHA256.String(): oidSignatureSHA256WithRSA,
+	oidDigestSHA384.String(): {1, 2, 840, 113549, 1, 1, 12},
+	oidDigestSHA512.String(): {1, 2, 840, 113549, 1, 1, 13},
+}
+
+// cmsCryptoHashes RSA签名使用的摘要算法
+var cmsCryptoHashes = map[string]crypto.Hash{
+	oidDigestSHA1.String():   crypto.SHA1,
+	oidDigestSHA256.String(): crypto.SHA256,
+	oidDigestSHA384.String(): crypto.SHA384,
+	oidDigestSHA512.String(): crypto.SHA512,
+}
+
+// ecdsaSignatureOIDs 摘要算法对应的ECDSA签名算法
+var ecdsaSignatureOIDs = map[string]asn1.ObjectIdentifier{
+	oidDigestSHA1.String():   {1, 2, 840, 10045, 4, 1},
+	oidDigestSHA256.String(): {1, 2, 840, 10045, 4, 3, 2},
+	oidDigestSHA384.String(): {1, 2, 840, 10045, 4, 3, 3},
+	oidDigestSHA512.String(): {1, 2, 840, 10045, 4, 3, 4},
+}
+
+type cmsSignedDataASN1 struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo cmsEncapContentInfoASN1
+	Certificates     asn1.RawValue       `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue       `asn1:"optional,tag:1"`
+	SignerInfos      []cmsSignerInfoASN1 `asn1:"set"`
+}
+
+type cmsEncapContentInfoASN1 struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type cmsSignerInfoASN1 struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type cmsIssuerAndSerialASN1 struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type cmsAttributeASN1 struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// CMSAttribute 签名者的签名或非签名属性
+type CMSAttribute struct {
+	Type string
+	Name string
+	// Values 属性值集合中各值的DER编码
+	Values [][]byte
+}
+
+// SignedDataInfo CMS SignedData(含GM/T 0010)信息
+type SignedDataInfo struct {
+	Version          int
+	DigestAlgorithms []string
+	ContentType      string
+	// Content 封装的内容，分离式签名时为nil
+	Content      []byte
+	Detached     bool
+	Certificates []*x509.Certificate
+	CRLCount     int
+	Signers      []*CMSSignerInfo
+	// SM2UserID 验证SM2签名时计算Z值的用户ID，为空时使用默认用户ID
+	SM2UserID []byte
+
+	contentType asn1.ObjectIdentifier
+}
+
+// CMSCertificateID 签名者或接收者证书标识，IssuerName、SerialNumber 以颁发者和序列号标识，
+// SubjectKeyID 以密钥标识符标识，二者取其一
+type CMSCertificateID struct {
+	IssuerName   string
+	SerialNumber *big.Int
+	SubjectKeyID []byte
+
+	rawIssuer []byte
+}
+
+// CMSSignerInfo 签名者信息
+type CMSSignerInfo struct {
+	Version int
+	CMSCertificateID
+	DigestAlgorithm    string
+	SignatureAlgorithm string
+	// SigningTime、MessageDigest 来自签名属性，未包含时为零值
+	SigningTime      time.Time
+	MessageDigest    []byte
+	SignedAttributes []CMSAttribute
+	// UnsignedAttributes 非签名属性，如签名时间戳
+	UnsignedAttributes []CMSAttribute
+	// Countersignatures 非签名属性中的副署签名，对本签名者的签名值签名
+	Countersignatures []*CMSSignerInfo
+	Signature         []byte
+	// Certificate 匹配到的签名者证书，调用VerifySignedData后更新
+	Certificate     *x509.Certificate
+	SignatureStatus string
+	SignatureError  string
+	Warnings        []string
+
+	digestOID    asn1.ObjectIdentifier
+	signatureAlg pkix.AlgorithmIdentifier
+	signedAttrs  []byte
+	attributes   []cmsAttributeASN1
+}
+
+// ParseSignedData 解析ContentInfo封装的SignedData，支持BER不定长编码
+func ParseSignedData(data []byte) (*SignedDataInfo, error) {
+	der, err := berToDER(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析CMS结构失败: %v", err)
+	}
+	var contentInfo pfxContentInfo
+	if err := unmarshalExact(der, &contentInfo); err != nil {
+		return nil, fmt.Errorf("解析ContentInfo失败: %v", err)
+	}
+	if !contentInfo.ContentType.Equal(oidPKCS7SignedData) && !contentInfo.ContentType.Equal(oidGMSignedData) {
+		return nil, fmt.Errorf("内容类型不是SignedData: %s", oidName(CMSNames, contentInfo.ContentType))
+	}
+	var sd cmsSignedDataASN1
+	if err := unmarshalExact(contentInfo.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("解析SignedData失败: %v", err)
+	}
+
+	info := &SignedDataInfo{
+		Version:     sd.Version,
+		ContentType: oidName(CMSNames, sd.EncapContentInfo.EContentType),
+		contentType: sd.EncapContentInfo.EContentType,
+	}
+	for _, alg := range sd.DigestAlgorithms {
+		info.DigestAlgorithms = append(info.DigestAlgorithms, pbeAlgorithmName(alg.Algorithm))
+	}
+	if eContent := sd.EncapContentInfo.EContent; len(eContent.FullBytes) > 0 {
+		// eContent为[0] EXPLICIT OCTET STRING，GM/T 0010的data内容同样按OCTET STRING封装
+		var content []byte
+		if err := unmarshalExact(eContent.Bytes, &content); err != nil {
+			return nil, fmt.Errorf("解析封装内容失败: %v", err)
+		}
+		info.Content = content
+	} else {
+		info.Detached = true
+	}
+	if len(sd.Certificates.FullBytes) > 0 {
+		if info.Certificates, err = ParseCertificates(sd.Certificates.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	for rest := sd.CRLs.Bytes; len(rest) > 0; info.CRLCount++ {
+		var crl asn1.RawValue
+		if rest, err = asn1.Unmarshal(rest, &crl); err != nil {
+			return nil, fmt.Errorf("解析CRL集合失败: %v", err)
+		}
+	}
+	for i, raw := range sd.SignerInfos {
+		signer, err := parseCMSSignerInfo(raw)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个签名者: %v", i+1, err)
+		}
+		info.Signers = append(info.Signers, signer)
+	}
+	return info, nil
+}
+
+func parseCMSSignerInfo(raw cmsSignerInfoASN1) (*CMSSignerInfo, error) {
+	signer := &CMSSignerInfo{
+		Version:            raw.Version,
+		DigestAlgorithm:    pbeAlgorithmName(raw.DigestAlgorithm.Algorithm),
+		SignatureAlgorithm: formatSignatureAlgorithm(raw.SignatureAlgorithm),
+		Signature:          raw.Signature,
+		SignatureStatus:    CMSSignatureUnverified,
+		digestOID:          raw.DigestAlgorithm.Algorithm,
+		signatureAlg:       raw.SignatureAlgorithm,
+	}
+	if raw.SignatureAlgorithm.Algorithm.Equal(oidSignatureSM2) {
+		signer.SignatureAlgorithm = "SM2"
+	}
+	var err error
+	if signer.CMSCertificateID, err = parseCMSCertificateID(raw.SID); err != nil {
+		return nil, fmt.Errorf("解析签名者标识失败: %v", err)
+	}
+
+	if len(raw.SignedAttrs.FullBytes) > 0 {
+		signer.signedAttrs = raw.SignedAttrs.FullBytes
+		attrs, err := parseCMSAttributes(raw.SignedAttrs.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析签名属性失败: %v", err)
+		}
+		signer.attributes = attrs
+		signer.SignedAttributes = describeCMSAttributes(attrs)
+		if value := findCMSAttribute(attrs, oidCMSMessageDigest); value != nil {
+			if err := unmarshalExact(value, &signer.MessageDigest); err != nil {
+				return nil, fmt.Errorf("解析messageDigest失败: %v", err)
+			}
+		}
+		if value := findCMSAttribute(attrs, oidCMSSigningTime); value != nil {
+			// signingTime可为UTCTime或GeneralizedTime
+			if err := unmarshalExact(value, &signer.SigningTime); err != nil {
+				return nil, fmt.Errorf("解析signingTime失败: %v", err)
+			}
+			signer.SigningTime = util.ToBeijingTime(signer.SigningTime)
+		}
+	}
+	if len(raw.UnsignedAttrs.FullBytes) > 0 {
+		attrs, err := parseCMSAttributes(raw.UnsignedAttrs.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析非签名属性失败: %v", err)
+		}
+		signer.UnsignedAttributes = describeCMSAttributes(attrs)
+		for _, attr := range attrs {
+			if !attr.Type.Equal(oidCMSCountersignature) {
+				continue
+			}
+			for rest := attr.Values.Bytes; len(rest) > 0; {
+				var raw cmsSignerInfoASN1
+				var err error
+				if rest, err = asn1.Unmarshal(rest, &raw); err != nil {
+					return nil, fmt.Errorf("解析副署签名失败: %v", err)
+				}
+				countersignature, err := parseCMSSignerInfo(raw)
+				if err != nil {
+					return nil, fmt.Errorf("解析副署签名失败: %v", err)
+				}
+				signer.Countersignatures = append(signer.Countersignatures, countersignature)
+			}
+		}
+	}
+	return signer, nil
+}
+
+// parseCMSCertificateID 解析issuerAndSerialNumber或[0]隐式标签的subjectKeyIdentifier
+func parseCMSCertificateID(raw asn1.RawValue) (CMSCertificateID, error) {
+	var id CMSCertificateID
+	switch {
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence:
+		var ias cmsIssuerAndSerialASN1
+		if err := unmarshalExact(raw.FullBytes, &ias); err != nil {
+			return id, fmt.Errorf("解析issuerAndSerialNumber失败: %v", err)
+		}
+		id.rawIssuer = ias.Issuer.FullBytes
+		id.SerialNumber = ias.SerialNumber
+		var name pkix.RDNSequence
+		if _, err := asn1.Unmarshal(ias.Issuer.FullBytes, &name); err == nil {
+			var issuer pkix.Name
+			issuer.FillFromRDNSequence(&name)
+			id.IssuerName = issuer.String()
+		}
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0:
+		id.SubjectKeyID = raw.Bytes
+	default:
+		return id, fmt.Errorf("无法识别的证书标识")
+	}
+	return id, nil
+}
+
+// Identifier 证书标识的文本表示
+func (id CMSCertificateID) Identifier() string {
+	if id.SubjectKeyID != nil {
+		return fmt.Sprintf("密钥标识符 %X", id.SubjectKeyID)
+	}
+	return fmt.Sprintf("颁发者 %s, 序列号 %X", id.IssuerName, id.SerialNumber)
+}
+
+// matches 判断证书是否与标识对应
+func (id CMSCertificateID) matches(cert *x509.Certificate) bool {
+	if id.SubjectKeyID != nil {
+		return bytes.Equal(cert.SubjectKeyId, id.SubjectKeyID)
+	}
+	return bytes.Equal(cert.RawIssuer, id.rawIssuer) && cert.SerialNumber.Cmp(id.SerialNumber) == 0
+}
+
+// FormatValues 属性值的文本表示，无法识别的属性显示DER编码的十六进制
+func (attr CMSAttribute) FormatValues() []string {
+	var result []string
+	for _, value := range attr.Values {
+		result = append(result, formatCMSAttributeValue(attr.Type, value))
+	}
+	return result
+}
+
+func formatCMSAttributeValue(attrType string, value []byte) string {
+	switch attrType {
+	case oidCMSContentType.String():
+		var oid asn1.ObjectIdentifier
+		if unmarshalExact(value, &oid) == nil {
+			return oidName(CMSNames, oid)
+		}
+	case oidCMSMessageDigest.String():
+		var digest []byte
+		if unmarshalExact(value, &digest) == nil {
+			return fmt.Sprintf("%X", digest)
+		}
+	case oidCMSSigningTime.String():
+		var t time.Time
+		if unmarshalExact(value, &t) == nil {
+			return util.ToBeijingTime(t).Format(util.DateTime)
+		}
+	case oidSigningCertificate.String():
+		var attr essSigningCertificateASN1
+		if unmarshalExact(value, &attr) == nil && len(attr.Certs) > 0 {
+			return fmt.Sprintf("SHA1证书摘要 %X", attr.Certs[0].CertHash)
+		}
+	case oidSigningCertificateV2.String():
+		var attr essSigningCertificateV2ASN1
+		if unmarshalExact(value, &attr) == nil && len(attr.Certs) > 0 {
+			hashOID := attr.Certs[0].HashAlgorithm.Algorithm
+			if len(hashOID) == 0 {
+				hashOID = oidDigestSHA256
+			}
+			return fmt.Sprintf("%s证书摘要 %X", pbeAlgorithmName(hashOID), attr.Certs[0].CertHash)
+		}
+	case oidCMSCountersignature.String():
+		var raw cmsSignerInfoASN1
+		if unmarshalExact(value, &raw) == nil {
+			if countersignature, err := parseCMSSignerInfo(raw); err == nil {
+				return "副署签名: " + countersignature.Identifier()
+			}
+		}
+	case oidSignatureTimeStampToken.String():
+		if info, err := ParseTimeStampResponse(value); err == nil {
+			return fmt.Sprintf("时间戳令牌: 时间 %s, 序列号 %X", info.TSTInfo.GenTime.Format(util.DateTime), info.TSTInfo.SerialNumber)
+		}
+	}
+	return fmt.Sprintf("%X", value)
+}
+
+func parseCMSAttributes(content []byte) ([]cmsAttributeASN1, error) {
+	var attrs []cmsAttributeASN1
+	for rest := content; len(rest) > 0; {
+		var attr cmsAttributeASN1
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+func describeCMSAttributes(attrs []cmsAttributeASN1) []CMSAttribute {
+	var result []CMSAttribute
+	for _, attr := range attrs {
+		item := CMSAttribute{Type: attr.Type.String(), Name: oidName(CMSNames, attr.Type)}
+		for rest := attr.Values.Bytes; len(rest) > 0; {
+			var value asn1.RawValue
+			var err error
+			if rest, err = asn1.Unmarshal(rest, &value); err != nil {
+				break
+			}
+			item.Values = append(item.Values, value.FullBytes)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// findCMSAttribute 返回属性的第一个值的DER编码，未找到时返回nil
+func findCMSAttribute(attrs []cmsAttributeASN1, oid asn1.ObjectIdentifier) []byte {
+	for _, attr := range attrs {
+		if !attr.Type.Equal(oid) {
+			continue
+		}
+		var value asn1.RawValue
+		if _, err := asn1.Unmarshal(attr.Values.Bytes, &value); err != nil {
+			return nil
+		}
+		return value.FullBytes
+	}
+	return nil
+}
+
+// VerifySignedData 验证所有签名者的签名，content用于分离式签名，certs为SignedData之外补充的证书；
+// 各签名者的结果记录在SignatureStatus中，返回第一个错误
+func VerifySignedData(info *SignedDataInfo, content []byte, certs []*x509.Certificate) error {
+	if len(info.Signers) == 0 {
+		return fmt.Errorf("SignedData中没有签名者")
+	}
+	if !info.Detached {
+		content = info.Content
+	} else if content == nil {
+		return fmt.Errorf("分离式签名需要提供原文")
+	}
+	candidates := append(append([]*x509.Certificate{}, info.Certificates...), certs...)
+	var firstErr error
+	for i, signer := range info.Signers {
+		if err := signer.verify(info.contentType, content, candidates, info.SM2UserID); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("第%d个签名者: %v", i+1, err)
+		}
+		for j, countersignature := range signer.Countersignatures {
+			// 副署签名的内容为被副署签名者的签名值，且不包含contentType属性
+			if err := countersignature.verify(nil, signer.Signature, candidates, info.SM2UserID); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("第%d个签名者的第%d个副署签名: %v", i+1, j+1, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// verify 验证签名并更新SignatureStatus，contentType为nil表示副署签名
+func (signer *CMSSignerInfo) verify(contentType asn1.ObjectIdentifier, content []byte, candidates []*x509.Certificate, userID []byte) error {
+	err := verifyCMSSigner(signer, contentType, content, candidates, userID)
+	if err != nil {
+		signer.SignatureStatus = CMSSignatureInvalid
+		signer.SignatureError = err.Error()
+		return err
+	}
+	signer.SignatureStatus = CMSSignatureValid
+	signer.SignatureError = ""
+	return nil
+}
+
+func verifyCMSSigner(signer *CMSSignerInfo, contentType asn1.ObjectIdentifier, content []byte, candidates []*x509.Certificate, userID []byte) error {
+	signer.Warnings = nil
+	signer.Certificate = signer.findCertificate(candidates)
+	if signer.Certificate == nil {
+		return fmt.Errorf("未找到签名者证书")
+	}
+	newHash, err := hashForOID(signer.digestOID)
+	if err != nil {
+		return err
+	}
+
+	signed := content
+	if signer.signedAttrs != nil {
+		value := findCMSAttribute(signer.attributes, oidCMSContentType)
+		switch {
+		case contentType == nil && value != nil:
+			return fmt.Errorf("副署签名不应包含contentType属性")
+		case contentType != nil:
+			var oid asn1.ObjectIdentifier
+			if value == nil || unmarshalExact(value, &oid) != nil {
+				return fmt.Errorf("签名属性缺少contentType")
+			}
+			if !oid.Equal(contentType) {
+				return fmt.Errorf("签名属性中的contentType(%s)与封装内容类型(%s)不一致", oidName(CMSNames, oid), oidName(CMSNames, contentType))
+			}
+		}
+		if signer.MessageDigest == nil {
+			return fmt.Errorf("签名属性缺少messageDigest")
+		}
+		h := newHash()
+		h.Write(content)
+		if digest := h.Sum(nil); !bytes.Equal(digest, signer.MessageDigest) {
+			return fmt.Errorf("messageDigest与内容摘要不一致: %X != %X", signer.MessageDigest, digest)
+		}
+		// 签名针对SET OF Attribute的DER编码，需将[0]隐式标签还原为SET
+		signed = append([]byte{0x31}, signer.signedAttrs[1:]...)
+	}
+
+	pub, err := CertificatePublicKey(signer.Certificate)
+	if err != nil {
+		return fmt.Errorf("解析签名者公钥失败: %v", err)
+	}
+	algorithm := cmsSignatureAlgorithm(signer.digestOID, signer.signatureAlg)
+	if algorithm.Algorithm.Equal(oidSignatureSM2WithSM3) {
+		if len(userID) == 0 {
+			userID = SM2DefaultUserID
+		}
+		if err := verifySM2Signature(pub, signed, signer.Signature, userID); err != nil {
+			return fmt.Errorf("签名验证失败(SM2用户ID: %s): %v", userID, err)
+		}
+	} else if err := VerifySignature(pub, algorithm, signed, signer.Signature); err != nil {
+		return fmt.Errorf("签名验证失败: %v", err)
+	}
+	if !signer.SigningTime.IsZero() && (signer.SigningTime.Before(signer.Certificate.NotBefore) || signer.SigningTime.After(signer.Certificate.NotAfter)) {
+		signer.Warnings = append(signer.Warnings, "签名时间不在签名者证书有效期内")
+	}
+	return nil
+}
+
+// verifySM2Signature 以指定用户ID计算Z值验证SM2签名
+func verifySM2Signature(pub crypto.PublicKey, signed, signature, userID []byte) error {
+	sm2Pub, err := toSM2PublicKey(pub)
+	if err != nil {
+		return fmt.Errorf("签名算法为SM2，但%v", err)
+	}
+	var sig sm2SignatureASN1
+	if err := unmarshalExact(signature, &sig); err != nil {
+		return fmt.Errorf("SM2签名值格式错误")
+	}
+	if !sm2.Sm2Verify(sm2Pub, signed, userID, sig.R, sig.S) {
+		return fmt.Errorf("签名值不匹配")
+	}
+	return nil
+}
+
+// toSM2PublicKey 将证书中解析出的SM2曲线公钥转换为cain-go的SM2公钥
+func toSM2PublicKey(pub crypto.PublicKey) (*sm2.PublicKey, error) {
+	switch key := pub.(type) {
+	case *sm2.PublicKey:
+		return key, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != sm2.P256Sm2() {
+			return nil, fmt.Errorf("公钥不是SM2曲线")
+		}
+		return &sm2.PublicKey{Curve: key.Curve, X: key.X, Y: key.Y}, nil
+	}
+	return nil, fmt.Errorf("公钥类型为%T", pub)
+}
+
+// findCertificate 按issuerAndSerialNumber或subjectKeyIdentifier查找签名者证书
+func (signer *CMSSignerInfo) findCertificate(candidates []*x509.Certificate) *x509.Certificate {
+	for _, cert := range candidates {
+		if signer.matches(cert) {
+			return cert
+		}
+	}
+	return nil
+}
+
+// cmsSignatureAlgorithm SignerInfo的signatureAlgorithm可仅标识公钥算法(rsaEncryption、SM2、ecPublicKey)，
+// 此时与digestAlgorithm组合为完整的签名算法
+func cmsSignatureAlgorithm(digest asn1.ObjectIdentifier, signature pkix.AlgorithmIdentifier) pkix.AlgorithmIdentifier {
+	switch {
+	case signature.Algorithm.Equal(oidPublicKeyRSA):
+		if oid, ok := rsaSignatureOIDs[digest.String()]; ok {
+			return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue}
+		}
+	case signature.Algorithm.Equal(oidSignatureSM2), signature.Algorithm.Equal(oidPublicKeySM2):
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM2WithSM3}
+	case signature.Algorithm.Equal(oidPublicKeyEC):
+		if digest.Equal(oidDigestSM3) {
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM2WithSM3}
+		}
+		if oid, ok := ecdsaSignatureOIDs[digest.String()]; ok {
+			return pkix.AlgorithmIdentifier{Algorithm: oid}
+		}
+	}
+	return signature
+}
+
+// SignedDataOptions 生成CMS SignedData的选项
+type SignedDataOptions struct {
+	// HashAlgorithm 摘要算法名称(同TSPHashAlgorithms)，为空时SM2使用SM3、RSA使用SHA256
+	HashAlgorithm string
+	// Detached 为true时生成分离式签名，不封装原文
+	Detached bool
+	// NoSignedAttributes 为true时直接对原文签名，不包含任何签名属性
+	NoSignedAttributes bool
+	// SigningTime 为零值时不包含signingTime属性
+	SigningTime time.Time
+	// Certificates SignedData中附带的证书，通常为签名证书及上级证书，为空时不附带
+	Certificates []*x509.Certificate
+	// GMOIDs 使用GM/T 0010的data及signedData内容类型OID
+	GMOIDs bool
+	// SM2UserID SM2签名计算Z值的用户ID，为空时使用默认用户ID
+	SM2UserID []byte
+}
+
+// CreateSignedData 以证书及私钥(SM2或RSA)对content签名，生成ContentInfo封装的SignedData
+func CreateSignedData(key crypto.Signer, cert *x509.Certificate, content []byte, opts SignedDataOptions) ([]byte, error) {
+	if key == nil || cert == nil {
+		return nil, fmt.Errorf("必须提供签名证书及私钥")
+	}
+	if err := checkKeyMatchesCertificate(key, cert); err != nil {
+		return nil, err
+	}
+	_, isSM2 := key.(*sm2.PrivateKey)
+	if opts.HashAlgorithm == "" {
+		opts.HashAlgorithm = TSPHashSHA256
+		if isSM2 {
+			opts.HashAlgorithm = TSPHashSM3
+		}
+	}
+	digestOID, ok := tspHashOIDs[opts.HashAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的摘要算法: %s", opts.HashAlgorithm)
+	}
+	if len(opts.SM2UserID) > 0 && !isSM2 {
+		return nil, fmt.Errorf("仅SM2签名可指定用户ID")
+	}
+	contentType := oidPKCS7Data
+	if opts.GMOIDs {
+		contentType = oidGMData
+	}
+	signer := cmsSigner{
+		certificate:        cert,
+		key:                key,
+		digestOID:          digestOID,
+		signingTime:        opts.SigningTime,
+		noSignedAttributes: opts.NoSignedAttributes,
+		userID:             opts.SM2UserID,
+	}
+	return createSignedData(contentType, content, opts.Detached, signer, opts.Certificates)
+}
+
+// cmsSigner 生成SignerInfo所需的签名者证书、私钥(SM2或RSA)及签名属性
+type cmsSigner struct {
+	certificate *x509.Certificate
+	key         crypto.Signer
+	digestOID   asn1.ObjectIdentifier
+	// signingTime 为零值时不包含signingTime属性
+	signingTime time.Time
+	// attributes 除contentType、signingTime、messageDigest之外的签名属性
+	attributes []cmsAttributeASN1
+	// noSignedAttributes 为true时直接对原文签名，此时忽略signingTime及attributes
+	noSignedAttributes bool
+	// userID SM2签名的用户ID，为空时使用默认用户ID
+	userID []byte
+}
+
+// createSignedData 对content签名并编码为ContentInfo封装的SignedData，detached为true时不封装原文，
+// certs为SignedData中附带的证书
+func createSignedData(contentType asn1.ObjectIdentifier, content []byte, detached bool, signer cmsSigner, certs []*x509.Certificate) ([]byte, error) {
+	signerInfo, err := signer.sign(contentType, content)
+	if err != nil {
+		return nil, err
+	}
+	sd := cmsSignedDataASN1{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithmIdentifier(signer.digestOID)},
+		EncapContentInfo: cmsEncapContentInfoASN1{EContentType: contentType},
+		SignerInfos:      []cmsSignerInfoASN1{signerInfo},
+	}
+	// RFC 5652 5.1: 封装内容不是id-data时版本为3
+	if !contentType.Equal(oidPKCS7Data) && !contentType.Equal(oidGMData) {
+		sd.Version = 3
+	}
+	if !detached {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		sd.EncapContentInfo.EContent = explicitTag0(octets)
+	}
+	if len(certs) > 0 {
+		var raw []byte
+		for _, cert := range certs {
+			raw = append(raw, cert.Raw...)
+		}
+		sd.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw}
+	}
+	sdDER, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, fmt.Errorf("编码SignedData失败: %v", err)
+	}
+	signedDataOID := oidPKCS7SignedData
+	if contentType.Equal(oidGMData) {
+		signedDataOID = oidGMSignedData
+	}
+	return asn1.Marshal(pfxContentInfo{ContentType: signedDataOID, Content: explicitTag0(sdDER)})
+}
+
+// sign 生成以issuerAndSerialNumber标识签名者的SignerInfo
+func (signer cmsSigner) sign(contentType asn1.ObjectIdentifier, content []byte) (cmsSignerInfoASN1, error) {
+	sid, err := asn1.Marshal(cmsIssuerAndSerialASN1{
+		Issuer:       asn1.RawValue{FullBytes: signer.certificate.RawIssuer},
+		SerialNumber: signer.certificate.SerialNumber,
+	})
+	if err != nil {
+		return cmsSignerInfoASN1{}, err
+	}
+	info := cmsSignerInfoASN1{
+		Version:         1,
+		SID:             asn1.RawValue{FullBytes: sid},
+		DigestAlgorithm: digestAlgorithmIdentifier(signer.digestOID),
+	}
+	if signer.noSignedAttributes {
+		info.SignatureAlgorithm, info.Signature, err = signCMSData(signer.key, signer.digestOID, content, signer.userID)
+		return info, err
+	}
+
+	newHash, err := hashForOID(signer.digestOID)
+	if err != nil {
+		return cmsSignerInfoASN1{}, err
+	}
+	h := newHash()
+	h.Write(content)
+	values := map[string]interface{}{
+		oidCMSContentType.String():   contentType,
+		oidCMSMessageDigest.String(): h.Sum(nil),
+	}
+	oids := []asn1.ObjectIdentifier{oidCMSContentType, oidCMSMessageDigest}
+	if !signer.signingTime.IsZero() {
+		values[oidCMSSigningTime.String()] = signer.signingTime.UTC()
+		oids = append(oids, oidCMSSigningTime)
+	}
+	attributes := make([]cmsAttributeASN1, 0, len(oids)+len(signer.attributes))
+	for _, oid := range oids {
+		value, err := asn1.Marshal(values[oid.String()])
+		if err != nil {
+			return cmsSignerInfoASN1{}, err
+		}
+		attributes = append(attributes, cmsAttributeASN1{Type: oid, Values: asn1Set(value)})
+	}
+	attributes = append(attributes, signer.attributes...)
+
+	// 签名属性为SET OF，DER要求按编码排序
+	var encoded [][]byte
+	for _, attr := range attributes {
+		der, err := asn1.Marshal(attr)
+		if err != nil {
+			return cmsSignerInfoASN1{}, err
+		}
+		encoded = append(encoded, der)
+	}
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+	signedAttrs, err := asn1.Marshal(asn1Set(encoded...))
+	if err != nil {
+		return cmsSignerInfoASN1{}, err
+	}
+	if info.SignatureAlgorithm, info.Signature, err = signCMSData(signer.key, signer.digestOID, signedAttrs, signer.userID); err != nil {
+		return cmsSignerInfoASN1{}, err
+	}
+	// 签名属性在SignerInfo中以[0]隐式标签编码，签名针对SET编码
+	info.SignedAttrs = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: bytes.Join(encoded, nil)}
+	return info, nil
+}
+
+// signCMSData 对签名属性或原文签名，SM2使用GM/T 0010的SM2签名算法标识，userID为空时使用默认用户ID；
+// RSA使用rsaEncryption标识及PKCS#1 v1.5
+func signCMSData(key crypto.Signer, digestOID asn1.ObjectIdentifier, signed, userID []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	switch priv := key.(type) {
+	case *sm2.PrivateKey:
+		if !digestOID.Equal(oidDigestSM3) {
+			return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("SM2签名仅支持SM3摘要")
+		}
+		if len(userID) == 0 {
+			userID = SM2DefaultUserID
+		}
+		r, s, err := sm2.Sm2Sign(priv, signed, userID, rand.Reader)
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("SM2签名失败: %v", err)
+		}
+		signature, err := asn1.Marshal(sm2SignatureASN1{R: r, S: s})
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM2}, signature, err
+	case *rsa.PrivateKey:
+		hash, ok := cmsCryptoHashes[digestOID.String()]
+		if !ok {
+			return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("RSA签名不支持摘要算法%s", pbeAlgorithmName(digestOID))
+		}
+		h := hash.New()
+		h.Write(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, priv, hash, h.Sum(nil))
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("RSA签名失败: %v", err)
+		}
+		return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyRSA, Parameters: asn1.NullRawValue}, signature, nil
+	}
+	return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("不支持的签名私钥类型: %T，仅支持SM2与RSA", key)
+}
+
+// digestAlgorithmIdentifier SHA系列摘要按惯例携带NULL参数，SM3不带参数
+func digestAlgorithmIdentifier(oid asn1.ObjectIdentifier) pkix.AlgorithmIdentifier {
+	if oid.Equal(oidDigestSM3) {
+		return pkix.AlgorithmIdentifier{Algorithm: oid}
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue}
+}
+
+// berToDER 将BER编码转换为DER：不定长改为定长，分段的OCTET STRING合并为单个OCTET STRING；
+// 已是DER的输入保持不变，其余BER特性(如未排序的SET)不做处理
+func berToDER(data []byte) ([]byte, error) {
+	der, rest, err := berElementToDER(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("存在%d字节多余数据", len(rest))
+	}
+	return der, nil
+}
+
+func berElementToDER(data []byte) ([]byte, []byte, error) {
+	idLen := 1
+	if len(data) > 0 && data[0]&0x1f == 0x1f {
+		for idLen < len(data) && data[idLen]&0x80 != 0 {
+			idLen++
+		}
+		idLen++
+	}
+	if len(data) < idLen+1 {
+		return nil, nil, fmt.Errorf("数据不完整")
+	}
+	identifier := data[:idLen]
+	constructed := data[0]&0x20 != 0
+	pos := idLen + 1
+
+	var children [][]byte
+	var rest []byte
+	if lengthByte := data[idLen]; lengthByte == 0x80 {
+		if !constructed {
+			return nil, nil, fmt.Errorf("基本类型不能使用不定长编码")
+		}
+		rest = data[pos:]
+		for {
+			if len(rest) < 2 {
+				return nil, nil, fmt.Errorf("不定长编码缺少结束标记")
+			}
+			if rest[0] == 0 && rest[1] == 0 {
+				rest = rest[2:]
+				break
+			}
+			child, remaining, err := berElementToDER(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			children = append(children, child)
+			rest = remaining
+		}
+	} else {
+		length := int(lengthByte)
+		if lengthByte&0x80 != 0 {
+			n := int(lengthByte & 0x7f)
+			if n > 4 || len(data) < pos+n {
+				return nil, nil, fmt.Errorf("长度字段无效")
+			}
+			length = 0
+			for _, b := range data[pos : pos+n] {
+				length = length<<8 | int(b)
+			}
+			pos += n
+		}
+		if length < 0 || len(data)-pos < length {
+			return nil, nil, fmt.Errorf("数据长度不足")
+		}
+		content := data[pos : pos+length]
+		rest = data[pos+length:]
+		if !constructed {
+			return encodeTLV(identifier, content), rest, nil
+		}
+		for remaining := content; len(remaining) > 0; {
+			child, next, err := berElementToDER(remaining)
+			if err != nil {
+				return nil, nil, err
+			}
+			children = append(children, child)
+			remaining = next
+		}
+	}
+
+	var content []byte
+	if len(identifier) == 1 && identifier[0] == 0x24 {
+		// 分段的OCTET STRING，合并各段内容
+		for _, child := range children {
+			var segment asn1.RawValue
+			if _, err := asn1.Unmarshal(child, &segment); err != nil {
+				return nil, nil, err
+			}
+			content = append(content, segment.Bytes...)
+		}
+		return encodeTLV([]byte{asn1.TagOctetString}, content), rest, nil
+	}
+	for _, child := range children {
+		content = append(content, child...)
+	}
+	return encodeTLV(identifier, content), rest, nil
+}
+
+// encodeTLV 按DER最短长度编码
+func encodeTLV(identifier, content []byte) []byte {
+	out := append([]byte{}, identifier...)
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	default:
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(out, 0x80|byte(len(length)))
+		out = append(out, length...)
+	}
+	return append(out, content...)
+}
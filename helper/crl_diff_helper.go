@@ -0,0 +1,363 @@
+package helper
+
+import (
+	"HeTu/util"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// CRL条目变更类型
+const (
+	CRLChangeAdded          = "新增吊销"
+	CRLChangeRemoved        = "移除"
+	CRLChangeReason         = "原因变更"
+	CRLChangeRevocationTime = "吊销时间变更"
+)
+
+// CRLDiffEntry 两个CRL之间单个序列号的变更
+type CRLDiffEntry struct {
+	Change            string
+	SerialNumber      string
+	OldRevocationTime time.Time
+	NewRevocationTime time.Time
+	OldReason         string
+	NewReason         string
+}
+
+// CRLDiff 同一颁发者两个版本CRL的比较结果
+type CRLDiff struct {
+	Issuer        string
+	OldCRLNumber  *big.Int
+	NewCRLNumber  *big.Int
+	OldThisUpdate time.Time
+	NewThisUpdate time.Time
+	OldNextUpdate time.Time
+	NewNextUpdate time.Time
+	// PublishInterval 两次发布的thisUpdate间隔
+	PublishInterval time.Duration
+	// CoverageGap 新CRL的thisUpdate与旧CRL的nextUpdate之差，正数表示期间没有有效CRL，负数表示两者重叠
+	CoverageGap time.Duration
+	// HasCoverageGap 旧CRL未设置nextUpdate时无法计算CoverageGap
+	HasCoverageGap bool
+	OldTotal       int
+	NewTotal       int
+	Added          int
+	Removed        int
+	Changed        int
+	Entries        []CRLDiffEntry
+	// Warnings 序号倒退、时间倒退、增量CRL等需要注意的情况
+	Warnings []string
+}
+
+// DiffCRL 比较同一颁发者的旧CRL与新CRL，颁发者不一致时返回错误
+func DiffCRL(oldCRL, newCRL *CRLInfo) (*CRLDiff, error) {
+	if oldCRL == nil || newCRL == nil {
+		return nil, fmt.Errorf("请提供需要比较的两个CRL")
+	}
+	if oldCRL.Issuer != newCRL.Issuer {
+		return nil, fmt.Errorf("两个CRL的颁发者不一致: %s 与 %s", oldCRL.Issuer, newCRL.Issuer)
+	}
+	if oldCRL.AuthorityKeyIdentifier != nil && newCRL.AuthorityKeyIdentifier != nil &&
+		len(oldCRL.AuthorityKeyIdentifier.KeyID) > 0 && len(newCRL.AuthorityKeyIdentifier.KeyID) > 0 &&
+		!bytes.Equal(oldCRL.AuthorityKeyIdentifier.KeyID, newCRL.AuthorityKeyIdentifier.KeyID) {
+		return nil, fmt.Errorf("两个CRL的颁发者密钥标识不一致，可能由不同密钥签发")
+	}
+
+	diff := &CRLDiff{
+		Issuer:          newCRL.Issuer,
+		OldCRLNumber:    oldCRL.CRLNumber,
+		NewCRLNumber:    newCRL.CRLNumber,
+		OldThisUpdate:   oldCRL.ThisUpdate,
+		NewThisUpdate:   newCRL.ThisUpdate,
+		OldNextUpdate:   oldCRL.NextUpdate,
+		NewNextUpdate:   newCRL.NextUpdate,
+		PublishInterval: newCRL.ThisUpdate.Sub(oldCRL.ThisUpdate),
+		OldTotal:        oldCRL.TotalRevoked,
+		NewTotal:        newCRL.TotalRevoked,
+	}
+	if !oldCRL.NextUpdate.IsZero() {
+		diff.CoverageGap = newCRL.ThisUpdate.Sub(oldCRL.NextUpdate)
+		diff.HasCoverageGap = true
+	}
+	diff.Warnings = diffCRLWarnings(oldCRL, newCRL, diff)
+
+	for _, revoked := range newCRL.RevokedCerts {
+		old := oldCRL.lookupEntry(revoked.SerialNumber)
+		if old == nil {
+			diff.Added++
+			diff.Entries = append(diff.Entries, CRLDiffEntry{
+				Change:            CRLChangeAdded,
+				SerialNumber:      revoked.SerialNumber,
+				NewRevocationTime: revoked.RevocationTime,
+				NewReason:         revoked.Reason,
+			})
+			continue
+		}
+		change := ""
+		switch {
+		case old.Reason != revoked.Reason:
+			change = CRLChangeReason
+		case !old.RevocationTime.Equal(revoked.RevocationTime):
+			change = CRLChangeRevocationTime
+		default:
+			continue
+		}
+		diff.Changed++
+		diff.Entries = append(diff.Entries, CRLDiffEntry{
+			Change:            change,
+			SerialNumber:      revoked.SerialNumber,
+			OldRevocationTime: old.RevocationTime,
+			NewRevocationTime: revoked.RevocationTime,
+			OldReason:         old.Reason,
+			NewReason:         revoked.Reason,
+		})
+	}
+	for _, revoked := range oldCRL.RevokedCerts {
+		if newCRL.lookupEntry(revoked.SerialNumber) != nil {
+			continue
+		}
+		diff.Removed++
+		diff.Entries = append(diff.Entries, CRLDiffEntry{
+			Change:            CRLChangeRemoved,
+			SerialNumber:      revoked.SerialNumber,
+			OldRevocationTime: revoked.RevocationTime,
+			OldReason:         revoked.Reason,
+		})
+	}
+	return diff, nil
+}
+
+// diffCRLWarnings 检查CRL序号及发布时间的递进关系
+func diffCRLWarnings(oldCRL, newCRL *CRLInfo, diff *CRLDiff) []string {
+	var warnings []string
+	switch {
+	case oldCRL.CRLNumber == nil || newCRL.CRLNumber == nil:
+		warnings = append(warnings, "至少一个CRL未包含CRL Number，无法判断发布顺序")
+	case newCRL.CRLNumber.Cmp(oldCRL.CRLNumber) == 0:
+		warnings = append(warnings, "两个CRL的CRL Number相同，RFC 5280要求每次发布递增")
+	case newCRL.CRLNumber.Cmp(oldCRL.CRLNumber) < 0:
+		warnings = append(warnings, "新CRL的CRL Number小于旧CRL，两个CRL的顺序可能颠倒")
+	}
+	if diff.PublishInterval < 0 {
+		warnings = append(warnings, "新CRL的thisUpdate早于旧CRL")
+	}
+	if diff.HasCoverageGap && diff.CoverageGap > 0 {
+		warnings = append(warnings, fmt.Sprintf("旧CRL过期%s后才发布新CRL，期间没有有效CRL", FormatDuration(diff.CoverageGap)))
+	}
+	if (oldCRL.DeltaCRLIndicator == nil) != (newCRL.DeltaCRLIndicator == nil) {
+		warnings = append(warnings, "比较的是完整CRL与增量CRL，新增/移除的条目不代表吊销状态的变化")
+	}
+	return warnings
+}
+
+// lookupEntry 按CRL中的序列号文本查找条目，ReadCRL构造的索引直接以该文本为键
+func (crlInfo *CRLInfo) lookupEntry(serialNumber string) *RevokedCertificate {
+	if crlInfo.index == nil {
+		crlInfo.buildIndex()
+	}
+	if i, ok := crlInfo.index[serialNumber]; ok {
+		return &crlInfo.RevokedCerts[i]
+	}
+	entry, _ := crlInfo.Lookup(serialNumber)
+	return entry
+}
+
+// CRLNumberProgression 描述CRL Number的变化，如 "5 → 7 (+2)"
+func (diff *CRLDiff) CRLNumberProgression() string {
+	format := func(n *big.Int) string {
+		if n == nil {
+			return "无"
+		}
+		return n.String()
+	}
+	text := format(diff.OldCRLNumber) + " → " + format(diff.NewCRLNumber)
+	if diff.OldCRLNumber != nil && diff.NewCRLNumber != nil {
+		step := new(big.Int).Sub(diff.NewCRLNumber, diff.OldCRLNumber)
+		if step.Sign() >= 0 {
+			text += fmt.Sprintf(" (+%s)", step)
+		} else {
+			text += fmt.Sprintf(" (%s)", step)
+		}
+	}
+	return text
+}
+
+// CoverageText 描述新旧CRL衔接情况
+func (diff *CRLDiff) CoverageText() string {
+	switch {
+	case !diff.HasCoverageGap:
+		return "旧CRL未设置nextUpdate"
+	case diff.CoverageGap > 0:
+		return "旧CRL过期后 " + FormatDuration(diff.CoverageGap) + " 才发布新CRL"
+	case diff.CoverageGap == 0:
+		return "新CRL恰好在旧CRL过期时发布"
+	default:
+		return "新CRL在旧CRL过期前 " + FormatDuration(-diff.CoverageGap) + " 发布"
+	}
+}
+
+// Summary 比较结果概要，每行一项
+func (diff *CRLDiff) Summary() []string {
+	formatNext := func(t time.Time) string {
+		if t.IsZero() {
+			return "未设置"
+		}
+		return t.Format(util.DateTime)
+	}
+	return []string{
+		"颁发者: " + diff.Issuer,
+		"CRL Number: " + diff.CRLNumberProgression(),
+		fmt.Sprintf("thisUpdate: %s → %s (间隔 %s)", diff.OldThisUpdate.Format(util.DateTime),
+			diff.NewThisUpdate.Format(util.DateTime), FormatDuration(diff.PublishInterval)),
+		fmt.Sprintf("nextUpdate: %s → %s", formatNext(diff.OldNextUpdate), formatNext(diff.NewNextUpdate)),
+		"衔接: " + diff.CoverageText(),
+		fmt.Sprintf("吊销条目: %d → %d，新增 %d，移除 %d，变更 %d", diff.OldTotal, diff.NewTotal, diff.Added, diff.Removed, diff.Changed),
+	}
+}
+
+// CRLDiffColumns 比较结果表格及CSV的列名
+var CRLDiffColumns = []string{"变更", "序列号", "旧吊销时间", "新吊销时间", "旧原因", "新原因"}
+
+// Row 返回条目在表格中的各列，与CRLDiffColumns对应
+func (entry CRLDiffEntry) Row() []string {
+	return []string{entry.Change, entry.SerialNumber, formatOptionalTime(entry.OldRevocationTime),
+		formatOptionalTime(entry.NewRevocationTime), entry.OldReason, entry.NewReason}
+}
+
+// WriteCSV 以CSV格式输出变更条目，首行为列名，带UTF-8 BOM以便Excel正确识别中文
+func (diff *CRLDiff) WriteCSV(w io.Writer) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CRLDiffColumns); err != nil {
+		return err
+	}
+	for _, entry := range diff.Entries {
+		if err := writer.Write(entry.Row()); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// crlDiffJSON 比较结果的JSON输出格式，时间统一为 util.DateTime 格式
+type crlDiffJSON struct {
+	Issuer               string             `json:"issuer"`
+	OldCRLNumber         string             `json:"oldCRLNumber,omitempty"`
+	NewCRLNumber         string             `json:"newCRLNumber,omitempty"`
+	CRLNumberProgression string             `json:"crlNumberProgression"`
+	OldThisUpdate        string             `json:"oldThisUpdate"`
+	NewThisUpdate        string             `json:"newThisUpdate"`
+	OldNextUpdate        string             `json:"oldNextUpdate,omitempty"`
+	NewNextUpdate        string             `json:"newNextUpdate,omitempty"`
+	PublishInterval      int64              `json:"publishIntervalSeconds"`
+	CoverageGap          *int64             `json:"coverageGapSeconds,omitempty"`
+	Coverage             string             `json:"coverage"`
+	OldTotal             int                `json:"oldTotal"`
+	NewTotal             int                `json:"newTotal"`
+	Added                int                `json:"added"`
+	Removed              int                `json:"removed"`
+	Changed              int                `json:"changed"`
+	Warnings             []string           `json:"warnings,omitempty"`
+	Entries              []crlDiffEntryJSON `json:"entries"`
+}
+
+type crlDiffEntryJSON struct {
+	Change            string `json:"change"`
+	SerialNumber      string `json:"serialNumber"`
+	OldRevocationTime string `json:"oldRevocationTime,omitempty"`
+	NewRevocationTime string `json:"newRevocationTime,omitempty"`
+	OldReason         string `json:"oldReason,omitempty"`
+	NewReason         string `json:"newReason,omitempty"`
+}
+
+// WriteJSON 以JSON格式输出概要及变更条目，间隔以秒为单位
+func (diff *CRLDiff) WriteJSON(w io.Writer) error {
+	view := crlDiffJSON{
+		Issuer:               diff.Issuer,
+		CRLNumberProgression: diff.CRLNumberProgression(),
+		OldThisUpdate:        formatOptionalTime(diff.OldThisUpdate),
+		NewThisUpdate:        formatOptionalTime(diff.NewThisUpdate),
+		OldNextUpdate:        formatOptionalTime(diff.OldNextUpdate),
+		NewNextUpdate:        formatOptionalTime(diff.NewNextUpdate),
+		PublishInterval:      int64(diff.PublishInterval / time.Second),
+		Coverage:             diff.CoverageText(),
+		OldTotal:             diff.OldTotal,
+		NewTotal:             diff.NewTotal,
+		Added:                diff.Added,
+		Removed:              diff.Removed,
+		Changed:              diff.Changed,
+		Warnings:             diff.Warnings,
+		Entries:              make([]crlDiffEntryJSON, 0, len(diff.Entries)),
+	}
+	if diff.OldCRLNumber != nil {
+		view.OldCRLNumber = diff.OldCRLNumber.String()
+	}
+	if diff.NewCRLNumber != nil {
+		view.NewCRLNumber = diff.NewCRLNumber.String()
+	}
+	if diff.HasCoverageGap {
+		seconds := int64(diff.CoverageGap / time.Second)
+		view.CoverageGap = &seconds
+	}
+	for _, entry := range diff.Entries {
+		view.Entries = append(view.Entries, crlDiffEntryJSON{
+			Change:            entry.Change,
+			SerialNumber:      entry.SerialNumber,
+			OldRevocationTime: formatOptionalTime(entry.OldRevocationTime),
+			NewRevocationTime: formatOptionalTime(entry.NewRevocationTime),
+			OldReason:         entry.OldReason,
+			NewReason:         entry.NewReason,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(view)
+}
+
+// formatOptionalTime 格式化时间，零值返回空字符串
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(util.DateTime)
+}
+
+// FormatDuration 将时间间隔格式化为 "x天x小时x分钟"
+func FormatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	d = d.Round(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	seconds := (d - minutes*time.Minute) / time.Second
+
+	text := ""
+	if days > 0 {
+		text += fmt.Sprintf("%d天", days)
+	}
+	if hours > 0 {
+		text += fmt.Sprintf("%d小时", hours)
+	}
+	if minutes > 0 {
+		text += fmt.Sprintf("%d分钟", minutes)
+	}
+	if seconds > 0 || text == "" {
+		text += fmt.Sprintf("%d秒", seconds)
+	}
+	return sign + text
+}
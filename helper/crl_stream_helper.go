@@ -0,0 +1,841 @@
+package helper
+
+import (
+	"HeTu/codec"
+	"HeTu/util"
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zaneway/cain-go/sm2"
+	"github.com/zaneway/cain-go/sm3"
+	"github.com/zaneway/cain-go/x509"
+)
+
+// maxDERElementSize 逐项读取时单个元素(如一个吊销条目或扩展列表)的最大长度，防止异常长度导致的超大内存分配
+const maxDERElementSize = 16 << 20
+
+// CRLProgressInterval 流式读取时每读取多少个吊销条目回调一次进度
+const CRLProgressInterval = 10000
+
+// maxRawTBSSize TBSCertList不超过该长度时保留原始编码，更大的CRL仅在读取时增量计算签名摘要
+const maxRawTBSSize = 1 << 20
+
+// maxUnknownSizeEstimate 输入总长度未知时按首个条目预估的条目数上限
+const maxUnknownSizeEstimate = 1 << 12
+
+// derHeader DER元素的标签与长度
+type derHeader struct {
+	Class       int
+	Tag         int
+	Constructed bool
+	Length      int64
+	Raw         []byte
+}
+
+// derStreamReader 按DER顺序逐元素读取，tee非空时读取的字节同时写入tee。
+// size为输入的总长度，未知时为-1
+type derStreamReader struct {
+	r      *bufio.Reader
+	offset int64
+	size   int64
+	tee    io.Writer
+}
+
+func (d *derStreamReader) readFull(buf []byte) error {
+	n, err := io.ReadFull(d.r, buf)
+	d.offset += int64(n)
+	if d.tee != nil {
+		d.tee.Write(buf[:n])
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("CRL数据在偏移%d处意外结束", d.offset)
+	}
+	return err
+}
+
+// readHeader 读取标签与长度，长度须为DER最短编码，不支持BER不定长编码
+func (d *derStreamReader) readHeader() (derHeader, error) {
+	var h derHeader
+	first := make([]byte, 2)
+	if err := d.readFull(first); err != nil {
+		return h, err
+	}
+	h.Raw = first
+	h.Class = int(first[0] >> 6)
+	h.Constructed = first[0]&0x20 != 0
+	h.Tag = int(first[0] & 0x1f)
+	if h.Tag == 0x1f {
+		return h, fmt.Errorf("偏移%d处不支持多字节标签", d.offset-2)
+	}
+	lengthByte := first[1]
+	if lengthByte < 0x80 {
+		h.Length = int64(lengthByte)
+		return h, nil
+	}
+	count := int(lengthByte & 0x7f)
+	if count == 0 || count > 8 {
+		return h, fmt.Errorf("偏移%d处长度编码无效(不定长或超长)", d.offset-2)
+	}
+	lengthBytes := make([]byte, count)
+	if err := d.readFull(lengthBytes); err != nil {
+		return h, err
+	}
+	h.Raw = append(h.Raw, lengthBytes...)
+	for _, b := range lengthBytes {
+		h.Length = h.Length<<8 | int64(b)
+	}
+	if h.Length < 0 {
+		return h, fmt.Errorf("偏移%d处长度无效", d.offset)
+	}
+	if lengthBytes[0] == 0 || h.Length < 0x80 {
+		return h, fmt.Errorf("偏移%d处长度不是DER最短编码", d.offset-int64(len(h.Raw)))
+	}
+	return h, nil
+}
+
+// readBody 读取元素内容，返回包含标签与长度的完整编码
+func (d *derStreamReader) readBody(h derHeader) ([]byte, error) {
+	if h.Length > maxDERElementSize {
+		return nil, fmt.Errorf("偏移%d处元素长度%d超出限制", d.offset, h.Length)
+	}
+	full := make([]byte, len(h.Raw)+int(h.Length))
+	copy(full, h.Raw)
+	if err := d.readFull(full[len(h.Raw):]); err != nil {
+		return nil, err
+	}
+	return full, nil
+}
+
+// within 检查元素内容不超出父元素的结束偏移，输入总长度已知时同时检查剩余数据足够
+func (d *derStreamReader) within(h derHeader, parentEnd int64) error {
+	if h.Length > parentEnd-d.offset {
+		return fmt.Errorf("偏移%d处元素长度%d超出所在结构的范围", d.offset, h.Length)
+	}
+	if d.size >= 0 && h.Length > d.size-d.offset {
+		return fmt.Errorf("偏移%d处元素长度%d超出剩余数据长度%d", d.offset, h.Length, d.size-d.offset)
+	}
+	return nil
+}
+
+// remaining 输入中尚未读取的字节数，总长度未知时返回-1
+func (d *derStreamReader) remaining() int64 {
+	if d.size < 0 {
+		return -1
+	}
+	return d.size - d.offset
+}
+
+// readHeaderWithin 读取标签与长度，并检查元素位于父元素之内
+func (d *derStreamReader) readHeaderWithin(parentEnd int64) (derHeader, error) {
+	h, err := d.readHeader()
+	if err != nil {
+		return h, err
+	}
+	return h, d.within(h, parentEnd)
+}
+
+// readElement 读取一个位于父元素之内的完整元素
+func (d *derStreamReader) readElement(parentEnd int64) (derHeader, []byte, error) {
+	h, err := d.readHeaderWithin(parentEnd)
+	if err != nil {
+		return h, nil, err
+	}
+	full, err := d.readBody(h)
+	return h, full, err
+}
+
+// inputSize 返回可确定的输入剩余长度(内存数据或普通文件)，无法确定时返回-1
+func inputSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		position, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - position
+	}
+	return -1
+}
+
+func (h derHeader) isUniversal(tag int) bool {
+	return h.Class == asn1.ClassUniversal && h.Tag == tag
+}
+
+// ReadCRL 流式读取DER或PEM编码的CRL：吊销条目逐个解码，不构造完整的pkix结构，
+// 同时建立序列号索引。progress非空时每读取CRLProgressInterval个条目回调一次已读取条目数。
+// 返回的CertificateList仅保留TBSCertList原始编码(不超过maxRawTBSSize时)与扩展，不含吊销条目；
+// 更大的CRL在读取时增量计算签名摘要，SM2签名的摘要依赖颁发者公钥，需通过issuers提供候选颁发者
+func ReadCRL(r io.Reader, issuers []*x509.Certificate, progress func(entries int)) (*CRLInfo, error) {
+	// 输入长度须在缓冲读取之前确定
+	size := inputSize(r)
+	br := bufio.NewReaderSize(r, 64*1024)
+	// 不以SEQUENCE标签开头的输入(PEM，可带BOM、前导空白或说明文字，以及Base64/Hex)整体读入后由codec解码
+	if first, err := br.Peek(1); err == nil && first[0] != 0x30 {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, fmt.Errorf("读取CRL失败: %v", err)
+		}
+		result, err := codec.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析CRL失败: %v", err)
+		}
+		if result.Encoding == codec.EncodingDER {
+			return nil, fmt.Errorf("解析CRL失败: 不是ASN.1 SEQUENCE")
+		}
+		der, ok := result.FirstOfType("X509 CRL", "CRL")
+		if !ok {
+			return nil, fmt.Errorf("PEM中未找到X509 CRL块，输入为 %s", result.Describe())
+		}
+		return ReadCRL(bytes.NewReader(der), issuers, progress)
+	}
+
+	d := &derStreamReader{r: br, size: size}
+	inputEnd := int64(math.MaxInt64)
+	if d.size >= 0 {
+		inputEnd = d.size
+	}
+	outer, err := d.readHeaderWithin(inputEnd)
+	if err != nil {
+		return nil, fmt.Errorf("解析CRL失败: %v", err)
+	}
+	if !outer.isUniversal(asn1.TagSequence) || !outer.Constructed {
+		return nil, fmt.Errorf("解析CRL失败: 不是ASN.1 SEQUENCE")
+	}
+	outerEnd := d.offset + outer.Length
+
+	crl, crlInfo, err := readTBSCertList(d, outerEnd, issuers, progress)
+	if err != nil {
+		return nil, fmt.Errorf("解析CRL失败: %v", err)
+	}
+
+	_, algBytes, err := d.readElement(outerEnd)
+	if err != nil {
+		return nil, fmt.Errorf("解析CRL签名算法失败: %v", err)
+	}
+	if err := unmarshalExact(algBytes, &crl.SignatureAlgorithm); err != nil {
+		return nil, fmt.Errorf("解析CRL签名算法失败: %v", err)
+	}
+	_, sigBytes, err := d.readElement(outerEnd)
+	if err != nil {
+		return nil, fmt.Errorf("解析CRL签名值失败: %v", err)
+	}
+	if err := unmarshalExact(sigBytes, &crl.SignatureValue); err != nil {
+		return nil, fmt.Errorf("解析CRL签名值失败: %v", err)
+	}
+	if d.offset != outerEnd {
+		return nil, fmt.Errorf("解析CRL失败: CertificateList长度与内容不一致")
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("解析CRL失败: CRL之后存在多余数据")
+	}
+
+	crlInfo.SignatureAlgorithm = formatSignatureAlgorithm(crl.SignatureAlgorithm)
+	if crl.SignatureValue.BitLength == 0 {
+		crlInfo.Warnings = append(crlInfo.Warnings, "CRL没有签名值")
+	}
+	if !crl.SignatureAlgorithm.Algorithm.Equal(crl.TBSCertList.Signature.Algorithm) {
+		crlInfo.Warnings = append(crlInfo.Warnings, "外层签名算法与TBSCertList中的签名算法不一致")
+	}
+	crlInfo.CertificateList = crl
+	return crlInfo, nil
+}
+
+// readTBSCertList 读取TBSCertList，吊销条目逐个转换为RevokedCertificate，
+// 读取的同时计算签名摘要，parentEnd为外层CertificateList的结束偏移
+func readTBSCertList(d *derStreamReader, parentEnd int64, issuers []*x509.Certificate, progress func(entries int)) (*pkix.CertificateList, *CRLInfo, error) {
+	tbsHeader, err := d.readHeaderWithin(parentEnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !tbsHeader.isUniversal(asn1.TagSequence) {
+		return nil, nil, fmt.Errorf("TBSCertList不是SEQUENCE")
+	}
+	// 签名算法之前的部分先缓存，确定摘要算法后写入摘要计算器
+	prefix := bytes.NewBuffer(append([]byte(nil), tbsHeader.Raw...))
+	d.tee = prefix
+	tbsEnd := d.offset + tbsHeader.Length
+
+	crl := &pkix.CertificateList{}
+	tbs := &crl.TBSCertList
+	h, element, err := d.readElement(tbsEnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h.isUniversal(asn1.TagInteger) {
+		if err := unmarshalExact(element, &tbs.Version); err != nil {
+			return nil, nil, fmt.Errorf("解析版本失败: %v", err)
+		}
+		if _, element, err = d.readElement(tbsEnd); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := unmarshalExact(element, &tbs.Signature); err != nil {
+		return nil, nil, fmt.Errorf("解析签名算法失败: %v", err)
+	}
+	hasher := newTBSHasher(tbs.Signature, issuers, tbsHeader.Length <= maxRawTBSSize)
+	hasher.Write(prefix.Bytes())
+	d.tee = hasher
+
+	if _, element, err = d.readElement(tbsEnd); err != nil {
+		return nil, nil, err
+	}
+	if err := unmarshalExact(element, &tbs.Issuer); err != nil {
+		return nil, nil, fmt.Errorf("解析颁发者失败: %v", err)
+	}
+	if _, element, err = d.readElement(tbsEnd); err != nil {
+		return nil, nil, err
+	}
+	if err := unmarshalExact(element, &tbs.ThisUpdate); err != nil {
+		return nil, nil, fmt.Errorf("解析thisUpdate失败: %v", err)
+	}
+
+	crlInfo := &CRLInfo{
+		Version:         tbs.Version + 1,
+		Issuer:          tbs.Issuer.String(),
+		ThisUpdate:      util.ToBeijingTime(tbs.ThisUpdate),
+		SignatureStatus: CRLSignatureUnverified,
+		index:           make(map[string]int),
+	}
+	var certificateIssuer []GeneralName
+	for d.offset < tbsEnd {
+		h, err := d.readHeaderWithin(tbsEnd)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case h.isUniversal(asn1.TagUTCTime) || h.isUniversal(asn1.TagGeneralizedTime):
+			full, err := d.readBody(h)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := unmarshalExact(full, &tbs.NextUpdate); err != nil {
+				return nil, nil, fmt.Errorf("解析nextUpdate失败: %v", err)
+			}
+			crlInfo.NextUpdate = util.ToBeijingTime(tbs.NextUpdate)
+		case h.isUniversal(asn1.TagSequence):
+			entriesEnd := d.offset + h.Length
+			for d.offset < entriesEnd {
+				_, full, err := d.readElement(entriesEnd)
+				if err != nil {
+					return nil, nil, err
+				}
+				revoked, err := decodeRevokedEntry(full)
+				if err != nil {
+					return nil, nil, fmt.Errorf("解析第%d个吊销条目失败: %v", len(crlInfo.RevokedCerts)+1, err)
+				}
+				if crlInfo.RevokedCerts == nil {
+					estimate := revokedEstimate(d, entriesEnd, len(full))
+					crlInfo.RevokedCerts = make([]RevokedCertificate, 0, estimate)
+					crlInfo.index = make(map[string]int, estimate)
+				}
+				crlInfo.addRevoked(revoked, &certificateIssuer)
+				if progress != nil && len(crlInfo.RevokedCerts)%CRLProgressInterval == 0 {
+					progress(len(crlInfo.RevokedCerts))
+				}
+			}
+			if d.offset != entriesEnd {
+				return nil, nil, fmt.Errorf("吊销条目列表长度与内容不一致")
+			}
+		case h.Class == asn1.ClassContextSpecific && h.Tag == 0:
+			full, err := d.readBody(h)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, err := asn1.UnmarshalWithParams(full, &tbs.Extensions, "explicit,tag:0"); err != nil {
+				return nil, nil, fmt.Errorf("解析CRL扩展失败: %v", err)
+			}
+		default:
+			return nil, nil, fmt.Errorf("TBSCertList中存在无法识别的元素(class %d, tag %d)", h.Class, h.Tag)
+		}
+	}
+	if d.offset != tbsEnd {
+		return nil, nil, fmt.Errorf("TBSCertList长度与内容不一致")
+	}
+	d.tee = nil
+	if hasher.raw != nil {
+		tbs.Raw = hasher.raw.Bytes()
+	}
+	crlInfo.tbsDigest = hasher.sum()
+
+	crlInfo.TotalRevoked = len(crlInfo.RevokedCerts)
+	crlInfo.Extensions = tbs.Extensions
+	parseCRLExtensions(crlInfo, tbs.Extensions)
+	return crl, crlInfo, nil
+}
+
+// revokedEstimate 按首个条目长度预估条目总数，减少大CRL的扩容与索引重建；
+// 预估所用的长度不超过实际剩余的数据，输入长度未知时限制在maxUnknownSizeEstimate之内
+func revokedEstimate(d *derStreamReader, entriesEnd int64, entrySize int) int {
+	left := entriesEnd - d.offset
+	if remaining := d.remaining(); remaining >= 0 {
+		left = min(left, remaining)
+	}
+	estimate := left/int64(entrySize) + 1
+	if d.size < 0 {
+		estimate = min(estimate, maxUnknownSizeEstimate)
+	}
+	return int(min(estimate, 1<<24))
+}
+
+// crlSignatureHashes RSA及ECDSA签名算法对应的摘要算法，RSA-PSS等依赖参数的算法仅能通过原始编码验证
+var crlSignatureHashes = map[string]crypto.Hash{
+	"1.2.840.113549.1.1.5":  crypto.SHA1,
+	"1.2.840.113549.1.1.11": crypto.SHA256,
+	"1.2.840.113549.1.1.12": crypto.SHA384,
+	"1.2.840.113549.1.1.13": crypto.SHA512,
+	"1.2.840.10045.4.1":     crypto.SHA1,
+	"1.2.840.10045.4.3.2":   crypto.SHA256,
+	"1.2.840.10045.4.3.3":   crypto.SHA384,
+	"1.2.840.10045.4.3.4":   crypto.SHA512,
+}
+
+// tbsHasher 读取TBSCertList时增量计算签名摘要。SM2签名的摘要为SM3(Z||TBSCertList)，
+// Z由颁发者公钥决定，按候选颁发者分别计算；RSA、ECDSA的摘要与公钥无关
+type tbsHasher struct {
+	raw     *bytes.Buffer
+	hashAlg crypto.Hash
+	hash    hash.Hash
+	sm2Keys []string
+	sm2     []hash.Hash
+}
+
+func newTBSHasher(algorithm pkix.AlgorithmIdentifier, issuers []*x509.Certificate, keepRaw bool) *tbsHasher {
+	hasher := new(tbsHasher)
+	if keepRaw {
+		hasher.raw = new(bytes.Buffer)
+	}
+	if algorithm.Algorithm.Equal(oidSignatureSM2WithSM3) {
+		for _, issuer := range issuers {
+			pub, err := CertificatePublicKey(issuer)
+			if err != nil {
+				continue
+			}
+			sm2Pub, err := toSM2PublicKey(pub)
+			if err != nil {
+				continue
+			}
+			za, err := sm2.ZA(sm2Pub, SM2DefaultUserID)
+			if err != nil {
+				continue
+			}
+			h := sm3.New()
+			h.Write(za)
+			hasher.sm2Keys = append(hasher.sm2Keys, string(MarshalSM2Point(sm2Pub)))
+			hasher.sm2 = append(hasher.sm2, h)
+		}
+	} else if hashAlg, ok := crlSignatureHashes[algorithm.Algorithm.String()]; ok && hashAlg.Available() {
+		hasher.hashAlg, hasher.hash = hashAlg, hashAlg.New()
+	}
+	return hasher
+}
+
+func (t *tbsHasher) Write(p []byte) (int, error) {
+	if t.raw != nil {
+		t.raw.Write(p)
+	}
+	if t.hash != nil {
+		t.hash.Write(p)
+	}
+	for _, h := range t.sm2 {
+		h.Write(p)
+	}
+	return len(p), nil
+}
+
+func (t *tbsHasher) sum() *crlTBSDigest {
+	digest := &crlTBSDigest{hashAlg: t.hashAlg, sm2: make(map[string][]byte, len(t.sm2))}
+	if t.hash != nil {
+		digest.digest = t.hash.Sum(nil)
+	}
+	for i, h := range t.sm2 {
+		digest.sm2[t.sm2Keys[i]] = h.Sum(nil)
+	}
+	return digest
+}
+
+// crlTBSDigest 读取时计算的TBSCertList签名摘要，sm2以颁发者公钥 04||X||Y 为键
+type crlTBSDigest struct {
+	hashAlg crypto.Hash
+	digest  []byte
+	sm2     map[string][]byte
+}
+
+// verify 使用读取时计算的摘要验证签名
+func (t *crlTBSDigest) verify(pub crypto.PublicKey, signature []byte) error {
+	if sm2Pub, err := toSM2PublicKey(pub); err == nil {
+		e, ok := t.sm2[string(MarshalSM2Point(sm2Pub))]
+		if !ok {
+			return fmt.Errorf("CRL超过%d字节，仅在读取时计算签名摘要，SM2签名需在读取CRL时提供该颁发者证书", maxRawTBSSize)
+		}
+		var sig sm2SignatureASN1
+		if err := unmarshalExact(signature, &sig); err != nil {
+			return fmt.Errorf("SM2签名值格式错误")
+		}
+		if !sm2.Verify(sm2Pub, e, sig.R, sig.S) {
+			return fmt.Errorf("SM2签名值不匹配")
+		}
+		return nil
+	}
+	if t.digest == nil {
+		return fmt.Errorf("CRL超过%d字节，读取时未能按该签名算法计算摘要，无法验证签名", maxRawTBSSize)
+	}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, t.hashAlg, t.digest, signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, t.digest, signature) {
+			return fmt.Errorf("ECDSA签名值不匹配")
+		}
+		return nil
+	}
+	return fmt.Errorf("不支持的公钥类型%T", pub)
+}
+
+// revokedEntry 解码后的吊销条目，序列号为serialHex规范形式
+type revokedEntry struct {
+	serialNumber   string
+	revocationTime time.Time
+	extensions     []pkix.Extension
+}
+
+// crlEntryExtensionOIDs 条目扩展OID的DER内容与对应的OID
+var crlEntryExtensionOIDs = map[string]asn1.ObjectIdentifier{
+	"\x55\x1d\x15": oidExtensionReasonCode,
+	"\x55\x1d\x18": oidExtensionInvalidityDate,
+	"\x55\x1d\x1d": oidExtensionCertificateIssuer,
+}
+
+// decodeRevokedEntry 解码单个吊销条目，常见编码按字节直接解析，其余交由encoding/asn1处理
+func decodeRevokedEntry(der []byte) (revokedEntry, error) {
+	if entry, ok := decodeRevokedEntryFast(der); ok {
+		return entry, nil
+	}
+	var revoked pkix.RevokedCertificate
+	if err := unmarshalExact(der, &revoked); err != nil {
+		return revokedEntry{}, err
+	}
+	return revokedEntry{
+		serialNumber:   serialHex(revoked.SerialNumber),
+		revocationTime: revoked.RevocationTime,
+		extensions:     revoked.Extensions,
+	}, nil
+}
+
+// decodeRevokedEntryFast 解析正序列号、UTCTime/GeneralizedTime(秒精度、Z结尾)及已知条目扩展的吊销条目，
+// 大型CRL中几乎所有条目都属于这种形式，无法处理时返回false
+func decodeRevokedEntryFast(der []byte) (revokedEntry, bool) {
+	var entry revokedEntry
+	tag, body, rest, ok := splitTLV(der)
+	if !ok || tag != 0x30 || len(rest) != 0 {
+		return entry, false
+	}
+	tag, serial, body, ok := splitTLV(body)
+	if !ok || tag != asn1.TagInteger || len(serial) == 0 || serial[0]&0x80 != 0 {
+		return entry, false
+	}
+	if serial[0] == 0 && len(serial) > 1 {
+		if serial[1]&0x80 == 0 {
+			return entry, false
+		}
+		serial = serial[1:]
+	}
+	if len(serial) == 1 && serial[0] == 0 {
+		serial = nil
+	}
+	entry.serialNumber = hex.EncodeToString(serial)
+
+	tag, value, body, ok := splitTLV(body)
+	if !ok {
+		return entry, false
+	}
+	if entry.revocationTime, ok = parseDERTime(tag, value); !ok {
+		return entry, false
+	}
+	if len(body) == 0 {
+		return entry, true
+	}
+
+	tag, extensions, rest, ok := splitTLV(body)
+	if !ok || tag != 0x30 || len(rest) != 0 {
+		return entry, false
+	}
+	for len(extensions) > 0 {
+		var extension []byte
+		if tag, extension, extensions, ok = splitTLV(extensions); !ok || tag != 0x30 {
+			return entry, false
+		}
+		var oid, critical, extValue []byte
+		if tag, oid, extension, ok = splitTLV(extension); !ok || tag != asn1.TagOID {
+			return entry, false
+		}
+		id, known := crlEntryExtensionOIDs[string(oid)]
+		if !known {
+			return entry, false
+		}
+		ext := pkix.Extension{Id: id}
+		if tag, critical, extension, ok = splitTLV(extension); ok && tag == asn1.TagBoolean {
+			if len(critical) != 1 {
+				return entry, false
+			}
+			ext.Critical = critical[0] != 0
+			tag, extValue, extension, ok = splitTLV(extension)
+		} else {
+			extValue = critical
+		}
+		if !ok || tag != asn1.TagOctetString || len(extension) != 0 {
+			return entry, false
+		}
+		ext.Value = extValue
+		entry.extensions = append(entry.extensions, ext)
+	}
+	return entry, true
+}
+
+// splitTLV 拆分DER编码的第一个元素，返回完整的标签字节(含类别与构造位)、内容及剩余数据，
+// 长度不是最短编码时返回false
+func splitTLV(data []byte) (byte, []byte, []byte, bool) {
+	if len(data) < 2 || data[0]&0x1f == 0x1f {
+		return 0, nil, nil, false
+	}
+	tag, length, offset := data[0], int(data[1]), 2
+	if length >= 0x80 {
+		count := length & 0x7f
+		if count == 0 || count > 4 || len(data) < 2+count || data[2] == 0 {
+			return 0, nil, nil, false
+		}
+		length = 0
+		for _, b := range data[2 : 2+count] {
+			length = length<<8 | int(b)
+		}
+		if length < 0x80 {
+			return 0, nil, nil, false
+		}
+		offset += count
+	}
+	if length < 0 || len(data)-offset < length {
+		return 0, nil, nil, false
+	}
+	return tag, data[offset : offset+length], data[offset+length:], true
+}
+
+// parseDERTime 解析 YYMMDDHHMMSSZ 形式的UTCTime及 YYYYMMDDHHMMSSZ 形式的GeneralizedTime
+func parseDERTime(tag byte, value []byte) (time.Time, bool) {
+	var digits []byte
+	year := 0
+	switch {
+	case tag == asn1.TagUTCTime && len(value) == 13 && value[12] == 'Z':
+		digits = value[:12]
+	case tag == asn1.TagGeneralizedTime && len(value) == 15 && value[14] == 'Z':
+		digits = value[2:14]
+		year = 100 * twoDigits(value[0:2])
+	default:
+		return time.Time{}, false
+	}
+	fields := make([]int, 6)
+	for i := range fields {
+		fields[i] = twoDigits(digits[2*i : 2*i+2])
+		if fields[i] < 0 {
+			return time.Time{}, false
+		}
+	}
+	if tag == asn1.TagUTCTime {
+		year = 1900
+		if fields[0] < 50 {
+			year = 2000
+		}
+	} else if year < 0 {
+		return time.Time{}, false
+	}
+	year += fields[0]
+	t := time.Date(year, time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, time.UTC)
+	if int(t.Month()) != fields[1] || t.Day() != fields[2] || t.Hour() != fields[3] || t.Minute() != fields[4] || t.Second() != fields[5] {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// twoDigits 解析两位十进制数字，非数字返回-1
+func twoDigits(b []byte) int {
+	if b[0] < '0' || b[0] > '9' || b[1] < '0' || b[1] > '9' {
+		return -1
+	}
+	return int(b[0]-'0')*10 + int(b[1]-'0')
+}
+
+// addRevoked 转换吊销条目并加入索引，certificateIssuer为间接CRL中沿用的证书颁发者
+func (crlInfo *CRLInfo) addRevoked(revoked revokedEntry, certificateIssuer *[]GeneralName) {
+	revokedCert := RevokedCertificate{
+		SerialNumber:   revoked.serialNumber,
+		RevocationTime: util.ToBeijingTime(revoked.revocationTime),
+		Reason:         parseRevocationReason(revoked.extensions),
+		ReasonCode:     CRLReasonOmitted,
+	}
+	for _, ext := range revoked.extensions {
+		switch ext.Id.String() {
+		case "2.5.29.24":
+			if date, err := ParseInvalidityDate(ext.Value); err == nil {
+				revokedCert.InvalidityDate = util.ToBeijingTime(date)
+			}
+		case "2.5.29.29":
+			if names, err := ParseGeneralNames(ext.Value); err == nil {
+				*certificateIssuer = names
+			}
+		case "2.5.29.21":
+			if code, err := decodeReasonCode(ext.Value); err == nil {
+				revokedCert.ReasonCode = code
+			}
+		default:
+			if ext.Critical {
+				crlInfo.Warnings = append(crlInfo.Warnings, fmt.Sprintf("序列号%s的条目包含无法识别的关键扩展%s", revokedCert.SerialNumber, ext.Id))
+			}
+		}
+	}
+	revokedCert.CertificateIssuer = *certificateIssuer
+	if _, exists := crlInfo.index[revokedCert.SerialNumber]; !exists {
+		crlInfo.index[revokedCert.SerialNumber] = len(crlInfo.RevokedCerts)
+	}
+	crlInfo.RevokedCerts = append(crlInfo.RevokedCerts, revokedCert)
+}
+
+// ReadCRLFile 流式读取CRL文件，issuers见ReadCRL
+func ReadCRLFile(filePath string, issuers []*x509.Certificate, progress func(entries int)) (*CRLInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取CRL文件失败: %v", err)
+	}
+	defer file.Close()
+	return ReadCRL(file, issuers, progress)
+}
+
+// SerialLookup 单个序列号的查询结果
+type SerialLookup struct {
+	SerialNumber string              `json:"serialNumber"`
+	Revoked      bool                `json:"revoked"`
+	Entry        *RevokedCertificate `json:"entry,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// serialIndexKey 将Hex序列号转换为索引键(serialHex规范形式)
+func serialIndexKey(serialNumber string) (string, error) {
+	n, err := ConvertSerialNumberToBigInt(serialNumber)
+	if err != nil {
+		return "", err
+	}
+	return serialHex(n), nil
+}
+
+// buildIndex 为非ReadCRL构造的CRLInfo补建序列号索引
+func (crlInfo *CRLInfo) buildIndex() {
+	crlInfo.index = make(map[string]int, len(crlInfo.RevokedCerts))
+	for i, revoked := range crlInfo.RevokedCerts {
+		key, err := serialIndexKey(revoked.SerialNumber)
+		if err != nil {
+			continue
+		}
+		if _, exists := crlInfo.index[key]; !exists {
+			crlInfo.index[key] = i
+		}
+	}
+}
+
+// Lookup 按序列号(Hex，可含空格、冒号等分隔符，负数以"-"开头)查找吊销条目，未吊销时返回nil
+func (crlInfo *CRLInfo) Lookup(serialNumber string) (*RevokedCertificate, error) {
+	key, err := serialIndexKey(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	return crlInfo.lookupKey(key), nil
+}
+
+// lookupSerial 按序列号查找issuer签发证书的吊销条目，未吊销时返回nil。
+// 间接CRL中Certificate Issuer不包含issuer的条目属于其他CA，予以跳过
+func (crlInfo *CRLInfo) lookupSerial(serial *big.Int, issuer *x509.Certificate) *RevokedCertificate {
+	key := serialHex(serial)
+	revoked := crlInfo.lookupKey(key)
+	if revoked == nil || namesCertificateIssuer(revoked.CertificateIssuer, issuer) {
+		return revoked
+	}
+	// 索引只记录序列号的第一个条目，间接CRL中同一序列号可能属于多个CA
+	for i := range crlInfo.RevokedCerts {
+		if revoked := &crlInfo.RevokedCerts[i]; revoked.SerialNumber == key && namesCertificateIssuer(revoked.CertificateIssuer, issuer) {
+			return revoked
+		}
+	}
+	return nil
+}
+
+// lookupKey 按索引键查找吊销条目
+func (crlInfo *CRLInfo) lookupKey(key string) *RevokedCertificate {
+	if crlInfo.index == nil {
+		crlInfo.buildIndex()
+	}
+	if i, ok := crlInfo.index[key]; ok {
+		return &crlInfo.RevokedCerts[i]
+	}
+	return nil
+}
+
+// LookupSerials 批量查询序列号，结果顺序与输入一致
+func (crlInfo *CRLInfo) LookupSerials(serialNumbers []string) []SerialLookup {
+	results := make([]SerialLookup, 0, len(serialNumbers))
+	for _, serialNumber := range serialNumbers {
+		result := SerialLookup{SerialNumber: serialNumber}
+		entry, err := crlInfo.Lookup(serialNumber)
+		if err != nil {
+			result.Error = err.Error()
+		} else if entry != nil {
+			result.Revoked = true
+			result.Entry = entry
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// SplitSerialNumbers 按换行、逗号或分号拆分批量输入的序列号，忽略空行
+func SplitSerialNumbers(text string) []string {
+	var serials []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ',' || r == ';'
+	}) {
+		if field = strings.TrimSpace(field); field != "" {
+			serials = append(serials, field)
+		}
+	}
+	return serials
+}
+
+// RevokedPage 返回第page页(从1开始)的吊销条目及总页数，page超出范围时取最近的有效页
+func (crlInfo *CRLInfo) RevokedPage(page, size int) ([]RevokedCertificate, int, int) {
+	if size <= 0 {
+		size = 100
+	}
+	pages := (len(crlInfo.RevokedCerts) + size - 1) / size
+	if pages == 0 {
+		return nil, 1, 1
+	}
+	page = max(1, min(page, pages))
+	start := (page - 1) * size
+	end := min(start+size, len(crlInfo.RevokedCerts))
+	return crlInfo.RevokedCerts[start:end], page, pages
+}
@@ -0,0 +1,351 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/rand"
+	stdx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zaneway/cain-go/sm2"
+	"github.com/zaneway/cain-go/x509"
+)
+
+// derTLV 按DER规则(最短长度形式)拼接单个元素
+func derTLV(tag byte, content ...[]byte) []byte {
+	body := bytes.Join(content, nil)
+	return append(testDERHeader(tag, len(body)), body...)
+}
+
+// testDERHeader 构造标签与长度，length可与实际内容不符以构造畸形输入
+func testDERHeader(tag byte, length int) []byte {
+	if length < 0x80 {
+		return []byte{tag, byte(length)}
+	}
+	var octets []byte
+	for n := length; n > 0; n >>= 8 {
+		octets = append([]byte{byte(n)}, octets...)
+	}
+	return append([]byte{tag, 0x80 | byte(len(octets))}, octets...)
+}
+
+var (
+	testReasonOID         = []byte{0x06, 0x03, 0x55, 0x1d, 0x15}
+	testInvalidityDateOID = []byte{0x06, 0x03, 0x55, 0x1d, 0x18}
+	testCertIssuerOID     = []byte{0x06, 0x03, 0x55, 0x1d, 0x1d}
+	testUnknownOID        = []byte{0x06, 0x03, 0x2a, 0x03, 0x04}
+	testUTCTime           = derTLV(asn1.TagUTCTime, []byte("250102030405Z"))
+	testIssuerName        = derTLV(0x30, derTLV(0x31, derTLV(0x30, []byte{0x06, 0x03, 0x55, 0x04, 0x03}, derTLV(asn1.TagUTF8String, []byte("Test CRL CA")))))
+)
+
+// testEntry 由序列号INTEGER内容、时间元素及扩展构造吊销条目
+func testEntry(serial []byte, revocationTime []byte, extensions ...[]byte) []byte {
+	parts := [][]byte{derTLV(asn1.TagInteger, serial), revocationTime}
+	if len(extensions) > 0 {
+		parts = append(parts, derTLV(0x30, extensions...))
+	}
+	return derTLV(0x30, parts...)
+}
+
+// testExtension 构造扩展，critical为nil时省略BOOLEAN
+func testExtension(oid []byte, critical []byte, value []byte) []byte {
+	parts := [][]byte{oid}
+	if critical != nil {
+		parts = append(parts, derTLV(asn1.TagBoolean, critical))
+	}
+	return derTLV(0x30, append(parts, derTLV(asn1.TagOctetString, value))...)
+}
+
+// testCRL 以给定条目构造CRL，签名值为占位数据，仅用于解析比对
+func testCRL(entries ...[]byte) []byte {
+	tbs := derTLV(0x30,
+		derTLV(asn1.TagInteger, []byte{1}),
+		derTLV(0x30, []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x04, 0x03, 0x02}),
+		testIssuerName,
+		testUTCTime,
+		derTLV(asn1.TagUTCTime, []byte("350102030405Z")),
+		derTLV(0x30, entries...),
+	)
+	return derTLV(0x30, tbs,
+		derTLV(0x30, []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x04, 0x03, 0x02}),
+		derTLV(asn1.TagBitString, []byte{0, 1, 2, 3, 4}))
+}
+
+// opaqueReader 隐藏Len方法，模拟长度未知的输入流
+type opaqueReader struct{ io.Reader }
+
+func TestDecodeRevokedEntryFast(t *testing.T) {
+	reason := func(code byte) []byte { return derTLV(asn1.TagEnum, []byte{code}) }
+	generalizedTime := derTLV(asn1.TagGeneralizedTime, []byte("20510102030405Z"))
+	dirName := derTLV(0x30, derTLV(0xa4, testIssuerName))
+	tests := []struct {
+		name string
+		der  []byte
+		// fast 是否应由快速路径处理
+		fast bool
+	}{
+		{"零序列号", testEntry([]byte{0x00}, testUTCTime), true},
+		{"最高位为1的正序列号", testEntry([]byte{0x00, 0x80}, testUTCTime), true},
+		{"长序列号", testEntry(bytes.Repeat([]byte{0x7f}, 20), testUTCTime), true},
+		{"负序列号", testEntry([]byte{0xff}, testUTCTime), false},
+		{"负序列号-256", testEntry([]byte{0xff, 0x00}, testUTCTime), false},
+		{"非最短INTEGER", testEntry([]byte{0x00, 0x01}, testUTCTime), false},
+		{"GeneralizedTime", testEntry([]byte{0x01}, generalizedTime), true},
+		{"带小数秒的GeneralizedTime", testEntry([]byte{0x01}, derTLV(asn1.TagGeneralizedTime, []byte("20250102030405.5Z"))), false},
+		{"1950年前的UTCTime", testEntry([]byte{0x01}, derTLV(asn1.TagUTCTime, []byte("990102030405Z"))), true},
+		{"非法日期", testEntry([]byte{0x01}, derTLV(asn1.TagUTCTime, []byte("250230030405Z"))), false},
+		{"原因码", testEntry([]byte{0x01}, testUTCTime, testExtension(testReasonOID, nil, reason(1))), true},
+		{"关键扩展TRUE", testEntry([]byte{0x01}, testUTCTime, testExtension(testReasonOID, []byte{0xff}, reason(6))), true},
+		{"显式FALSE", testEntry([]byte{0x01}, testUTCTime, testExtension(testReasonOID, []byte{0x00}, reason(6))), true},
+		{"BOOLEAN长度错误", testEntry([]byte{0x01}, testUTCTime, testExtension(testReasonOID, []byte{0xff, 0xff}, reason(6))), false},
+		{"失效日期与证书颁发者", testEntry([]byte{0x01}, testUTCTime,
+			testExtension(testInvalidityDateOID, nil, derTLV(asn1.TagGeneralizedTime, []byte("20240102030405Z"))),
+			testExtension(testCertIssuerOID, []byte{0xff}, dirName)), true},
+		{"未知扩展", testEntry([]byte{0x01}, testUTCTime, testExtension(testUnknownOID, nil, []byte{0x05, 0x00})), false},
+		{"条目后多余数据", append(testEntry([]byte{0x01}, testUTCTime), 0x00), false},
+		{"非最短长度", append([]byte{0x30, 0x81, 0x12}, testEntry([]byte{0x01}, testUTCTime)[2:]...), false},
+		{"长度超出输入", append([]byte{0x30, 0x7f}, testEntry([]byte{0x01}, testUTCTime)[2:]...), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fast, ok := decodeRevokedEntryFast(test.der)
+			if ok != test.fast {
+				t.Fatalf("快速路径处理结果为%v，期望%v", ok, test.fast)
+			}
+			var revoked pkix.RevokedCertificate
+			slowErr := unmarshalExact(test.der, &revoked)
+			if !ok {
+				return
+			}
+			if slowErr != nil {
+				t.Fatalf("快速路径接受了encoding/asn1拒绝的条目: %v", slowErr)
+			}
+			if want := serialHex(revoked.SerialNumber); fast.serialNumber != want {
+				t.Errorf("序列号为%q，期望%q", fast.serialNumber, want)
+			}
+			if !fast.revocationTime.Equal(revoked.RevocationTime) {
+				t.Errorf("吊销时间为%v，期望%v", fast.revocationTime, revoked.RevocationTime)
+			}
+			want := revoked.Extensions
+			if len(want) == 0 {
+				want = nil
+			}
+			if !reflect.DeepEqual(fast.extensions, want) {
+				t.Errorf("扩展为%+v，期望%+v", fast.extensions, want)
+			}
+		})
+	}
+}
+
+func TestReadCRLMatchesStandardLibrary(t *testing.T) {
+	entries := [][]byte{
+		testEntry([]byte{0x00}, testUTCTime),
+		testEntry([]byte{0xff}, testUTCTime, testExtension(testReasonOID, nil, derTLV(asn1.TagEnum, []byte{1}))),
+		testEntry([]byte{0x00, 0x80}, derTLV(asn1.TagGeneralizedTime, []byte("20510102030405Z"))),
+		testEntry([]byte{0x01}, testUTCTime, testExtension(testReasonOID, []byte{0xff}, derTLV(asn1.TagEnum, []byte{0}))),
+		testEntry([]byte{0x02}, testUTCTime, testExtension(testReasonOID, []byte{0x00}, derTLV(asn1.TagEnum, []byte{8}))),
+		testEntry([]byte{0x04}, testUTCTime, testExtension(testUnknownOID, nil, []byte{0x05, 0x00})),
+	}
+	der := testCRL(entries...)
+	want, err := stdx509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("标准库解析失败: %v", err)
+	}
+	readers := map[string]func() io.Reader{
+		"已知长度": func() io.Reader { return bytes.NewReader(der) },
+		"未知长度": func() io.Reader { return opaqueReader{bytes.NewReader(der)} },
+		"PEM":  func() io.Reader { return bytes.NewReader(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})) },
+		"BOM及说明文字的PEM": func() io.Reader {
+			return strings.NewReader("\ufeff\n  test CRL\n" + string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})))
+		},
+	}
+	for name, reader := range readers {
+		t.Run(name, func(t *testing.T) {
+			crlInfo, err := ReadCRL(reader(), nil, nil)
+			if err != nil {
+				t.Fatalf("ReadCRL失败: %v", err)
+			}
+			if len(crlInfo.RevokedCerts) != len(want.RevokedCertificateEntries) {
+				t.Fatalf("条目数为%d，期望%d", len(crlInfo.RevokedCerts), len(want.RevokedCertificateEntries))
+			}
+			for i, entry := range want.RevokedCertificateEntries {
+				got := crlInfo.RevokedCerts[i]
+				if wantSerial := serialHex(entry.SerialNumber); got.SerialNumber != wantSerial {
+					t.Errorf("第%d个条目序列号为%q，期望%q", i, got.SerialNumber, wantSerial)
+				}
+				if !got.RevocationTime.Equal(entry.RevocationTime) {
+					t.Errorf("第%d个条目吊销时间为%v，期望%v", i, got.RevocationTime, entry.RevocationTime)
+				}
+				wantReason := CRLReasonOmitted
+				for _, ext := range entry.Extensions {
+					if ext.Id.Equal(oidExtensionReasonCode) {
+						wantReason = entry.ReasonCode
+					}
+				}
+				if got.ReasonCode != wantReason {
+					t.Errorf("第%d个条目原因码为%d，期望%d", i, got.ReasonCode, wantReason)
+				}
+				if entry.SerialNumber.Sign() >= 0 {
+					if found, err := crlInfo.Lookup(entry.SerialNumber.Text(16)); err != nil || found == nil {
+						t.Errorf("按序列号%X未找到第%d个条目: %v", entry.SerialNumber, i, err)
+					}
+				}
+			}
+			if found, _ := crlInfo.Lookup("-1"); found == nil || found.SerialNumber != "-01" {
+				t.Errorf("负序列号-1查询结果为%+v", found)
+			}
+		})
+	}
+}
+
+func TestReadCRLRejectsMalformedLengths(t *testing.T) {
+	der := testCRL(testEntry([]byte{0x01}, testUTCTime), testEntry([]byte{0x02}, testUTCTime))
+	entry := testEntry([]byte{0x01}, testUTCTime)
+	// 条目声明的长度超出吊销列表SEQUENCE，但仍在输入范围内
+	overlong := append(testDERHeader(0x30, len(entry)-2+3), entry[2:]...)
+	tbs := derTLV(0x30,
+		derTLV(asn1.TagInteger, []byte{1}),
+		derTLV(0x30, []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x04, 0x03, 0x02}),
+		testIssuerName, testUTCTime,
+		derTLV(0x30, overlong),
+		derTLV(0xa0, derTLV(0x30)),
+	)
+	entryBeyondParent := derTLV(0x30, tbs, derTLV(0x30, []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x04, 0x03, 0x02}), derTLV(asn1.TagBitString, []byte{0}))
+
+	tests := []struct {
+		name string
+		der  []byte
+		// stdRejects 标准库同样拒绝该输入，标准库忽略CRL之后的多余数据
+		stdRejects bool
+	}{
+		{"截断于开头", der[:1], true},
+		{"截断于头部", der[:3], true},
+		{"截断于中间", der[:len(der)/2], true},
+		{"缺少最后一字节", der[:len(der)-1], true},
+		{"外层长度超出输入", append(testDERHeader(0x30, len(der)+100), der[2:]...), true},
+		{"超长长度字段", append([]byte{0x30, 0x88, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, der[2:]...), true},
+		{"外层长度非最短编码", append([]byte{0x30, 0x82, 0x00, byte(len(der) - 2)}, der[2:]...), true},
+		{"条目超出父元素", entryBeyondParent, true},
+		{"末尾多余数据", append(append([]byte{}, der...), 0x00), false},
+	}
+	for _, test := range tests {
+		data := test.der
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := stdx509.ParseRevocationList(data); test.stdRejects && err == nil {
+				t.Fatalf("标准库接受了畸形CRL，用例无效")
+			}
+			if _, err := ReadCRL(bytes.NewReader(data), nil, nil); err == nil {
+				t.Errorf("已知长度时接受了畸形CRL")
+			}
+			if _, err := ReadCRL(opaqueReader{bytes.NewReader(data)}, nil, nil); err == nil {
+				t.Errorf("未知长度时接受了畸形CRL")
+			}
+		})
+	}
+}
+
+// newTestSM2CRLIssuer 生成可签发CRL的SM2自签名CA
+func newTestSM2CRLIssuer(t *testing.T) (*x509.Certificate, *sm2.PrivateKey) {
+	t.Helper()
+	key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test SM2 CRL CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SignatureAlgorithm:    x509.SM2WithSM3,
+	}
+	der, err := x509.CreateCertificate(template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issuer, key
+}
+
+func TestReadLargeSM2CRL(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过大型CRL测试")
+	}
+	issuer, key := newTestSM2CRLIssuer(t)
+	template := &CRLTemplate{NextUpdate: time.Now().Add(time.Hour), CRLNumber: big.NewInt(1)}
+	for i := 0; i < 40000; i++ {
+		template.Entries = append(template.Entries, CRLEntry{
+			SerialNumber: new(big.Int).Lsh(big.NewInt(int64(i+1)), 64),
+			ReasonCode:   RevocationReasonCodes[i%len(RevocationReasonCodes)],
+		})
+	}
+	der, err := CreateCRL(template, issuer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(der) <= maxRawTBSSize {
+		t.Fatalf("CRL仅%d字节，未超过%d字节", len(der), maxRawTBSSize)
+	}
+	issuers := []*x509.Certificate{issuer}
+
+	crlInfo, err := ReadCRL(opaqueReader{bytes.NewReader(der)}, issuers, nil)
+	if err != nil {
+		t.Fatalf("ReadCRL失败: %v", err)
+	}
+	if crlInfo.CertificateList.TBSCertList.Raw != nil {
+		t.Errorf("超过%d字节的TBSCertList不应保留原始编码", maxRawTBSSize)
+	}
+	if err := VerifyCRL(crlInfo, issuer); err != nil {
+		t.Errorf("签名验证失败: %v", err)
+	}
+	want, err := stdx509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("标准库解析失败: %v", err)
+	}
+	if crlInfo.TotalRevoked != len(want.RevokedCertificateEntries) {
+		t.Errorf("条目数为%d，期望%d", crlInfo.TotalRevoked, len(want.RevokedCertificateEntries))
+	}
+	for _, i := range []int{0, 1, 12345, len(want.RevokedCertificateEntries) - 1} {
+		entry := want.RevokedCertificateEntries[i]
+		got := crlInfo.RevokedCerts[i]
+		if got.SerialNumber != serialHex(entry.SerialNumber) || got.ReasonCode != entry.ReasonCode {
+			t.Errorf("第%d个条目为%s/%d，期望%X/%d", i, got.SerialNumber, got.ReasonCode, entry.SerialNumber, entry.ReasonCode)
+		}
+	}
+
+	unverifiable, err := ReadCRL(bytes.NewReader(der), nil, nil)
+	if err != nil {
+		t.Fatalf("ReadCRL失败: %v", err)
+	}
+	if err := VerifyCRL(unverifiable, issuer); err == nil {
+		t.Errorf("读取时未提供颁发者，SM2签名不应验证通过")
+	}
+
+	// 修改最后一个条目的序列号，签名应验证失败
+	tampered := append([]byte{}, der...)
+	serial := want.RevokedCertificateEntries[len(want.RevokedCertificateEntries)-1].SerialNumber.Bytes()
+	index := bytes.LastIndex(tampered, serial)
+	if index < 0 {
+		t.Fatal("未找到最后一个条目的序列号")
+	}
+	tampered[index] ^= 0x01
+	tamperedInfo, err := ReadCRL(bytes.NewReader(tampered), issuers, nil)
+	if err != nil {
+		t.Fatalf("ReadCRL失败: %v", err)
+	}
+	if err := VerifyCRL(tamperedInfo, issuer); err == nil {
+		t.Errorf("篡改后的CRL不应验证通过")
+	}
+}
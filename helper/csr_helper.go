@@ -0,0 +1,594 @@
+package helper
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zaneway/cain-go/sm2"
+	"github.com/zaneway/cain-go/x509"
+)
+
+// SM2DefaultUserID GM/T 0009 规定的SM2默认用户ID "1234567812345678"
+var SM2DefaultUserID = []byte("1234567812345678")
+
+var (
+	oidSignatureSM2WithSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+	oidPublicKeySM2        = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+)
+
+// CSRAttributeNames PKCS#9/PKCS#10 常见属性OID与名称的映射
+var CSRAttributeNames = map[string]string{
+	"1.2.840.113549.1.9.7":      "challengePassword",
+	"1.2.840.113549.1.9.2":      "unstructuredName",
+	"1.2.840.113549.1.9.14":     "extensionRequest",
+	"1.3.6.1.4.1.311.2.1.14":    "msExtensionRequest",
+	"1.3.6.1.4.1.311.13.2.3":    "msOSVersion",
+	"1.3.6.1.4.1.311.21.20":     "msClientInfo",
+	"1.3.6.1.4.1.311.13.2.2":    "msEnrollmentCSP",
+	"1.2.840.113549.1.9.15":     "smimeCapabilities",
+	"1.2.840.113549.1.9.16.2.1": "receiptRequest",
+}
+
+// CSRAttribute PKCS#10 属性
+type CSRAttribute struct {
+	OID    string   `json:"oid"`
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// CSRInfo PKCS#10 证书请求解析结果
+type CSRInfo struct {
+	Version            int                      `json:"version"`
+	Subject            string                   `json:"subject"`
+	PublicKeyAlgorithm string                   `json:"publicKeyAlgorithm"`
+	PublicKeySize      int                      `json:"publicKeySize"`
+	PublicKey          string                   `json:"publicKey"`
+	SignatureAlgorithm string                   `json:"signatureAlgorithm"`
+	Signature          string                   `json:"signature"`
+	ChallengePassword  string                   `json:"challengePassword,omitempty"`
+	Attributes         []CSRAttribute           `json:"attributes,omitempty"`
+	Extensions         []pkix.Extension         `json:"-"`
+	SubjectAltNames    []GeneralName            `json:"subjectAltNames,omitempty"`
+	KeyUsage           []string                 `json:"keyUsage,omitempty"`
+	ExtKeyUsage        []string                 `json:"extKeyUsage,omitempty"`
+	SignatureValid     bool                     `json:"signatureValid"`
+	SignatureError     string                   `json:"signatureError,omitempty"`
+	Request            *x509.CertificateRequest `json:"-"`
+}
+
+type csrAttributeASN1 struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type tbsCSRAttributesASN1 struct {
+	Version       int
+	Subject       asn1.RawValue
+	PublicKey     asn1.RawValue
+	RawAttributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+type certificateRequestASN1 struct {
+	TBSCSR             asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type subjectPublicKeyInfoASN1 struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type sm2SignatureASN1 struct {
+	R, S *big.Int
+}
+
+// ParseCSR 解析PKCS#10证书请求，并校验请求自签名
+func ParseCSR(der []byte) (*CSRInfo, error) {
+	if len(der) == 0 {
+		return nil, fmt.Errorf("证书请求数据为空")
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书请求失败: %v", err)
+	}
+	// 部分SM2请求在SPKI中直接使用SM2算法OID，底层库无法识别，这里补充解析
+	if csr.PublicKey == nil {
+		if csr.PublicKey, err = parseSM2SubjectPublicKeyInfo(csr.RawSubjectPublicKeyInfo); err != nil {
+			return nil, fmt.Errorf("解析证书请求公钥失败: %v", err)
+		}
+	}
+
+	info := &CSRInfo{
+		Version:    csr.Version,
+		Subject:    csr.Subject.String(),
+		PublicKey:  base64.StdEncoding.EncodeToString(csr.RawSubjectPublicKeyInfo),
+		Signature:  hex.EncodeToString(csr.Signature),
+		Extensions: csr.Extensions,
+		Request:    csr,
+	}
+	info.PublicKeyAlgorithm, info.PublicKeySize = describePublicKey(csr.PublicKey)
+
+	var outer certificateRequestASN1
+	if _, err := asn1.Unmarshal(der, &outer); err != nil {
+		return nil, fmt.Errorf("解析证书请求结构失败: %v", err)
+	}
+	info.SignatureAlgorithm = formatSignatureAlgorithm(outer.SignatureAlgorithm)
+
+	if info.Attributes, err = parseCSRAttributes(csr.RawTBSCertificateRequest); err != nil {
+		return nil, err
+	}
+	for _, attr := range info.Attributes {
+		if attr.Name == "challengePassword" && len(attr.Values) > 0 {
+			info.ChallengePassword = attr.Values[0]
+		}
+	}
+
+	for _, ext := range csr.Extensions {
+		switch ext.Id.String() {
+		case "2.5.29.17":
+			if info.SubjectAltNames, err = ParseGeneralNames(ext.Value); err != nil {
+				return nil, err
+			}
+		case "2.5.29.15":
+			if info.KeyUsage, err = ParseKeyUsageExtension(ext.Value); err != nil {
+				return nil, err
+			}
+		case "2.5.29.37":
+			if info.ExtKeyUsage, err = ParseExtendedKeyUsage(ext.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := VerifyCSRSignature(csr, outer.SignatureAlgorithm.Algorithm); err != nil {
+		info.SignatureError = err.Error()
+	} else {
+		info.SignatureValid = true
+	}
+	return info, nil
+}
+
+// VerifyCSRSignature 校验证书请求自签名，SM2-SM3签名使用默认用户ID计算Z值
+func VerifyCSRSignature(csr *x509.CertificateRequest, sigAlg asn1.ObjectIdentifier) error {
+	if pub, ok := csr.PublicKey.(*ecdsa.PublicKey); ok && sigAlg.Equal(oidSignatureSM2WithSM3) {
+		if pub.Curve != sm2.P256Sm2() {
+			return fmt.Errorf("签名算法为SM2-SM3，但公钥不是SM2曲线")
+		}
+		var sig sm2SignatureASN1
+		if rest, err := asn1.Unmarshal(csr.Signature, &sig); err != nil || len(rest) > 0 {
+			return fmt.Errorf("SM2签名值格式错误")
+		}
+		sm2Pub := &sm2.PublicKey{Curve: pub.Curve, X: pub.X, Y: pub.Y}
+		if !sm2.Sm2Verify(sm2Pub, csr.RawTBSCertificateRequest, SM2DefaultUserID, sig.R, sig.S) {
+			return fmt.Errorf("SM2签名验证失败(用户ID: %s)", SM2DefaultUserID)
+		}
+		return nil
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return fmt.Errorf("签名验证失败: %v", err)
+	}
+	return nil
+}
+
+// parseCSRAttributes 解析CertificationRequestInfo中的attributes [0]
+func parseCSRAttributes(tbs []byte) ([]CSRAttribute, error) {
+	var info tbsCSRAttributesASN1
+	if _, err := asn1.Unmarshal(tbs, &info); err != nil {
+		return nil, fmt.Errorf("解析证书请求属性失败: %v", err)
+	}
+
+	var attributes []CSRAttribute
+	for _, raw := range info.RawAttributes {
+		var attr csrAttributeASN1
+		if _, err := asn1.Unmarshal(raw.FullBytes, &attr); err != nil {
+			return nil, fmt.Errorf("解析证书请求属性失败: %v", err)
+		}
+		item := CSRAttribute{OID: attr.Type.String(), Name: oidName(CSRAttributeNames, attr.Type)}
+		for _, value := range attr.Values {
+			switch item.Name {
+			case "extensionRequest", "msExtensionRequest":
+				var exts []pkix.Extension
+				if _, err := asn1.Unmarshal(value.FullBytes, &exts); err != nil {
+					return nil, fmt.Errorf("解析extensionRequest失败: %v", err)
+				}
+				for _, ext := range exts {
+					name := ext.Id.String()
+					if n, ok := ExtensionNames[name]; ok {
+						name = fmt.Sprintf("%s (%s)", n, name)
+					}
+					item.Values = append(item.Values, name)
+				}
+			default:
+				if text, err := parseDisplayText(value); err == nil {
+					item.Values = append(item.Values, text)
+				} else {
+					item.Values = append(item.Values, hex.EncodeToString(value.FullBytes))
+				}
+			}
+		}
+		attributes = append(attributes, item)
+	}
+	return attributes, nil
+}
+
+// parseSM2SubjectPublicKeyInfo 解析算法OID为1.2.156.10197.1.301或id-ecPublicKey+SM2曲线的SM2公钥
+func parseSM2SubjectPublicKeyInfo(spki []byte) (*ecdsa.PublicKey, error) {
+	var info subjectPublicKeyInfoASN1
+	if _, err := asn1.Unmarshal(spki, &info); err != nil {
+		return nil, err
+	}
+	if !isSM2Curve(info.Algorithm) {
+		return nil, fmt.Errorf("不支持的公钥算法: %s", info.Algorithm.Algorithm)
+	}
+	curve := sm2.P256Sm2()
+	x, y := elliptic.Unmarshal(curve, info.PublicKey.RightAlign())
+	if x == nil {
+		return nil, fmt.Errorf("SM2公钥点格式错误")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// describePublicKey 返回公钥算法名称与位数
+func describePublicKey(pub interface{}) (string, int) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		if key.Curve == sm2.P256Sm2() {
+			return "SM2", key.Curve.Params().BitSize
+		}
+		return "ECDSA " + key.Curve.Params().Name, key.Curve.Params().BitSize
+	case *sm2.PublicKey:
+		return "SM2", key.Curve.Params().BitSize
+	}
+	return "Unknown", 0
+}
+
+var (
+	oidSignatureSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidChallengePassword      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 7}
+	oidExtensionRequest       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+	oidExtensionSubjectAlt    = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidExtensionKeyUsage      = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionExtKeyUsage   = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidEmailAddress           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+)
+
+// CSRTemplate 生成证书请求所需的主题与扩展信息
+type CSRTemplate struct {
+	Subject pkix.Name
+	// SubjectAltNames 每项形如 "DNS:a.com"、"IP:1.2.3.4"、"email:a@b.c"、"URI:http://x"，未带前缀时自动识别
+	SubjectAltNames []string
+	// KeyUsage 置位的KeyUsage位序号，见KeyUsageNames
+	KeyUsage []int
+	// ExtKeyUsage 扩展密钥用法OID
+	ExtKeyUsage       []string
+	ChallengePassword string
+}
+
+type tbsCSRASN1 struct {
+	Version    int
+	Subject    asn1.RawValue
+	PublicKey  asn1.RawValue
+	Attributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+type csrASN1 struct {
+	TBSCSR             asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type csrAttributeOut struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// CreateCSR 使用私钥生成DER编码的PKCS#10证书请求，SM2私钥使用SM2-SM3(默认用户ID)签名，RSA私钥使用SHA256-RSA签名
+func CreateCSR(template *CSRTemplate, key crypto.Signer) ([]byte, error) {
+	spki, err := MarshalPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	subject, err := asn1.Marshal(template.Subject.ToRDNSequence())
+	if err != nil {
+		return nil, fmt.Errorf("编码主题失败: %v", err)
+	}
+
+	attributes, err := buildCSRAttributes(template)
+	if err != nil {
+		return nil, err
+	}
+
+	tbs, err := asn1.Marshal(tbsCSRASN1{
+		Version:    0,
+		Subject:    asn1.RawValue{FullBytes: subject},
+		PublicKey:  asn1.RawValue{FullBytes: spki},
+		Attributes: attributes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("编码证书请求信息失败: %v", err)
+	}
+
+	sigAlg, signature, err := signTBS(key, tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(csrASN1{
+		TBSCSR:             asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: sigAlg,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// buildCSRAttributes 构造challengePassword与extensionRequest属性
+func buildCSRAttributes(template *CSRTemplate) ([]asn1.RawValue, error) {
+	var extensions []pkix.Extension
+	if len(template.SubjectAltNames) > 0 {
+		value, err := MarshalGeneralNames(template.SubjectAltNames)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionSubjectAlt, Value: value})
+	}
+	if len(template.KeyUsage) > 0 {
+		value, err := marshalKeyUsage(template.KeyUsage)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionKeyUsage, Critical: true, Value: value})
+	}
+	if len(template.ExtKeyUsage) > 0 {
+		var oids []asn1.ObjectIdentifier
+		for _, s := range template.ExtKeyUsage {
+			oid, err := ParseOID(s)
+			if err != nil {
+				return nil, err
+			}
+			oids = append(oids, oid)
+		}
+		value, err := asn1.Marshal(oids)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionExtKeyUsage, Value: value})
+	}
+
+	var attributes []asn1.RawValue
+	if template.ChallengePassword != "" {
+		password, err := asn1.MarshalWithParams(template.ChallengePassword, "utf8")
+		if err != nil {
+			return nil, err
+		}
+		attr, err := asn1.Marshal(csrAttributeOut{Type: oidChallengePassword, Values: []asn1.RawValue{{FullBytes: password}}})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, asn1.RawValue{FullBytes: attr})
+	}
+	if len(extensions) > 0 {
+		exts, err := asn1.Marshal(extensions)
+		if err != nil {
+			return nil, fmt.Errorf("编码扩展项失败: %v", err)
+		}
+		attr, err := asn1.Marshal(csrAttributeOut{Type: oidExtensionRequest, Values: []asn1.RawValue{{FullBytes: exts}}})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, asn1.RawValue{FullBytes: attr})
+	}
+	return attributes, nil
+}
+
+// signatureAlgorithm 返回私钥对应的签名算法标识：SM2为SM2-SM3，RSA为SHA256-RSA
+func signatureAlgorithm(key crypto.Signer) (pkix.AlgorithmIdentifier, error) {
+	switch key.(type) {
+	case *sm2.PrivateKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM2WithSM3}, nil
+	case *rsa.PrivateKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSHA256WithRSA, Parameters: asn1.NullRawValue}, nil
+	}
+	return pkix.AlgorithmIdentifier{}, fmt.Errorf("不支持的签名私钥类型: %T，仅支持SM2与RSA", key)
+}
+
+// signTBS 对待签名数据签名，返回签名算法标识与签名值
+func signTBS(key crypto.Signer, tbs []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	switch priv := key.(type) {
+	case *sm2.PrivateKey:
+		r, s, err := sm2.Sm2Sign(priv, tbs, SM2DefaultUserID, rand.Reader)
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("SM2签名失败: %v", err)
+		}
+		signature, err := asn1.Marshal(sm2SignatureASN1{R: r, S: s})
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM2WithSM3}, signature, err
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(tbs)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("RSA签名失败: %v", err)
+		}
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSHA256WithRSA, Parameters: asn1.NullRawValue}, signature, nil
+	}
+	return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("不支持的签名私钥类型: %T，仅支持SM2与RSA", key)
+}
+
+// MarshalGeneralNames 将 "类型:值" 形式的名称编码为GeneralNames
+func MarshalGeneralNames(names []string) ([]byte, error) {
+	var raws []asn1.RawValue
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		raw, err := marshalGeneralName(name)
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	}
+	if len(raws) == 0 {
+		return nil, fmt.Errorf("主题备用名称为空")
+	}
+	return asn1.Marshal(raws)
+}
+
+// marshalGeneralName 编码单个GeneralName，支持DNS/IP/email/URI/RID前缀
+func marshalGeneralName(name string) (asn1.RawValue, error) {
+	kind, value := "", name
+	if i := strings.Index(name, ":"); i > 0 {
+		switch prefix := strings.ToUpper(name[:i]); prefix {
+		case "DNS", "IP", "EMAIL", "URI", "RID":
+			kind, value = prefix, strings.TrimSpace(name[i+1:])
+		}
+	}
+	if kind == "" {
+		switch {
+		case net.ParseIP(value) != nil:
+			kind = "IP"
+		case strings.Contains(value, "://"):
+			kind = "URI"
+		case strings.Contains(value, "@"):
+			kind = "EMAIL"
+		default:
+			kind = "DNS"
+		}
+	}
+
+	switch kind {
+	case "DNS":
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte(value)}, nil
+	case "EMAIL":
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, Bytes: []byte(value)}, nil
+	case "URI":
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 6, Bytes: []byte(value)}, nil
+	case "IP":
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return asn1.RawValue{}, fmt.Errorf("无效的IP地址: %s", value)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 7, Bytes: ip}, nil
+	case "RID":
+		oid, err := ParseOID(value)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		der, err := asn1.MarshalWithParams(oid, "tag:8")
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		return asn1.RawValue{FullBytes: der}, nil
+	}
+	return asn1.RawValue{}, fmt.Errorf("不支持的名称类型: %s", name)
+}
+
+// marshalKeyUsage 按位序号编码KeyUsage位串，去除尾部未使用的位
+func marshalKeyUsage(bitsSet []int) ([]byte, error) {
+	var bytes [2]byte
+	maxBit := -1
+	for _, bit := range bitsSet {
+		if bit < 0 || bit >= len(KeyUsageNames) {
+			return nil, fmt.Errorf("无效的KeyUsage位: %d", bit)
+		}
+		bytes[bit/8] |= 0x80 >> uint(bit%8)
+		if bit > maxBit {
+			maxBit = bit
+		}
+	}
+	bitLength := maxBit + 1
+	return asn1.Marshal(asn1.BitString{Bytes: bytes[:(bitLength+7)/8], BitLength: bitLength})
+}
+
+// ParseOID 解析点分形式的OID
+func ParseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("无效的OID: %s", s)
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("无效的OID: %s", s)
+		}
+		oid[i] = n
+	}
+	return oid, nil
+}
+
+// ParseDistinguishedName 解析 "CN=张三,O=机构,C=CN" 形式的名称，支持CN/O/OU/L/ST/C/E/SERIALNUMBER，值中的逗号可用反斜杠转义
+func ParseDistinguishedName(dn string) (pkix.Name, error) {
+	var name pkix.Name
+	for _, part := range splitDN(dn) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		i := strings.Index(part, "=")
+		if i <= 0 {
+			return name, fmt.Errorf("无效的名称项: %s", part)
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(part[:i])), strings.TrimSpace(part[i+1:])
+		switch key {
+		case "CN":
+			name.CommonName = value
+		case "O":
+			name.Organization = append(name.Organization, value)
+		case "OU":
+			name.OrganizationalUnit = append(name.OrganizationalUnit, value)
+		case "L":
+			name.Locality = append(name.Locality, value)
+		case "ST", "S":
+			name.Province = append(name.Province, value)
+		case "C":
+			name.Country = append(name.Country, value)
+		case "SERIALNUMBER":
+			name.SerialNumber = value
+		case "E", "EMAIL", "EMAILADDRESS":
+			name.ExtraNames = append(name.ExtraNames, pkix.AttributeTypeAndValue{
+				Type:  oidEmailAddress,
+				Value: asn1.RawValue{Tag: asn1.TagIA5String, Bytes: []byte(value)},
+			})
+		default:
+			return name, fmt.Errorf("不支持的名称属性: %s", key)
+		}
+	}
+	return name, nil
+}
+
+// splitDN 按未转义的逗号拆分名称
+func splitDN(dn string) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(dn); i++ {
+		switch {
+		case dn[i] == '\\' && i+1 < len(dn):
+			i++
+			current.WriteByte(dn[i])
+		case dn[i] == ',':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(dn[i])
+		}
+	}
+	return append(parts, current.String())
+}
@@ -0,0 +1,949 @@
+package helper
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ExtensionNames 常见证书/CRL扩展项OID与名称的映射
+var ExtensionNames = map[string]string{
+	"2.5.29.14":            "Subject Key Identifier",
+	"2.5.29.15":            "Key Usage",
+	"2.5.29.17":            "Subject Alternative Name",
+	"2.5.29.18":            "Issuer Alternative Name",
+	"2.5.29.19":            "Basic Constraints",
+	"2.5.29.20":            "CRL Number",
+	"2.5.29.21":            "Reason Code",
+	"2.5.29.24":            "Invalidity Date",
+	"2.5.29.27":            "Delta CRL Indicator",
+	"2.5.29.28":            "Issuing Distribution Point",
+	"2.5.29.29":            "Certificate Issuer",
+	"2.5.29.30":            "Name Constraints",
+	"2.5.29.31":            "CRL Distribution Points",
+	"2.5.29.32":            "Certificate Policies",
+	"2.5.29.33":            "Policy Mappings",
+	"2.5.29.35":            "Authority Key Identifier",
+	"2.5.29.36":            "Policy Constraints",
+	"2.5.29.37":            "Extended Key Usage",
+	"2.5.29.46":            "Freshest CRL",
+	"2.5.29.54":            "Inhibit Any Policy",
+	"1.3.6.1.5.5.7.1.1":    "Authority Information Access",
+	"1.3.6.1.5.5.7.1.11":   "Subject Information Access",
+	"1.3.6.1.5.5.7.48.1.2": "OCSP Nonce",
+	"1.3.6.1.5.5.7.48.1.5": "OCSP No Check",
+}
+
+// AccessMethodNames AIA/SIA访问方法OID与名称的映射
+var AccessMethodNames = map[string]string{
+	"1.3.6.1.5.5.7.48.1": "OCSP",
+	"1.3.6.1.5.5.7.48.2": "CA Issuers",
+	"1.3.6.1.5.5.7.48.3": "Time Stamping",
+	"1.3.6.1.5.5.7.48.5": "CA Repository",
+}
+
+// ExtKeyUsageNames 扩展密钥用法OID与名称的映射
+var ExtKeyUsageNames = map[string]string{
+	"2.5.29.37.0":             "Any Extended Key Usage",
+	"1.3.6.1.5.5.7.3.1":       "Server Authentication (服务器认证)",
+	"1.3.6.1.5.5.7.3.2":       "Client Authentication (客户端认证)",
+	"1.3.6.1.5.5.7.3.3":       "Code Signing (代码签名)",
+	"1.3.6.1.5.5.7.3.4":       "Email Protection (邮件保护)",
+	"1.3.6.1.5.5.7.3.5":       "IPSec End System",
+	"1.3.6.1.5.5.7.3.6":       "IPSec Tunnel",
+	"1.3.6.1.5.5.7.3.7":       "IPSec User",
+	"1.3.6.1.5.5.7.3.8":       "Time Stamping (时间戳)",
+	"1.3.6.1.5.5.7.3.9":       "OCSP Signing (OCSP签名)",
+	"1.3.6.1.4.1.311.10.3.12": "Document Signing (文档签名)",
+}
+
+// PolicyQualifierNames 策略限定符OID与名称的映射
+var PolicyQualifierNames = map[string]string{
+	"1.3.6.1.5.5.7.2.1": "CPS",
+	"1.3.6.1.5.5.7.2.2": "User Notice",
+}
+
+// KeyUsageNames KeyUsage位定义，下标即位序号
+var KeyUsageNames = []string{
+	"Digital Signature (数字签名)",
+	"Non Repudiation (不可否认)",
+	"Key Encipherment (密钥加密)",
+	"Data Encipherment (数据加密)",
+	"Key Agreement (密钥协商)",
+	"Cert Sign (证书签发)",
+	"CRL Sign (CRL签发)",
+	"Encipher Only (仅加密)",
+	"Decipher Only (仅解密)",
+}
+
+// reasonFlagNames ReasonFlags位定义，下标即位序号
+var reasonFlagNames = []string{
+	"unused",
+	"keyCompromise",
+	"cACompromise",
+	"affiliationChanged",
+	"superseded",
+	"cessationOfOperation",
+	"certificateHold",
+	"privilegeWithdrawn",
+	"aACompromise",
+}
+
+// GeneralName RFC 5280 GeneralName
+type GeneralName struct {
+	Tag   int    `json:"tag"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	// raw 标签内的原始内容，名称约束匹配DirName/IP时使用
+	raw []byte
+}
+
+func (n GeneralName) String() string {
+	return n.Type + ": " + n.Value
+}
+
+// AccessDescription AIA/SIA中的访问描述
+type AccessDescription struct {
+	Method     string      `json:"method"`
+	MethodName string      `json:"methodName"`
+	Location   GeneralName `json:"location"`
+}
+
+// DistributionPoint CRL分发点
+type DistributionPoint struct {
+	FullName     []GeneralName `json:"fullName,omitempty"`
+	RelativeName string        `json:"relativeName,omitempty"`
+	Reasons      []string      `json:"reasons,omitempty"`
+	CRLIssuer    []GeneralName `json:"crlIssuer,omitempty"`
+}
+
+// UserNotice 用户声明限定符
+type UserNotice struct {
+	Organization  string `json:"organization,omitempty"`
+	NoticeNumbers []int  `json:"noticeNumbers,omitempty"`
+	ExplicitText  string `json:"explicitText,omitempty"`
+}
+
+// PolicyQualifier 策略限定符
+type PolicyQualifier struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	CPSURI     string      `json:"cpsUri,omitempty"`
+	UserNotice *UserNotice `json:"userNotice,omitempty"`
+	// Raw 未知限定符的原始DER(Hex)
+	Raw string `json:"raw,omitempty"`
+}
+
+// PolicyInformation 证书策略
+type PolicyInformation struct {
+	Policy     string            `json:"policy"`
+	Qualifiers []PolicyQualifier `json:"qualifiers,omitempty"`
+}
+
+// AuthorityKeyIdentifier 颁发机构密钥标识符
+type AuthorityKeyIdentifier struct {
+	KeyID        []byte        `json:"keyId,omitempty"`
+	Issuer       []GeneralName `json:"issuer,omitempty"`
+	SerialNumber *big.Int      `json:"serialNumber,omitempty"`
+}
+
+// BasicConstraints 基本约束，MaxPathLen为-1表示未限制
+type BasicConstraints struct {
+	IsCA       bool `json:"isCA"`
+	MaxPathLen int  `json:"maxPathLen"`
+}
+
+// GeneralSubtree 名称约束中的子树，Maximum为-1表示未限制
+type GeneralSubtree struct {
+	Base    GeneralName `json:"base"`
+	Minimum int         `json:"minimum,omitempty"`
+	Maximum int         `json:"maximum"`
+}
+
+// NameConstraints 名称约束
+type NameConstraints struct {
+	Permitted []GeneralSubtree `json:"permitted,omitempty"`
+	Excluded  []GeneralSubtree `json:"excluded,omitempty"`
+}
+
+// PolicyConstraints 策略约束，字段为-1表示未出现
+type PolicyConstraints struct {
+	RequireExplicitPolicy int `json:"requireExplicitPolicy"`
+	InhibitPolicyMapping  int `json:"inhibitPolicyMapping"`
+}
+
+// PolicyMapping 策略映射
+type PolicyMapping struct {
+	IssuerDomainPolicy  string `json:"issuerDomainPolicy"`
+	SubjectDomainPolicy string `json:"subjectDomainPolicy"`
+}
+
+// IssuingDistributionPoint CRL颁发分发点
+type IssuingDistributionPoint struct {
+	FullName                   []GeneralName `json:"fullName,omitempty"`
+	RelativeName               string        `json:"relativeName,omitempty"`
+	OnlyContainsUserCerts      bool          `json:"onlyContainsUserCerts,omitempty"`
+	OnlyContainsCACerts        bool          `json:"onlyContainsCACerts,omitempty"`
+	OnlySomeReasons            []string      `json:"onlySomeReasons,omitempty"`
+	IndirectCRL                bool          `json:"indirectCRL,omitempty"`
+	OnlyContainsAttributeCerts bool          `json:"onlyContainsAttributeCerts,omitempty"`
+}
+
+type issuingDistributionPointASN1 struct {
+	DistributionPoint          asn1.RawValue  `asn1:"optional,tag:0"`
+	OnlyContainsUserCerts      bool           `asn1:"optional,tag:1"`
+	OnlyContainsCACerts        bool           `asn1:"optional,tag:2"`
+	OnlySomeReasons            asn1.BitString `asn1:"optional,tag:3"`
+	IndirectCRL                bool           `asn1:"optional,tag:4"`
+	OnlyContainsAttributeCerts bool           `asn1:"optional,tag:5"`
+}
+
+type generalSubtreeASN1 struct {
+	Base    asn1.RawValue
+	Minimum int `asn1:"optional,tag:0,default:0"`
+	Maximum int `asn1:"optional,tag:1,default:-1"`
+}
+
+type nameConstraintsASN1 struct {
+	Permitted []generalSubtreeASN1 `asn1:"optional,tag:0"`
+	Excluded  []generalSubtreeASN1 `asn1:"optional,tag:1"`
+}
+
+type policyConstraintsASN1 struct {
+	RequireExplicitPolicy int `asn1:"optional,tag:0,default:-1"`
+	InhibitPolicyMapping  int `asn1:"optional,tag:1,default:-1"`
+}
+
+type policyMappingASN1 struct {
+	IssuerDomainPolicy  asn1.ObjectIdentifier
+	SubjectDomainPolicy asn1.ObjectIdentifier
+}
+
+type distributionPointASN1 struct {
+	DistributionPoint asn1.RawValue  `asn1:"optional,tag:0"`
+	Reasons           asn1.BitString `asn1:"optional,tag:1"`
+	CRLIssuer         asn1.RawValue  `asn1:"optional,tag:2"`
+}
+
+type policyInformationASN1 struct {
+	Policy     asn1.ObjectIdentifier
+	Qualifiers []policyQualifierASN1 `asn1:"optional"`
+}
+
+type policyQualifierASN1 struct {
+	ID        asn1.ObjectIdentifier
+	Qualifier asn1.RawValue
+}
+
+type accessDescriptionASN1 struct {
+	Method   asn1.ObjectIdentifier
+	Location asn1.RawValue
+}
+
+type authorityKeyIdentifierASN1 struct {
+	KeyID        []byte        `asn1:"optional,tag:0"`
+	Issuer       asn1.RawValue `asn1:"optional,tag:1"`
+	SerialNumber *big.Int      `asn1:"optional,tag:2"`
+}
+
+type basicConstraintsASN1 struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+// ParseAuthorityInfoAccess 解析AIA/SIA扩展 (SEQUENCE OF AccessDescription)
+func ParseAuthorityInfoAccess(data []byte) ([]AccessDescription, error) {
+	var descriptions []accessDescriptionASN1
+	if err := unmarshalExact(data, &descriptions); err != nil {
+		return nil, fmt.Errorf("解析Authority Information Access失败: %v", err)
+	}
+
+	result := make([]AccessDescription, 0, len(descriptions))
+	for _, desc := range descriptions {
+		location, err := parseGeneralName(desc.Location)
+		if err != nil {
+			return nil, fmt.Errorf("解析访问位置失败: %v", err)
+		}
+		result = append(result, AccessDescription{
+			Method:     desc.Method.String(),
+			MethodName: oidName(AccessMethodNames, desc.Method),
+			Location:   location,
+		})
+	}
+	return result, nil
+}
+
+// ParseCRLDistributionPoints 解析CRL分发点扩展，也适用于Freshest CRL
+func ParseCRLDistributionPoints(data []byte) ([]DistributionPoint, error) {
+	var points []distributionPointASN1
+	if err := unmarshalExact(data, &points); err != nil {
+		return nil, fmt.Errorf("解析CRL Distribution Points失败: %v", err)
+	}
+
+	result := make([]DistributionPoint, 0, len(points))
+	for _, point := range points {
+		dp := DistributionPoint{Reasons: parseReasonFlags(point.Reasons)}
+
+		if len(point.DistributionPoint.FullBytes) > 0 {
+			fullName, relativeName, err := parseDistributionPointName(point.DistributionPoint.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			dp.FullName = fullName
+			dp.RelativeName = relativeName
+		}
+
+		if len(point.CRLIssuer.FullBytes) > 0 {
+			issuer, err := parseGeneralNameList(point.CRLIssuer.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("解析cRLIssuer失败: %v", err)
+			}
+			dp.CRLIssuer = issuer
+		}
+		result = append(result, dp)
+	}
+	return result, nil
+}
+
+// parseDistributionPointName 解析DistributionPointName CHOICE
+func parseDistributionPointName(der []byte) ([]GeneralName, string, error) {
+	var name asn1.RawValue
+	if err := unmarshalExact(der, &name); err != nil {
+		return nil, "", fmt.Errorf("解析DistributionPointName失败: %v", err)
+	}
+	switch name.Tag {
+	case 0:
+		fullName, err := parseGeneralNameList(name.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("解析fullName失败: %v", err)
+		}
+		return fullName, "", nil
+	case 1:
+		rdn, err := parseRelativeName(name.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("解析nameRelativeToCRLIssuer失败: %v", err)
+		}
+		return nil, rdn, nil
+	}
+	return nil, "", fmt.Errorf("未知的DistributionPointName标签: %d", name.Tag)
+}
+
+// ParseCertificatePolicies 解析证书策略扩展
+func ParseCertificatePolicies(data []byte) ([]PolicyInformation, error) {
+	var policies []policyInformationASN1
+	if err := unmarshalExact(data, &policies); err != nil {
+		return nil, fmt.Errorf("解析Certificate Policies失败: %v", err)
+	}
+
+	result := make([]PolicyInformation, 0, len(policies))
+	for _, policy := range policies {
+		info := PolicyInformation{Policy: policy.Policy.String()}
+		for _, q := range policy.Qualifiers {
+			qualifier := PolicyQualifier{ID: q.ID.String(), Name: oidName(PolicyQualifierNames, q.ID)}
+			switch q.ID.String() {
+			case "1.3.6.1.5.5.7.2.1":
+				text, err := parseDisplayText(q.Qualifier)
+				if err != nil {
+					return nil, fmt.Errorf("解析CPS URI失败: %v", err)
+				}
+				qualifier.CPSURI = text
+			case "1.3.6.1.5.5.7.2.2":
+				notice, err := parseUserNotice(q.Qualifier)
+				if err != nil {
+					return nil, fmt.Errorf("解析User Notice失败: %v", err)
+				}
+				qualifier.UserNotice = notice
+			default:
+				qualifier.Raw = hex.EncodeToString(q.Qualifier.FullBytes)
+			}
+			info.Qualifiers = append(info.Qualifiers, qualifier)
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// ParseAuthorityKeyIdentifier 解析颁发机构密钥标识符扩展
+func ParseAuthorityKeyIdentifier(data []byte) (*AuthorityKeyIdentifier, error) {
+	var aki authorityKeyIdentifierASN1
+	if err := unmarshalExact(data, &aki); err != nil {
+		return nil, fmt.Errorf("解析Authority Key Identifier失败: %v", err)
+	}
+	result := &AuthorityKeyIdentifier{KeyID: aki.KeyID, SerialNumber: aki.SerialNumber}
+	if len(aki.Issuer.FullBytes) > 0 {
+		issuer, err := parseGeneralNameList(aki.Issuer.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析authorityCertIssuer失败: %v", err)
+		}
+		result.Issuer = issuer
+	}
+	return result, nil
+}
+
+// ParseSubjectKeyIdentifier 解析主题密钥标识符扩展
+func ParseSubjectKeyIdentifier(data []byte) ([]byte, error) {
+	var keyID []byte
+	if err := unmarshalExact(data, &keyID); err != nil {
+		return nil, fmt.Errorf("解析Subject Key Identifier失败: %v", err)
+	}
+	return keyID, nil
+}
+
+// ParseBasicConstraints 解析基本约束扩展
+func ParseBasicConstraints(data []byte) (*BasicConstraints, error) {
+	var constraints basicConstraintsASN1
+	if err := unmarshalExact(data, &constraints); err != nil {
+		return nil, fmt.Errorf("解析Basic Constraints失败: %v", err)
+	}
+	return &BasicConstraints{IsCA: constraints.IsCA, MaxPathLen: constraints.MaxPathLen}, nil
+}
+
+// ParseKeyUsageExtension 解析密钥用法扩展，返回已设置位的名称
+func ParseKeyUsageExtension(data []byte) ([]string, error) {
+	var bits asn1.BitString
+	if err := unmarshalExact(data, &bits); err != nil {
+		return nil, fmt.Errorf("解析Key Usage失败: %v", err)
+	}
+	return bitStringNames(bits, KeyUsageNames), nil
+}
+
+// ParseExtendedKeyUsage 解析扩展密钥用法扩展，返回OID列表
+func ParseExtendedKeyUsage(data []byte) ([]string, error) {
+	var oids []asn1.ObjectIdentifier
+	if err := unmarshalExact(data, &oids); err != nil {
+		return nil, fmt.Errorf("解析Extended Key Usage失败: %v", err)
+	}
+	result := make([]string, 0, len(oids))
+	for _, oid := range oids {
+		result = append(result, oid.String())
+	}
+	return result, nil
+}
+
+// ParseGeneralNames 解析GeneralNames (SEQUENCE OF GeneralName)，用于SAN/IAN等扩展
+func ParseGeneralNames(data []byte) ([]GeneralName, error) {
+	var seq asn1.RawValue
+	if err := unmarshalExact(data, &seq); err != nil {
+		return nil, fmt.Errorf("解析GeneralNames失败: %v", err)
+	}
+	if seq.Class != asn1.ClassUniversal || seq.Tag != asn1.TagSequence {
+		return nil, fmt.Errorf("GeneralNames不是SEQUENCE")
+	}
+	return parseGeneralNameList(seq.Bytes)
+}
+
+// ParseNameConstraints 解析名称约束扩展
+func ParseNameConstraints(data []byte) (*NameConstraints, error) {
+	var constraints nameConstraintsASN1
+	if err := unmarshalExact(data, &constraints); err != nil {
+		return nil, fmt.Errorf("解析Name Constraints失败: %v", err)
+	}
+	permitted, err := parseGeneralSubtrees(constraints.Permitted)
+	if err != nil {
+		return nil, fmt.Errorf("解析permittedSubtrees失败: %v", err)
+	}
+	excluded, err := parseGeneralSubtrees(constraints.Excluded)
+	if err != nil {
+		return nil, fmt.Errorf("解析excludedSubtrees失败: %v", err)
+	}
+	return &NameConstraints{Permitted: permitted, Excluded: excluded}, nil
+}
+
+// parseGeneralSubtrees 解析GeneralSubtrees
+func parseGeneralSubtrees(subtrees []generalSubtreeASN1) ([]GeneralSubtree, error) {
+	result := make([]GeneralSubtree, 0, len(subtrees))
+	for _, subtree := range subtrees {
+		base, err := parseGeneralName(subtree.Base)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, GeneralSubtree{Base: base, Minimum: subtree.Minimum, Maximum: subtree.Maximum})
+	}
+	return result, nil
+}
+
+// ParsePolicyConstraints 解析策略约束扩展
+func ParsePolicyConstraints(data []byte) (*PolicyConstraints, error) {
+	var constraints policyConstraintsASN1
+	if err := unmarshalExact(data, &constraints); err != nil {
+		return nil, fmt.Errorf("解析Policy Constraints失败: %v", err)
+	}
+	return &PolicyConstraints{
+		RequireExplicitPolicy: constraints.RequireExplicitPolicy,
+		InhibitPolicyMapping:  constraints.InhibitPolicyMapping,
+	}, nil
+}
+
+// ParsePolicyMappings 解析策略映射扩展
+func ParsePolicyMappings(data []byte) ([]PolicyMapping, error) {
+	var mappings []policyMappingASN1
+	if err := unmarshalExact(data, &mappings); err != nil {
+		return nil, fmt.Errorf("解析Policy Mappings失败: %v", err)
+	}
+	result := make([]PolicyMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		result = append(result, PolicyMapping{
+			IssuerDomainPolicy:  mapping.IssuerDomainPolicy.String(),
+			SubjectDomainPolicy: mapping.SubjectDomainPolicy.String(),
+		})
+	}
+	return result, nil
+}
+
+// ParseInhibitAnyPolicy 解析禁止任意策略扩展，返回SkipCerts
+func ParseInhibitAnyPolicy(data []byte) (int, error) {
+	var skipCerts int
+	if err := unmarshalExact(data, &skipCerts); err != nil {
+		return 0, fmt.Errorf("解析Inhibit Any Policy失败: %v", err)
+	}
+	return skipCerts, nil
+}
+
+// ParseCRLNumber 解析CRL Number或Delta CRL Indicator扩展
+func ParseCRLNumber(data []byte) (*big.Int, error) {
+	var number *big.Int
+	if err := unmarshalExact(data, &number); err != nil {
+		return nil, fmt.Errorf("解析CRL Number失败: %v", err)
+	}
+	return number, nil
+}
+
+// ParseIssuingDistributionPoint 解析CRL颁发分发点扩展
+func ParseIssuingDistributionPoint(data []byte) (*IssuingDistributionPoint, error) {
+	var idp issuingDistributionPointASN1
+	if err := unmarshalExact(data, &idp); err != nil {
+		return nil, fmt.Errorf("解析Issuing Distribution Point失败: %v", err)
+	}
+	result := &IssuingDistributionPoint{
+		OnlyContainsUserCerts:      idp.OnlyContainsUserCerts,
+		OnlyContainsCACerts:        idp.OnlyContainsCACerts,
+		OnlySomeReasons:            parseReasonFlags(idp.OnlySomeReasons),
+		IndirectCRL:                idp.IndirectCRL,
+		OnlyContainsAttributeCerts: idp.OnlyContainsAttributeCerts,
+	}
+	if len(idp.DistributionPoint.FullBytes) > 0 {
+		fullName, relativeName, err := parseDistributionPointName(idp.DistributionPoint.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		result.FullName = fullName
+		result.RelativeName = relativeName
+	}
+	return result, nil
+}
+
+// ParseReasonCode 解析CRL条目的吊销原因扩展
+func ParseReasonCode(data []byte) (int, error) {
+	var reason asn1.Enumerated
+	if err := unmarshalExact(data, &reason); err != nil {
+		return 0, fmt.Errorf("解析Reason Code失败: %v", err)
+	}
+	return int(reason), nil
+}
+
+// ParseInvalidityDate 解析CRL条目的失效日期扩展
+func ParseInvalidityDate(data []byte) (time.Time, error) {
+	var date time.Time
+	if err := unmarshalExact(data, &date); err != nil {
+		return time.Time{}, fmt.Errorf("解析Invalidity Date失败: %v", err)
+	}
+	return date, nil
+}
+
+// DecodeExtension 按OID解码扩展项，返回类型化结果；不支持的扩展返回nil
+func DecodeExtension(oid string, data []byte) (interface{}, error) {
+	switch oid {
+	case "2.5.29.14":
+		return ParseSubjectKeyIdentifier(data)
+	case "2.5.29.15":
+		return ParseKeyUsageExtension(data)
+	case "2.5.29.17", "2.5.29.18":
+		return ParseGeneralNames(data)
+	case "2.5.29.19":
+		return ParseBasicConstraints(data)
+	case "2.5.29.20", "2.5.29.27":
+		return ParseCRLNumber(data)
+	case "2.5.29.21":
+		reason, err := ParseReasonCode(data)
+		if err != nil {
+			return nil, err
+		}
+		return getRevocationReasonText(reason), nil
+	case "2.5.29.24":
+		return ParseInvalidityDate(data)
+	case "2.5.29.28":
+		return ParseIssuingDistributionPoint(data)
+	case "2.5.29.29":
+		return ParseGeneralNames(data)
+	case "2.5.29.31", "2.5.29.46":
+		return ParseCRLDistributionPoints(data)
+	case "2.5.29.30":
+		return ParseNameConstraints(data)
+	case "2.5.29.32":
+		return ParseCertificatePolicies(data)
+	case "2.5.29.33":
+		return ParsePolicyMappings(data)
+	case "2.5.29.36":
+		return ParsePolicyConstraints(data)
+	case "2.5.29.54":
+		return ParseInhibitAnyPolicy(data)
+	case "2.5.29.35":
+		return ParseAuthorityKeyIdentifier(data)
+	case "2.5.29.37":
+		return ParseExtendedKeyUsage(data)
+	case "1.3.6.1.5.5.7.1.1", "1.3.6.1.5.5.7.1.11":
+		return ParseAuthorityInfoAccess(data)
+	}
+	return nil, nil
+}
+
+// FormatExtension 将扩展项解码为可读文本，不支持或解析失败时回退为Hex
+func FormatExtension(oid string, data []byte) string {
+	decoded, err := DecodeExtension(oid, data)
+	if err != nil {
+		return fmt.Sprintf("%v\nHex: %s", err, hex.EncodeToString(data))
+	}
+
+	var b strings.Builder
+	switch v := decoded.(type) {
+	case []byte:
+		b.WriteString(hex.EncodeToString(v))
+	case []string:
+		for _, item := range v {
+			if name, ok := ExtKeyUsageNames[item]; ok {
+				item = fmt.Sprintf("%s (%s)", name, item)
+			}
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	case []GeneralName:
+		for _, name := range v {
+			fmt.Fprintf(&b, "- %s\n", name)
+		}
+	case *BasicConstraints:
+		fmt.Fprintf(&b, "CA: %t\n", v.IsCA)
+		if v.MaxPathLen >= 0 {
+			fmt.Fprintf(&b, "Path Length Constraint: %d\n", v.MaxPathLen)
+		} else {
+			b.WriteString("Path Length Constraint: 无限制\n")
+		}
+	case *AuthorityKeyIdentifier:
+		if len(v.KeyID) > 0 {
+			fmt.Fprintf(&b, "KeyID: %s\n", hex.EncodeToString(v.KeyID))
+		}
+		for _, name := range v.Issuer {
+			fmt.Fprintf(&b, "Issuer: %s\n", name)
+		}
+		if v.SerialNumber != nil {
+			fmt.Fprintf(&b, "SerialNumber: %x\n", v.SerialNumber)
+		}
+	case []AccessDescription:
+		for i, desc := range v {
+			fmt.Fprintf(&b, "%d. Method: %s (%s)\n", i+1, desc.MethodName, desc.Method)
+			fmt.Fprintf(&b, "   Location: %s\n", desc.Location)
+		}
+	case []DistributionPoint:
+		for i, dp := range v {
+			fmt.Fprintf(&b, "%d. Distribution Point\n", i+1)
+			for _, name := range dp.FullName {
+				fmt.Fprintf(&b, "   Full Name: %s\n", name)
+			}
+			if dp.RelativeName != "" {
+				fmt.Fprintf(&b, "   Relative Name: %s\n", dp.RelativeName)
+			}
+			if len(dp.Reasons) > 0 {
+				fmt.Fprintf(&b, "   Reasons: %s\n", strings.Join(dp.Reasons, ", "))
+			}
+			for _, name := range dp.CRLIssuer {
+				fmt.Fprintf(&b, "   CRL Issuer: %s\n", name)
+			}
+		}
+	case int:
+		fmt.Fprintf(&b, "SkipCerts: %d", v)
+	case string:
+		b.WriteString(v)
+	case *big.Int:
+		fmt.Fprintf(&b, "%s (0x%X)", v, v)
+	case time.Time:
+		b.WriteString(v.Format("2006-01-02 15:04:05 MST"))
+	case *IssuingDistributionPoint:
+		for _, name := range v.FullName {
+			fmt.Fprintf(&b, "Full Name: %s\n", name)
+		}
+		if v.RelativeName != "" {
+			fmt.Fprintf(&b, "Relative Name: %s\n", v.RelativeName)
+		}
+		if v.OnlyContainsUserCerts {
+			b.WriteString("Only Contains User Certs\n")
+		}
+		if v.OnlyContainsCACerts {
+			b.WriteString("Only Contains CA Certs\n")
+		}
+		if len(v.OnlySomeReasons) > 0 {
+			fmt.Fprintf(&b, "Only Some Reasons: %s\n", strings.Join(v.OnlySomeReasons, ", "))
+		}
+		if v.IndirectCRL {
+			b.WriteString("Indirect CRL\n")
+		}
+		if v.OnlyContainsAttributeCerts {
+			b.WriteString("Only Contains Attribute Certs\n")
+		}
+	case *NameConstraints:
+		for _, subtree := range v.Permitted {
+			fmt.Fprintf(&b, "Permitted: %s\n", subtree.Base)
+		}
+		for _, subtree := range v.Excluded {
+			fmt.Fprintf(&b, "Excluded: %s\n", subtree.Base)
+		}
+	case *PolicyConstraints:
+		if v.RequireExplicitPolicy >= 0 {
+			fmt.Fprintf(&b, "Require Explicit Policy: %d\n", v.RequireExplicitPolicy)
+		}
+		if v.InhibitPolicyMapping >= 0 {
+			fmt.Fprintf(&b, "Inhibit Policy Mapping: %d\n", v.InhibitPolicyMapping)
+		}
+	case []PolicyMapping:
+		for _, mapping := range v {
+			fmt.Fprintf(&b, "- %s -> %s\n", mapping.IssuerDomainPolicy, mapping.SubjectDomainPolicy)
+		}
+	case []PolicyInformation:
+		for i, policy := range v {
+			fmt.Fprintf(&b, "%d. Policy: %s\n", i+1, policy.Policy)
+			for _, q := range policy.Qualifiers {
+				switch {
+				case q.CPSURI != "":
+					fmt.Fprintf(&b, "   CPS: %s\n", q.CPSURI)
+				case q.UserNotice != nil:
+					if q.UserNotice.Organization != "" {
+						fmt.Fprintf(&b, "   User Notice Organization: %s %v\n", q.UserNotice.Organization, q.UserNotice.NoticeNumbers)
+					}
+					if q.UserNotice.ExplicitText != "" {
+						fmt.Fprintf(&b, "   User Notice: %s\n", q.UserNotice.ExplicitText)
+					}
+				default:
+					fmt.Fprintf(&b, "   %s: %s\n", q.ID, q.Raw)
+				}
+			}
+		}
+	default:
+		b.WriteString(hex.EncodeToString(data))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// parseGeneralNameList 解析连续编码的GeneralName
+func parseGeneralNameList(content []byte) ([]GeneralName, error) {
+	var names []GeneralName
+	for rest := content; len(rest) > 0; {
+		var raw asn1.RawValue
+		var err error
+		rest, err = asn1.Unmarshal(rest, &raw)
+		if err != nil {
+			return nil, err
+		}
+		name, err := parseGeneralName(raw)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parseGeneralName 按上下文标签解析单个GeneralName
+func parseGeneralName(raw asn1.RawValue) (GeneralName, error) {
+	if raw.Class != asn1.ClassContextSpecific {
+		return GeneralName{}, fmt.Errorf("GeneralName标签类别错误: %d", raw.Class)
+	}
+	name := GeneralName{Tag: raw.Tag, raw: raw.Bytes}
+	switch raw.Tag {
+	case 0:
+		name.Type = "otherName"
+		name.Value = parseOtherName(raw.Bytes)
+	case 1:
+		name.Type = "email"
+		name.Value = string(raw.Bytes)
+	case 2:
+		name.Type = "DNS"
+		name.Value = string(raw.Bytes)
+	case 3:
+		name.Type = "x400Address"
+		name.Value = hex.EncodeToString(raw.Bytes)
+	case 4:
+		name.Type = "DirName"
+		var rdn pkix.RDNSequence
+		if err := unmarshalExact(raw.Bytes, &rdn); err != nil {
+			return GeneralName{}, fmt.Errorf("解析directoryName失败: %v", err)
+		}
+		var dn pkix.Name
+		dn.FillFromRDNSequence(&rdn)
+		name.Value = dn.String()
+	case 5:
+		name.Type = "ediPartyName"
+		name.Value = hex.EncodeToString(raw.Bytes)
+	case 6:
+		name.Type = "URI"
+		name.Value = string(raw.Bytes)
+	case 7:
+		name.Type = "IP"
+		switch len(raw.Bytes) {
+		case net.IPv4len, net.IPv6len:
+			name.Value = net.IP(raw.Bytes).String()
+		case 2 * net.IPv4len, 2 * net.IPv6len:
+			// 名称约束中的IP为地址加掩码
+			half := len(raw.Bytes) / 2
+			ipNet := net.IPNet{IP: raw.Bytes[:half], Mask: raw.Bytes[half:]}
+			name.Value = ipNet.String()
+		default:
+			name.Value = hex.EncodeToString(raw.Bytes)
+		}
+	case 8:
+		name.Type = "RID"
+		var oid asn1.ObjectIdentifier
+		if _, err := asn1.UnmarshalWithParams(raw.FullBytes, &oid, "tag:8"); err != nil {
+			return GeneralName{}, fmt.Errorf("解析registeredID失败: %v", err)
+		}
+		name.Value = oid.String()
+	default:
+		return GeneralName{}, fmt.Errorf("未知的GeneralName标签: %d", raw.Tag)
+	}
+	return name, nil
+}
+
+// parseOtherName 解析otherName，值为字符串类型时直接展示
+func parseOtherName(content []byte) string {
+	var typeID asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(content, &typeID)
+	if err != nil {
+		return hex.EncodeToString(content)
+	}
+	var explicit asn1.RawValue
+	if _, err := asn1.Unmarshal(rest, &explicit); err != nil {
+		return typeID.String()
+	}
+	var value asn1.RawValue
+	if _, err := asn1.Unmarshal(explicit.Bytes, &value); err == nil {
+		if text, err := parseDisplayText(value); err == nil {
+			return typeID.String() + "=" + text
+		}
+	}
+	return typeID.String() + "=" + hex.EncodeToString(explicit.Bytes)
+}
+
+// parseRelativeName 解析RelativeDistinguishedName的内容
+func parseRelativeName(content []byte) (string, error) {
+	var atvs []pkix.AttributeTypeAndValue
+	for rest := content; len(rest) > 0; {
+		var atv pkix.AttributeTypeAndValue
+		var err error
+		rest, err = asn1.Unmarshal(rest, &atv)
+		if err != nil {
+			return "", err
+		}
+		atvs = append(atvs, atv)
+	}
+	rdn := pkix.RDNSequence{atvs}
+	return rdn.String(), nil
+}
+
+// parseUserNotice 解析UserNotice ::= SEQUENCE { noticeRef OPTIONAL, explicitText OPTIONAL }
+func parseUserNotice(raw asn1.RawValue) (*UserNotice, error) {
+	if raw.Tag != asn1.TagSequence {
+		return nil, fmt.Errorf("UserNotice不是SEQUENCE")
+	}
+	notice := &UserNotice{}
+	for rest := raw.Bytes; len(rest) > 0; {
+		var field asn1.RawValue
+		var err error
+		rest, err = asn1.Unmarshal(rest, &field)
+		if err != nil {
+			return nil, err
+		}
+		if field.Class == asn1.ClassUniversal && field.Tag == asn1.TagSequence {
+			// NoticeReference ::= SEQUENCE { organization DisplayText, noticeNumbers SEQUENCE OF INTEGER }
+			var org asn1.RawValue
+			numbers, err := asn1.Unmarshal(field.Bytes, &org)
+			if err != nil {
+				return nil, err
+			}
+			if notice.Organization, err = parseDisplayText(org); err != nil {
+				return nil, err
+			}
+			if _, err := asn1.Unmarshal(numbers, &notice.NoticeNumbers); err != nil {
+				return nil, fmt.Errorf("解析noticeNumbers失败: %v", err)
+			}
+			continue
+		}
+		if notice.ExplicitText, err = parseDisplayText(field); err != nil {
+			return nil, err
+		}
+	}
+	return notice, nil
+}
+
+// parseDisplayText 解析DisplayText等字符串类型
+func parseDisplayText(raw asn1.RawValue) (string, error) {
+	if raw.Class != asn1.ClassUniversal {
+		return "", fmt.Errorf("非字符串类型: class %d tag %d", raw.Class, raw.Tag)
+	}
+	switch raw.Tag {
+	case asn1.TagIA5String, asn1.TagUTF8String, asn1.TagPrintableString, asn1.TagT61String, 26: // 26: VisibleString
+		return string(raw.Bytes), nil
+	case asn1.TagBMPString:
+		if len(raw.Bytes)%2 != 0 {
+			return "", fmt.Errorf("BMPString长度错误")
+		}
+		units := make([]uint16, len(raw.Bytes)/2)
+		for i := range units {
+			units[i] = uint16(raw.Bytes[2*i])<<8 | uint16(raw.Bytes[2*i+1])
+		}
+		return string(utf16.Decode(units)), nil
+	}
+	return "", fmt.Errorf("非字符串类型: tag %d", raw.Tag)
+}
+
+// parseReasonFlags 解析ReasonFlags位串
+func parseReasonFlags(bits asn1.BitString) []string {
+	return bitStringNames(bits, reasonFlagNames)
+}
+
+// bitStringNames 返回位串中已设置位对应的名称
+func bitStringNames(bits asn1.BitString, names []string) []string {
+	var result []string
+	for i := 0; i < bits.BitLength; i++ {
+		if bits.At(i) == 0 {
+			continue
+		}
+		if i < len(names) {
+			result = append(result, names[i])
+		} else {
+			result = append(result, fmt.Sprintf("Unknown (Bit %d)", i))
+		}
+	}
+	return result
+}
+
+// oidName 返回OID对应的名称，未知时返回OID本身
+func oidName(names map[string]string, oid asn1.ObjectIdentifier) string {
+	if name, ok := names[oid.String()]; ok {
+		return name
+	}
+	return oid.String()
+}
+
+// unmarshalExact 解析DER并拒绝尾部多余数据
+func unmarshalExact(data []byte, v interface{}) error {
+	rest, err := asn1.Unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("存在%d字节多余数据", len(rest))
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package helper
+
+import (
+	"HeTu/security"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zaneway/cain-go/sm2"
+	gmx509 "github.com/zaneway/cain-go/x509"
+)
+
+var oidPublicKeyRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+
+// ParsePrivateKey 自动识别裸SM2私钥、PKCS#8(SM2/RSA/ECDSA)、PKCS#1 RSA及SEC1格式的私钥
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("私钥数据为空")
+	}
+	// 裸SM2私钥
+	if len(data) == 30 || len(data) == 32 {
+		return ParseSM2PrivateKey(data)
+	}
+
+	var info pkcs8PrivateKey
+	if rest, err := asn1.Unmarshal(data, &info); err == nil && len(rest) == 0 {
+		switch {
+		case info.Algo.Algorithm.Equal(oidPublicKeyRSA):
+			key, err := x509.ParsePKCS8PrivateKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("解析PKCS#8 RSA私钥失败: %v", err)
+			}
+			return key.(crypto.Signer), nil
+		case info.Algo.Algorithm.Equal(oidPublicKeyEC) || info.Algo.Algorithm.Equal(oidPublicKeySM2):
+			if isSM2Curve(info.Algo) {
+				return parsePKCS8PrivateKey(data)
+			}
+			key, err := x509.ParsePKCS8PrivateKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("解析PKCS#8 EC私钥失败: %v", err)
+			}
+			return key.(crypto.Signer), nil
+		}
+		return nil, fmt.Errorf("不支持的PKCS#8私钥算法: %s", info.Algo.Algorithm)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := gmx509.ParseSm2PrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(data); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("无法识别的私钥格式，支持裸SM2私钥、PKCS#8、PKCS#1及SEC1")
+}
+
+// isSM2Curve 判断PKCS#8算法标识中的曲线参数是否为SM2
+func isSM2Curve(algo pkix.AlgorithmIdentifier) bool {
+	if algo.Algorithm.Equal(oidPublicKeySM2) {
+		return true
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &curve); err != nil {
+		return false
+	}
+	return curve.Equal(oidPublicKeySM2)
+}
+
+// CertificatePublicKey 返回证书公钥，cain-go未解析的SM2公钥从SubjectPublicKeyInfo补充解析
+func CertificatePublicKey(cert *gmx509.Certificate) (crypto.PublicKey, error) {
+	if cert.PublicKey != nil {
+		return cert.PublicKey, nil
+	}
+	return parseSM2SubjectPublicKeyInfo(cert.RawSubjectPublicKeyInfo)
+}
+
+// VerifySignature 按签名算法标识使用公钥验证签名，SM2-SM3签名使用默认用户ID
+func VerifySignature(pub crypto.PublicKey, algorithm pkix.AlgorithmIdentifier, signed, signature []byte) error {
+	// cain-go仅对CRL公开了按AlgorithmIdentifier验签的接口，借用CertificateList承载待验证数据
+	signer := &gmx509.Certificate{PublicKey: pub}
+	return signer.CheckCRLSignature(&pkix.CertificateList{
+		TBSCertList:        pkix.TBSCertificateList{Raw: signed},
+		SignatureAlgorithm: algorithm,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+}
+
+// publicKeyBitString 返回SubjectPublicKeyInfo中subjectPublicKey的内容，用于计算密钥标识
+func publicKeyBitString(spki []byte) ([]byte, error) {
+	var info struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(spki, &info); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("解析SubjectPublicKeyInfo失败")
+	}
+	return info.PublicKey.RightAlign(), nil
+}
+
+// checkKeyMatchesCertificate 检查私钥与证书公钥是否匹配
+func checkKeyMatchesCertificate(key crypto.Signer, cert *gmx509.Certificate) error {
+	certPub, err := CertificatePublicKey(cert)
+	if err != nil {
+		return fmt.Errorf("解析证书公钥失败: %v", err)
+	}
+	certSPKI, err := MarshalPublicKey(certPub)
+	if err != nil {
+		return err
+	}
+	keySPKI, err := MarshalPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(certSPKI, keySPKI) {
+		return fmt.Errorf("私钥与证书公钥不匹配")
+	}
+	return nil
+}
+
+// MarshalPublicKey 将公钥编码为SubjectPublicKeyInfo，SM2公钥使用id-ecPublicKey+SM2曲线
+func MarshalPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	switch key := pub.(type) {
+	case *sm2.PublicKey:
+		return gmx509.MarshalSm2PublicKey(key)
+	case *ecdsa.PublicKey:
+		if key.Curve == sm2.P256Sm2() {
+			return gmx509.MarshalSm2PublicKey(&sm2.PublicKey{Curve: key.Curve, X: key.X, Y: key.Y})
+		}
+	case *rsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("不支持的公钥类型: %T", pub)
+	}
+	return x509.MarshalPKIXPublicKey(pub)
+}
+
+// KeyEncoding 密钥的一种编码形式，PEMType为空表示该形式没有PEM封装(如裸密钥)
+type KeyEncoding struct {
+	Name    string `json:"name"`
+	PEMType string `json:"pemType,omitempty"`
+	Data    []byte `json:"data"`
+}
+
+// PEM 返回PEM编码文本，无PEM封装时返回空串
+func (e KeyEncoding) PEM() string {
+	if e.PEMType == "" {
+		return ""
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: e.PEMType, Bytes: e.Data}))
+}
+
+// GeneratedKey 生成的密钥及其各种编码，对称密钥仅有Private中的裸密钥
+type GeneratedKey struct {
+	Spec      string        `json:"spec"`
+	Algorithm string        `json:"algorithm"`
+	Bits      int           `json:"bits"`
+	Private   []KeyEncoding `json:"private"`
+	Public    []KeyEncoding `json:"public,omitempty"`
+}
+
+// GenerateKey 按security包中的规格(如SM2_256、RSA_2048、AES_128、SM4_128)生成密钥
+// AES_384/AES_512为AES-SIV等组合模式使用的双倍长度密钥，不能直接作为AES分组密钥
+func GenerateKey(spec string) (*GeneratedKey, error) {
+	algorithm, size, ok := strings.Cut(spec, "_")
+	bits, err := strconv.Atoi(size)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("无效的密钥规格: %s", spec)
+	}
+	key := &GeneratedKey{Spec: spec, Algorithm: algorithm, Bits: bits}
+
+	switch {
+	case slices.Contains(security.ALL_SYM_KEYS, spec):
+		raw := make([]byte, bits/8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("生成随机密钥失败: %v", err)
+		}
+		key.Private = []KeyEncoding{{Name: "Raw", Data: raw}}
+	case spec == security.SM2_256:
+		priv, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("生成SM2密钥失败: %v", err)
+		}
+		if key.Private, key.Public, err = encodeSM2KeyPair(priv); err != nil {
+			return nil, err
+		}
+	case slices.Contains(security.ALL_ASYM_KEYS, spec) && algorithm == "RSA":
+		priv, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, fmt.Errorf("生成RSA密钥失败: %v", err)
+		}
+		if key.Private, key.Public, err = encodeRSAKeyPair(priv); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的密钥规格: %s", spec)
+	}
+	return key, nil
+}
+
+// encodeSM2KeyPair 输出SM2私钥的裸/SEC1/PKCS#8形式及公钥的裸/SubjectPublicKeyInfo形式
+func encodeSM2KeyPair(priv *sm2.PrivateKey) ([]KeyEncoding, []KeyEncoding, error) {
+	sec1, err := MarshalSM2PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkcs8, err := MarshalSM2PKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	spki, err := MarshalPublicKey(&priv.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	private := []KeyEncoding{
+		{Name: "Raw", Data: priv.D.FillBytes(make([]byte, 32))},
+		{Name: "SEC1", PEMType: "EC PRIVATE KEY", Data: sec1},
+		{Name: "PKCS#8", PEMType: "PRIVATE KEY", Data: pkcs8},
+	}
+	public := []KeyEncoding{
+		{Name: "Raw", Data: MarshalSM2Point(&priv.PublicKey)},
+		{Name: "SubjectPublicKeyInfo", PEMType: "PUBLIC KEY", Data: spki},
+	}
+	return private, public, nil
+}
+
+// encodeRSAKeyPair 输出RSA私钥的PKCS#1/PKCS#8形式及公钥的PKCS#1/SubjectPublicKeyInfo形式
+func encodeRSAKeyPair(priv *rsa.PrivateKey) ([]KeyEncoding, []KeyEncoding, error) {
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("编码PKCS#8私钥失败: %v", err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("编码公钥失败: %v", err)
+	}
+	private := []KeyEncoding{
+		{Name: "PKCS#1", PEMType: "RSA PRIVATE KEY", Data: x509.MarshalPKCS1PrivateKey(priv)},
+		{Name: "PKCS#8", PEMType: "PRIVATE KEY", Data: pkcs8},
+	}
+	public := []KeyEncoding{
+		{Name: "PKCS#1", PEMType: "RSA PUBLIC KEY", Data: x509.MarshalPKCS1PublicKey(&priv.PublicKey)},
+		{Name: "SubjectPublicKeyInfo", PEMType: "PUBLIC KEY", Data: spki},
+	}
+	return private, public, nil
+}
+
+type sec1PrivateKey struct {
+	Version    int
+	PrivateKey []byte
+	NamedCurve asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey  asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// MarshalSM2PrivateKey 将SM2私钥编码为SEC1(RFC 5915) ECPrivateKey，私钥固定32字节
+func MarshalSM2PrivateKey(priv *sm2.PrivateKey) ([]byte, error) {
+	point := MarshalSM2Point(&priv.PublicKey)
+	return asn1.Marshal(sec1PrivateKey{
+		Version:    1,
+		PrivateKey: priv.D.FillBytes(make([]byte, 32)),
+		NamedCurve: oidPublicKeySM2,
+		PublicKey:  asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+	})
+}
+
+// MarshalSM2PKCS8PrivateKey 将SM2私钥编码为PKCS#8，算法为id-ecPublicKey+SM2曲线
+func MarshalSM2PKCS8PrivateKey(priv *sm2.PrivateKey) ([]byte, error) {
+	point := MarshalSM2Point(&priv.PublicKey)
+	inner, err := asn1.Marshal(sec1PrivateKey{
+		Version:    1,
+		PrivateKey: priv.D.FillBytes(make([]byte, 32)),
+		PublicKey:  asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+	})
+	if err != nil {
+		return nil, err
+	}
+	curve, err := asn1.Marshal(oidPublicKeySM2)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8PrivateKey{
+		Version:    0,
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyEC, Parameters: asn1.RawValue{FullBytes: curve}},
+		PrivateKey: inner,
+	})
+}
+
+// MarshalSM2Point 将SM2公钥编码为非压缩点 04||X||Y
+func MarshalSM2Point(pub *sm2.PublicKey) []byte {
+	point := make([]byte, 65)
+	point[0] = 0x04
+	pub.X.FillBytes(point[1:33])
+	pub.Y.FillBytes(point[33:])
+	return point
+}
@@ -0,0 +1,783 @@
+package helper
+
+import (
+	"HeTu/util"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+// OCSP CertID摘要算法
+const (
+	OCSPHashSHA1   = "SHA1"
+	OCSPHashSHA256 = "SHA256"
+	OCSPHashSM3    = "SM3"
+)
+
+// OCSP证书状态
+const (
+	OCSPStatusGood    = "good"
+	OCSPStatusRevoked = "revoked"
+	OCSPStatusUnknown = "unknown"
+)
+
+// OCSP响应签名状态
+const (
+	OCSPSignatureUnverified = "未验证"
+	OCSPSignatureValid      = "验证通过"
+	OCSPSignatureInvalid    = "验证失败"
+)
+
+// ocspNonceSize 生成的nonce长度，RFC 8954要求1到32字节
+const ocspNonceSize = 16
+
+var (
+	oidOCSPBasic    = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidOCSPNonce    = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+	oidOCSPNoCheck  = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+	oidOCSPSigning  = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 9}
+	ocspHashOIDs    = map[string]asn1.ObjectIdentifier{OCSPHashSHA1: oidDigestSHA1, OCSPHashSHA256: oidDigestSHA256, OCSPHashSM3: oidDigestSM3}
+	ocspStatusTexts = map[string]string{OCSPStatusGood: "正常", OCSPStatusRevoked: "已吊销", OCSPStatusUnknown: "未知"}
+)
+
+// ocspResponseStatusTexts OCSPResponseStatus取值说明
+var ocspResponseStatusTexts = map[int]string{
+	0: "successful 成功",
+	1: "malformedRequest 请求格式错误",
+	2: "internalError 响应者内部错误",
+	3: "tryLater 请稍后重试",
+	5: "sigRequired 请求需要签名",
+	6: "unauthorized 未授权",
+}
+
+type ocspCertIDASN1 struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type ocspRequestEntryASN1 struct {
+	CertID     ocspCertIDASN1
+	Extensions []pkix.Extension `asn1:"optional,explicit,tag:0"`
+}
+
+type ocspTBSRequestASN1 struct {
+	Version       int           `asn1:"optional,explicit,tag:0,default:0"`
+	RequestorName asn1.RawValue `asn1:"optional,explicit,tag:1"`
+	RequestList   []ocspRequestEntryASN1
+	Extensions    []pkix.Extension `asn1:"optional,explicit,tag:2"`
+}
+
+type ocspRequestASN1 struct {
+	TBSRequest        ocspTBSRequestASN1
+	OptionalSignature asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type ocspResponseASN1 struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytesASN1 `asn1:"optional,explicit,tag:0"`
+}
+
+type ocspResponseBytesASN1 struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicOCSPResponseASN1 struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type ocspResponseDataASN1 struct {
+	Version     int `asn1:"optional,explicit,tag:0,default:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []ocspSingleResponseASN1
+	Extensions  []pkix.Extension `asn1:"optional,explicit,tag:1"`
+}
+
+type ocspSingleResponseASN1 struct {
+	CertID     ocspCertIDASN1
+	Good       asn1.Flag           `asn1:"optional,tag:0"`
+	Revoked    ocspRevokedInfoASN1 `asn1:"optional,tag:1"`
+	Unknown    asn1.Flag           `asn1:"optional,tag:2"`
+	ThisUpdate time.Time           `asn1:"generalized"`
+	NextUpdate time.Time           `asn1:"optional,generalized,explicit,tag:0"`
+	Extensions []pkix.Extension    `asn1:"optional,explicit,tag:1"`
+}
+
+type ocspRevokedInfoASN1 struct {
+	RevocationTime time.Time `asn1:"generalized"`
+	// Reason 可选的[0] CRLReason，以RawValue区分缺省与unspecified(0)
+	Reason asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// OCSPCertID 标识被查询证书的CertID
+type OCSPCertID struct {
+	HashAlgorithm  string
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+	hashOID        asn1.ObjectIdentifier
+}
+
+// OCSPRequestTemplate OCSP请求参数
+type OCSPRequestTemplate struct {
+	// HashAlgorithm CertID摘要算法，缺省为SHA1
+	HashAlgorithm string
+	SerialNumbers []*big.Int
+	// Nonce 为空时请求不包含nonce扩展
+	Nonce []byte
+}
+
+// OCSPRequestInfo OCSP请求信息
+type OCSPRequestInfo struct {
+	Version       int
+	RequestorName string
+	CertIDs       []OCSPCertID
+	Nonce         []byte
+	Extensions    []pkix.Extension
+	// Signed 请求是否包含optionalSignature，签名内容不做验证
+	Signed bool
+}
+
+// OCSPSingleResponse 单个证书的状态
+type OCSPSingleResponse struct {
+	CertID OCSPCertID
+	Status string
+	// RevocationTime、ReasonCode仅在已吊销时有效，未包含原因时ReasonCode为CRLReasonOmitted
+	RevocationTime time.Time
+	ReasonCode     int
+	ThisUpdate     time.Time
+	// NextUpdate 未设置时为零值
+	NextUpdate time.Time
+	Extensions []pkix.Extension
+}
+
+// OCSPResponseInfo OCSP响应信息
+type OCSPResponseInfo struct {
+	ResponseStatus int
+	ResponseType   string
+	Version        int
+	// ResponderName、ResponderKeyHash 按ResponderID类型二选一
+	ResponderName      string
+	ResponderKeyHash   []byte
+	ProducedAt         time.Time
+	Responses          []OCSPSingleResponse
+	Nonce              []byte
+	Extensions         []pkix.Extension
+	SignatureAlgorithm string
+	// Certificates 响应中附带的证书，通常为委托响应者证书
+	Certificates []*x509.Certificate
+	// SignatureStatus 签名验证状态，调用VerifyOCSPResponse后更新
+	SignatureStatus string
+	SignatureError  string
+	// Signer 验证通过的签名证书，Delegated表示由CA委托的响应者签名
+	Signer    *x509.Certificate
+	Delegated bool
+	// Warnings 验证时发现的非致命问题
+	Warnings []string
+
+	responderNameRaw []byte
+	tbsResponseData  []byte
+	signature        []byte
+	signatureAlg     pkix.AlgorithmIdentifier
+}
+
+// StatusText 证书状态的中文说明
+func (r *OCSPSingleResponse) StatusText() string {
+	return ocspStatusTexts[r.Status]
+}
+
+// ResponseStatusText OCSP响应状态说明
+func (info *OCSPResponseInfo) ResponseStatusText() string {
+	return ocspResponseStatusText(info.ResponseStatus)
+}
+
+func ocspResponseStatusText(status int) string {
+	if text, ok := ocspResponseStatusTexts[status]; ok {
+		return text
+	}
+	return fmt.Sprintf("未知状态(%d)", status)
+}
+
+// ResponderID 响应者标识的文本表示
+func (info *OCSPResponseInfo) ResponderID() string {
+	if info.ResponderName != "" {
+		return "byName: " + info.ResponderName
+	}
+	return fmt.Sprintf("byKey: %X", info.ResponderKeyHash)
+}
+
+// NewOCSPCertID 按指定摘要算法计算issuer签发的序列号为serial的证书的CertID
+func NewOCSPCertID(issuer *x509.Certificate, serial *big.Int, hashAlgorithm string) (*OCSPCertID, error) {
+	if hashAlgorithm == "" {
+		hashAlgorithm = OCSPHashSHA1
+	}
+	oid, ok := ocspHashOIDs[hashAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的CertID摘要算法: %s", hashAlgorithm)
+	}
+	nameHash, keyHash, err := ocspIssuerHashes(issuer, oid)
+	if err != nil {
+		return nil, err
+	}
+	return &OCSPCertID{
+		HashAlgorithm:  hashAlgorithm,
+		IssuerNameHash: nameHash,
+		IssuerKeyHash:  keyHash,
+		SerialNumber:   serial,
+		hashOID:        oid,
+	}, nil
+}
+
+// ocspIssuerHashes 计算颁发者名称及公钥的摘要
+func ocspIssuerHashes(issuer *x509.Certificate, oid asn1.ObjectIdentifier) ([]byte, []byte, error) {
+	newHash, err := hashForOID(oid)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := publicKeyBitString(issuer.RawSubjectPublicKeyInfo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("颁发者证书%v", err)
+	}
+	h := newHash()
+	h.Write(issuer.RawSubject)
+	nameHash := h.Sum(nil)
+	h = newHash()
+	h.Write(publicKey)
+	return nameHash, h.Sum(nil), nil
+}
+
+// MatchesIssuer 判断CertID中的颁发者摘要是否与issuer一致
+func (id *OCSPCertID) MatchesIssuer(issuer *x509.Certificate) bool {
+	nameHash, keyHash, err := ocspIssuerHashes(issuer, id.hashOID)
+	return err == nil && bytes.Equal(nameHash, id.IssuerNameHash) && bytes.Equal(keyHash, id.IssuerKeyHash)
+}
+
+// Equal 判断两个CertID是否一致，摘要算法不同时视为不一致
+func (id *OCSPCertID) Equal(other *OCSPCertID) bool {
+	return id.hashOID.Equal(other.hashOID) &&
+		bytes.Equal(id.IssuerNameHash, other.IssuerNameHash) &&
+		bytes.Equal(id.IssuerKeyHash, other.IssuerKeyHash) &&
+		id.SerialNumber.Cmp(other.SerialNumber) == 0
+}
+
+func (id *OCSPCertID) marshalASN1() ocspCertIDASN1 {
+	algorithm := pkix.AlgorithmIdentifier{Algorithm: id.hashOID}
+	// SHA系列摘要按惯例携带NULL参数，SM3不带参数
+	if !id.hashOID.Equal(oidDigestSM3) {
+		algorithm.Parameters = asn1.NullRawValue
+	}
+	return ocspCertIDASN1{
+		HashAlgorithm:  algorithm,
+		IssuerNameHash: id.IssuerNameHash,
+		IssuerKeyHash:  id.IssuerKeyHash,
+		SerialNumber:   id.SerialNumber,
+	}
+}
+
+func parseOCSPCertID(raw ocspCertIDASN1) OCSPCertID {
+	id := OCSPCertID{
+		HashAlgorithm:  raw.HashAlgorithm.Algorithm.String(),
+		IssuerNameHash: raw.IssuerNameHash,
+		IssuerKeyHash:  raw.IssuerKeyHash,
+		SerialNumber:   raw.SerialNumber,
+		hashOID:        raw.HashAlgorithm.Algorithm,
+	}
+	for name, oid := range ocspHashOIDs {
+		if oid.Equal(raw.HashAlgorithm.Algorithm) {
+			id.HashAlgorithm = name
+		}
+	}
+	return id
+}
+
+// NewOCSPNonce 生成随机nonce
+func NewOCSPNonce() ([]byte, error) {
+	nonce := make([]byte, ocspNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成nonce失败: %v", err)
+	}
+	return nonce, nil
+}
+
+// CreateOCSPRequest 为issuer签发的一个或多个证书序列号构造未签名的OCSP请求
+func CreateOCSPRequest(template *OCSPRequestTemplate, issuer *x509.Certificate) ([]byte, error) {
+	if len(template.SerialNumbers) == 0 {
+		return nil, fmt.Errorf("请至少指定一个证书序列号")
+	}
+	var tbs ocspTBSRequestASN1
+	for _, serial := range template.SerialNumbers {
+		id, err := NewOCSPCertID(issuer, serial, template.HashAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		tbs.RequestList = append(tbs.RequestList, ocspRequestEntryASN1{CertID: id.marshalASN1()})
+	}
+	if len(template.Nonce) > 0 {
+		value, err := asn1.Marshal(template.Nonce)
+		if err != nil {
+			return nil, err
+		}
+		tbs.Extensions = append(tbs.Extensions, pkix.Extension{Id: oidOCSPNonce, Value: value})
+	}
+	return asn1.Marshal(ocspRequestASN1{TBSRequest: tbs})
+}
+
+// ParseOCSPRequest 解析OCSP请求
+func ParseOCSPRequest(der []byte) (*OCSPRequestInfo, error) {
+	var req ocspRequestASN1
+	if err := unmarshalExact(der, &req); err != nil {
+		return nil, fmt.Errorf("解析OCSP请求失败: %v", err)
+	}
+	info := &OCSPRequestInfo{
+		Version:    req.TBSRequest.Version + 1,
+		Extensions: req.TBSRequest.Extensions,
+		Signed:     len(req.OptionalSignature.FullBytes) > 0,
+	}
+	if len(req.TBSRequest.RequestorName.Bytes) > 0 {
+		if name, err := parseGeneralName(req.TBSRequest.RequestorName); err == nil {
+			info.RequestorName = name.String()
+		}
+	}
+	for _, entry := range req.TBSRequest.RequestList {
+		info.CertIDs = append(info.CertIDs, parseOCSPCertID(entry.CertID))
+	}
+	info.Nonce = findOCSPNonce(info.Extensions)
+	return info, nil
+}
+
+// findOCSPNonce 提取nonce扩展的值，兼容未按RFC 8954以OCTET STRING封装的实现
+func findOCSPNonce(extensions []pkix.Extension) []byte {
+	for _, ext := range extensions {
+		if !ext.Id.Equal(oidOCSPNonce) {
+			continue
+		}
+		var nonce []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &nonce); err == nil && len(rest) == 0 {
+			return nonce
+		}
+		return ext.Value
+	}
+	return nil
+}
+
+// ParseOCSPResponse 解析OCSP响应，非successful状态的响应只包含状态
+func ParseOCSPResponse(der []byte) (*OCSPResponseInfo, error) {
+	var resp ocspResponseASN1
+	if err := unmarshalExact(der, &resp); err != nil {
+		return nil, fmt.Errorf("解析OCSP响应失败: %v", err)
+	}
+	info := &OCSPResponseInfo{ResponseStatus: int(resp.Status), SignatureStatus: OCSPSignatureUnverified}
+	if info.ResponseStatus != 0 {
+		return info, nil
+	}
+	info.ResponseType = resp.Response.ResponseType.String()
+	if !resp.Response.ResponseType.Equal(oidOCSPBasic) {
+		return nil, fmt.Errorf("不支持的OCSP响应类型: %s", info.ResponseType)
+	}
+	info.ResponseType = "id-pkix-ocsp-basic"
+
+	var basic basicOCSPResponseASN1
+	if err := unmarshalExact(resp.Response.Response, &basic); err != nil {
+		return nil, fmt.Errorf("解析BasicOCSPResponse失败: %v", err)
+	}
+	info.tbsResponseData = basic.TBSResponseData.FullBytes
+	info.signature = basic.Signature.RightAlign()
+	info.signatureAlg = basic.SignatureAlgorithm
+	info.SignatureAlgorithm = formatSignatureAlgorithm(basic.SignatureAlgorithm)
+	for _, raw := range basic.Certificates {
+		cert, err := ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析响应附带的证书失败: %v", err)
+		}
+		info.Certificates = append(info.Certificates, cert)
+	}
+
+	var data ocspResponseDataASN1
+	if err := unmarshalExact(basic.TBSResponseData.FullBytes, &data); err != nil {
+		return nil, fmt.Errorf("解析ResponseData失败: %v", err)
+	}
+	info.Version = data.Version + 1
+	info.ProducedAt = util.ToBeijingTime(data.ProducedAt)
+	info.Extensions = data.Extensions
+	info.Nonce = findOCSPNonce(data.Extensions)
+	if err := info.parseResponderID(data.ResponderID); err != nil {
+		return nil, err
+	}
+	for _, single := range data.Responses {
+		response, err := parseOCSPSingleResponse(single)
+		if err != nil {
+			return nil, err
+		}
+		info.Responses = append(info.Responses, response)
+	}
+	return info, nil
+}
+
+// parseResponderID 解析 byName [1] Name 或 byKey [2] KeyHash
+func (info *OCSPResponseInfo) parseResponderID(raw asn1.RawValue) error {
+	if raw.Class != asn1.ClassContextSpecific || !raw.IsCompound {
+		return fmt.Errorf("ResponderID格式错误")
+	}
+	switch raw.Tag {
+	case 1:
+		var name pkix.RDNSequence
+		if err := unmarshalExact(raw.Bytes, &name); err != nil {
+			return fmt.Errorf("解析响应者名称失败: %v", err)
+		}
+		info.ResponderName = name.String()
+		info.responderNameRaw = raw.Bytes
+	case 2:
+		if err := unmarshalExact(raw.Bytes, &info.ResponderKeyHash); err != nil {
+			return fmt.Errorf("解析响应者密钥标识失败: %v", err)
+		}
+	default:
+		return fmt.Errorf("未知的ResponderID类型[%d]", raw.Tag)
+	}
+	return nil
+}
+
+func parseOCSPSingleResponse(single ocspSingleResponseASN1) (OCSPSingleResponse, error) {
+	response := OCSPSingleResponse{
+		CertID:     parseOCSPCertID(single.CertID),
+		ReasonCode: CRLReasonOmitted,
+		ThisUpdate: util.ToBeijingTime(single.ThisUpdate),
+		Extensions: single.Extensions,
+	}
+	if !single.NextUpdate.IsZero() {
+		response.NextUpdate = util.ToBeijingTime(single.NextUpdate)
+	}
+	switch {
+	case bool(single.Good):
+		response.Status = OCSPStatusGood
+	case bool(single.Unknown):
+		response.Status = OCSPStatusUnknown
+	default:
+		response.Status = OCSPStatusRevoked
+		response.RevocationTime = util.ToBeijingTime(single.Revoked.RevocationTime)
+		if len(single.Revoked.Reason.FullBytes) > 0 {
+			code, err := ParseReasonCode(single.Revoked.Reason.Bytes)
+			if err != nil {
+				return response, fmt.Errorf("序列号%X的吊销原因%v", single.CertID.SerialNumber, err)
+			}
+			response.ReasonCode = code
+		}
+	}
+	return response, nil
+}
+
+// VerifyOCSPResponse 验证OCSP响应签名并更新SignatureStatus。
+// 签名证书可以是CA本身，也可以是响应中附带的、由CA签发且包含id-kp-OCSPSigning的委托响应者证书；
+// issuer为空时仅使用附带证书验证签名，无法确认响应者授权
+func VerifyOCSPResponse(info *OCSPResponseInfo, issuer *x509.Certificate) error {
+	info.Signer, info.Delegated, info.Warnings = nil, false, nil
+	err := verifyOCSPResponse(info, issuer)
+	if err != nil {
+		info.SignatureStatus = OCSPSignatureInvalid
+		info.SignatureError = err.Error()
+		return err
+	}
+	info.SignatureStatus = OCSPSignatureValid
+	info.SignatureError = ""
+	return nil
+}
+
+func verifyOCSPResponse(info *OCSPResponseInfo, issuer *x509.Certificate) error {
+	if info.ResponseStatus != 0 {
+		return fmt.Errorf("OCSP响应状态为 %s，不包含签名", info.ResponseStatusText())
+	}
+	candidates := info.Certificates
+	if issuer != nil {
+		candidates = append([]*x509.Certificate{issuer}, candidates...)
+	}
+	var signer *x509.Certificate
+	for _, cert := range candidates {
+		if info.matchesResponderID(cert) {
+			signer = cert
+			break
+		}
+	}
+	if signer == nil {
+		return fmt.Errorf("未找到与响应者标识(%s)匹配的签名证书", info.ResponderID())
+	}
+	pub, err := CertificatePublicKey(signer)
+	if err != nil {
+		return fmt.Errorf("解析签名证书公钥失败: %v", err)
+	}
+	if err := VerifySignature(pub, info.signatureAlg, info.tbsResponseData, info.signature); err != nil {
+		if info.signatureAlg.Algorithm.Equal(oidSignatureSM2WithSM3) {
+			return fmt.Errorf("OCSP响应签名验证失败(SM2用户ID: %s): %v", SM2DefaultUserID, err)
+		}
+		return fmt.Errorf("OCSP响应签名验证失败: %v", err)
+	}
+	info.Signer = signer
+
+	if issuer == nil {
+		info.Warnings = append(info.Warnings, "未提供颁发者证书，无法确认响应者是否获得CA授权")
+	} else if !isSameCertificate(signer, issuer) {
+		info.Delegated = true
+		if err := checkDelegatedResponder(signer, issuer, info.ProducedAt); err != nil {
+			return err
+		}
+		if _, ok := findExtension(signer, oidOCSPNoCheck.String()); !ok {
+			info.Warnings = append(info.Warnings, "委托响应者证书未包含id-pkix-ocsp-nocheck扩展，严格的客户端还需检查其吊销状态")
+		}
+	}
+	if issuer != nil {
+		for _, response := range info.Responses {
+			if !response.CertID.MatchesIssuer(issuer) {
+				info.Warnings = append(info.Warnings, fmt.Sprintf("序列号%X的CertID与颁发者证书不匹配", response.CertID.SerialNumber))
+			}
+		}
+	}
+	return nil
+}
+
+// checkDelegatedResponder 检查委托响应者证书由CA签发、包含OCSPSigning扩展用途且在响应产生时有效
+func checkDelegatedResponder(signer, issuer *x509.Certificate, producedAt time.Time) error {
+	if err := CheckCertificateSignature(signer, issuer); err != nil {
+		return fmt.Errorf("委托响应者证书不是由颁发者证书签发: %v", err)
+	}
+	hasOCSPSigning := false
+	for _, usage := range signer.ExtKeyUsage {
+		hasOCSPSigning = hasOCSPSigning || usage == x509.ExtKeyUsageOCSPSigning
+	}
+	for _, oid := range signer.UnknownExtKeyUsage {
+		hasOCSPSigning = hasOCSPSigning || oid.Equal(oidOCSPSigning)
+	}
+	if !hasOCSPSigning {
+		return fmt.Errorf("委托响应者证书的扩展密钥用法不包含OCSPSigning")
+	}
+	if producedAt.Before(signer.NotBefore) || producedAt.After(signer.NotAfter) {
+		return fmt.Errorf("响应产生时间 %s 不在委托响应者证书有效期内", producedAt.Format(util.DateTime))
+	}
+	return nil
+}
+
+// matchesResponderID 判断证书是否与ResponderID一致，byKey为公钥的SHA-1摘要
+func (info *OCSPResponseInfo) matchesResponderID(cert *x509.Certificate) bool {
+	if info.responderNameRaw != nil {
+		return bytes.Equal(info.responderNameRaw, cert.RawSubject)
+	}
+	publicKey, err := publicKeyBitString(cert.RawSubjectPublicKeyInfo)
+	if err != nil {
+		return false
+	}
+	keyHash := sha1.Sum(publicKey)
+	return bytes.Equal(keyHash[:], info.ResponderKeyHash)
+}
+
+// isSameCertificate 判断两个证书主题及公钥是否相同
+func isSameCertificate(a, b *x509.Certificate) bool {
+	return bytes.Equal(a.RawSubject, b.RawSubject) && bytes.Equal(a.RawSubjectPublicKeyInfo, b.RawSubjectPublicKeyInfo)
+}
+
+// CheckFreshness 检查响应的时效，at为检查时间
+func (info *OCSPResponseInfo) CheckFreshness(at time.Time) []string {
+	var problems []string
+	if info.SignatureStatus != OCSPSignatureValid {
+		if info.SignatureError != "" {
+			problems = append(problems, info.SignatureError)
+		} else {
+			problems = append(problems, "OCSP响应签名未验证，状态不可信")
+		}
+	}
+	for _, response := range info.Responses {
+		serial := fmt.Sprintf("%X", response.CertID.SerialNumber)
+		switch {
+		case response.ThisUpdate.After(at):
+			problems = append(problems, fmt.Sprintf("序列号%s的状态尚未生效(thisUpdate %s)", serial, response.ThisUpdate.Format(util.DateTime)))
+		case !response.NextUpdate.IsZero() && response.NextUpdate.Before(at):
+			problems = append(problems, fmt.Sprintf("序列号%s的状态已过期(nextUpdate %s)", serial, response.NextUpdate.Format(util.DateTime)))
+		}
+	}
+	return append(problems, info.Warnings...)
+}
+
+// CheckRequest 检查响应是否对应请求：nonce一致且包含请求的每个CertID
+func (info *OCSPResponseInfo) CheckRequest(req *OCSPRequestInfo) []string {
+	var problems []string
+	switch {
+	case len(req.Nonce) > 0 && len(info.Nonce) == 0:
+		problems = append(problems, "请求包含nonce但响应未返回nonce，无法排除重放")
+	case len(req.Nonce) > 0 && !bytes.Equal(req.Nonce, info.Nonce):
+		problems = append(problems, "响应nonce与请求不一致")
+	}
+	for i := range req.CertIDs {
+		found := false
+		for j := range info.Responses {
+			found = found || req.CertIDs[i].Equal(&info.Responses[j].CertID)
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("响应中没有序列号%X的状态", req.CertIDs[i].SerialNumber))
+		}
+	}
+	return problems
+}
+
+// FindResponse 查找序列号对应的单个响应
+func (info *OCSPResponseInfo) FindResponse(serial *big.Int) *OCSPSingleResponse {
+	for i := range info.Responses {
+		if info.Responses[i].CertID.SerialNumber.Cmp(serial) == 0 {
+			return &info.Responses[i]
+		}
+	}
+	return nil
+}
+
+// maxHTTPResponseSize 读取OCSP、时间戳等服务响应的最大长度
+const maxHTTPResponseSize = 10 << 20
+
+// QueryOCSP 以HTTP POST方式向OCSP服务发送请求并返回响应
+func QueryOCSP(url string, request []byte, timeout time.Duration) ([]byte, error) {
+	return postRequest("OCSP", url, "application/ocsp-request", request, timeout)
+}
+
+// postRequest 以HTTP POST方式发送DER编码的请求，service用于错误提示
+func postRequest(service, url, contentType string, request []byte, timeout time.Duration) ([]byte, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(url, contentType, bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("请求%s服务失败: %v", service, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取%s响应失败: %v", service, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s服务返回HTTP %d", service, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// OCSPResponseEntry 构造OCSP响应时单个证书的状态
+type OCSPResponseEntry struct {
+	CertID OCSPCertID
+	Status string
+	// RevocationTime、ReasonCode仅在Status为revoked时使用，ReasonCode为CRLReasonOmitted时不包含原因
+	RevocationTime time.Time
+	ReasonCode     int
+	ThisUpdate     time.Time
+	// NextUpdate 为零值时不包含nextUpdate
+	NextUpdate time.Time
+}
+
+// OCSPResponseTemplate OCSP响应参数
+type OCSPResponseTemplate struct {
+	ProducedAt time.Time
+	Entries    []OCSPResponseEntry
+	// NonceExtension 原样回显请求中的nonce扩展值，为空时不包含nonce
+	NonceExtension []byte
+	// ResponderIDByKey 以公钥SHA-1摘要而非主题名称标识响应者
+	ResponderIDByKey bool
+	// Certificates 响应中附带的证书，委托响应者通常附带自身证书
+	Certificates []*x509.Certificate
+}
+
+// CreateOCSPResponse 使用响应者证书及私钥(SM2或RSA)签发successful状态的BasicOCSPResponse
+func CreateOCSPResponse(template *OCSPResponseTemplate, responder *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	if err := checkKeyMatchesCertificate(key, responder); err != nil {
+		return nil, err
+	}
+	responderID := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: responder.RawSubject}
+	if template.ResponderIDByKey {
+		publicKey, err := publicKeyBitString(responder.RawSubjectPublicKeyInfo)
+		if err != nil {
+			return nil, fmt.Errorf("响应者证书%v", err)
+		}
+		keyHash := sha1.Sum(publicKey)
+		value, err := asn1.Marshal(keyHash[:])
+		if err != nil {
+			return nil, err
+		}
+		responderID = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: value}
+	}
+
+	data := ocspResponseDataASN1{
+		ResponderID: responderID,
+		ProducedAt:  template.ProducedAt.UTC().Truncate(time.Second),
+	}
+	for _, entry := range template.Entries {
+		single, err := buildOCSPSingleResponse(entry)
+		if err != nil {
+			return nil, err
+		}
+		data.Responses = append(data.Responses, single)
+	}
+	if len(template.NonceExtension) > 0 {
+		data.Extensions = append(data.Extensions, pkix.Extension{Id: oidOCSPNonce, Value: template.NonceExtension})
+	}
+	tbs, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("编码ResponseData失败: %v", err)
+	}
+	algorithm, signature, err := signTBS(key, tbs)
+	if err != nil {
+		return nil, err
+	}
+	basic := basicOCSPResponseASN1{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: algorithm,
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	for _, cert := range template.Certificates {
+		basic.Certificates = append(basic.Certificates, asn1.RawValue{FullBytes: cert.Raw})
+	}
+	basicDER, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, fmt.Errorf("编码BasicOCSPResponse失败: %v", err)
+	}
+	return asn1.Marshal(ocspResponseASN1{
+		Response: ocspResponseBytesASN1{ResponseType: oidOCSPBasic, Response: basicDER},
+	})
+}
+
+func buildOCSPSingleResponse(entry OCSPResponseEntry) (ocspSingleResponseASN1, error) {
+	single := ocspSingleResponseASN1{
+		CertID:     entry.CertID.marshalASN1(),
+		ThisUpdate: entry.ThisUpdate.UTC().Truncate(time.Second),
+	}
+	if !entry.NextUpdate.IsZero() {
+		single.NextUpdate = entry.NextUpdate.UTC().Truncate(time.Second)
+	}
+	switch entry.Status {
+	case OCSPStatusGood:
+		single.Good = true
+	case OCSPStatusUnknown:
+		single.Unknown = true
+	case OCSPStatusRevoked:
+		single.Revoked.RevocationTime = entry.RevocationTime.UTC().Truncate(time.Second)
+		if entry.ReasonCode != CRLReasonOmitted {
+			reason, err := asn1.Marshal(asn1.Enumerated(entry.ReasonCode))
+			if err != nil {
+				return single, err
+			}
+			single.Revoked.Reason = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: reason}
+		}
+	default:
+		return single, fmt.Errorf("未知的证书状态: %s", entry.Status)
+	}
+	return single, nil
+}
+
+// CreateOCSPErrorResponse 构造不含responseBytes的错误状态响应，如malformedRequest(1)、unauthorized(6)
+func CreateOCSPErrorResponse(status int) []byte {
+	der, _ := asn1.Marshal(ocspResponseASN1{Status: asn1.Enumerated(status)})
+	return der
+}
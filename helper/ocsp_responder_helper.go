@@ -0,0 +1,343 @@
+package helper
+
+import (
+	"HeTu/util"
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+const (
+	// DefaultOCSPResponderAddr 本地OCSP服务默认监听地址
+	DefaultOCSPResponderAddr = "127.0.0.1:8888"
+	// maxOCSPRequestSize 单个OCSP请求的最大长度
+	maxOCSPRequestSize = 64 * 1024
+	// OCSPResponseStatus取值
+	ocspMalformedRequest = 1
+	ocspInternalError    = 2
+)
+
+// OCSPStatusEntry 手工指定的证书状态
+type OCSPStatusEntry struct {
+	SerialNumber *big.Int
+	Status       string
+	// RevocationTime、ReasonCode仅在Status为revoked时使用
+	RevocationTime time.Time
+	ReasonCode     int
+}
+
+// OCSPResponderConfig 本地OCSP服务配置
+type OCSPResponderConfig struct {
+	// Issuer 被查询证书的颁发者(CA)证书，CertID与之不匹配的查询返回unknown
+	Issuer *x509.Certificate
+	// Certificate、Key 响应签名证书及私钥(SM2或RSA)，Certificate为空时由CA直接签名
+	Certificate *x509.Certificate
+	Key         crypto.Signer
+	// CRL 状态来源，未列入CRL的证书返回good
+	CRL *CRLInfo
+	// Statuses 手工状态表，优先于CRL；两者均未命中时返回unknown
+	Statuses []OCSPStatusEntry
+	// Validity 手工状态响应的有效期(nextUpdate-thisUpdate)，为0时不包含nextUpdate；CRL来源沿用CRL的更新时间
+	Validity         time.Duration
+	ResponderIDByKey bool
+}
+
+// OCSPRequestLog 本地OCSP服务收到的单个请求
+type OCSPRequestLog struct {
+	Time           time.Time
+	RemoteAddr     string
+	Method         string
+	SerialNumbers  []string
+	Statuses       []string
+	Nonce          []byte
+	ResponseStatus int
+	Error          string
+}
+
+// String 单行日志文本
+func (l OCSPRequestLog) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", util.ToBeijingTime(l.Time).Format(util.DateTime), l.RemoteAddr, l.Method)
+	for i, serial := range l.SerialNumbers {
+		fmt.Fprintf(&b, " %s=%s", serial, l.Statuses[i])
+	}
+	if len(l.Nonce) > 0 {
+		fmt.Fprintf(&b, " nonce=%s", hex.EncodeToString(l.Nonce))
+	}
+	if l.ResponseStatus != 0 {
+		fmt.Fprintf(&b, " 响应状态: %s", ocspResponseStatusText(l.ResponseStatus))
+	}
+	if l.Error != "" {
+		fmt.Fprintf(&b, " 错误: %s", l.Error)
+	}
+	return b.String()
+}
+
+// OCSPResponder 本地OCSP响应服务，实现http.Handler，可直接交给httptest.NewServer或http.Server
+type OCSPResponder struct {
+	// Log 每收到一个请求调用一次，调用已串行化但不在主goroutine中
+	Log func(OCSPRequestLog)
+	// Warnings 配置中发现的问题，如委托响应者缺少OCSPSigning用途、CRL签名无效等，服务仍按配置响应
+	Warnings []string
+
+	config    OCSPResponderConfig
+	responder *x509.Certificate
+	delegated bool
+	statuses  map[string]OCSPStatusEntry
+	mu        sync.Mutex
+}
+
+// NewOCSPResponder 检查配置并创建本地OCSP服务
+func NewOCSPResponder(config OCSPResponderConfig) (*OCSPResponder, error) {
+	if config.Issuer == nil {
+		return nil, fmt.Errorf("必须提供颁发者证书")
+	}
+	if config.Key == nil {
+		return nil, fmt.Errorf("必须提供响应签名私钥")
+	}
+	responder := &OCSPResponder{config: config, responder: config.Certificate, statuses: map[string]OCSPStatusEntry{}}
+	if responder.responder == nil {
+		responder.responder = config.Issuer
+	}
+	if err := checkKeyMatchesCertificate(config.Key, responder.responder); err != nil {
+		return nil, fmt.Errorf("响应签名证书: %v", err)
+	}
+	if !isSameCertificate(responder.responder, config.Issuer) {
+		responder.delegated = true
+		if err := checkDelegatedResponder(responder.responder, config.Issuer, time.Now()); err != nil {
+			responder.Warnings = append(responder.Warnings, err.Error())
+		}
+	}
+	for _, entry := range config.Statuses {
+		if _, ok := ocspStatusTexts[entry.Status]; !ok {
+			return nil, fmt.Errorf("序列号%X的状态无效: %s", entry.SerialNumber, entry.Status)
+		}
+		responder.statuses[serialHex(entry.SerialNumber)] = entry
+	}
+	if crl := config.CRL; crl != nil {
+		// 未经颁发者验证的CRL或单独的增量CRL不能作为签名应答的依据
+		if err := VerifyCRL(crl, config.Issuer); err != nil {
+			return nil, fmt.Errorf("CRL签名验证失败: %v", err)
+		}
+		if crl.DeltaCRLIndicator != nil {
+			return nil, fmt.Errorf("不支持以增量CRL作为状态来源，请加载完整CRL")
+		}
+		responder.Warnings = append(responder.Warnings, crl.CheckFreshness(time.Now())...)
+		// 预先建立索引，避免并发查询时重复构建
+		if crl.index == nil {
+			crl.buildIndex()
+		}
+	}
+	return responder, nil
+}
+
+// ParseOCSPStatusTable 解析手工状态表，每行(或以分号分隔)一个条目，格式为
+// "序列号(Hex),状态[,吊销原因[,吊销时间]]"，状态为good、revoked、unknown或对应中文名称
+func ParseOCSPStatusTable(text string) ([]OCSPStatusEntry, error) {
+	var entries []OCSPStatusEntry
+	lines := strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ';' })
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 || len(fields) > 4 {
+			return nil, fmt.Errorf("第%d个条目格式错误: %s", i+1, line)
+		}
+		for len(fields) < 4 {
+			fields = append(fields, "")
+		}
+		serial, err := ConvertSerialNumberToBigInt(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("第%d个条目: %v", i+1, err)
+		}
+		entry := OCSPStatusEntry{SerialNumber: serial, ReasonCode: CRLReasonOmitted}
+		if entry.Status, err = parseOCSPStatus(fields[1]); err != nil {
+			return nil, fmt.Errorf("第%d个条目: %v", i+1, err)
+		}
+		if entry.Status != OCSPStatusRevoked {
+			if strings.TrimSpace(fields[2]+fields[3]) != "" {
+				return nil, fmt.Errorf("第%d个条目: 仅revoked状态可指定吊销原因及时间", i+1)
+			}
+			entries = append(entries, entry)
+			continue
+		}
+		if entry.ReasonCode, err = ParseRevocationReasonCode(fields[2]); err != nil {
+			return nil, fmt.Errorf("第%d个条目: %v", i+1, err)
+		}
+		if entry.RevocationTime, err = util.ParseDateTime(fields[3]); err != nil {
+			return nil, fmt.Errorf("第%d个条目吊销时间: %v", i+1, err)
+		}
+		if entry.RevocationTime.IsZero() {
+			entry.RevocationTime = time.Now()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseOCSPStatus(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for status, text := range ocspStatusTexts {
+		if strings.EqualFold(s, status) || s == text {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("无法识别的证书状态: %s", s)
+}
+
+// Respond 根据DER编码的请求生成响应，解析失败时返回malformedRequest响应，log记录请求内容及结果
+func (responder *OCSPResponder) Respond(request []byte) ([]byte, OCSPRequestLog) {
+	now := time.Now()
+	log := OCSPRequestLog{Time: now}
+	req, err := ParseOCSPRequest(request)
+	if err == nil && len(req.CertIDs) == 0 {
+		err = fmt.Errorf("请求不包含待查询证书")
+	}
+	if err != nil {
+		log.ResponseStatus = ocspMalformedRequest
+		log.Error = err.Error()
+		return CreateOCSPErrorResponse(ocspMalformedRequest), log
+	}
+
+	template := &OCSPResponseTemplate{ProducedAt: now, ResponderIDByKey: responder.config.ResponderIDByKey}
+	if responder.delegated {
+		template.Certificates = []*x509.Certificate{responder.responder}
+	}
+	for _, ext := range req.Extensions {
+		if ext.Id.Equal(oidOCSPNonce) {
+			template.NonceExtension = ext.Value
+		}
+	}
+	log.Nonce = req.Nonce
+	for _, id := range req.CertIDs {
+		entry := responder.lookup(id, now)
+		template.Entries = append(template.Entries, entry)
+		log.SerialNumbers = append(log.SerialNumbers, fmt.Sprintf("%X", id.SerialNumber))
+		log.Statuses = append(log.Statuses, entry.Status)
+	}
+	response, err := CreateOCSPResponse(template, responder.responder, responder.config.Key)
+	if err != nil {
+		log.ResponseStatus = ocspInternalError
+		log.Error = err.Error()
+		return CreateOCSPErrorResponse(ocspInternalError), log
+	}
+	return response, log
+}
+
+// lookup 依次按CertID颁发者、手工状态表、CRL确定证书状态
+func (responder *OCSPResponder) lookup(id OCSPCertID, now time.Time) OCSPResponseEntry {
+	entry := OCSPResponseEntry{CertID: id, Status: OCSPStatusUnknown, ReasonCode: CRLReasonOmitted, ThisUpdate: now}
+	if responder.config.Validity > 0 {
+		entry.NextUpdate = now.Add(responder.config.Validity)
+	}
+	if !id.MatchesIssuer(responder.config.Issuer) {
+		return entry
+	}
+	if status, ok := responder.statuses[serialHex(id.SerialNumber)]; ok {
+		entry.Status = status.Status
+		entry.RevocationTime = status.RevocationTime
+		entry.ReasonCode = status.ReasonCode
+		return entry
+	}
+	crl := responder.config.CRL
+	if crl == nil {
+		return entry
+	}
+	entry.ThisUpdate = crl.ThisUpdate
+	entry.NextUpdate = crl.NextUpdate
+	revoked := crl.lookupSerial(id.SerialNumber, responder.config.Issuer)
+	if revoked == nil {
+		// CRL已超过nextUpdate时无法确认证书仍未吊销，返回unknown而非过期的good
+		if !crl.IsStale(now) {
+			entry.Status = OCSPStatusGood
+		}
+	} else {
+		entry.Status = OCSPStatusRevoked
+		entry.RevocationTime = revoked.RevocationTime
+		entry.ReasonCode = revoked.ReasonCode
+	}
+	return entry
+}
+
+// ServeHTTP 支持RFC 6960附录A的POST及GET(路径为URL编码的Base64请求)方式
+func (responder *OCSPResponder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request []byte
+	var readErr error
+	switch r.Method {
+	case http.MethodPost:
+		request, readErr = io.ReadAll(io.LimitReader(r.Body, maxOCSPRequestSize+1))
+		if readErr == nil && len(request) > maxOCSPRequestSize {
+			readErr = fmt.Errorf("请求超过%d字节", maxOCSPRequestSize)
+		}
+	case http.MethodGet:
+		request, readErr = decodeOCSPGetRequest(strings.TrimPrefix(r.URL.Path, "/"))
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var response []byte
+	var log OCSPRequestLog
+	if readErr != nil {
+		response = CreateOCSPErrorResponse(ocspMalformedRequest)
+		log = OCSPRequestLog{Time: time.Now(), ResponseStatus: ocspMalformedRequest, Error: readErr.Error()}
+	} else {
+		response, log = responder.Respond(request)
+	}
+	log.RemoteAddr = r.RemoteAddr
+	log.Method = r.Method
+	responder.log(log)
+
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.Write(response)
+}
+
+func (responder *OCSPResponder) log(entry OCSPRequestLog) {
+	if responder.Log == nil {
+		return
+	}
+	responder.mu.Lock()
+	defer responder.mu.Unlock()
+	responder.Log(entry)
+}
+
+// decodeOCSPGetRequest 解码GET请求路径中的Base64，兼容未做URL编码及URL安全字符集的实现
+func decodeOCSPGetRequest(path string) ([]byte, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("GET请求路径为空")
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if der, err := encoding.DecodeString(path); err == nil {
+			return der, nil
+		}
+	}
+	return nil, fmt.Errorf("GET请求路径不是有效的Base64")
+}
+
+// Listen 在addr(为空时使用DefaultOCSPResponderAddr)上启动HTTP服务，返回服务及访问地址，调用Close停止
+func (responder *OCSPResponder) Listen(addr string) (*http.Server, string, error) {
+	if addr == "" {
+		addr = DefaultOCSPResponderAddr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("监听%s失败: %v", addr, err)
+	}
+	server := &http.Server{Handler: responder, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	return server, "http://" + listener.Addr().String(), nil
+}
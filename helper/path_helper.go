@@ -0,0 +1,914 @@
+package helper
+
+import (
+	"HeTu/util"
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	gmx509 "github.com/zaneway/cain-go/x509"
+)
+
+const (
+	anyPolicy            = "2.5.29.32.0"
+	anyExtKeyUsage       = "2.5.29.37.0"
+	extKeyUsageExtension = "2.5.29.37"
+	// maxPathDepth 路径构建的最大深度，防止异常证书池导致的过深搜索
+	maxPathDepth = 10
+	// maxPaths 最多返回的路径数量
+	maxPaths = 8
+)
+
+var oidEmailAddressAttribute = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+
+// handledCriticalExtensions 路径验证已处理的扩展，其余关键扩展视为无法识别；
+// 扩展密钥用法按PathOptions.KeyPurposes检查
+var handledCriticalExtensions = map[string]bool{
+	"2.5.29.14": true,
+	"2.5.29.15": true,
+	"2.5.29.17": true,
+	"2.5.29.19": true,
+	"2.5.29.30": true,
+	"2.5.29.32": true,
+	"2.5.29.33": true,
+	"2.5.29.35": true,
+	"2.5.29.36": true,
+	"2.5.29.37": true,
+	"2.5.29.54": true,
+}
+
+// PathOptions 路径构建与验证参数
+type PathOptions struct {
+	// Roots 信任锚，为空时以证书池中的自签名证书作为信任锚
+	Roots []*gmx509.Certificate
+	// Intermediates 无序的中间证书池
+	Intermediates []*gmx509.Certificate
+	// Time 验证时间，零值表示当前时间
+	Time time.Time
+	// InitialPolicies 初始策略集(OID)，为空表示anyPolicy
+	InitialPolicies []string
+	// CRLs 用于吊销检查的CRL，为空时不检查吊销状态
+	CRLs                  []*pkix.CertificateList
+	RequireExplicitPolicy bool
+	InhibitPolicyMapping  bool
+	InhibitAnyPolicy      bool
+	// KeyPurposes 要求的扩展密钥用法(OID)，满足其一即可；为空表示不限用途。
+	// 路径中包含扩展密钥用法扩展的证书须包含所要求的用途或anyExtendedKeyUsage
+	KeyPurposes []string
+}
+
+// PathCheck 单项检查结果
+type PathCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Warning 检查未失败但需要关注，如吊销状态未知、CRL已过期
+	Warning bool   `json:"warning,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// PathElement 路径中的一张证书及其检查结果
+type PathElement struct {
+	Certificate  *gmx509.Certificate `json:"-"`
+	Subject      string              `json:"subject"`
+	Issuer       string              `json:"issuer"`
+	SerialNumber string              `json:"serialNumber"`
+	TrustAnchor  bool                `json:"trustAnchor"`
+	Checks       []PathCheck         `json:"checks"`
+	Revocation   *RevocationStatus   `json:"revocation,omitempty"`
+}
+
+// Valid 证书的全部检查是否通过
+func (e *PathElement) Valid() bool {
+	for _, check := range e.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *PathElement) addCheck(name string, err error, detail string) {
+	if err != nil {
+		e.Checks = append(e.Checks, PathCheck{Name: name, Detail: err.Error()})
+		return
+	}
+	e.Checks = append(e.Checks, PathCheck{Name: name, Passed: true, Detail: detail})
+}
+
+// CertificatePath 从终端证书到信任锚的一条路径，Elements按终端证书在前排列
+type CertificatePath struct {
+	Elements []*PathElement `json:"elements"`
+	Anchored bool           `json:"anchored"`
+	Valid    bool           `json:"valid"`
+	// Policies 路径末端的有效策略集
+	Policies []string `json:"policies,omitempty"`
+	// Errors 路径级错误，如未找到信任锚、显式策略要求未满足
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PathValidation 一张终端证书的路径构建与验证结果
+type PathValidation struct {
+	Subject string             `json:"subject"`
+	Time    time.Time          `json:"time"`
+	Notes   []string           `json:"notes,omitempty"`
+	Paths   []*CertificatePath `json:"paths"`
+}
+
+// Valid 是否至少存在一条通过验证的路径
+func (v *PathValidation) Valid() bool {
+	return len(v.Paths) > 0 && v.Paths[0].Valid
+}
+
+// ValidateCertificatePath 从无序证书池中按名称及AKI/SKI构建终端证书到信任锚的路径，
+// 并逐证书检查签名、有效期、基本约束/路径长度、keyCertSign、名称约束、证书策略及关键扩展。
+// 信任锚的路径长度与名称约束同样生效，策略相关扩展从信任锚之下的证书开始处理
+func ValidateCertificatePath(leaf *gmx509.Certificate, opts PathOptions) *PathValidation {
+	result := &PathValidation{Subject: leaf.Subject.String(), Time: opts.Time}
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+
+	builder := &pathBuilder{anchors: opts.Roots, pool: opts.Intermediates}
+	if len(builder.anchors) == 0 {
+		for _, cert := range append([]*gmx509.Certificate{leaf}, opts.Intermediates...) {
+			if isSelfSigned(cert) && !containsCertificate(builder.anchors, cert) {
+				builder.anchors = append(builder.anchors, cert)
+			}
+		}
+		result.Notes = append(result.Notes, fmt.Sprintf("未指定信任锚，以证书池中的%d张自签名证书作为信任锚", len(builder.anchors)))
+	}
+	builder.extend([]*gmx509.Certificate{leaf})
+
+	for _, chain := range builder.paths {
+		result.Paths = append(result.Paths, validatePath(chain, true, opts, result.Time))
+	}
+	if len(result.Paths) == 0 {
+		result.Paths = append(result.Paths, validatePath(builder.partial, false, opts, result.Time))
+	}
+	// 通过验证的路径优先，其次为较短的路径
+	sort.SliceStable(result.Paths, func(i, j int) bool {
+		if result.Paths[i].Valid != result.Paths[j].Valid {
+			return result.Paths[i].Valid
+		}
+		return len(result.Paths[i].Elements) < len(result.Paths[j].Elements)
+	})
+	return result
+}
+
+// FindLeafCertificates 返回证书池中未签发池内其他证书的证书，即可能的终端证书
+func FindLeafCertificates(certs []*gmx509.Certificate) []*gmx509.Certificate {
+	var leaves []*gmx509.Certificate
+	for _, cert := range certs {
+		issuesOther := false
+		for _, other := range certs {
+			if other != cert && !bytes.Equal(other.Raw, cert.Raw) && isIssuerOf(cert, other) {
+				issuesOther = true
+				break
+			}
+		}
+		if !issuesOther {
+			leaves = append(leaves, cert)
+		}
+	}
+	if len(leaves) == 0 && len(certs) > 0 {
+		leaves = certs[:1]
+	}
+	return leaves
+}
+
+// CheckCertificateSignature 使用上级证书公钥验证证书签名，SM2签名使用默认用户ID
+func CheckCertificateSignature(cert, issuer *gmx509.Certificate) error {
+	pub, err := CertificatePublicKey(issuer)
+	if err != nil {
+		return fmt.Errorf("解析上级证书公钥失败: %v", err)
+	}
+	// cain-go未解析SM2证书公钥，补充公钥后使用其签名验证
+	signer := &gmx509.Certificate{PublicKey: pub}
+	if err := signer.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		return fmt.Errorf("签名验证失败: %v", err)
+	}
+	return nil
+}
+
+// pathBuilder 深度优先构建证书路径
+type pathBuilder struct {
+	anchors []*gmx509.Certificate
+	pool    []*gmx509.Certificate
+	paths   [][]*gmx509.Certificate
+	// partial 未能到达信任锚时最长的部分路径
+	partial []*gmx509.Certificate
+}
+
+func (b *pathBuilder) extend(path []*gmx509.Certificate) {
+	if len(b.paths) >= maxPaths {
+		return
+	}
+	current := path[len(path)-1]
+	if containsCertificate(b.anchors, current) {
+		b.paths = append(b.paths, append([]*gmx509.Certificate(nil), path...))
+		return
+	}
+	extended := false
+	if len(path) < maxPathDepth {
+		for _, candidate := range b.candidates(current) {
+			if containsCertificate(path, candidate) {
+				continue
+			}
+			extended = true
+			b.extend(append(append([]*gmx509.Certificate(nil), path...), candidate))
+		}
+	}
+	if !extended && len(path) > len(b.partial) {
+		b.partial = append([]*gmx509.Certificate(nil), path...)
+	}
+}
+
+// candidates 返回可能签发cert的证书，信任锚优先
+func (b *pathBuilder) candidates(cert *gmx509.Certificate) []*gmx509.Certificate {
+	var result []*gmx509.Certificate
+	for _, candidate := range append(append([]*gmx509.Certificate(nil), b.anchors...), b.pool...) {
+		if isIssuerOf(candidate, cert) && !containsCertificate(result, candidate) {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// isIssuerOf 判断issuer的主题是否与cert的颁发者一致，且双方均有密钥标识时AKI与SKI一致
+func isIssuerOf(issuer, cert *gmx509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, issuer.RawSubject) && cert.Issuer.String() != issuer.Subject.String() {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, issuer.SubjectKeyId)
+	}
+	return true
+}
+
+func isSelfIssued(cert *gmx509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject)
+}
+
+func isSelfSigned(cert *gmx509.Certificate) bool {
+	return isSelfIssued(cert) && CheckCertificateSignature(cert, cert) == nil
+}
+
+func containsCertificate(certs []*gmx509.Certificate, cert *gmx509.Certificate) bool {
+	for _, c := range certs {
+		if bytes.Equal(c.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathLenConstraint 返回基本约束中的路径长度限制
+func pathLenConstraint(cert *gmx509.Certificate) (int, bool) {
+	if !cert.BasicConstraintsValid {
+		return 0, false
+	}
+	if cert.MaxPathLen > 0 || (cert.MaxPathLen == 0 && cert.MaxPathLenZero) {
+		return cert.MaxPathLen, true
+	}
+	return 0, false
+}
+
+// findExtension 返回指定OID的扩展值
+func findExtension(cert *gmx509.Certificate, oid string) ([]byte, bool) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.String() == oid {
+			return ext.Value, true
+		}
+	}
+	return nil, false
+}
+
+// validatePath 按RFC 5280 第6.1节自信任锚向终端证书依次处理路径
+func validatePath(chain []*gmx509.Certificate, anchored bool, opts PathOptions, at time.Time) *CertificatePath {
+	path := &CertificatePath{Anchored: anchored}
+	for _, cert := range chain {
+		path.Elements = append(path.Elements, &PathElement{
+			Certificate:  cert,
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: strings.ToUpper(cert.SerialNumber.Text(16)),
+		})
+	}
+
+	n := len(chain) - 1
+	top := chain[n]
+	topElement := path.Elements[n]
+	topElement.TrustAnchor = anchored
+	if anchored {
+		topElement.addCheck("信任锚", nil, "证书位于信任锚列表中")
+	} else {
+		if isSelfIssued(top) {
+			path.Errors = append(path.Errors, "路径终止于不受信任的自签名证书")
+		} else {
+			path.Errors = append(path.Errors, "未找到上级证书: "+top.Issuer.String())
+		}
+		topElement.addCheck("信任锚", fmt.Errorf("未找到信任锚"), "")
+	}
+	topElement.addCheck("有效期", checkValidity(top, at), validityDetail(top))
+	if isSelfIssued(top) {
+		topElement.addCheck("自签名", CheckCertificateSignature(top, top), "自签名验证通过")
+	}
+	if n > 0 {
+		topElement.addCheck("密钥用法", checkKeyCertSign(top), "包含keyCertSign")
+	}
+
+	maxPathLength := n
+	if pathLen, ok := pathLenConstraint(top); ok && pathLen < maxPathLength {
+		maxPathLength = pathLen
+	}
+	var constraints []nameConstraintSource
+	if nc, err := certificateNameConstraints(top); err != nil {
+		topElement.addCheck("名称约束", err, "")
+	} else if nc != nil {
+		constraints = append(constraints, nameConstraintSource{issuer: top.Subject.String(), constraints: nc})
+	}
+	policy := newPolicyState(n, opts)
+
+	for idx := n - 1; idx >= 0; idx-- {
+		cert, issuer, element := chain[idx], chain[idx+1], path.Elements[idx]
+		last := idx == 0
+
+		element.addCheck("签名", CheckCertificateSignature(cert, issuer), "由 "+issuer.Subject.String()+" 签发")
+		element.addCheck("有效期", checkValidity(cert, at), validityDetail(cert))
+		if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 {
+			element.addCheck("密钥标识", nil, "AKI与上级SKI一致: "+hex.EncodeToString(cert.AuthorityKeyId))
+		}
+		if len(constraints) > 0 && (last || !isSelfIssued(cert)) {
+			element.addCheck("名称约束", checkNameConstraints(cert, constraints), fmt.Sprintf("符合%d项名称约束", len(constraints)))
+		}
+		element.addCheck("证书策略", policy.processCertificate(cert, !last && isSelfIssued(cert)), policy.describe())
+		if _, present := findExtension(cert, extKeyUsageExtension); present || (last && len(opts.KeyPurposes) > 0) {
+			detail, err := checkExtKeyUsage(cert, opts.KeyPurposes)
+			element.addCheck("扩展密钥用法", err, detail)
+		}
+		element.addCheck("关键扩展", checkCriticalExtensions(cert), "关键扩展均已处理")
+		if len(opts.CRLs) > 0 {
+			element.Revocation = CheckRevocationWithCRLs(cert, issuer, opts.CRLs, at)
+			element.Checks = append(element.Checks, revocationCheck(element.Revocation))
+		}
+
+		if last {
+			continue
+		}
+		// 为下一张证书做准备: 当前证书作为CA
+		if !cert.BasicConstraintsValid || !cert.IsCA {
+			element.addCheck("基本约束", fmt.Errorf("非CA证书不能签发证书"), "")
+		} else {
+			element.addCheck("基本约束", nil, "CA: true")
+		}
+		if !isSelfIssued(cert) {
+			if maxPathLength <= 0 {
+				element.addCheck("路径长度", fmt.Errorf("超出上级证书的路径长度约束"), "")
+			}
+			maxPathLength--
+		}
+		if pathLen, ok := pathLenConstraint(cert); ok && pathLen < maxPathLength {
+			maxPathLength = pathLen
+		}
+		if maxPathLength >= 0 {
+			element.addCheck("路径长度", nil, fmt.Sprintf("其下还可有%d级非自颁发CA", maxPathLength))
+		}
+		element.addCheck("密钥用法", checkKeyCertSign(cert), "包含keyCertSign")
+		if nc, err := certificateNameConstraints(cert); err != nil {
+			element.addCheck("名称约束", err, "")
+		} else if nc != nil {
+			constraints = append(constraints, nameConstraintSource{issuer: cert.Subject.String(), constraints: nc})
+		}
+		if err := policy.prepareNext(cert); err != nil {
+			element.addCheck("策略映射", err, "")
+		}
+	}
+
+	if n > 0 {
+		var err error
+		path.Policies, err = policy.wrapUp(chain[0], opts.InitialPolicies)
+		if err != nil {
+			path.Errors = append(path.Errors, err.Error())
+		}
+	}
+
+	path.Valid = anchored && len(path.Errors) == 0
+	for _, element := range path.Elements {
+		if !element.Valid() {
+			path.Valid = false
+		}
+	}
+	return path
+}
+
+// revocationCheck 将吊销状态转换为检查项，状态未知或CRL过期时给出警告
+func revocationCheck(status *RevocationStatus) PathCheck {
+	check := PathCheck{Name: "吊销状态", Passed: status.Status != RevocationRevoked}
+	var details []string
+	switch status.Status {
+	case RevocationRevoked:
+		details = append(details, fmt.Sprintf("已于 %s 吊销，原因: %s", util.ToBeijingTime(status.RevocationTime).Format(util.DateTime), status.Reason))
+	case RevocationGood:
+		details = append(details, "未吊销")
+	default:
+		check.Warning = true
+		details = append(details, "未知")
+	}
+	if status.Status != RevocationUnknown {
+		details = append(details, fmt.Sprintf("CRL %s 至 %s", util.ToBeijingTime(status.ThisUpdate).Format(util.DateTime), formatNextUpdate(status.NextUpdate)))
+	}
+	if status.Stale {
+		check.Warning = true
+		details = append(details, "CRL已过期")
+	}
+	details = append(details, status.Notes...)
+	check.Detail = strings.Join(details, "; ")
+	return check
+}
+
+func formatNextUpdate(t time.Time) string {
+	if t.IsZero() {
+		return "未设置"
+	}
+	return util.ToBeijingTime(t).Format(util.DateTime)
+}
+
+func checkValidity(cert *gmx509.Certificate, at time.Time) error {
+	if at.Before(cert.NotBefore) {
+		return fmt.Errorf("证书尚未生效 (%s)", validityDetail(cert))
+	}
+	if at.After(cert.NotAfter) {
+		return fmt.Errorf("证书已过期 (%s)", validityDetail(cert))
+	}
+	return nil
+}
+
+func validityDetail(cert *gmx509.Certificate) string {
+	return util.ToBeijingTime(cert.NotBefore).Format(util.DateTime) + " 至 " + util.ToBeijingTime(cert.NotAfter).Format(util.DateTime)
+}
+
+func checkKeyCertSign(cert *gmx509.Certificate) error {
+	if cert.KeyUsage != 0 && cert.KeyUsage&gmx509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("密钥用法不包含keyCertSign")
+	}
+	return nil
+}
+
+// checkExtKeyUsage 检查扩展密钥用法的编码，并在purposes非空时要求包含其一或anyExtendedKeyUsage，
+// 未包含该扩展的证书不限用途
+func checkExtKeyUsage(cert *gmx509.Certificate, purposes []string) (string, error) {
+	value, present := findExtension(cert, extKeyUsageExtension)
+	if !present {
+		return "未限制扩展密钥用法", nil
+	}
+	var oids []asn1.ObjectIdentifier
+	if err := unmarshalExact(value, &oids); err != nil || len(oids) == 0 {
+		return "", fmt.Errorf("扩展密钥用法编码无效")
+	}
+	usages := make([]string, len(oids))
+	names := make([]string, len(oids))
+	for i, oid := range oids {
+		usages[i] = oid.String()
+		names[i] = usages[i]
+		if name, ok := ExtKeyUsageNames[usages[i]]; ok {
+			names[i] = name
+		}
+	}
+	detail := strings.Join(names, ", ")
+	if len(purposes) == 0 || slices.Contains(usages, anyExtKeyUsage) {
+		return detail, nil
+	}
+	for _, purpose := range purposes {
+		if slices.Contains(usages, purpose) {
+			return detail, nil
+		}
+	}
+	return "", fmt.Errorf("扩展密钥用法(%s)不包含要求的用途: %s", detail, strings.Join(purposes, ", "))
+}
+
+func checkCriticalExtensions(cert *gmx509.Certificate) error {
+	var unknown []string
+	for _, ext := range cert.Extensions {
+		if ext.Critical && !handledCriticalExtensions[ext.Id.String()] {
+			unknown = append(unknown, ext.Id.String())
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("无法识别的关键扩展: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// nameConstraintSource 名称约束及其所在的CA证书
+type nameConstraintSource struct {
+	issuer      string
+	constraints *NameConstraints
+}
+
+func certificateNameConstraints(cert *gmx509.Certificate) (*NameConstraints, error) {
+	value, ok := findExtension(cert, "2.5.29.30")
+	if !ok {
+		return nil, nil
+	}
+	return ParseNameConstraints(value)
+}
+
+// certificateNames 收集参与名称约束检查的名称: 主题DN、主题中的邮件地址及SAN
+func certificateNames(cert *gmx509.Certificate) ([]GeneralName, error) {
+	var names []GeneralName
+	var rdn pkix.RDNSequence
+	if err := unmarshalExact(cert.RawSubject, &rdn); err != nil {
+		return nil, fmt.Errorf("解析主题失败: %v", err)
+	}
+	if len(rdn) > 0 {
+		names = append(names, GeneralName{Tag: 4, Type: "DirName", Value: cert.Subject.String(), raw: cert.RawSubject})
+	}
+	for _, set := range rdn {
+		for _, attr := range set {
+			if email, ok := attr.Value.(string); ok && attr.Type.Equal(oidEmailAddressAttribute) {
+				names = append(names, GeneralName{Tag: 1, Type: "email", Value: email})
+			}
+		}
+	}
+	if value, ok := findExtension(cert, "2.5.29.17"); ok {
+		san, err := ParseGeneralNames(value)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, san...)
+	}
+	return names, nil
+}
+
+// checkNameConstraints 检查证书名称是否满足路径上各CA的名称约束
+func checkNameConstraints(cert *gmx509.Certificate, sources []nameConstraintSource) error {
+	names, err := certificateNames(cert)
+	if err != nil {
+		return err
+	}
+	var failures []string
+	for _, source := range sources {
+		for _, name := range names {
+			permitted, constrained := false, false
+			for _, subtree := range source.constraints.Permitted {
+				if subtree.Base.Tag != name.Tag {
+					continue
+				}
+				constrained = true
+				if matchGeneralName(name, subtree.Base) {
+					permitted = true
+					break
+				}
+			}
+			if constrained && !permitted {
+				failures = append(failures, fmt.Sprintf("%s 不在允许的子树内 (约束来自 %s)", name, source.issuer))
+			}
+			for _, subtree := range source.constraints.Excluded {
+				if subtree.Base.Tag == name.Tag && matchGeneralName(name, subtree.Base) {
+					failures = append(failures, fmt.Sprintf("%s 位于排除的子树 %s 内 (约束来自 %s)", name, subtree.Base, source.issuer))
+				}
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// matchGeneralName 判断名称是否位于约束子树内
+func matchGeneralName(name, base GeneralName) bool {
+	switch name.Tag {
+	case 1:
+		return matchEmailConstraint(name.Value, base.Value)
+	case 2:
+		return matchDomainConstraint(name.Value, base.Value)
+	case 4:
+		return matchDirNameConstraint(name.raw, base.raw)
+	case 6:
+		u, err := url.Parse(name.Value)
+		if err != nil || u.Hostname() == "" {
+			return false
+		}
+		return matchHostConstraint(u.Hostname(), base.Value)
+	case 7:
+		ip, constraint := name.raw, base.raw
+		if len(constraint) != 2*len(ip) {
+			return false
+		}
+		for i := range ip {
+			mask := constraint[len(ip)+i]
+			if ip[i]&mask != constraint[i]&mask {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// matchDomainConstraint 约束为a.com时匹配a.com及其子域，为.a.com时仅匹配子域
+func matchDomainConstraint(name, constraint string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	constraint = strings.ToLower(strings.TrimSuffix(constraint, "."))
+	if constraint == "" {
+		return true
+	}
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(name, constraint)
+	}
+	return name == constraint || strings.HasSuffix(name, "."+constraint)
+}
+
+// matchHostConstraint URI约束为主机名时精确匹配，以点开头时匹配子域
+func matchHostConstraint(host, constraint string) bool {
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(strings.ToLower(host), strings.ToLower(constraint))
+	}
+	return strings.EqualFold(host, constraint)
+}
+
+// matchEmailConstraint 约束含@时匹配完整邮箱，否则匹配邮箱域名
+func matchEmailConstraint(email, constraint string) bool {
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	return matchHostConstraint(email[at+1:], constraint)
+}
+
+// matchDirNameConstraint 主题DN以约束DN的全部RDN开头时匹配
+func matchDirNameConstraint(name, constraint []byte) bool {
+	var nameRDN, constraintRDN pkix.RDNSequence
+	if unmarshalExact(name, &nameRDN) != nil || unmarshalExact(constraint, &constraintRDN) != nil {
+		return false
+	}
+	if len(constraintRDN) > len(nameRDN) {
+		return false
+	}
+	for i, set := range constraintRDN {
+		if len(set) != len(nameRDN[i]) {
+			return false
+		}
+		for j, attr := range set {
+			other := nameRDN[i][j]
+			if !attr.Type.Equal(other.Type) {
+				return false
+			}
+			if !strings.EqualFold(strings.Join(strings.Fields(fmt.Sprint(attr.Value)), " "), strings.Join(strings.Fields(fmt.Sprint(other.Value)), " ")) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// policyState RFC 5280 策略处理状态，valid为期望策略到其在信任锚域中有效策略的映射，nil表示策略树为空
+type policyState struct {
+	valid          map[string]string
+	explicitPolicy int
+	policyMapping  int
+	inhibitAny     int
+}
+
+func newPolicyState(n int, opts PathOptions) *policyState {
+	state := &policyState{
+		valid:          map[string]string{anyPolicy: anyPolicy},
+		explicitPolicy: n + 1,
+		policyMapping:  n + 1,
+		inhibitAny:     n + 1,
+	}
+	if opts.RequireExplicitPolicy {
+		state.explicitPolicy = 0
+	}
+	if opts.InhibitPolicyMapping {
+		state.policyMapping = 0
+	}
+	if opts.InhibitAnyPolicy {
+		state.inhibitAny = 0
+	}
+	return state
+}
+
+// processCertificate 按证书的certificatePolicies更新有效策略集
+func (s *policyState) processCertificate(cert *gmx509.Certificate, selfIssuedCA bool) error {
+	if s.valid != nil {
+		if len(cert.PolicyIdentifiers) == 0 {
+			s.valid = nil
+		} else {
+			next := make(map[string]string)
+			assertsAny := false
+			for _, oid := range cert.PolicyIdentifiers {
+				policy := oid.String()
+				if policy == anyPolicy {
+					assertsAny = true
+					continue
+				}
+				if root, ok := s.valid[policy]; ok {
+					next[policy] = root
+				} else if _, ok := s.valid[anyPolicy]; ok {
+					next[policy] = policy
+				}
+			}
+			if assertsAny && (s.inhibitAny > 0 || selfIssuedCA) {
+				for expected, root := range s.valid {
+					if _, ok := next[expected]; !ok {
+						next[expected] = root
+					}
+				}
+			}
+			s.valid = next
+			if len(s.valid) == 0 {
+				s.valid = nil
+			}
+		}
+	}
+	if s.explicitPolicy == 0 && s.valid == nil {
+		return fmt.Errorf("要求显式策略，但有效策略集为空")
+	}
+	return nil
+}
+
+// prepareNext 处理CA证书的策略映射、策略约束及禁止任意策略
+func (s *policyState) prepareNext(cert *gmx509.Certificate) error {
+	if value, ok := findExtension(cert, "2.5.29.33"); ok {
+		mappings, err := ParsePolicyMappings(value)
+		if err != nil {
+			return err
+		}
+		subjects := make(map[string][]string)
+		var issuers []string
+		for _, mapping := range mappings {
+			if mapping.IssuerDomainPolicy == anyPolicy || mapping.SubjectDomainPolicy == anyPolicy {
+				return fmt.Errorf("策略映射不能包含anyPolicy")
+			}
+			if _, ok := subjects[mapping.IssuerDomainPolicy]; !ok {
+				issuers = append(issuers, mapping.IssuerDomainPolicy)
+			}
+			subjects[mapping.IssuerDomainPolicy] = append(subjects[mapping.IssuerDomainPolicy], mapping.SubjectDomainPolicy)
+		}
+		if s.valid != nil {
+			next := make(map[string]string, len(s.valid))
+			for expected, root := range s.valid {
+				next[expected] = root
+			}
+			for _, issuerPolicy := range issuers {
+				root, mapped := s.valid[issuerPolicy]
+				_, viaAny := s.valid[anyPolicy]
+				delete(next, issuerPolicy)
+				if s.policyMapping == 0 {
+					continue
+				}
+				if !mapped && viaAny {
+					root, mapped = issuerPolicy, true
+				}
+				if mapped {
+					for _, subjectPolicy := range subjects[issuerPolicy] {
+						next[subjectPolicy] = root
+					}
+				}
+			}
+			s.valid = next
+			if len(s.valid) == 0 {
+				s.valid = nil
+			}
+		}
+	}
+
+	if !isSelfIssued(cert) {
+		for _, counter := range []*int{&s.explicitPolicy, &s.policyMapping, &s.inhibitAny} {
+			if *counter > 0 {
+				*counter--
+			}
+		}
+	}
+	if value, ok := findExtension(cert, "2.5.29.36"); ok {
+		constraints, err := ParsePolicyConstraints(value)
+		if err != nil {
+			return err
+		}
+		if constraints.RequireExplicitPolicy >= 0 && constraints.RequireExplicitPolicy < s.explicitPolicy {
+			s.explicitPolicy = constraints.RequireExplicitPolicy
+		}
+		if constraints.InhibitPolicyMapping >= 0 && constraints.InhibitPolicyMapping < s.policyMapping {
+			s.policyMapping = constraints.InhibitPolicyMapping
+		}
+	}
+	if value, ok := findExtension(cert, "2.5.29.54"); ok {
+		skipCerts, err := ParseInhibitAnyPolicy(value)
+		if err != nil {
+			return err
+		}
+		if skipCerts < s.inhibitAny {
+			s.inhibitAny = skipCerts
+		}
+	}
+	return nil
+}
+
+// wrapUp 处理终端证书的策略约束并与初始策略集求交，返回最终有效策略
+func (s *policyState) wrapUp(leaf *gmx509.Certificate, initial []string) ([]string, error) {
+	if s.explicitPolicy > 0 {
+		s.explicitPolicy--
+	}
+	if value, ok := findExtension(leaf, "2.5.29.36"); ok {
+		if constraints, err := ParsePolicyConstraints(value); err == nil && constraints.RequireExplicitPolicy == 0 {
+			s.explicitPolicy = 0
+		}
+	}
+
+	selected := make(map[string]bool)
+	for expected, root := range s.valid {
+		switch {
+		case len(initial) == 0 || slices.Contains(initial, anyPolicy):
+			selected[expected] = true
+		case root == anyPolicy:
+			for _, policy := range initial {
+				selected[policy] = true
+			}
+		case slices.Contains(initial, root):
+			selected[expected] = true
+		}
+	}
+	policies := make([]string, 0, len(selected))
+	for policy := range selected {
+		policies = append(policies, policy)
+	}
+	sort.Strings(policies)
+	if s.explicitPolicy == 0 && len(policies) == 0 {
+		return nil, fmt.Errorf("要求显式策略，但路径不存在可接受的证书策略")
+	}
+	return policies, nil
+}
+
+func (s *policyState) describe() string {
+	if s.valid == nil {
+		return "有效策略集为空"
+	}
+	var policies []string
+	for expected := range s.valid {
+		if expected == anyPolicy {
+			expected = "anyPolicy"
+		}
+		policies = append(policies, expected)
+	}
+	sort.Strings(policies)
+	return "有效策略: " + strings.Join(policies, ", ")
+}
+
+// FormatPathValidation 将路径验证结果格式化为逐证书的可读文本
+func FormatPathValidation(v *PathValidation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "终端证书: %s\n", v.Subject)
+	fmt.Fprintf(&b, "验证时间: %s\n", util.ToBeijingTime(v.Time).Format(util.DateTime))
+	for _, note := range v.Notes {
+		fmt.Fprintf(&b, "说明: %s\n", note)
+	}
+	for i, path := range v.Paths {
+		status := "✅ 验证通过"
+		if !path.Valid {
+			status = "❌ 验证失败"
+		}
+		fmt.Fprintf(&b, "\n路径 #%d (%d张证书): %s\n", i+1, len(path.Elements), status)
+		for _, err := range path.Errors {
+			fmt.Fprintf(&b, "  ❌ %s\n", err)
+		}
+		if len(path.Policies) > 0 {
+			policies := slices.Clone(path.Policies)
+			for j, policy := range policies {
+				if policy == anyPolicy {
+					policies[j] = "anyPolicy"
+				}
+			}
+			fmt.Fprintf(&b, "  有效策略: %s\n", strings.Join(policies, ", "))
+		}
+		for j, element := range path.Elements {
+			anchor := ""
+			if element.TrustAnchor {
+				anchor = " [信任锚]"
+			}
+			fmt.Fprintf(&b, "  [%d] %s%s\n", j+1, element.Subject, anchor)
+			fmt.Fprintf(&b, "      序列号: %s\n", element.SerialNumber)
+			for _, check := range element.Checks {
+				mark := "✅"
+				if !check.Passed {
+					mark = "❌"
+				} else if check.Warning {
+					mark = "⚠️"
+				}
+				fmt.Fprintf(&b, "      %s %s: %s\n", mark, check.Name, check.Detail)
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,372 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/big"
+	"unicode/utf16"
+
+	"github.com/zaneway/cain-go/pkcs12"
+	"github.com/zaneway/cain-go/sm3"
+	"github.com/zaneway/cain-go/sm4"
+)
+
+// PKCS#12 PBE(RFC 7292 附录C) 与 PBES2(RFC 8018) 相关OID
+var (
+	oidPBEWithSHAAnd128BitRC4     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 1}
+	oidPBEWithSHAAnd40BitRC4      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 2}
+	oidPBEWithSHAAnd3KeyTripleDES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd2KeyTripleDES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 4}
+	oidPBEWithSHAAnd128BitRC2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA224 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidHMACWithSM3    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401, 2}
+
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidSM4CBC     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 2}
+
+	oidDigestSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA224 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 4}
+	oidDigestSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidDigestSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidDigestSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidDigestSM3    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}
+)
+
+// PBEAlgorithmNames 口令加密、PRF、分组密码及摘要算法OID与名称的映射
+var PBEAlgorithmNames = map[string]string{
+	oidPBEWithSHAAnd128BitRC4.String():     "pbeWithSHAAnd128BitRC4",
+	oidPBEWithSHAAnd40BitRC4.String():      "pbeWithSHAAnd40BitRC4",
+	oidPBEWithSHAAnd3KeyTripleDES.String(): "pbeWithSHAAnd3-KeyTripleDES-CBC",
+	oidPBEWithSHAAnd2KeyTripleDES.String(): "pbeWithSHAAnd2-KeyTripleDES-CBC",
+	oidPBEWithSHAAnd128BitRC2.String():     "pbeWithSHAAnd128BitRC2-CBC",
+	oidPBEWithSHAAnd40BitRC2.String():      "pbeWithSHAAnd40BitRC2-CBC",
+	oidPBES2.String():                      "PBES2",
+	oidPBKDF2.String():                     "PBKDF2",
+	oidHMACWithSHA1.String():               "HMAC-SHA1",
+	oidHMACWithSHA224.String():             "HMAC-SHA224",
+	oidHMACWithSHA256.String():             "HMAC-SHA256",
+	oidHMACWithSHA384.String():             "HMAC-SHA384",
+	oidHMACWithSHA512.String():             "HMAC-SHA512",
+	oidHMACWithSM3.String():                "HMAC-SM3",
+	oidDESEDE3CBC.String():                 "DES-EDE3-CBC",
+	oidAES128CBC.String():                  "AES-128-CBC",
+	oidAES192CBC.String():                  "AES-192-CBC",
+	oidAES256CBC.String():                  "AES-256-CBC",
+	oidSM4CBC.String():                     "SM4-CBC",
+	oidDigestSHA1.String():                 "SHA1",
+	oidDigestSHA224.String():               "SHA224",
+	oidDigestSHA256.String():               "SHA256",
+	oidDigestSHA384.String():               "SHA384",
+	oidDigestSHA512.String():               "SHA512",
+	oidDigestSM3.String():                  "SM3",
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// pbeAlgorithmName 返回算法OID对应的名称，未知时返回OID
+func pbeAlgorithmName(oid asn1.ObjectIdentifier) string {
+	return oidName(PBEAlgorithmNames, oid)
+}
+
+// DescribePBEAlgorithm 描述口令加密算法及其参数，如 "PBES2 (PBKDF2-HMAC-SHA256, 2048次迭代, AES-256-CBC)"
+func DescribePBEAlgorithm(alg pkix.AlgorithmIdentifier) string {
+	name := pbeAlgorithmName(alg.Algorithm)
+	if alg.Algorithm.Equal(oidPBES2) {
+		var params pbes2Params
+		if err := unmarshalExact(alg.Parameters.FullBytes, &params); err != nil {
+			return name + " (参数解析失败)"
+		}
+		kdf := pbeAlgorithmName(params.KeyDerivationFunc.Algorithm)
+		if params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+			var kdfParams pbkdf2Params
+			if err := unmarshalExact(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err == nil {
+				prf := oidHMACWithSHA1
+				if len(kdfParams.PRF.Algorithm) > 0 {
+					prf = kdfParams.PRF.Algorithm
+				}
+				kdf = fmt.Sprintf("PBKDF2-%s, %d次迭代", pbeAlgorithmName(prf), kdfParams.IterationCount)
+			}
+		}
+		return fmt.Sprintf("%s (%s, %s)", name, kdf, pbeAlgorithmName(params.EncryptionScheme.Algorithm))
+	}
+	var params pbeParams
+	if err := unmarshalExact(alg.Parameters.FullBytes, &params); err == nil {
+		return fmt.Sprintf("%s (%d次迭代)", name, params.Iterations)
+	}
+	return name
+}
+
+// PBEDecrypt 使用口令解密PKCS#12 PBE或PBES2加密的数据并去除PKCS#7填充
+func PBEDecrypt(alg pkix.AlgorithmIdentifier, password string, data []byte) ([]byte, error) {
+	block, iv, err := pbeCipher(alg, password)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := block.BlockSize()
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, fmt.Errorf("密文长度 %d 不是分组长度 %d 的整数倍", len(data), blockSize)
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > blockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("解密失败，口令错误或数据已损坏")
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+// PBEEncrypt 使用口令按算法标识加密数据，补齐PKCS#7填充
+func PBEEncrypt(alg pkix.AlgorithmIdentifier, password string, data []byte) ([]byte, error) {
+	block, iv, err := pbeCipher(alg, password)
+	if err != nil {
+		return nil, err
+	}
+	padding := block.BlockSize() - len(data)%block.BlockSize()
+	padded := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+	return padded, nil
+}
+
+// pbeCipher 根据算法标识与口令派生分组密码及IV
+func pbeCipher(alg pkix.AlgorithmIdentifier, password string) (cipher.Block, []byte, error) {
+	if alg.Algorithm.Equal(oidPBES2) {
+		return pbes2Cipher(alg, password)
+	}
+
+	var params pbeParams
+	if err := unmarshalExact(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, fmt.Errorf("解析PBE参数失败: %v", err)
+	}
+	bmp := bmpPassword(password)
+	deriveKey := func(size int) []byte {
+		return pkcs12KDF(sha1.New, bmp, params.Salt, params.Iterations, 1, size)
+	}
+	deriveIV := func(size int) []byte {
+		return pkcs12KDF(sha1.New, bmp, params.Salt, params.Iterations, 2, size)
+	}
+
+	var block cipher.Block
+	var err error
+	switch {
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDES):
+		block, err = des.NewTripleDESCipher(deriveKey(24))
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd2KeyTripleDES):
+		key := deriveKey(16)
+		block, err = des.NewTripleDESCipher(append(key, key[:8]...))
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2):
+		block, err = pkcs12.New(deriveKey(16), 128)
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2):
+		block, err = pkcs12.New(deriveKey(5), 40)
+	default:
+		return nil, nil, fmt.Errorf("不支持的口令加密算法: %s", pbeAlgorithmName(alg.Algorithm))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, deriveIV(block.BlockSize()), nil
+}
+
+// pbes2Cipher 按PBES2参数以PBKDF2派生密钥，口令按UTF-8字节参与运算
+func pbes2Cipher(alg pkix.AlgorithmIdentifier, password string) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if err := unmarshalExact(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, fmt.Errorf("解析PBES2参数失败: %v", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("不支持的密钥派生算法: %s", pbeAlgorithmName(params.KeyDerivationFunc.Algorithm))
+	}
+	var kdfParams pbkdf2Params
+	if err := unmarshalExact(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, nil, fmt.Errorf("解析PBKDF2参数失败: %v", err)
+	}
+	prf := oidHMACWithSHA1
+	if len(kdfParams.PRF.Algorithm) > 0 {
+		prf = kdfParams.PRF.Algorithm
+	}
+	newHash, err := hashForOID(prf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scheme := params.EncryptionScheme.Algorithm
+	var keyLen int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch {
+	case scheme.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case scheme.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case scheme.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	case scheme.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	case scheme.Equal(oidSM4CBC):
+		keyLen, newCipher = 16, sm4.NewCipher
+	default:
+		return nil, nil, fmt.Errorf("不支持的加密算法: %s", pbeAlgorithmName(scheme))
+	}
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != keyLen {
+		return nil, nil, fmt.Errorf("PBKDF2密钥长度 %d 与 %s 不匹配", kdfParams.KeyLength, pbeAlgorithmName(scheme))
+	}
+
+	var iv []byte
+	if err := unmarshalExact(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("解析IV失败: %v", err)
+	}
+	key := pbkdf2Key(newHash, []byte(password), kdfParams.Salt, kdfParams.IterationCount, keyLen)
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, nil, fmt.Errorf("IV长度 %d 与分组长度不匹配", len(iv))
+	}
+	return block, iv, nil
+}
+
+// hashForOID 根据摘要或HMAC算法OID返回哈希构造函数
+func hashForOID(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case oid.Equal(oidDigestSHA1), oid.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidDigestSHA224), oid.Equal(oidHMACWithSHA224):
+		return sha256.New224, nil
+	case oid.Equal(oidDigestSHA256), oid.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidDigestSHA384), oid.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case oid.Equal(oidDigestSHA512), oid.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	case oid.Equal(oidDigestSM3), oid.Equal(oidHMACWithSM3):
+		return sm3.New, nil
+	}
+	return nil, fmt.Errorf("不支持的摘要算法: %s", pbeAlgorithmName(oid))
+}
+
+// bmpPassword 将口令编码为以两个零字节结尾的BMPString，供PKCS#12密钥派生使用
+func bmpPassword(password string) []byte {
+	units := utf16.Encode([]rune(password))
+	out := make([]byte, 0, 2*len(units)+2)
+	for _, u := range units {
+		out = append(out, byte(u>>8), byte(u))
+	}
+	return append(out, 0, 0)
+}
+
+// pkcs12KDF RFC 7292 附录B.2 密钥派生，id为1派生加密密钥，2派生IV，3派生MAC密钥
+func pkcs12KDF(newHash func() hash.Hash, password, salt []byte, iterations int, id byte, size int) []byte {
+	h := newHash()
+	u, v := h.Size(), h.BlockSize()
+
+	d := bytes.Repeat([]byte{id}, v)
+	i := append(fillWithRepeats(salt, v), fillWithRepeats(password, v)...)
+
+	one := big.NewInt(1)
+	out := make([]byte, 0, size+u)
+	for len(out) < size {
+		h.Reset()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+		for j := 1; j < iterations; j++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		out = append(out, a...)
+		if len(out) >= size {
+			break
+		}
+
+		// I_j = (I_j + B + 1) mod 2^v
+		b := new(big.Int).SetBytes(fillWithRepeats(a, v))
+		for j := 0; j < len(i); j += v {
+			ij := new(big.Int).SetBytes(i[j : j+v])
+			ij.Add(ij, b).Add(ij, one)
+			sum := ij.Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+			clear(i[j : j+v])
+			copy(i[j+v-len(sum):j+v], sum)
+		}
+	}
+	return out[:size]
+}
+
+// fillWithRepeats 将pattern重复填充到v的整数倍长度，pattern为空时返回空
+func fillWithRepeats(pattern []byte, v int) []byte {
+	if len(pattern) == 0 {
+		return nil
+	}
+	outputLen := v * ((len(pattern) + v - 1) / v)
+	return bytes.Repeat(pattern, (outputLen+len(pattern)-1)/len(pattern))[:outputLen]
+}
+
+// pbkdf2Key RFC 8018 PBKDF2密钥派生
+func pbkdf2Key(newHash func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(newHash, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	var counter [4]byte
+	out := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, 0, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		out = prf.Sum(out)
+		t := out[len(out)-hashLen:]
+		u = append(u[:0], t...)
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for x := range u {
+				t[x] ^= u[x]
+			}
+		}
+	}
+	return out[:keyLen]
+}
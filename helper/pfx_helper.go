@@ -0,0 +1,793 @@
+package helper
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/zaneway/cain-go/sm2"
+	gmx509 "github.com/zaneway/cain-go/x509"
+)
+
+// PKCS#12 内容类型、SafeBag类型及属性OID，GM/T 0010 使用1.2.156.10197.6.1.4.2下的内容类型
+var (
+	oidPKCS7Data          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7EncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidPKCS7EnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidGMData             = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 1}
+	oidGMEnvelopedData    = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 3}
+	oidGMEncryptedData    = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCRLBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 4}
+	oidSecretBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 5}
+	oidSafeContentsBag     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 6}
+
+	oidFriendlyName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidX509CertType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidX509CRLType  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 23, 1}
+)
+
+// PFXNames PKCS#12中内容类型、SafeBag类型及属性OID与名称的映射
+var PFXNames = map[string]string{
+	oidPKCS7Data.String():           "data",
+	oidPKCS7EncryptedData.String():  "encryptedData",
+	oidPKCS7EnvelopedData.String():  "envelopedData",
+	oidGMData.String():              "data (GM/T 0010)",
+	oidGMEnvelopedData.String():     "envelopedData (GM/T 0010)",
+	oidGMEncryptedData.String():     "encryptedData (GM/T 0010)",
+	oidKeyBag.String():              "keyBag",
+	oidPKCS8ShroudedKeyBag.String(): "pkcs8ShroudedKeyBag",
+	oidCertBag.String():             "certBag",
+	oidCRLBag.String():              "crlBag",
+	oidSecretBag.String():           "secretBag",
+	oidSafeContentsBag.String():     "safeContentsBag",
+	oidFriendlyName.String():        "friendlyName",
+	oidLocalKeyID.String():          "localKeyId",
+	oidX509CertType.String():        "x509Certificate",
+	oidX509CRLType.String():         "x509CRL",
+	"1.3.6.1.4.1.311.17.1":          "Microsoft CSP Name",
+	"1.3.6.1.4.1.311.17.2":          "Microsoft Local Machine Keyset",
+	"2.16.840.1.113894.746875.1.1":  "Java Trusted Key Usage",
+}
+
+type pfxPDU struct {
+	Version  int
+	AuthSafe pfxContentInfo
+	MacData  pfxMacData `asn1:"optional"`
+}
+
+type pfxContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type pfxMacData struct {
+	Mac        pfxDigestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type pfxDigestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pfxEncryptedData struct {
+	Version              int
+	EncryptedContentInfo pfxEncryptedContentInfo
+}
+
+type pfxEncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type pfxSafeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue      `asn1:"tag:0,explicit"`
+	Attributes []pfxAttributeASN1 `asn1:"set,optional"`
+}
+
+type pfxAttributeASN1 struct {
+	ID     asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+type pfxTypedBag struct {
+	TypeID asn1.ObjectIdentifier
+	Value  asn1.RawValue `asn1:"tag:0,explicit"`
+}
+
+type pfxEncryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// PFXAttribute SafeBag属性
+type PFXAttribute struct {
+	OID    string   `json:"oid"`
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// PFXBag 解析后的SafeBag
+type PFXBag struct {
+	Type                string         `json:"type"`
+	TypeName            string         `json:"typeName"`
+	FriendlyName        string         `json:"friendlyName,omitempty"`
+	LocalKeyID          string         `json:"localKeyId,omitempty"`
+	Attributes          []PFXAttribute `json:"attributes,omitempty"`
+	EncryptionAlgorithm string         `json:"encryptionAlgorithm,omitempty"`
+	// 证书包
+	Subject      string `json:"subject,omitempty"`
+	Issuer       string `json:"issuer,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// 私钥包
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	KeySize      int    `json:"keySize,omitempty"`
+	// 其他包的类型标识，如secretBag的secretTypeId
+	ValueType string `json:"valueType,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	Certificate *gmx509.Certificate `json:"-"`
+	PrivateKey  crypto.Signer       `json:"-"`
+	// PrivateKeyDER 解密后的PKCS#8私钥
+	PrivateKeyDER []byte `json:"-"`
+	// Data CRL或秘密值等原始数据
+	Data []byte `json:"-"`
+}
+
+// PFXSafe AuthenticatedSafe中的一个ContentInfo
+type PFXSafe struct {
+	ContentType         string    `json:"contentType"`
+	Encrypted           bool      `json:"encrypted"`
+	EncryptionAlgorithm string    `json:"encryptionAlgorithm,omitempty"`
+	Bags                []*PFXBag `json:"bags"`
+	Error               string    `json:"error,omitempty"`
+}
+
+// PFXInfo PKCS#12解析结果
+type PFXInfo struct {
+	Version       int        `json:"version"`
+	MACAlgorithm  string     `json:"macAlgorithm,omitempty"`
+	MACIterations int        `json:"macIterations,omitempty"`
+	MACSalt       string     `json:"macSalt,omitempty"`
+	Safes         []*PFXSafe `json:"safes"`
+
+	// PrivateKey 第一个成功解析的私钥
+	PrivateKey    crypto.Signer `json:"-"`
+	PrivateKeyDER []byte        `json:"-"`
+	// Certificates 按证书链排序，与私钥匹配的终端证书在前
+	Certificates []*gmx509.Certificate `json:"-"`
+}
+
+// ParsePFX 使用口令解析PKCS#12(RFC 7292)或GM/T 0010 SM2 PFX，校验MAC并解密全部SafeBag
+func ParsePFX(der []byte, password string) (*PFXInfo, error) {
+	var pfx pfxPDU
+	if err := unmarshalExact(der, &pfx); err != nil {
+		return nil, fmt.Errorf("解析PFX结构失败: %v", err)
+	}
+	if pfx.Version != 3 {
+		return nil, fmt.Errorf("不支持的PFX版本: %d", pfx.Version)
+	}
+	if !isPFXData(pfx.AuthSafe.ContentType) {
+		return nil, fmt.Errorf("不支持的AuthSafe内容类型: %s，仅支持口令完整性保护", oidName(PFXNames, pfx.AuthSafe.ContentType))
+	}
+	var authSafe []byte
+	if err := unmarshalExact(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, fmt.Errorf("解析AuthSafe失败: %v", err)
+	}
+
+	info := &PFXInfo{Version: pfx.Version}
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		info.MACAlgorithm = pbeAlgorithmName(pfx.MacData.Mac.Algorithm.Algorithm)
+		info.MACIterations = pfx.MacData.Iterations
+		info.MACSalt = hex.EncodeToString(pfx.MacData.MacSalt)
+		if err := verifyPFXMac(&pfx.MacData, authSafe, password); err != nil {
+			return nil, err
+		}
+	}
+
+	var contentInfos []pfxContentInfo
+	if err := unmarshalExact(authSafe, &contentInfos); err != nil {
+		return nil, fmt.Errorf("解析AuthenticatedSafe失败: %v", err)
+	}
+	for _, ci := range contentInfos {
+		info.Safes = append(info.Safes, parsePFXSafe(ci, password))
+	}
+
+	var keyID string
+	for _, bag := range info.Bags() {
+		if bag.PrivateKey != nil && info.PrivateKey == nil {
+			info.PrivateKey, info.PrivateKeyDER, keyID = bag.PrivateKey, bag.PrivateKeyDER, bag.LocalKeyID
+		}
+	}
+	info.Certificates = orderPFXChain(info.Bags(), info.PrivateKey, keyID)
+	return info, nil
+}
+
+// Bags 按出现顺序返回全部SafeBag(含嵌套的safeContentsBag)
+func (info *PFXInfo) Bags() []*PFXBag {
+	var bags []*PFXBag
+	for _, safe := range info.Safes {
+		bags = append(bags, safe.Bags...)
+	}
+	return bags
+}
+
+// ExportPEM 导出私钥(PKCS#8)及按链排序的证书PEM
+func (info *PFXInfo) ExportPEM() string {
+	var sb strings.Builder
+	if info.PrivateKeyDER != nil {
+		sb.Write(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: info.PrivateKeyDER}))
+	}
+	for _, cert := range info.Certificates {
+		sb.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+	return sb.String()
+}
+
+// isPFXData 判断是否为PKCS#7或GM/T 0010的data类型
+func isPFXData(oid asn1.ObjectIdentifier) bool {
+	return oid.Equal(oidPKCS7Data) || oid.Equal(oidGMData)
+}
+
+// verifyPFXMac 校验MacData，空口令时兼容不带结尾零字节的实现
+func verifyPFXMac(macData *pfxMacData, message []byte, password string) error {
+	newHash, err := hashForOID(macData.Mac.Algorithm.Algorithm)
+	if err != nil {
+		return fmt.Errorf("MAC%v", err)
+	}
+	candidates := [][]byte{bmpPassword(password)}
+	if password == "" {
+		candidates = append(candidates, nil)
+	}
+	for _, bmp := range candidates {
+		key := pkcs12KDF(newHash, bmp, macData.MacSalt, macData.Iterations, 3, newHash().Size())
+		mac := hmac.New(newHash, key)
+		mac.Write(message)
+		if hmac.Equal(mac.Sum(nil), macData.Mac.Digest) {
+			return nil
+		}
+	}
+	return fmt.Errorf("MAC校验失败，口令错误或文件已被篡改")
+}
+
+// parsePFXSafe 解析AuthenticatedSafe中的ContentInfo，加密内容使用口令解密
+func parsePFXSafe(ci pfxContentInfo, password string) *PFXSafe {
+	safe := &PFXSafe{ContentType: oidName(PFXNames, ci.ContentType)}
+	var contents []byte
+	switch {
+	case isPFXData(ci.ContentType):
+		if err := unmarshalExact(ci.Content.Bytes, &contents); err != nil {
+			safe.Error = fmt.Sprintf("解析data内容失败: %v", err)
+			return safe
+		}
+	case ci.ContentType.Equal(oidPKCS7EncryptedData) || ci.ContentType.Equal(oidGMEncryptedData):
+		safe.Encrypted = true
+		var encrypted pfxEncryptedData
+		if err := unmarshalExact(ci.Content.Bytes, &encrypted); err != nil {
+			safe.Error = fmt.Sprintf("解析encryptedData失败: %v", err)
+			return safe
+		}
+		alg := encrypted.EncryptedContentInfo.ContentEncryptionAlgorithm
+		safe.EncryptionAlgorithm = DescribePBEAlgorithm(alg)
+		plain, err := PBEDecrypt(alg, password, encrypted.EncryptedContentInfo.EncryptedContent)
+		if err != nil {
+			safe.Error = err.Error()
+			return safe
+		}
+		contents = plain
+	default:
+		safe.Encrypted = true
+		safe.Error = fmt.Sprintf("不支持的内容类型 %s，公钥保护模式需要接收者私钥", safe.ContentType)
+		return safe
+	}
+
+	bags, err := parsePFXSafeContents(contents, password)
+	if err != nil {
+		safe.Error = err.Error()
+	}
+	safe.Bags = bags
+	return safe
+}
+
+// parsePFXSafeContents 解析SafeContents中的全部SafeBag，safeContentsBag展开为其子项
+func parsePFXSafeContents(data []byte, password string) ([]*PFXBag, error) {
+	var raws []pfxSafeBag
+	if err := unmarshalExact(data, &raws); err != nil {
+		return nil, fmt.Errorf("解析SafeContents失败: %v", err)
+	}
+	var bags []*PFXBag
+	for _, raw := range raws {
+		if raw.ID.Equal(oidSafeContentsBag) {
+			nested, err := parsePFXSafeContents(raw.Value.Bytes, password)
+			if err != nil {
+				bags = append(bags, &PFXBag{Type: raw.ID.String(), TypeName: oidName(PFXNames, raw.ID), Error: err.Error()})
+			}
+			bags = append(bags, nested...)
+			continue
+		}
+		bags = append(bags, parsePFXBag(raw, password))
+	}
+	return bags, nil
+}
+
+// parsePFXBag 解析单个SafeBag，错误记录在Error中而不中断整体解析
+func parsePFXBag(raw pfxSafeBag, password string) *PFXBag {
+	bag := &PFXBag{Type: raw.ID.String(), TypeName: oidName(PFXNames, raw.ID)}
+	for _, attr := range raw.Attributes {
+		parsed := parsePFXAttribute(attr)
+		switch {
+		case attr.ID.Equal(oidFriendlyName) && len(parsed.Values) > 0:
+			bag.FriendlyName = parsed.Values[0]
+		case attr.ID.Equal(oidLocalKeyID) && len(parsed.Values) > 0:
+			bag.LocalKeyID = parsed.Values[0]
+		}
+		bag.Attributes = append(bag.Attributes, parsed)
+	}
+
+	value := raw.Value.Bytes
+	var err error
+	switch {
+	case raw.ID.Equal(oidKeyBag):
+		err = bag.setPrivateKey(value)
+	case raw.ID.Equal(oidPKCS8ShroudedKeyBag):
+		var encrypted pfxEncryptedPrivateKeyInfo
+		if err = unmarshalExact(value, &encrypted); err != nil {
+			err = fmt.Errorf("解析EncryptedPrivateKeyInfo失败: %v", err)
+			break
+		}
+		bag.EncryptionAlgorithm = DescribePBEAlgorithm(encrypted.Algorithm)
+		var plain []byte
+		if plain, err = PBEDecrypt(encrypted.Algorithm, password, encrypted.EncryptedData); err == nil {
+			err = bag.setPrivateKey(plain)
+		}
+	case raw.ID.Equal(oidCertBag):
+		var data []byte
+		var typeID asn1.ObjectIdentifier
+		if typeID, data, err = parsePFXTypedBag(bag, value); err != nil {
+			break
+		}
+		if !typeID.Equal(oidX509CertType) {
+			bag.Data = data
+			break
+		}
+		var cert *gmx509.Certificate
+		if cert, err = ParseCertificate(data); err != nil {
+			break
+		}
+		bag.Certificate = cert
+		bag.Subject = cert.Subject.String()
+		bag.Issuer = cert.Issuer.String()
+		bag.SerialNumber = strings.ToUpper(cert.SerialNumber.Text(16))
+		if pub, err := CertificatePublicKey(cert); err == nil {
+			bag.KeyAlgorithm, bag.KeySize = describePublicKey(pub)
+		}
+	case raw.ID.Equal(oidCRLBag), raw.ID.Equal(oidSecretBag):
+		_, bag.Data, err = parsePFXTypedBag(bag, value)
+	default:
+		bag.Data = value
+	}
+	if err != nil {
+		bag.Error = err.Error()
+	}
+	return bag
+}
+
+// parsePFXTypedBag 解析certBag/crlBag/secretBag共有的 {类型OID, [0] EXPLICIT 值} 结构
+func parsePFXTypedBag(bag *PFXBag, value []byte) (asn1.ObjectIdentifier, []byte, error) {
+	var typed pfxTypedBag
+	if err := unmarshalExact(value, &typed); err != nil {
+		return nil, nil, fmt.Errorf("解析%s失败: %v", bag.TypeName, err)
+	}
+	bag.ValueType = oidName(PFXNames, typed.TypeID)
+	var data []byte
+	if _, err := asn1.Unmarshal(typed.Value.Bytes, &data); err != nil {
+		// 非OCTET STRING的值保留原始编码
+		return typed.TypeID, typed.Value.Bytes, nil
+	}
+	return typed.TypeID, data, nil
+}
+
+// setPrivateKey 解析PKCS#8私钥并记录算法信息
+func (bag *PFXBag) setPrivateKey(der []byte) error {
+	key, err := ParsePrivateKey(der)
+	if err != nil {
+		return err
+	}
+	bag.PrivateKey = key
+	bag.PrivateKeyDER = der
+	bag.KeyAlgorithm, bag.KeySize = describePublicKey(key.Public())
+	return nil
+}
+
+// parsePFXAttribute 解析SafeBag属性，friendlyName按BMPString解码，其余值以Hex展示
+func parsePFXAttribute(attr pfxAttributeASN1) PFXAttribute {
+	parsed := PFXAttribute{OID: attr.ID.String(), Name: oidName(PFXNames, attr.ID)}
+	rest := attr.Values.Bytes
+	for len(rest) > 0 {
+		var value asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &value); err != nil {
+			parsed.Values = append(parsed.Values, hex.EncodeToString(rest))
+			break
+		}
+		if text, err := parseDisplayText(value); err == nil {
+			parsed.Values = append(parsed.Values, text)
+		} else if value.Tag == asn1.TagOctetString && value.Class == asn1.ClassUniversal {
+			parsed.Values = append(parsed.Values, hex.EncodeToString(value.Bytes))
+		} else {
+			parsed.Values = append(parsed.Values, hex.EncodeToString(value.FullBytes))
+		}
+	}
+	return parsed
+}
+
+// orderPFXChain 以与私钥匹配(localKeyId或公钥)的证书为起点按颁发者排序，其余证书追加在后
+func orderPFXChain(bags []*PFXBag, key crypto.Signer, keyID string) []*gmx509.Certificate {
+	var certs []*gmx509.Certificate
+	var leaf *gmx509.Certificate
+	var keySPKI []byte
+	if key != nil {
+		keySPKI, _ = MarshalPublicKey(key.Public())
+	}
+	for _, bag := range bags {
+		if bag.Certificate == nil {
+			continue
+		}
+		certs = append(certs, bag.Certificate)
+		if leaf != nil || key == nil {
+			continue
+		}
+		if keyID != "" && bag.LocalKeyID == keyID {
+			leaf = bag.Certificate
+		} else if pub, err := CertificatePublicKey(bag.Certificate); err == nil {
+			if spki, err := MarshalPublicKey(pub); err == nil && bytes.Equal(spki, keySPKI) {
+				leaf = bag.Certificate
+			}
+		}
+	}
+	if leaf == nil {
+		return certs
+	}
+
+	used := map[*gmx509.Certificate]bool{leaf: true}
+	chain := []*gmx509.Certificate{leaf}
+	for current := leaf; !bytes.Equal(current.RawIssuer, current.RawSubject); {
+		var next *gmx509.Certificate
+		for _, cert := range certs {
+			if !used[cert] && bytes.Equal(cert.RawSubject, current.RawIssuer) {
+				next = cert
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		used[next] = true
+		chain = append(chain, next)
+		current = next
+	}
+	for _, cert := range certs {
+		if !used[cert] {
+			chain = append(chain, cert)
+		}
+	}
+	return chain
+}
+
+// PFX生成时可选的口令加密方案
+const (
+	// PFXEncryptionAES256 PBES2(PBKDF2-HMAC-SHA256 + AES-256-CBC)，MAC使用SHA-256，与OpenSSL 3默认一致
+	PFXEncryptionAES256 = "PBES2-AES-256-CBC"
+	// PFXEncryptionSM4 PBES2(PBKDF2-HMAC-SM3 + SM4-CBC)，MAC使用SM3
+	PFXEncryptionSM4 = "PBES2-SM4-CBC"
+	// PFXEncryption3DES 证书与私钥均使用pbeWithSHAAnd3-KeyTripleDES-CBC，MAC使用SHA-1
+	PFXEncryption3DES = "PBE-SHA1-3DES"
+	// PFXEncryptionRC2 证书使用pbeWithSHAAnd40BitRC2-CBC、私钥使用3DES，兼容旧版Windows与Java
+	PFXEncryptionRC2 = "PBE-SHA1-RC2-40"
+)
+
+// PFXEncryptions 全部可选的加密方案
+var PFXEncryptions = []string{PFXEncryptionAES256, PFXEncryptionSM4, PFXEncryption3DES, PFXEncryptionRC2}
+
+// PFXOptions PFX生成参数
+type PFXOptions struct {
+	Password     string
+	FriendlyName string
+	// LocalKeyID 为空时使用终端证书的SHA-1指纹
+	LocalKeyID []byte
+	// Encryption 加密方案，为空时使用PFXEncryptionAES256
+	Encryption string
+	// Iterations 口令加密迭代次数，默认2048
+	Iterations int
+	// MACIterations MAC迭代次数，默认2048
+	MACIterations int
+}
+
+// CreatePFX 生成包含私钥、终端证书及证书链的PKCS#12，证书以encryptedData保护，私钥以pkcs8ShroudedKeyBag保护
+func CreatePFX(key crypto.Signer, leaf *gmx509.Certificate, chain []*gmx509.Certificate, opts PFXOptions) ([]byte, error) {
+	if key == nil || leaf == nil {
+		return nil, fmt.Errorf("私钥与证书不能为空")
+	}
+	if opts.Encryption == "" {
+		opts.Encryption = PFXEncryptionAES256
+	}
+	if opts.Iterations <= 0 {
+		opts.Iterations = 2048
+	}
+	if opts.MACIterations <= 0 {
+		opts.MACIterations = 2048
+	}
+
+	if err := checkKeyMatchesCertificate(key, leaf); err != nil {
+		return nil, err
+	}
+
+	keyDER, err := marshalPKCS8(key)
+	if err != nil {
+		return nil, err
+	}
+
+	certAlg, keyAlg, macAlg, err := pfxAlgorithms(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	localKeyID := opts.LocalKeyID
+	if len(localKeyID) == 0 {
+		sum := sha1.Sum(leaf.Raw)
+		localKeyID = sum[:]
+	}
+	attributes, err := pfxBagAttributes(localKeyID, opts.FriendlyName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 证书包: 终端证书携带属性，链证书去除与终端证书重复的项
+	var certBags []pfxSafeBag
+	leafBag, err := pfxCertBag(leaf.Raw, attributes)
+	if err != nil {
+		return nil, err
+	}
+	certBags = append(certBags, leafBag)
+	for _, cert := range chain {
+		if bytes.Equal(cert.Raw, leaf.Raw) {
+			continue
+		}
+		bag, err := pfxCertBag(cert.Raw, nil)
+		if err != nil {
+			return nil, err
+		}
+		certBags = append(certBags, bag)
+	}
+	certContents, err := asn1.Marshal(certBags)
+	if err != nil {
+		return nil, err
+	}
+	encryptedCerts, err := PBEEncrypt(certAlg, opts.Password, certContents)
+	if err != nil {
+		return nil, err
+	}
+	certSafe, err := asn1.Marshal(pfxEncryptedData{
+		EncryptedContentInfo: pfxEncryptedContentInfo{
+			ContentType:                oidPKCS7Data,
+			ContentEncryptionAlgorithm: certAlg,
+			EncryptedContent:           encryptedCerts,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 私钥包
+	encryptedKey, err := PBEEncrypt(keyAlg, opts.Password, keyDER)
+	if err != nil {
+		return nil, err
+	}
+	shrouded, err := asn1.Marshal(pfxEncryptedPrivateKeyInfo{Algorithm: keyAlg, EncryptedData: encryptedKey})
+	if err != nil {
+		return nil, err
+	}
+	keyContents, err := asn1.Marshal([]pfxSafeBag{{
+		ID:         oidPKCS8ShroudedKeyBag,
+		Value:      explicitTag0(shrouded),
+		Attributes: attributes,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	keySafe, err := asn1.Marshal(keyContents)
+	if err != nil {
+		return nil, err
+	}
+
+	authSafe, err := asn1.Marshal([]pfxContentInfo{
+		{ContentType: oidPKCS7EncryptedData, Content: explicitTag0(certSafe)},
+		{ContentType: oidPKCS7Data, Content: explicitTag0(keySafe)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	macData, err := computePFXMac(macAlg, opts.Password, authSafe, opts.MACIterations)
+	if err != nil {
+		return nil, err
+	}
+	authSafeOctets, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pfxPDU{
+		Version:  3,
+		AuthSafe: pfxContentInfo{ContentType: oidPKCS7Data, Content: explicitTag0(authSafeOctets)},
+		MacData:  *macData,
+	})
+}
+
+// pfxAlgorithms 按加密方案生成证书加密、私钥加密的算法标识及MAC摘要算法
+func pfxAlgorithms(opts PFXOptions) (certAlg, keyAlg pkix.AlgorithmIdentifier, macAlg asn1.ObjectIdentifier, err error) {
+	switch opts.Encryption {
+	case PFXEncryptionAES256, PFXEncryptionSM4:
+		prf, scheme, blockSize := oidHMACWithSHA256, oidAES256CBC, 16
+		macAlg = oidDigestSHA256
+		if opts.Encryption == PFXEncryptionSM4 {
+			prf, scheme, macAlg = oidHMACWithSM3, oidSM4CBC, oidDigestSM3
+		}
+		if certAlg, err = newPBES2Algorithm(prf, scheme, blockSize, opts.Iterations); err != nil {
+			return
+		}
+		keyAlg, err = newPBES2Algorithm(prf, scheme, blockSize, opts.Iterations)
+	case PFXEncryption3DES:
+		macAlg = oidDigestSHA1
+		if certAlg, err = newPBEAlgorithm(oidPBEWithSHAAnd3KeyTripleDES, opts.Iterations); err != nil {
+			return
+		}
+		keyAlg, err = newPBEAlgorithm(oidPBEWithSHAAnd3KeyTripleDES, opts.Iterations)
+	case PFXEncryptionRC2:
+		macAlg = oidDigestSHA1
+		if certAlg, err = newPBEAlgorithm(oidPBEWithSHAAnd40BitRC2, opts.Iterations); err != nil {
+			return
+		}
+		keyAlg, err = newPBEAlgorithm(oidPBEWithSHAAnd3KeyTripleDES, opts.Iterations)
+	default:
+		err = fmt.Errorf("不支持的PFX加密方案: %s", opts.Encryption)
+	}
+	return
+}
+
+// newPBEAlgorithm 生成带随机盐的PKCS#12 PBE算法标识
+func newPBEAlgorithm(oid asn1.ObjectIdentifier, iterations int) (pkix.AlgorithmIdentifier, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: iterations})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+// newPBES2Algorithm 生成带随机盐与IV的PBES2算法标识
+func newPBES2Algorithm(prf, scheme asn1.ObjectIdentifier, blockSize, iterations int) (pkix.AlgorithmIdentifier, error) {
+	salt := make([]byte, 16)
+	iv := make([]byte, blockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: prf, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: scheme, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+// computePFXMac 生成MacData，MAC密钥按RFC 7292 附录B由口令派生
+func computePFXMac(alg asn1.ObjectIdentifier, password string, message []byte, iterations int) (*pfxMacData, error) {
+	newHash, err := hashForOID(alg)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, newHash().Size())
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key := pkcs12KDF(newHash, bmpPassword(password), salt, iterations, 3, newHash().Size())
+	mac := hmac.New(newHash, key)
+	mac.Write(message)
+	return &pfxMacData{
+		Mac: pfxDigestInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: alg, Parameters: asn1.NullRawValue},
+			Digest:    mac.Sum(nil),
+		},
+		MacSalt:    salt,
+		Iterations: iterations,
+	}, nil
+}
+
+// pfxBagAttributes 构造localKeyId与friendlyName属性
+func pfxBagAttributes(localKeyID []byte, friendlyName string) ([]pfxAttributeASN1, error) {
+	keyID, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []pfxAttributeASN1{{ID: oidLocalKeyID, Values: asn1Set(keyID)}}
+	if friendlyName != "" {
+		name := bmpPassword(friendlyName)
+		bmp, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: name[:len(name)-2]})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, pfxAttributeASN1{ID: oidFriendlyName, Values: asn1Set(bmp)})
+	}
+	return attributes, nil
+}
+
+// pfxCertBag 构造x509Certificate类型的certBag
+func pfxCertBag(certDER []byte, attributes []pfxAttributeASN1) (pfxSafeBag, error) {
+	octets, err := asn1.Marshal(certDER)
+	if err != nil {
+		return pfxSafeBag{}, err
+	}
+	typed, err := asn1.Marshal(pfxTypedBag{TypeID: oidX509CertType, Value: explicitTag0(octets)})
+	if err != nil {
+		return pfxSafeBag{}, err
+	}
+	return pfxSafeBag{ID: oidCertBag, Value: explicitTag0(typed), Attributes: attributes}, nil
+}
+
+// marshalPKCS8 将私钥编码为PKCS#8，SM2私钥使用id-ecPublicKey+SM2曲线
+func marshalPKCS8(key crypto.Signer) ([]byte, error) {
+	if priv, ok := key.(*sm2.PrivateKey); ok {
+		return MarshalSM2PKCS8PrivateKey(priv)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("编码PKCS#8私钥失败: %v", err)
+	}
+	return der, nil
+}
+
+// explicitTag0 以[0] EXPLICIT包装已编码的元素，asn1.Marshal不会对RawValue应用tag参数
+func explicitTag0(inner []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner}
+}
+
+// asn1Set 将已编码的元素包装为SET
+func asn1Set(elements ...[]byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: bytes.Join(elements, nil)}
+}
@@ -1,6 +1,9 @@
 package helper
 
 import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
 	"github.com/zaneway/cain-go/sm2"
 	"github.com/zaneway/cain-go/x509"
 	"math/big"
@@ -20,7 +23,7 @@ func BuildPublicKeyUseRaw(realPublicKey []byte) *sm2.PublicKey {
 	}
 	publicKey := new(sm2.PublicKey)
 	publicKey.Curve = sm2.P256Sm2()
-	publicKey.X = new(big.Int).SetBytes(realPublicKey[len(realPublicKey)-64 : 32])
+	publicKey.X = new(big.Int).SetBytes(realPublicKey[len(realPublicKey)-64 : len(realPublicKey)-32])
 	publicKey.Y = new(big.Int).SetBytes(realPublicKey[len(realPublicKey)-32:])
 	return publicKey
 }
@@ -39,6 +42,25 @@ func BuildPublicKey(publicKey []byte) (*sm2.PublicKey, error) {
 	return x509.ParseSm2PublicKey(publicKey)
 }
 
+// ParseSM2PublicKey 自动识别裸公钥(X||Y、04||X||Y)、SubjectPublicKeyInfo及SM2证书
+func ParseSM2PublicKey(data []byte) (*sm2.PublicKey, error) {
+	if len(data) == 64 || (len(data) == 65 && data[0] == 0x04) {
+		return BuildPublicKeyUseRaw(data), nil
+	}
+	if publicKey, err := x509.ParseSm2PublicKey(data); err == nil {
+		return publicKey, nil
+	}
+	certs, err := ParseCertificates(data)
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("无法识别的SM2公钥格式，支持裸公钥、SubjectPublicKeyInfo及证书")
+	}
+	pub, err := CertificatePublicKey(certs[0])
+	if err != nil {
+		return nil, err
+	}
+	return toSM2PublicKey(pub)
+}
+
 func BuildPrivateKey(privateKey []byte) (*sm2.PrivateKey, error) {
 	return x509.ParseSm2PrivateKey(privateKey)
 }
@@ -50,3 +72,57 @@ func BuildKeyPair(publicKey []byte, privateKey []byte) (*KeyPair, error) {
 		PublicKey:  BuildPublicKeyUseRaw(publicKey),
 	}, nil
 }
+
+var oidPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+type pkcs8PrivateKey struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type ecPrivateKey struct {
+	Version    int
+	PrivateKey []byte
+	Parameters asn1.RawValue `asn1:"optional"`
+	PublicKey  asn1.RawValue `asn1:"optional"`
+}
+
+// ParseSM2PrivateKey 自动识别裸私钥(30/32字节)、PKCS#8及SEC1格式的SM2私钥
+func ParseSM2PrivateKey(data []byte) (*sm2.PrivateKey, error) {
+	if len(data) == 32 {
+		return BuildPrivateKeyUseRaw(data), nil
+	}
+	if len(data) == 30 {
+		padded := make([]byte, 32)
+		copy(padded[2:], data)
+		return BuildPrivateKeyUseRaw(padded), nil
+	}
+
+	privKey, err := parsePKCS8PrivateKey(data)
+	if err == nil {
+		return privKey, nil
+	}
+
+	return x509.ParseSm2PrivateKey(data)
+}
+
+func parsePKCS8PrivateKey(data []byte) (*sm2.PrivateKey, error) {
+	var privKeyInfo pkcs8PrivateKey
+	_, err := asn1.Unmarshal(data, &privKeyInfo)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal PKCS8 failed: %v", err)
+	}
+
+	if !privKeyInfo.Algo.Algorithm.Equal(oidPublicKeyEC) && !privKeyInfo.Algo.Algorithm.Equal(oidPublicKeySM2) {
+		return nil, fmt.Errorf("not EC key")
+	}
+
+	var ecPriv ecPrivateKey
+	_, err = asn1.Unmarshal(privKeyInfo.PrivateKey, &ecPriv)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal EC private key failed: %v", err)
+	}
+
+	return BuildPrivateKeyUseRaw(ecPriv.PrivateKey), nil
+}
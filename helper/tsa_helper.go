@@ -0,0 +1,379 @@
+package helper
+
+import (
+	"HeTu/util"
+	"crypto"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zaneway/cain-go/sm2"
+	"github.com/zaneway/cain-go/x509"
+)
+
+const (
+	// DefaultTSAAddr 本地时间戳服务默认监听地址
+	DefaultTSAAddr = "127.0.0.1:3161"
+	// maxTSARequestSize 单个时间戳请求的最大长度
+	maxTSARequestSize = 64 * 1024
+	// PKIStatus取值
+	tspGranted   = 0
+	tspRejection = 2
+	// PKIFailureInfo比特位
+	tspFailBadAlg              = 0
+	tspFailBadRequest          = 2
+	tspFailBadDataFormat       = 5
+	tspFailUnacceptedPolicy    = 15
+	tspFailUnacceptedExtension = 16
+	tspFailSystemFailure       = 25
+)
+
+// tspStatusInfoOutASN1 编码响应状态用，PKIFreeText须为UTF8String
+type tspStatusInfoOutASN1 struct {
+	Status       int
+	StatusString []asn1.RawValue `asn1:"optional"`
+	FailInfo     asn1.BitString  `asn1:"optional"`
+}
+
+type tspResponseOutASN1 struct {
+	Status         tspStatusInfoOutASN1
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// TSAConfig 本地时间戳服务配置
+type TSAConfig struct {
+	// Certificate、Key TSA证书及签名私钥(SM2或RSA)
+	Certificate *x509.Certificate
+	Key         crypto.Signer
+	// Chain 请求要求附带证书时随TSA证书一同附带的上级证书
+	Chain []*x509.Certificate
+	// Policy TSA策略OID，请求指定其他策略时拒绝
+	Policy string
+	// HashAlgorithm 令牌签名的摘要算法，为空时SM2使用SM3、RSA使用SHA256
+	HashAlgorithm string
+	Accuracy      TimeStampAccuracy
+	Ordering      bool
+	// IncludeTSAName 以TSA证书主题作为令牌中的tsa名称
+	IncludeTSAName bool
+	// GenTime 固定的签发时间，零值表示使用当前时间，用于生成回溯时间的测试令牌
+	GenTime time.Time
+}
+
+// TSARequestLog 本地时间戳服务处理的单个请求
+type TSARequestLog struct {
+	Time          time.Time
+	RemoteAddr    string
+	HashAlgorithm string
+	HashedMessage []byte
+	Nonce         *big.Int
+	// SerialNumber、GenTime 签发的令牌信息，请求被拒绝时为零值
+	SerialNumber *big.Int
+	GenTime      time.Time
+	Status       int
+	Error        string
+}
+
+// String 单行日志文本
+func (l TSARequestLog) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s", util.ToBeijingTime(l.Time).Format(util.DateTime))
+	if l.RemoteAddr != "" {
+		fmt.Fprintf(&b, " %s", l.RemoteAddr)
+	}
+	if l.HashAlgorithm != "" {
+		fmt.Fprintf(&b, " %s=%X", l.HashAlgorithm, l.HashedMessage)
+	}
+	if l.Nonce != nil {
+		fmt.Fprintf(&b, " nonce=%X", l.Nonce)
+	}
+	if l.Status == tspGranted {
+		fmt.Fprintf(&b, " 序列号=%X genTime=%s", l.SerialNumber, util.ToBeijingTime(l.GenTime).Format(util.DateTime))
+	} else {
+		fmt.Fprintf(&b, " 响应状态: %s", tspStatusTexts[l.Status])
+	}
+	if l.Error != "" {
+		fmt.Fprintf(&b, " 错误: %s", l.Error)
+	}
+	return b.String()
+}
+
+// TimeStampAuthority 本地时间戳服务，实现http.Handler，可直接交给httptest.NewServer或http.Server
+type TimeStampAuthority struct {
+	// Log 每处理一个请求调用一次，调用已串行化但不在主goroutine中
+	Log func(TSARequestLog)
+	// Warnings 配置中发现的问题，如TSA证书缺少timeStamping用途、genTime不在证书有效期内等，服务仍按配置签发
+	Warnings []string
+
+	config    TSAConfig
+	policy    asn1.ObjectIdentifier
+	digestOID asn1.ObjectIdentifier
+	// essAttribute 标识TSA证书的signingCertificate(V2)签名属性
+	essAttribute cmsAttributeASN1
+	serial       *big.Int
+	mu           sync.Mutex
+}
+
+// NewTimeStampAuthority 检查配置并创建本地时间戳服务
+func NewTimeStampAuthority(config TSAConfig) (*TimeStampAuthority, error) {
+	if config.Certificate == nil || config.Key == nil {
+		return nil, fmt.Errorf("必须提供TSA证书及签名私钥")
+	}
+	if err := checkKeyMatchesCertificate(config.Key, config.Certificate); err != nil {
+		return nil, fmt.Errorf("TSA证书: %v", err)
+	}
+	_, isSM2 := config.Key.(*sm2.PrivateKey)
+	if config.HashAlgorithm == "" {
+		config.HashAlgorithm = TSPHashSHA256
+		if isSM2 {
+			config.HashAlgorithm = TSPHashSM3
+		}
+	}
+	if isSM2 != (config.HashAlgorithm == TSPHashSM3) {
+		return nil, fmt.Errorf("SM2私钥须使用SM3摘要，RSA私钥不支持SM3摘要")
+	}
+	if strings.TrimSpace(config.Policy) == "" {
+		return nil, fmt.Errorf("必须指定TSA策略OID")
+	}
+
+	tsa := &TimeStampAuthority{config: config, serial: big.NewInt(time.Now().UnixNano())}
+	var ok bool
+	if tsa.digestOID, ok = tspHashOIDs[config.HashAlgorithm]; !ok {
+		return nil, fmt.Errorf("不支持的摘要算法: %s", config.HashAlgorithm)
+	}
+	var err error
+	if tsa.policy, err = ParseOID(strings.TrimSpace(config.Policy)); err != nil {
+		return nil, fmt.Errorf("策略OID格式错误: %v", err)
+	}
+	if tsa.essAttribute, err = essSigningCertificateAttribute(config.Certificate, tsa.digestOID); err != nil {
+		return nil, err
+	}
+	tsa.Warnings = checkTSACertificate(config.Certificate, config.GenTime)
+	return tsa, nil
+}
+
+// Certificate 签发令牌使用的TSA证书
+func (tsa *TimeStampAuthority) Certificate() *x509.Certificate {
+	return tsa.config.Certificate
+}
+
+// essSigningCertificateAttribute 生成标识TSA证书的签名属性，SHA1使用signingCertificate，其余使用signingCertificateV2
+func essSigningCertificateAttribute(cert *x509.Certificate, digestOID asn1.ObjectIdentifier) (cmsAttributeASN1, error) {
+	newHash, err := hashForOID(digestOID)
+	if err != nil {
+		return cmsAttributeASN1{}, err
+	}
+	h := newHash()
+	h.Write(cert.Raw)
+	attrType := oidSigningCertificateV2
+	var value interface{}
+	if digestOID.Equal(oidDigestSHA1) {
+		attrType = oidSigningCertificate
+		value = essSigningCertificateASN1{Certs: []essCertIDASN1{{CertHash: h.Sum(nil)}}}
+	} else {
+		certID := essCertIDv2ASN1{CertHash: h.Sum(nil)}
+		// hashAlgorithm缺省为SHA256，DER编码时省略
+		if !digestOID.Equal(oidDigestSHA256) {
+			certID.HashAlgorithm = digestAlgorithmIdentifier(digestOID)
+		}
+		value = essSigningCertificateV2ASN1{Certs: []essCertIDv2ASN1{certID}}
+	}
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		return cmsAttributeASN1{}, err
+	}
+	return cmsAttributeASN1{Type: attrType, Values: asn1Set(der)}, nil
+}
+
+// checkTSACertificate 检查TSA证书的timeStamping用途及签发时间是否在有效期内
+func checkTSACertificate(cert *x509.Certificate, genTime time.Time) []string {
+	var warnings []string
+	hasTimeStamping := false
+	for _, usage := range cert.ExtKeyUsage {
+		hasTimeStamping = hasTimeStamping || usage == x509.ExtKeyUsageTimeStamping
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		hasTimeStamping = hasTimeStamping || oid.Equal(oidExtKeyUsageTimeStamping)
+	}
+	if !hasTimeStamping {
+		warnings = append(warnings, "TSA证书的扩展密钥用法不包含timeStamping，签发的令牌将无法通过验证")
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtensionExtKeyUsage) && !ext.Critical {
+			warnings = append(warnings, "TSA证书的扩展密钥用法未标记为关键扩展")
+		}
+	}
+	if genTime.IsZero() {
+		genTime = time.Now()
+	}
+	if genTime.Before(cert.NotBefore) || genTime.After(cert.NotAfter) {
+		warnings = append(warnings, fmt.Sprintf("签发时间 %s 不在TSA证书有效期内", util.ToBeijingTime(genTime).Format(util.DateTime)))
+	}
+	return warnings
+}
+
+// Issue 根据DER编码的请求签发时间戳响应，请求无效时返回rejection响应，log记录请求内容及结果
+func (tsa *TimeStampAuthority) Issue(request []byte) ([]byte, TSARequestLog) {
+	log := TSARequestLog{Time: time.Now()}
+	req, err := ParseTimeStampRequest(request)
+	if err != nil {
+		return tsa.reject(&log, tspFailBadDataFormat, err.Error())
+	}
+	log.HashAlgorithm = req.HashAlgorithm
+	log.HashedMessage = req.HashedMessage
+	log.Nonce = req.Nonce
+	if req.Version != 1 {
+		return tsa.reject(&log, tspFailBadRequest, fmt.Sprintf("不支持的请求版本: %d", req.Version))
+	}
+	newHash, err := hashForOID(req.hashOID)
+	if err != nil {
+		return tsa.reject(&log, tspFailBadAlg, err.Error())
+	}
+	if size := newHash().Size(); len(req.HashedMessage) != size {
+		return tsa.reject(&log, tspFailBadDataFormat, fmt.Sprintf("%s摘要长度应为%d字节，实际为%d字节", req.HashAlgorithm, size, len(req.HashedMessage)))
+	}
+	if req.Policy != "" && req.Policy != tsa.policy.String() {
+		return tsa.reject(&log, tspFailUnacceptedPolicy, fmt.Sprintf("不支持的策略: %s", req.Policy))
+	}
+	if len(req.Extensions) > 0 {
+		return tsa.reject(&log, tspFailUnacceptedExtension, "不支持请求扩展")
+	}
+
+	genTime := tsa.config.GenTime
+	if genTime.IsZero() {
+		genTime = log.Time
+	}
+	tst := tstInfoASN1{
+		Version: 1,
+		Policy:  tsa.policy,
+		MessageImprint: tspMessageImprintASN1{
+			HashAlgorithm: digestAlgorithmIdentifier(req.hashOID),
+			HashedMessage: req.HashedMessage,
+		},
+		SerialNumber: tsa.nextSerial(),
+		// GeneralizedTime须以UTC(Z)表示
+		GenTime:  genTime.UTC(),
+		Accuracy: tspAccuracyASN1{Seconds: tsa.config.Accuracy.Seconds, Millis: tsa.config.Accuracy.Millis, Micros: tsa.config.Accuracy.Micros},
+		Ordering: tsa.config.Ordering,
+		Nonce:    req.Nonce,
+	}
+	if tsa.config.IncludeTSAName {
+		name, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: tsa.config.Certificate.RawSubject})
+		if err != nil {
+			return tsa.reject(&log, tspFailSystemFailure, err.Error())
+		}
+		tst.TSA = explicitTag0(name)
+	}
+	tstDER, err := asn1.Marshal(tst)
+	if err != nil {
+		return tsa.reject(&log, tspFailSystemFailure, fmt.Sprintf("编码TSTInfo失败: %v", err))
+	}
+	var certs []*x509.Certificate
+	if req.CertReq {
+		certs = append([]*x509.Certificate{tsa.config.Certificate}, tsa.config.Chain...)
+	}
+	signer := cmsSigner{
+		certificate: tsa.config.Certificate,
+		key:         tsa.config.Key,
+		digestOID:   tsa.digestOID,
+		signingTime: genTime,
+		attributes:  []cmsAttributeASN1{tsa.essAttribute},
+	}
+	token, err := createSignedData(oidTSTInfo, tstDER, false, signer, certs)
+	if err != nil {
+		return tsa.reject(&log, tspFailSystemFailure, err.Error())
+	}
+	response, err := asn1.Marshal(tspResponseOutASN1{
+		Status:         tspStatusInfoOutASN1{Status: tspGranted},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+	if err != nil {
+		return tsa.reject(&log, tspFailSystemFailure, err.Error())
+	}
+	log.SerialNumber = tst.SerialNumber
+	log.GenTime = genTime
+	return response, log
+}
+
+func (tsa *TimeStampAuthority) nextSerial() *big.Int {
+	tsa.mu.Lock()
+	defer tsa.mu.Unlock()
+	tsa.serial.Add(tsa.serial, big.NewInt(1))
+	return new(big.Int).Set(tsa.serial)
+}
+
+// reject 记录错误并返回rejection响应
+func (tsa *TimeStampAuthority) reject(log *TSARequestLog, failBit int, text string) ([]byte, TSARequestLog) {
+	log.Status = tspRejection
+	log.Error = text
+	return CreateTimeStampErrorResponse(failBit, text), *log
+}
+
+// CreateTimeStampErrorResponse 生成rejection状态的时间戳响应，failBit为PKIFailureInfo比特位
+func CreateTimeStampErrorResponse(failBit int, text string) []byte {
+	failInfo := make([]byte, failBit/8+1)
+	failInfo[failBit/8] = 0x80 >> uint(failBit%8)
+	status := tspStatusInfoOutASN1{
+		Status:   tspRejection,
+		FailInfo: asn1.BitString{Bytes: failInfo, BitLength: failBit + 1},
+	}
+	if text != "" {
+		status.StatusString = []asn1.RawValue{{Tag: asn1.TagUTF8String, Bytes: []byte(text)}}
+	}
+	response, _ := asn1.Marshal(tspResponseOutASN1{Status: status})
+	return response
+}
+
+// ServeHTTP 按RFC 3161第3.4节处理POST请求
+func (tsa *TimeStampAuthority) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var response []byte
+	var log TSARequestLog
+	request, err := io.ReadAll(io.LimitReader(r.Body, maxTSARequestSize+1))
+	if err == nil && len(request) > maxTSARequestSize {
+		err = fmt.Errorf("请求超过%d字节", maxTSARequestSize)
+	}
+	if err != nil {
+		log = TSARequestLog{Time: time.Now()}
+		response, log = tsa.reject(&log, tspFailBadDataFormat, err.Error())
+	} else {
+		response, log = tsa.Issue(request)
+	}
+	log.RemoteAddr = r.RemoteAddr
+	tsa.log(log)
+
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(response)
+}
+
+func (tsa *TimeStampAuthority) log(entry TSARequestLog) {
+	if tsa.Log == nil {
+		return
+	}
+	tsa.mu.Lock()
+	defer tsa.mu.Unlock()
+	tsa.Log(entry)
+}
+
+// Listen 在addr(为空时使用DefaultTSAAddr)上启动HTTP服务，返回服务及访问地址，调用Close停止
+func (tsa *TimeStampAuthority) Listen(addr string) (*http.Server, string, error) {
+	if addr == "" {
+		addr = DefaultTSAAddr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("监听%s失败: %v", addr, err)
+	}
+	server := &http.Server{Handler: tsa, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	return server, "http://" + listener.Addr().String(), nil
+}
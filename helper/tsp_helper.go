@@ -0,0 +1,519 @@
+package helper
+
+import (
+	"HeTu/util"
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/zaneway/cain-go/x509"
+)
+
+// 时间戳请求支持的摘要算法
+const (
+	TSPHashSHA1   = "SHA1"
+	TSPHashSHA256 = "SHA256"
+	TSPHashSHA384 = "SHA384"
+	TSPHashSHA512 = "SHA512"
+	TSPHashSM3    = "SM3"
+)
+
+// TSPHashAlgorithms 可选的消息摘要算法，首项为默认值
+var TSPHashAlgorithms = []string{TSPHashSHA256, TSPHashSM3, TSPHashSHA1, TSPHashSHA384, TSPHashSHA512}
+
+var (
+	tspHashOIDs = map[string]asn1.ObjectIdentifier{
+		TSPHashSHA1:   oidDigestSHA1,
+		TSPHashSHA256: oidDigestSHA256,
+		TSPHashSHA384: oidDigestSHA384,
+		TSPHashSHA512: oidDigestSHA512,
+		TSPHashSM3:    oidDigestSM3,
+	}
+	oidExtKeyUsageTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+)
+
+// tspStatusTexts PKIStatus取值说明
+var tspStatusTexts = map[int]string{
+	0: "granted 已授予",
+	1: "grantedWithMods 已授予(有修改)",
+	2: "rejection 拒绝",
+	3: "waiting 等待中",
+	4: "revocationWarning 即将吊销",
+	5: "revocationNotification 已吊销",
+}
+
+// tspFailureInfoTexts PKIFailureInfo各比特位说明
+var tspFailureInfoTexts = map[int]string{
+	0:  "badAlg 不支持的摘要算法",
+	2:  "badRequest 不允许的请求",
+	5:  "badDataFormat 数据格式错误",
+	14: "timeNotAvailable 时间源不可用",
+	15: "unacceptedPolicy 不支持的策略",
+	16: "unacceptedExtension 不支持的扩展",
+	17: "addInfoNotAvailable 附加信息不可用",
+	25: "systemFailure 系统故障",
+}
+
+type tspMessageImprintASN1 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type tspRequestASN1 struct {
+	Version        int
+	MessageImprint tspMessageImprintASN1
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+	Extensions     []pkix.Extension      `asn1:"optional,tag:0"`
+}
+
+type tspStatusInfoASN1 struct {
+	Status       int
+	StatusString []string       `asn1:"optional,utf8"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type tspResponseASN1 struct {
+	Status         tspStatusInfoASN1
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type tspAccuracyASN1 struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfoASN1 struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint tspMessageImprintASN1
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       tspAccuracyASN1  `asn1:"optional"`
+	Ordering       bool             `asn1:"optional,default:false"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+type essCertIDASN1 struct {
+	CertHash     []byte
+	IssuerSerial asn1.RawValue `asn1:"optional"`
+}
+
+type essSigningCertificateASN1 struct {
+	Certs    []essCertIDASN1
+	Policies asn1.RawValue `asn1:"optional"`
+}
+
+type essCertIDv2ASN1 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"`
+	CertHash      []byte
+	IssuerSerial  asn1.RawValue `asn1:"optional"`
+}
+
+type essSigningCertificateV2ASN1 struct {
+	Certs    []essCertIDv2ASN1
+	Policies asn1.RawValue `asn1:"optional"`
+}
+
+// TimeStampRequestTemplate 时间戳请求参数
+type TimeStampRequestTemplate struct {
+	HashAlgorithm string
+	// HashedMessage 待加盖时间戳数据的摘要，见ComputeMessageImprint
+	HashedMessage []byte
+	// Policy 请求的TSA策略OID，为空时不指定
+	Policy string
+	// Nonce 为nil时请求不包含nonce
+	Nonce *big.Int
+	// CertReq 要求TSA在令牌中附带签名证书
+	CertReq bool
+}
+
+// TimeStampRequestInfo 时间戳请求信息
+type TimeStampRequestInfo struct {
+	Version       int
+	HashAlgorithm string
+	HashedMessage []byte
+	Policy        string
+	Nonce         *big.Int
+	CertReq       bool
+	Extensions    []pkix.Extension
+
+	hashOID asn1.ObjectIdentifier
+}
+
+// TimeStampAccuracy genTime的精度，均为0表示未指定
+type TimeStampAccuracy struct {
+	Seconds int
+	Millis  int
+	Micros  int
+}
+
+// NewTimeStampAccuracy 将时长拆分为秒、毫秒、微秒，不足1微秒的部分舍去
+func NewTimeStampAccuracy(d time.Duration) TimeStampAccuracy {
+	return TimeStampAccuracy{
+		Seconds: int(d / time.Second),
+		Millis:  int(d % time.Second / time.Millisecond),
+		Micros:  int(d % time.Millisecond / time.Microsecond),
+	}
+}
+
+// String 精度的文本表示，如 "±1秒500毫秒"
+func (a TimeStampAccuracy) String() string {
+	if a.Seconds == 0 && a.Millis == 0 && a.Micros == 0 {
+		return "未指定"
+	}
+	var b strings.Builder
+	b.WriteString("±")
+	if a.Seconds > 0 {
+		fmt.Fprintf(&b, "%d秒", a.Seconds)
+	}
+	if a.Millis > 0 {
+		fmt.Fprintf(&b, "%d毫秒", a.Millis)
+	}
+	if a.Micros > 0 {
+		fmt.Fprintf(&b, "%d微秒", a.Micros)
+	}
+	return b.String()
+}
+
+// TSTInfo 时间戳令牌内容
+type TSTInfo struct {
+	Version       int
+	Policy        string
+	HashAlgorithm string
+	HashedMessage []byte
+	SerialNumber  *big.Int
+	GenTime       time.Time
+	Accuracy      TimeStampAccuracy
+	Ordering      bool
+	// Nonce 未包含时为nil
+	Nonce *big.Int
+	// TSAName TSA名称(GeneralName)，未包含时为空
+	TSAName    string
+	Extensions []pkix.Extension
+
+	hashOID asn1.ObjectIdentifier
+}
+
+// TimeStampResponseInfo 时间戳响应信息，仅含令牌时Status为granted
+type TimeStampResponseInfo struct {
+	Status        int
+	StatusStrings []string
+	FailureInfo   []string
+	// Token 时间戳令牌(ContentInfo)的DER编码，请求被拒绝时为空
+	Token      []byte
+	SignedData *SignedDataInfo
+	TSTInfo    *TSTInfo
+	// SignatureStatus 令牌签名验证状态，调用VerifyTimeStampResponse后更新
+	SignatureStatus string
+	SignatureError  string
+	// Signer 验证通过的TSA证书
+	Signer   *x509.Certificate
+	Warnings []string
+}
+
+// StatusText 响应状态说明
+func (info *TimeStampResponseInfo) StatusText() string {
+	if text, ok := tspStatusTexts[info.Status]; ok {
+		return text
+	}
+	return fmt.Sprintf("未知状态(%d)", info.Status)
+}
+
+// ComputeMessageImprint 计算待加盖时间戳数据的摘要
+func ComputeMessageImprint(hashAlgorithm string, data []byte) ([]byte, error) {
+	oid, ok := tspHashOIDs[hashAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的摘要算法: %s", hashAlgorithm)
+	}
+	newHash, err := hashForOID(oid)
+	if err != nil {
+		return nil, err
+	}
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// NewTimeStampNonce 生成64位随机nonce
+func NewTimeStampNonce() (*big.Int, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("生成nonce失败: %v", err)
+	}
+	return n, nil
+}
+
+// CreateTimeStampRequest 构造RFC 3161 TimeStampReq
+func CreateTimeStampRequest(template *TimeStampRequestTemplate) ([]byte, error) {
+	oid, ok := tspHashOIDs[template.HashAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的摘要算法: %s", template.HashAlgorithm)
+	}
+	newHash, err := hashForOID(oid)
+	if err != nil {
+		return nil, err
+	}
+	if size := newHash().Size(); len(template.HashedMessage) != size {
+		return nil, fmt.Errorf("%s摘要长度应为%d字节，实际为%d字节", template.HashAlgorithm, size, len(template.HashedMessage))
+	}
+	req := tspRequestASN1{
+		Version: 1,
+		MessageImprint: tspMessageImprintASN1{
+			HashAlgorithm: digestAlgorithmIdentifier(oid),
+			HashedMessage: template.HashedMessage,
+		},
+		Nonce:   template.Nonce,
+		CertReq: template.CertReq,
+	}
+	if policy := strings.TrimSpace(template.Policy); policy != "" {
+		if req.ReqPolicy, err = ParseOID(policy); err != nil {
+			return nil, fmt.Errorf("策略OID格式错误: %v", err)
+		}
+	}
+	return asn1.Marshal(req)
+}
+
+// ParseTimeStampRequest 解析TimeStampReq
+func ParseTimeStampRequest(der []byte) (*TimeStampRequestInfo, error) {
+	var req tspRequestASN1
+	if err := unmarshalExact(der, &req); err != nil {
+		return nil, fmt.Errorf("解析时间戳请求失败: %v", err)
+	}
+	info := &TimeStampRequestInfo{
+		Version:       req.Version,
+		HashAlgorithm: pbeAlgorithmName(req.MessageImprint.HashAlgorithm.Algorithm),
+		HashedMessage: req.MessageImprint.HashedMessage,
+		Nonce:         req.Nonce,
+		CertReq:       req.CertReq,
+		Extensions:    req.Extensions,
+		hashOID:       req.MessageImprint.HashAlgorithm.Algorithm,
+	}
+	if len(req.ReqPolicy) > 0 {
+		info.Policy = req.ReqPolicy.String()
+	}
+	return info, nil
+}
+
+// ParseTimeStampResponse 解析TimeStampResp，也可直接输入时间戳令牌(ContentInfo)
+func ParseTimeStampResponse(der []byte) (*TimeStampResponseInfo, error) {
+	info := &TimeStampResponseInfo{SignatureStatus: CMSSignatureUnverified}
+	var resp tspResponseASN1
+	if err := unmarshalExact(der, &resp); err != nil {
+		// 令牌以内容类型OID开头，响应以PKIStatusInfo开头
+		var contentInfo pfxContentInfo
+		if _, tokenErr := asn1.Unmarshal(der, &contentInfo); tokenErr != nil {
+			return nil, fmt.Errorf("解析时间戳响应失败: %v", err)
+		}
+		info.Token = der
+	} else {
+		info.Status = resp.Status.Status
+		info.StatusStrings = resp.Status.StatusString
+		for bit, text := range tspFailureInfoTexts {
+			if resp.Status.FailInfo.At(bit) == 1 {
+				info.FailureInfo = append(info.FailureInfo, text)
+			}
+		}
+		info.Token = resp.TimeStampToken.FullBytes
+	}
+	if len(info.Token) == 0 {
+		if info.Status <= 1 {
+			return nil, fmt.Errorf("响应状态为%s但未包含时间戳令牌", info.StatusText())
+		}
+		return info, nil
+	}
+
+	signedData, err := ParseSignedData(info.Token)
+	if err != nil {
+		return nil, fmt.Errorf("解析时间戳令牌失败: %v", err)
+	}
+	if !signedData.contentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("时间戳令牌的内容类型不是id-ct-TSTInfo: %s", signedData.ContentType)
+	}
+	if signedData.Detached {
+		return nil, fmt.Errorf("时间戳令牌未包含TSTInfo")
+	}
+	if info.TSTInfo, err = parseTSTInfo(signedData.Content); err != nil {
+		return nil, err
+	}
+	if len(signedData.Signers) != 1 {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("时间戳令牌应只有一个签名者，实际为%d个", len(signedData.Signers)))
+	}
+	info.SignedData = signedData
+	return info, nil
+}
+
+func parseTSTInfo(der []byte) (*TSTInfo, error) {
+	var raw tstInfoASN1
+	if err := unmarshalExact(der, &raw); err != nil {
+		return nil, fmt.Errorf("解析TSTInfo失败: %v", err)
+	}
+	tst := &TSTInfo{
+		Version:       raw.Version,
+		Policy:        raw.Policy.String(),
+		HashAlgorithm: pbeAlgorithmName(raw.MessageImprint.HashAlgorithm.Algorithm),
+		HashedMessage: raw.MessageImprint.HashedMessage,
+		SerialNumber:  raw.SerialNumber,
+		GenTime:       util.ToBeijingTime(raw.GenTime),
+		Accuracy:      TimeStampAccuracy{Seconds: raw.Accuracy.Seconds, Millis: raw.Accuracy.Millis, Micros: raw.Accuracy.Micros},
+		Ordering:      raw.Ordering,
+		Nonce:         raw.Nonce,
+		Extensions:    raw.Extensions,
+		hashOID:       raw.MessageImprint.HashAlgorithm.Algorithm,
+	}
+	if len(raw.TSA.Bytes) > 0 {
+		// tsa为[0] EXPLICIT GeneralName
+		var name asn1.RawValue
+		if _, err := asn1.Unmarshal(raw.TSA.Bytes, &name); err == nil {
+			if generalName, err := parseGeneralName(name); err == nil {
+				tst.TSAName = generalName.String()
+			}
+		}
+	}
+	return tst, nil
+}
+
+// VerifyTimeStampResponse 验证时间戳令牌的CMS签名、ESS签名证书属性及TSA证书的timeStamping用途，
+// certs为令牌未附带TSA证书时补充的证书
+func VerifyTimeStampResponse(info *TimeStampResponseInfo, certs []*x509.Certificate) error {
+	err := verifyTimeStampResponse(info, certs)
+	if err != nil {
+		info.SignatureStatus = CMSSignatureInvalid
+		info.SignatureError = err.Error()
+		return err
+	}
+	info.SignatureStatus = CMSSignatureValid
+	info.SignatureError = ""
+	return nil
+}
+
+func verifyTimeStampResponse(info *TimeStampResponseInfo, certs []*x509.Certificate) error {
+	if info.SignedData == nil {
+		return fmt.Errorf("响应未包含时间戳令牌")
+	}
+	if err := VerifySignedData(info.SignedData, nil, certs); err != nil {
+		return err
+	}
+	signer := info.SignedData.Signers[0]
+	info.Signer = signer.Certificate
+	info.Warnings = append(info.Warnings, signer.Warnings...)
+	if err := checkESSSigningCertificate(signer); err != nil {
+		return err
+	}
+
+	hasTimeStamping := false
+	for _, usage := range info.Signer.ExtKeyUsage {
+		hasTimeStamping = hasTimeStamping || usage == x509.ExtKeyUsageTimeStamping
+	}
+	for _, oid := range info.Signer.UnknownExtKeyUsage {
+		hasTimeStamping = hasTimeStamping || oid.Equal(oidExtKeyUsageTimeStamping)
+	}
+	if !hasTimeStamping {
+		return fmt.Errorf("TSA证书的扩展密钥用法不包含timeStamping")
+	}
+	for _, ext := range info.Signer.Extensions {
+		if ext.Id.Equal(oidExtensionExtKeyUsage) && !ext.Critical {
+			info.Warnings = append(info.Warnings, "TSA证书的扩展密钥用法未标记为关键扩展")
+		}
+	}
+	if len(info.Signer.ExtKeyUsage)+len(info.Signer.UnknownExtKeyUsage) > 1 {
+		info.Warnings = append(info.Warnings, "TSA证书的扩展密钥用法除timeStamping外还包含其他用途")
+	}
+	genTime := info.TSTInfo.GenTime
+	if genTime.Before(info.Signer.NotBefore) || genTime.After(info.Signer.NotAfter) {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("genTime %s 不在TSA证书有效期内", genTime.Format(util.DateTime)))
+	}
+	return nil
+}
+
+// checkESSSigningCertificate 检查signingCertificate或signingCertificateV2属性中的证书摘要与签名者证书一致
+func checkESSSigningCertificate(signer *CMSSignerInfo) error {
+	var hashOID asn1.ObjectIdentifier
+	var certHash []byte
+	if value := findCMSAttribute(signer.attributes, oidSigningCertificateV2); value != nil {
+		var attr essSigningCertificateV2ASN1
+		if err := unmarshalExact(value, &attr); err != nil || len(attr.Certs) == 0 {
+			return fmt.Errorf("解析signingCertificateV2属性失败")
+		}
+		hashOID = attr.Certs[0].HashAlgorithm.Algorithm
+		if len(hashOID) == 0 {
+			hashOID = oidDigestSHA256
+		}
+		certHash = attr.Certs[0].CertHash
+	} else if value := findCMSAttribute(signer.attributes, oidSigningCertificate); value != nil {
+		var attr essSigningCertificateASN1
+		if err := unmarshalExact(value, &attr); err != nil || len(attr.Certs) == 0 {
+			return fmt.Errorf("解析signingCertificate属性失败")
+		}
+		hashOID = oidDigestSHA1
+		certHash = attr.Certs[0].CertHash
+	} else {
+		return fmt.Errorf("签名属性缺少signingCertificate/signingCertificateV2")
+	}
+	newHash, err := hashForOID(hashOID)
+	if err != nil {
+		return err
+	}
+	h := newHash()
+	h.Write(signer.Certificate.Raw)
+	if !bytes.Equal(h.Sum(nil), certHash) {
+		return fmt.Errorf("签名证书属性中的证书摘要与TSA证书不一致")
+	}
+	return nil
+}
+
+// VerifyMessageImprint 检查令牌中的消息摘要与数据一致
+func (info *TimeStampResponseInfo) VerifyMessageImprint(data []byte) error {
+	if info.TSTInfo == nil {
+		return fmt.Errorf("响应未包含时间戳令牌")
+	}
+	newHash, err := hashForOID(info.TSTInfo.hashOID)
+	if err != nil {
+		return err
+	}
+	h := newHash()
+	h.Write(data)
+	if digest := h.Sum(nil); !bytes.Equal(digest, info.TSTInfo.HashedMessage) {
+		return fmt.Errorf("消息摘要不一致: 令牌中为%X，数据的%s摘要为%X", info.TSTInfo.HashedMessage, info.TSTInfo.HashAlgorithm, digest)
+	}
+	return nil
+}
+
+// CheckRequest 检查响应与请求的摘要、nonce、策略及certReq是否对应，返回发现的问题
+func (info *TimeStampResponseInfo) CheckRequest(req *TimeStampRequestInfo) []string {
+	if info.TSTInfo == nil {
+		return nil
+	}
+	var problems []string
+	tst := info.TSTInfo
+	if !tst.hashOID.Equal(req.hashOID) || !bytes.Equal(tst.HashedMessage, req.HashedMessage) {
+		problems = append(problems, "令牌中的消息摘要与请求不一致")
+	}
+	switch {
+	case req.Nonce != nil && tst.Nonce == nil:
+		problems = append(problems, "请求包含nonce，但令牌中没有nonce")
+	case req.Nonce != nil && req.Nonce.Cmp(tst.Nonce) != 0:
+		problems = append(problems, "令牌中的nonce与请求不一致")
+	}
+	if req.Policy != "" && req.Policy != tst.Policy {
+		problems = append(problems, fmt.Sprintf("令牌策略%s与请求的策略%s不一致", tst.Policy, req.Policy))
+	}
+	if req.CertReq && len(info.SignedData.Certificates) == 0 {
+		problems = append(problems, "请求要求附带证书，但令牌中没有证书")
+	}
+	return problems
+}
+
+// QueryTSA 以HTTP POST方式向时间戳服务发送请求并返回响应
+func QueryTSA(url string, request []byte, timeout time.Duration) ([]byte, error) {
+	return postRequest("时间戳", url, "application/timestamp-query", request, timeout)
+}
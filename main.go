@@ -1,7 +1,15 @@
 package main
 
-import "HeTu/window"
+import (
+	"HeTu/cli"
+	"HeTu/window"
+	"os"
+)
 
 func main() {
+	// 带参数时以命令行模式运行，否则启动图形界面
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
 	window.NewWindow()
 }
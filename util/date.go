@@ -1,14 +1,28 @@
 package util
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 const DateTime = "2006-01-02 15:04:05"
 
 // yyyy-MM-dd HH:mm:ss
 const FormatStr = "060102150405Z0700"
 
+// beijingLocation 所有转换共用同一时区对象，避免大批量转换(如CRL条目)时重复分配
+var beijingLocation = time.FixedZone("CST", 8*3600)
+
 // toBeijingTime 转换为北京时间
 func ToBeijingTime(t time.Time) time.Time {
-	loc := time.FixedZone("CST", 8*3600)
-	return t.In(loc)
+	return t.In(beijingLocation)
+}
+
+// ParseDateTime 按DateTime格式解析北京时间，空串返回零值
+func ParseDateTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(DateTime, s, beijingLocation)
 }
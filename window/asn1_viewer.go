@@ -3,7 +3,6 @@ package window
 import (
 	. "HeTu/helper"
 	"HeTu/util"
-	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"strings"
@@ -25,7 +24,7 @@ func buildAccordion(node ASN1Node, level int) *widget.AccordionItem {
 	}
 
 	// 根据节点Tag获取指定类型
-	name := getRealTag(node.Tag)
+	name := TagName(node.Tag)
 
 	// 标签名称，添加更多信息和状态图标
 	var value string
@@ -311,38 +310,20 @@ func Asn1Structure(input *widget.Entry) *fyne.Container {
 			}
 
 			fyne.Do(func() {
-				statusLabel.SetText("正在清理输入数据...")
-				progressBar.SetValue(0.2)
+				statusLabel.SetText("正在解码数据...")
+				progressBar.SetValue(0.4)
 			})
 
-			cleanedInput := cleanInputForASN1(inputData)
-			if cleanedInput == "" {
+			decodedData, encoding, err := decodeInput(inputData)
+			if err != nil {
 				fyne.Do(func() {
-					dialog.ShowError(fmt.Errorf("清理后的数据为空"), fyne.CurrentApp().Driver().AllWindows()[0])
-					statusLabel.SetText("解析失败：数据无效")
+					dialog.ShowError(fmt.Errorf("无法解码输入数据\n%v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+					statusLabel.SetText("解析失败：解码错误")
 					progressBar.Hide()
 				})
 				return
 			}
 
-			fyne.Do(func() {
-				statusLabel.SetText("正在解码数据...")
-				progressBar.SetValue(0.4)
-			})
-
-			decodedData, err := base64.StdEncoding.DecodeString(cleanedInput)
-			if err != nil {
-				decodedData, err = hex.DecodeString(cleanedInput)
-				if err != nil {
-					fyne.Do(func() {
-						dialog.ShowError(fmt.Errorf("无法解码输入数据\nBase64错误: %v\nHex错误: %v", err, err), fyne.CurrentApp().Driver().AllWindows()[0])
-						statusLabel.SetText("解析失败：解码错误")
-						progressBar.Hide()
-					})
-					return
-				}
-			}
-
 			if len(decodedData) < 2 || len(decodedData) > 2*1024*1024 {
 				fyne.Do(func() {
 					dialog.ShowError(fmt.Errorf("解码后数据大小异常（%d 字节）", len(decodedData)), fyne.CurrentApp().Driver().AllWindows()[0])
@@ -373,11 +354,12 @@ func Asn1Structure(input *widget.Entry) *fyne.Container {
 
 			statsInfo := widget.NewRichTextFromMarkdown(fmt.Sprintf(
 				"📊 **解析统计**\n\n"+
+					"- 输入格式: %s\n"+
 					"- 数据大小: %d 字节\n"+
 					"- 节点总数: %d\n"+
 					"- 最大深度: %d\n"+
 					"- 根节点类型: %s",
-				len(decodedData), childrenCount, maxDepth, getRealTag(rootNode.Tag)))
+				encoding, len(decodedData), childrenCount, maxDepth, TagName(rootNode.Tag)))
 
 			fyne.Do(func() {
 				if accordion.Items != nil && len(accordion.Items) > 0 {
@@ -433,49 +415,6 @@ func Asn1Structure(input *widget.Entry) *fyne.Container {
 	return container.NewMax(scrollContainer)
 }
 
-func getRealTag(tag int) string {
-	prefix := ""
-	//32 = 0x20, ASN1中小于0x20的都是通用简单类型
-
-	//0x20 到 0x40 通用,结构类型
-	if 32 <= tag && tag < 64 {
-		//prefix = "Universal Structure "
-		tag -= 32
-	} else if 64 <= tag && tag < 96 {
-		prefix = "Application Simple "
-		tag -= 64
-	} else if 96 <= tag && tag < 128 {
-		prefix = "Application Structure "
-		tag -= 96
-	} else if 128 <= tag && tag < 160 {
-		prefix = "Context Specific Simple "
-		tag -= 128
-	} else if 160 <= tag && tag < 192 {
-		prefix = "Context Specific Structure "
-		tag -= 160
-	} else if 192 <= tag && tag < 224 {
-		prefix = "Private Simple "
-		tag -= 192
-	} else if 224 <= tag && tag < 256 {
-		prefix = "Private Structure "
-		tag -= 224
-	}
-	if len(prefix) > 0 {
-		prefix = fmt.Sprintf("%s :", prefix)
-	}
-	return prefix + TagToName[tag]
-}
-
-// cleanInputForASN1 清理ASN1输入数据，移除可能影响解析的字符
-func cleanInputForASN1(input string) string {
-	// 移除所有空格、换行符、制表符等空白字符
-	cleaned := strings.ReplaceAll(input, " ", "")
-	cleaned = strings.ReplaceAll(cleaned, "\n", "")
-	cleaned = strings.ReplaceAll(cleaned, "\r", "")
-	cleaned = strings.ReplaceAll(cleaned, "\t", "")
-	return strings.TrimSpace(cleaned)
-}
-
 // countChildren 计算节点总数
 func countChildren(node ASN1Node) int {
 	count := 1 // 当前节点
@@ -1,6 +1,7 @@
 package window
 
 import (
+	"HeTu/codec"
 	"HeTu/util"
 	"encoding/base64"
 	"encoding/hex"
@@ -40,20 +41,15 @@ func CoderStructure(input *widget.Entry) *fyne.Container {
 		}
 
 		output.Text = ""
-		decodedData, err := hex.DecodeString(inputData)
-		if err == nil {
+		decoded := codec.DecodeOrText(inputData)
+		decodedData := decoded.Bytes()
+		if decoded.Encoding == codec.EncodingHex {
 			output.Text = base64.StdEncoding.EncodeToString(decodedData)
 		} else {
-			decodedData, err = base64.StdEncoding.DecodeString(inputData)
-			if err == nil {
-				output.Text = hex.EncodeToString(decodedData)
-			} else {
-				decodedData = []byte(inputData)
-				output.Text = hex.EncodeToString(decodedData)
-			}
+			output.Text = hex.EncodeToString(decodedData)
 		}
 		dataLen := len(decodedData)
-		dataLenPrint.Text = fmt.Sprintf("%s%d%s", "数据长度为:", dataLen, "字节(优先按照HEX解析,后按照Base64解析,普通字符串兜底)")
+		dataLenPrint.Text = fmt.Sprintf("数据长度为:%d字节(输入格式: %s，普通字符串兜底)", dataLen, decoded.Describe())
 
 		dataLenPrint.Refresh()
 		output.Show()
@@ -1,35 +1,64 @@
 package window
 
 import (
+	"HeTu/codec"
 	"HeTu/helper"
+	"HeTu/util"
+	"bufio"
+	"bytes"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"image/color"
+	"io"
+	"math/big"
+	"strconv"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/zaneway/cain-go/x509"
 )
 
+// crlPageSize CRL吊销条目每页显示数量
+const crlPageSize = 100
+
 // CRL证书撤销列表解析和验证功能
 func CrlStructure(input *widget.Entry) *fyne.Container {
 	// 移除占位符设置，由主界面统一管理
 	structure := container.NewVBox()
 	input.Wrapping = fyne.TextWrapWord
-	certSNInput := buildInputCertEntry("请输入要验证的证书序列号")
+	certSNInput := buildInputCertEntry("请输入要验证的证书序列号，多个序列号按行或逗号分隔")
 	certSNInput.Wrapping = fyne.TextWrapWord
+	issuerInput := buildInputCertEntry("请输入CRL颁发者证书(PEM/Base64/Hex)，用于验证CRL签名")
+	issuerInput.Wrapping = fyne.TextWrapWord
+
+	// 解析进度，大CRL在后台解析
+	statusLabel := widget.NewLabel("")
+	progressBar := widget.NewProgressBarInfinite()
+	progressBar.Hide()
 
 	// 创建CRL详情显示区域
 	crlDetails := widget.NewMultiLineEntry()
 	crlDetails.SetPlaceHolder("CRL详细信息将在这里显示")
 	crlDetails.Hide()
 
+	// 吊销条目分页显示
+	entriesOutput := widget.NewMultiLineEntry()
+	entriesOutput.Hide()
+	pageLabel := widget.NewLabel("")
+	pageInput := widget.NewEntry()
+	pageInput.SetPlaceHolder("页码")
+
 	// 创建验证结果输出框
 	output := widget.NewMultiLineEntry()
 	output.SetPlaceHolder("证书验证结果将在这里显示")
@@ -37,6 +66,73 @@ func CrlStructure(input *widget.Entry) *fyne.Container {
 
 	// 当前加载的CRL信息
 	var currentCRLInfo *helper.CRLInfo
+	var currentEncoding string
+	currentPage := 1
+
+	showPage := func(page int) {
+		if currentCRLInfo == nil {
+			return
+		}
+		entries, page, pages := currentCRLInfo.RevokedPage(page, crlPageSize)
+		currentPage = page
+		entriesOutput.SetText(formatRevokedEntries(entries, (page-1)*crlPageSize))
+		pageLabel.SetText(fmt.Sprintf("第 %d/%d 页，共 %d 条", page, pages, currentCRLInfo.TotalRevoked))
+		entriesOutput.Show()
+	}
+	prevBtn := widget.NewButtonWithIcon("上一页", theme.NavigateBackIcon(), func() { showPage(currentPage - 1) })
+	nextBtn := widget.NewButtonWithIcon("下一页", theme.NavigateNextIcon(), func() { showPage(currentPage + 1) })
+	jumpBtn := widget.NewButton("跳转", func() {
+		page, err := strconv.Atoi(strings.TrimSpace(pageInput.Text))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("页码必须为整数"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		showPage(page)
+	})
+	pager := container.NewHBox(prevBtn, pageLabel, nextBtn, layout.NewSpacer(), pageInput, jumpBtn)
+	pager.Hide()
+
+	// loadCRL 在后台解析CRL并验证签名，完成后刷新详情与第一页条目。
+	// 颁发者证书在读取前解析，大型CRL的SM2签名摘要需要在读取时计算，解析失败的错误由签名验证报告
+	loadCRL := func(load func(issuers []*x509.Certificate, progress func(entries int)) (*helper.CRLInfo, string, error)) {
+		issuerText := strings.TrimSpace(issuerInput.Text)
+		var issuers []*x509.Certificate
+		if issuerText != "" {
+			issuers, _ = decodeCertificateChain(issuerText)
+		}
+		statusLabel.SetText("正在解析CRL...")
+		progressBar.Show()
+		go func() {
+			crlInfo, encoding, err := load(issuers, func(entries int) {
+				fyne.Do(func() {
+					statusLabel.SetText(fmt.Sprintf("正在解析CRL，已读取 %d 个吊销条目...", entries))
+				})
+			})
+			var verifyErr error
+			if err == nil && issuerText != "" {
+				fyne.Do(func() { statusLabel.SetText("正在验证CRL签名...") })
+				verifyErr = verifyCRLWithIssuerInput(crlInfo, issuerText)
+			}
+			fyne.Do(func() {
+				progressBar.Hide()
+				if err != nil {
+					statusLabel.SetText("解析失败")
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+					return
+				}
+				if verifyErr != nil {
+					dialog.ShowError(verifyErr, fyne.CurrentApp().Driver().AllWindows()[0])
+				}
+				currentCRLInfo = crlInfo
+				currentEncoding = encoding
+				displayCRLDetails(crlDetails, crlInfo, encoding)
+				crlDetails.Show()
+				showPage(1)
+				pager.Show()
+				statusLabel.SetText(fmt.Sprintf("✅ 解析完成，共 %d 个吊销条目", crlInfo.TotalRevoked))
+			})
+		}()
+	}
 
 	// 解析CRL按钮 - 从输入框解析Base64/Hex/PEM格式的CRL数据
 	parseBtn := buildButton("解析CRL", theme.ConfirmIcon(), func() {
@@ -46,68 +142,21 @@ func CrlStructure(input *widget.Entry) *fyne.Container {
 			return
 		}
 
-		// 尝试解析CRL数据
-		var decodeData []byte
-		var err error
-		var isPEMFormat bool
-
-		// 检查是否是PEM格式
-		trimmedInput := strings.TrimSpace(inputData)
-		if strings.HasPrefix(trimmedInput, "-----BEGIN X509 CRL-----") ||
-			strings.HasPrefix(trimmedInput, "-----BEGIN CRL-----") ||
-			strings.Contains(trimmedInput, "-----BEGIN") {
-			isPEMFormat = true
-			// 尝试处理PEM格式CRL
-			decodeData, err = parsePEMCRL(inputData)
+		loadCRL(func(issuers []*x509.Certificate, progress func(entries int)) (*helper.CRLInfo, string, error) {
+			decodeData, encoding, err := decodeInput(inputData, "X509 CRL", "CRL")
 			if err != nil {
-				// PEM解析失败，回退到Base64/Hex解码
-				isPEMFormat = false
+				return nil, "", fmt.Errorf("无法解码输入数据，请确保输入的是有效的Base64、Hex或PEM格式CRL数据\n\n%v", err)
 			}
-		}
-
-		// 如果不是PEM格式，或者PEM解析失败，尝试Base64/Hex解码
-		if !isPEMFormat {
-			// 清理输入数据，移除空格和换行符
-			cleanedInput := cleanInputData(inputData)
-
-			// 尝试Base64解码
-			decodeData, err = base64.StdEncoding.DecodeString(cleanedInput)
+			// 验证解码后的数据长度
+			if len(decodeData) < 50 {
+				return nil, "", fmt.Errorf("解码后的数据太短（%d 字节），不像是有效的CRL数据", len(decodeData))
+			}
+			crlInfo, err := helper.ReadCRL(bytes.NewReader(decodeData), issuers, progress)
 			if err != nil {
-				// 尝试URL-safe Base64
-				decodeData, err = base64.URLEncoding.DecodeString(cleanedInput)
-				if err != nil {
-					// 尝试添加填充后解码
-					cleanedWithPadding := addBase64Padding(cleanedInput)
-					decodeData, err = base64.StdEncoding.DecodeString(cleanedWithPadding)
-					if err != nil {
-						// 如果Base64失败，尝试Hex解码
-						decodeData, err = hex.DecodeString(cleanedInput)
-						if err != nil {
-							dialog.ShowError(fmt.Errorf("无法解码输入数据，请确保输入的是有效的Base64、Hex或PEM格式CRL数据\n\nBase64错误: %v\nHex错误: %v", err, err), fyne.CurrentApp().Driver().AllWindows()[0])
-							return
-						}
-					}
-				}
+				return nil, "", err
 			}
-		}
-
-		// 验证解码后的数据长度
-		if len(decodeData) < 50 {
-			dialog.ShowError(fmt.Errorf("解码后的数据太短（%d 字节），不像是有效的CRL数据", len(decodeData)), fyne.CurrentApp().Driver().AllWindows()[0])
-			return
-		}
-
-		// 解析CRL
-		crlInfo, err := helper.ParseCRL(decodeData)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("解析CRL失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
-			return
-		}
-
-		currentCRLInfo = crlInfo
-		// 显示CRL详情
-		displayCRLDetails(crlDetails, crlInfo)
-		crlDetails.Show()
+			return crlInfo, encoding, nil
+		})
 	})
 
 	// 文件选择按钮
@@ -121,36 +170,15 @@ func CrlStructure(input *widget.Entry) *fyne.Container {
 			if reader == nil {
 				return
 			}
-			defer reader.Close()
 
 			// 获取文件路径并显示
 			filePath := reader.URI().Path()
 			input.SetText(fmt.Sprintf("已选择文件: %s", filePath))
 
-			// 读取文件内容
-			data := make([]byte, 0)
-			buffer := make([]byte, 1024)
-			for {
-				n, err := reader.Read(buffer)
-				if n > 0 {
-					data = append(data, buffer[:n]...)
-				}
-				if err != nil {
-					break
-				}
-			}
-
-			// 解析CRL
-			crlInfo, err := helper.ParseCRL(data)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("解析CRL文件失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
-				return
-			}
-
-			currentCRLInfo = crlInfo
-			// 显示CRL详情
-			displayCRLDetails(crlDetails, crlInfo)
-			crlDetails.Show()
+			loadCRL(func(issuers []*x509.Certificate, progress func(entries int)) (*helper.CRLInfo, string, error) {
+				defer reader.Close()
+				return readCRLFile(reader, issuers, progress)
+			})
 		}, fyne.CurrentApp().Driver().AllWindows()[0])
 
 		// 设置文件过滤器
@@ -165,24 +193,62 @@ func CrlStructure(input *widget.Entry) *fyne.Container {
 			return
 		}
 
-		inputCertSN := strings.TrimSpace(certSNInput.Text)
-		if inputCertSN == "" {
+		serials := helper.SplitSerialNumbers(certSNInput.Text)
+		if len(serials) == 0 {
 			dialog.ShowInformation("提示", "请输入要验证的证书序列号", fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
 
-		isRevoked, revokedCert := helper.CheckCertificateRevocation(currentCRLInfo, inputCertSN)
-		displayVerificationResult(output, inputCertSN, isRevoked, revokedCert)
+		problems := currentCRLInfo.CheckFreshness(time.Now())
+		if len(serials) == 1 {
+			isRevoked, revokedCert := helper.CheckCertificateRevocation(currentCRLInfo, serials[0])
+			displayVerificationResult(output, serials[0], isRevoked, revokedCert, problems)
+		} else {
+			displayBatchVerificationResult(output, currentCRLInfo.LookupSerials(serials), problems)
+		}
 		output.Show()
 	})
 
+	// 验证CRL签名按钮
+	verifySignatureBtn := buildButton("验证CRL签名", theme.ConfirmIcon(), func() {
+		if currentCRLInfo == nil {
+			dialog.ShowInformation("提示", "请先解析CRL或选择CRL文件", fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		issuerText := strings.TrimSpace(issuerInput.Text)
+		if issuerText == "" {
+			dialog.ShowInformation("提示", "请输入CRL颁发者证书", fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		crlInfo := currentCRLInfo
+		statusLabel.SetText("正在验证CRL签名...")
+		progressBar.Show()
+		go func() {
+			err := verifyCRLWithIssuerInput(crlInfo, issuerText)
+			fyne.Do(func() {
+				progressBar.Hide()
+				statusLabel.SetText("")
+				if err != nil {
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				}
+				displayCRLDetails(crlDetails, crlInfo, currentEncoding)
+				crlDetails.Show()
+			})
+		}()
+	})
+
 	//清除按钮
 	clear := buildButton("清除", theme.CancelIcon(), func() {
 		input.SetText("")
 		certSNInput.SetText("")
+		issuerInput.SetText("")
 		crlDetails.SetText("")
+		entriesOutput.SetText("")
 		output.SetText("")
+		statusLabel.SetText("")
 		crlDetails.Hide()
+		entriesOutput.Hide()
+		pager.Hide()
 		output.Hide()
 		currentCRLInfo = nil
 		input.Refresh()
@@ -197,6 +263,12 @@ func CrlStructure(input *widget.Entry) *fyne.Container {
 	// structure.Add(widget.NewLabel("CRL数据输入:"))
 	// structure.Add(input)
 	structure.Add(buttonRow1)
+	structure.Add(statusLabel)
+	structure.Add(progressBar)
+	structure.Add(widget.NewSeparator())
+	structure.Add(widget.NewLabel("颁发者证书:"))
+	structure.Add(issuerInput)
+	structure.Add(verifySignatureBtn)
 	structure.Add(widget.NewSeparator())
 	structure.Add(widget.NewLabel("证书序列号:"))
 	structure.Add(certSNInput)
@@ -204,53 +276,465 @@ func CrlStructure(input *widget.Entry) *fyne.Container {
 	structure.Add(widget.NewSeparator())
 	structure.Add(widget.NewLabel("CRL详情:"))
 	structure.Add(crlDetails)
+	structure.Add(widget.NewLabel("被吊销证书列表:"))
+	structure.Add(pager)
+	structure.Add(entriesOutput)
 	structure.Add(widget.NewLabel("验证结果:"))
 	structure.Add(output)
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildCrlGenerateForm())
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildCrlDiffForm())
 
 	// 使用滚动容器支持长内容
 	scrollContainer := container.NewScroll(structure)
 	return container.NewMax(scrollContainer)
 }
 
+// readCRLFile 读取CRL文件，DER及PEM文件流式解析，Base64/Hex等文本编码交给codec识别后解析
+func readCRLFile(reader io.Reader, issuers []*x509.Certificate, progress func(entries int)) (*helper.CRLInfo, string, error) {
+	buffered := bufio.NewReader(reader)
+	if prefix, _ := buffered.Peek(11); len(prefix) > 0 && (prefix[0] == 0x30 || string(prefix) == "-----BEGIN ") {
+		encoding := "DER"
+		if prefix[0] != 0x30 {
+			encoding = "PEM"
+		}
+		crlInfo, err := helper.ReadCRL(buffered, issuers, progress)
+		if err != nil {
+			return nil, "", fmt.Errorf("解析CRL文件失败: %v", err)
+		}
+		return crlInfo, encoding, nil
+	}
+
+	data, err := io.ReadAll(buffered)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取CRL文件失败: %v", err)
+	}
+	decoded, err := codec.Decode(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("无法识别CRL文件格式: %v", err)
+	}
+	crlData, ok := decoded.FirstOfType("X509 CRL", "CRL")
+	if !ok {
+		return nil, "", fmt.Errorf("CRL文件中未找到CRL数据，输入为 %s", decoded.Describe())
+	}
+	crlInfo, err := helper.ReadCRL(bytes.NewReader(crlData), issuers, progress)
+	if err != nil {
+		return nil, "", fmt.Errorf("解析CRL文件失败: %v", err)
+	}
+	return crlInfo, decoded.Describe(), nil
+}
+
+// buildCrlGenerateForm 由CA证书与私钥签发CRL，可设置吊销条目、CRL Number、增量CRL及有效期
+func buildCrlGenerateForm() *fyne.Container {
+	title := widget.NewLabel("签发CRL")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	certInput := buildInputCertEntry("请输入CA证书(PEM/Base64/Hex)")
+	certInput.Wrapping = fyne.TextWrapWord
+	keyInput := buildInputCertEntry("请输入CA私钥(SM2或RSA)")
+	keyInput.Wrapping = fyne.TextWrapWord
+	entriesInput := widget.NewMultiLineEntry()
+	entriesInput.SetPlaceHolder("吊销条目，每行一个: 序列号(Hex),原因,吊销时间,失效日期\n如: 1A2B,keyCompromise,2026-01-02 10:00:00,2026-01-01 00:00:00\n原因可为原因码、名称(如superseded)或中文名称，后三项可省略，不填则为空CRL")
+	entriesInput.Wrapping = fyne.TextWrapWord
+
+	now := time.Now()
+	thisUpdateInput := widget.NewEntry()
+	thisUpdateInput.SetText(now.Format(util.DateTime))
+	nextUpdateInput := widget.NewEntry()
+	nextUpdateInput.SetText(now.AddDate(0, 0, 7).Format(util.DateTime))
+	nextUpdateInput.SetPlaceHolder("留空则不包含nextUpdate，可早于当前时间以构造过期CRL")
+	crlNumberInput := widget.NewEntry()
+	crlNumberInput.SetPlaceHolder("可选，十进制")
+	baseNumberInput := widget.NewEntry()
+	baseNumberInput.SetPlaceHolder("可选，十进制，填写后签发增量CRL")
+	formatSelect := widget.NewSelect([]string{"PEM", "Base64(DER)", "Hex(DER)"}, nil)
+	formatSelect.SetSelected("PEM")
+
+	form := widget.NewForm(
+		widget.NewFormItem("thisUpdate", thisUpdateInput),
+		widget.NewFormItem("nextUpdate", nextUpdateInput),
+		widget.NewFormItem("CRL Number", crlNumberInput),
+		widget.NewFormItem("基础CRL序号", baseNumberInput),
+		widget.NewFormItem("输出格式", formatSelect),
+	)
+
+	output := widget.NewMultiLineEntry()
+	output.Wrapping = fyne.TextWrapWord
+	output.Hide()
+
+	confirm := buildButton("签发CRL", theme.ConfirmIcon(), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+
+		decodeCert, _, err := decodeInput(certInput.Text, "CERTIFICATE")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("CA证书解码失败: %v", err), window)
+			return
+		}
+		issuer, err := helper.ParseCertificate(decodeCert)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析CA证书错误: %v", err), window)
+			return
+		}
+		decodeKey, _, err := decodeInput(keyInput.Text, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("私钥解码失败: %v", err), window)
+			return
+		}
+		privateKey, err := helper.ParsePrivateKey(decodeKey)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析私钥错误: %v", err), window)
+			return
+		}
+
+		template := &helper.CRLTemplate{}
+		if template.ThisUpdate, err = util.ParseDateTime(thisUpdateInput.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("thisUpdate格式应为 %s", util.DateTime), window)
+			return
+		}
+		if template.NextUpdate, err = util.ParseDateTime(nextUpdateInput.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("nextUpdate格式应为 %s", util.DateTime), window)
+			return
+		}
+		if template.CRLNumber, err = parseOptionalDecimal(crlNumberInput.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("CRL Number: %v", err), window)
+			return
+		}
+		if template.BaseCRLNumber, err = parseOptionalDecimal(baseNumberInput.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("基础CRL序号: %v", err), window)
+			return
+		}
+		if template.Entries, err = helper.ParseCRLEntries(entriesInput.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("解析吊销条目失败: %v", err), window)
+			return
+		}
+
+		crl, err := helper.CreateCRL(template, issuer, privateKey)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("签发CRL失败: %v", err), window)
+			return
+		}
+		switch formatSelect.Selected {
+		case "Base64(DER)":
+			output.SetText(base64.StdEncoding.EncodeToString(crl))
+		case "Hex(DER)":
+			output.SetText(hex.EncodeToString(crl))
+		default:
+			output.SetText(string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl})))
+		}
+		output.Show()
+	})
+	clear := buildButton("清除", theme.CancelIcon(), func() {
+		certInput.SetText("")
+		keyInput.SetText("")
+		entriesInput.SetText("")
+		crlNumberInput.SetText("")
+		baseNumberInput.SetText("")
+		output.SetText("")
+		output.Hide()
+	})
+
+	allButton := container.New(layout.NewGridLayout(2), confirm, clear)
+	return container.NewVBox(title, certInput, keyInput, widget.NewLabel("吊销条目:"), entriesInput, form, allButton, output)
+}
+
+// crlSource CRL比较中的一个输入，可粘贴文本或选择文件
+type crlSource struct {
+	input *widget.Entry
+	uri   fyne.URI
+}
+
+// newCRLSource 创建CRL输入框及文件选择按钮，选择文件后输入框显示文件路径
+func newCRLSource(placeholder string) (*crlSource, *fyne.Container) {
+	source := &crlSource{input: buildInputCertEntry(placeholder)}
+	source.input.OnChanged = func(string) { source.uri = nil }
+	selectBtn := buildButton("选择文件", theme.FolderOpenIcon(), func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("打开文件失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			if reader == nil {
+				return
+			}
+			reader.Close()
+			source.input.SetText(fmt.Sprintf("已选择文件: %s", reader.URI().Path()))
+			source.uri = reader.URI()
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".crl", ".der", ".pem", ".cer", ".crt"}))
+		fileDialog.Show()
+	})
+	return source, container.NewBorder(nil, nil, nil, selectBtn, source.input)
+}
+
+// load 读取并解析CRL，文件输入流式读取，issuers为候选颁发者证书，大型SM2 CRL需提供才能验证签名
+func (source *crlSource) load(issuers []*x509.Certificate) (*helper.CRLInfo, error) {
+	if source.uri != nil {
+		reader, err := storage.Reader(source.uri)
+		if err != nil {
+			return nil, fmt.Errorf("打开文件失败: %v", err)
+		}
+		defer reader.Close()
+		crlInfo, _, err := readCRLFile(reader, issuers, nil)
+		return crlInfo, err
+	}
+	inputData := strings.TrimSpace(source.input.Text)
+	if inputData == "" {
+		return nil, fmt.Errorf("请输入CRL数据或选择CRL文件")
+	}
+	decodeData, _, err := decodeInput(inputData, "X509 CRL", "CRL")
+	if err != nil {
+		return nil, fmt.Errorf("无法解码CRL数据: %v", err)
+	}
+	return helper.ReadCRL(bytes.NewReader(decodeData), issuers, nil)
+}
+
+// buildCrlDiffForm 比较同一颁发者的新旧两个CRL，结果以表格显示并可导出CSV/JSON
+func buildCrlDiffForm() *fyne.Container {
+	title := widget.NewLabel("CRL比较")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	oldSource, oldRow := newCRLSource("请输入旧CRL(PEM/Base64/Hex)")
+	newSource, newRow := newCRLSource("请输入新CRL(PEM/Base64/Hex)")
+	statusLabel := widget.NewLabel("")
+	progressBar := widget.NewProgressBarInfinite()
+	progressBar.Hide()
+
+	summary := widget.NewMultiLineEntry()
+	summary.Wrapping = fyne.TextWrapWord
+	summary.Hide()
+
+	var currentDiff *helper.CRLDiff
+	table := widget.NewTable(
+		func() (int, int) {
+			if currentDiff == nil {
+				return 0, len(helper.CRLDiffColumns)
+			}
+			return len(currentDiff.Entries) + 1, len(helper.CRLDiffColumns)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(helper.CRLDiffColumns[id.Col])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			label.SetText(currentDiff.Entries[id.Row-1].Row()[id.Col])
+		},
+	)
+	for col, width := range []float32{110, 320, 180, 180, 130, 130} {
+		table.SetColumnWidth(col, width)
+	}
+	tableMinSize := canvas.NewRectangle(color.Transparent)
+	tableMinSize.SetMinSize(fyne.NewSize(0, 400))
+	tableArea := container.NewStack(tableMinSize, table)
+	tableArea.Hide()
+
+	// export 将比较结果保存为文件
+	export := func(fileName string, write func(diff *helper.CRLDiff, w io.Writer) error) {
+		if currentDiff == nil {
+			dialog.ShowInformation("提示", "请先比较CRL", fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		diff := currentDiff
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("保存文件失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			buffered := bufio.NewWriter(writer)
+			if err := write(diff, buffered); err != nil {
+				dialog.ShowError(fmt.Errorf("导出失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			if err := buffered.Flush(); err != nil {
+				dialog.ShowError(fmt.Errorf("导出失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			dialog.ShowInformation("导出成功", fmt.Sprintf("已保存到 %s", writer.URI().Path()), fyne.CurrentApp().Driver().AllWindows()[0])
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+		saveDialog.SetFileName(fileName)
+		saveDialog.Show()
+	}
+	exportCSVBtn := buildButton("导出CSV", theme.DocumentSaveIcon(), func() {
+		export("crl-diff.csv", (*helper.CRLDiff).WriteCSV)
+	})
+	exportJSONBtn := buildButton("导出JSON", theme.DocumentSaveIcon(), func() {
+		export("crl-diff.json", (*helper.CRLDiff).WriteJSON)
+	})
+
+	compareBtn := buildButton("比较", theme.ConfirmIcon(), func() {
+		statusLabel.SetText("正在解析并比较CRL...")
+		progressBar.Show()
+		go func() {
+			diff, err := func() (*helper.CRLDiff, error) {
+				oldCRL, err := oldSource.load(nil)
+				if err != nil {
+					return nil, fmt.Errorf("旧CRL: %v", err)
+				}
+				newCRL, err := newSource.load(nil)
+				if err != nil {
+					return nil, fmt.Errorf("新CRL: %v", err)
+				}
+				return helper.DiffCRL(oldCRL, newCRL)
+			}()
+			fyne.Do(func() {
+				progressBar.Hide()
+				if err != nil {
+					statusLabel.SetText("比较失败")
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+					return
+				}
+				currentDiff = diff
+				text := strings.Join(diff.Summary(), "\n")
+				for _, warning := range diff.Warnings {
+					text += "\n⚠️ " + warning
+				}
+				summary.SetText(text)
+				summary.Show()
+				table.Refresh()
+				table.ScrollToTop()
+				tableArea.Show()
+				statusLabel.SetText(fmt.Sprintf("✅ 比较完成，共 %d 处变更", len(diff.Entries)))
+			})
+		}()
+	})
+	clear := buildButton("清除", theme.CancelIcon(), func() {
+		oldSource.input.SetText("")
+		newSource.input.SetText("")
+		currentDiff = nil
+		summary.SetText("")
+		statusLabel.SetText("")
+		summary.Hide()
+		table.Refresh()
+		tableArea.Hide()
+	})
+
+	buttons := container.New(layout.NewGridLayout(4), compareBtn, exportCSVBtn, exportJSONBtn, clear)
+	return container.NewVBox(title, widget.NewLabel("旧CRL:"), oldRow, widget.NewLabel("新CRL:"), newRow,
+		buttons, statusLabel, progressBar, summary, tableArea)
+}
+
+// parseOptionalDecimal 解析十进制大整数，空输入返回nil
+func parseOptionalDecimal(input string) (*big.Int, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(input, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的十进制整数: %s", input)
+	}
+	return n, nil
+}
+
 // displayCRLDetails 显示CRL详细信息
-func displayCRLDetails(detailsWidget *widget.Entry, crlInfo *helper.CRLInfo) {
-	details := fmt.Sprintf(`CRL详细信息:
-`+
-		//手动换行
-		`颁发者: %s
-`+
-		`本次更新时间: %s
-`+
-		`下次更新时间: %s
-`+
-		`签名算法: %s
-`+
-		`被吊销证书总数: %d
-`+
-		`被吊销证书列表:
-`,
-		crlInfo.Issuer,
-		crlInfo.ThisUpdate.Format("2006-01-02 15:04:05"),
-		crlInfo.NextUpdate.Format("2006-01-02 15:04:05"),
-		crlInfo.SignatureAlgorithm,
-		crlInfo.TotalRevoked)
-
-	for i, cert := range crlInfo.RevokedCerts {
-		if i >= 20 { // 限制显示前20个，避免界面过长
-			details += fmt.Sprintf("... 还有 %d 个被吊销的证书\n", len(crlInfo.RevokedCerts)-20)
-			break
-		}
-		details += fmt.Sprintf("%d. 序列号: %s, 吊销时间: %s, 原因: %s\n",
-			i+1, cert.SerialNumber,
-			cert.RevocationTime.Format("2006-01-02 15:04:05"),
+func displayCRLDetails(detailsWidget *widget.Entry, crlInfo *helper.CRLInfo, encoding string) {
+	var b strings.Builder
+	b.WriteString("CRL详细信息:\n")
+	fmt.Fprintf(&b, "输入格式: %s\n", encoding)
+	fmt.Fprintf(&b, "版本: V%d\n", crlInfo.Version)
+	fmt.Fprintf(&b, "颁发者: %s\n", crlInfo.Issuer)
+	fmt.Fprintf(&b, "本次更新时间: %s\n", crlInfo.ThisUpdate.Format(util.DateTime))
+	if crlInfo.NextUpdate.IsZero() {
+		b.WriteString("下次更新时间: 未设置\n")
+	} else {
+		fmt.Fprintf(&b, "下次更新时间: %s\n", crlInfo.NextUpdate.Format(util.DateTime))
+	}
+	fmt.Fprintf(&b, "签名算法: %s\n", crlInfo.SignatureAlgorithm)
+	switch crlInfo.SignatureStatus {
+	case helper.CRLSignatureValid:
+		fmt.Fprintf(&b, "签名状态: ✅ %s\n", crlInfo.SignatureStatus)
+	case helper.CRLSignatureInvalid:
+		fmt.Fprintf(&b, "签名状态: ❌ %s: %s\n", crlInfo.SignatureStatus, crlInfo.SignatureError)
+	default:
+		fmt.Fprintf(&b, "签名状态: ⚠️ %s\n", crlInfo.SignatureStatus)
+	}
+	if crlInfo.CRLNumber != nil {
+		fmt.Fprintf(&b, "CRL序号: %s\n", crlInfo.CRLNumber)
+	}
+	if crlInfo.DeltaCRLIndicator != nil {
+		fmt.Fprintf(&b, "增量CRL，基础CRL序号: %s\n", crlInfo.DeltaCRLIndicator)
+	}
+	for _, ext := range crlInfo.Extensions {
+		switch ext.Id.String() {
+		case "2.5.29.20", "2.5.29.27":
+			continue
+		}
+		name := helper.ExtensionNames[ext.Id.String()]
+		if name == "" {
+			name = ext.Id.String()
+		}
+		if ext.Critical {
+			name += " (关键)"
+		}
+		fmt.Fprintf(&b, "%s:\n  %s\n", name, strings.ReplaceAll(strings.TrimSpace(helper.FormatExtension(ext.Id.String(), ext.Value)), "\n", "\n  "))
+	}
+
+	if problems := crlInfo.CheckFreshness(time.Now()); len(problems) > 0 {
+		b.WriteString("\n⚠️ 注意:\n")
+		for _, problem := range problems {
+			fmt.Fprintf(&b, "- %s\n", problem)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "被吊销证书总数: %d\n", crlInfo.TotalRevoked)
+
+	detailsWidget.SetText(b.String())
+}
+
+// formatRevokedEntries 格式化一页吊销条目，offset为该页首个条目之前的条目数
+func formatRevokedEntries(entries []helper.RevokedCertificate, offset int) string {
+	var b strings.Builder
+	for i, cert := range entries {
+		fmt.Fprintf(&b, "%d. 序列号: %s, 吊销时间: %s, 原因: %s",
+			offset+i+1, cert.SerialNumber,
+			cert.RevocationTime.Format(util.DateTime),
 			cert.Reason)
+		if !cert.InvalidityDate.IsZero() {
+			fmt.Fprintf(&b, ", 失效日期: %s", cert.InvalidityDate.Format(util.DateTime))
+		}
+		if len(cert.CertificateIssuer) > 0 {
+			fmt.Fprintf(&b, ", 证书颁发者: %s", formatGeneralNames(cert.CertificateIssuer))
+		}
+		b.WriteString("\n")
+	}
+	if len(entries) == 0 {
+		b.WriteString("CRL中没有被吊销的证书\n")
+	}
+	return b.String()
+}
+
+// formatGeneralNames 将GeneralName列表格式化为逗号分隔的文本
+func formatGeneralNames(names []helper.GeneralName) string {
+	texts := make([]string, len(names))
+	for i, name := range names {
+		texts[i] = name.String()
 	}
+	return strings.Join(texts, ", ")
+}
 
-	detailsWidget.SetText(details)
+// verifyCRLWithIssuerInput 解析颁发者证书输入并验证CRL签名
+func verifyCRLWithIssuerInput(crlInfo *helper.CRLInfo, input string) error {
+	certs, err := decodeCertificateChain(input)
+	if err != nil {
+		return fmt.Errorf("解析颁发者证书失败: %v", err)
+	}
+	return helper.VerifyCRLWithCertificates(crlInfo, certs)
 }
 
 // displayVerificationResult 显示验证结果
-func displayVerificationResult(outputWidget *widget.Entry, serialNumber string, isRevoked bool, revokedCert *helper.RevokedCertificate) {
+// problems为CRL本身的可信问题(签名未验证、已过期等)，非空时结果仅供参考
+func displayVerificationResult(outputWidget *widget.Entry, serialNumber string, isRevoked bool, revokedCert *helper.RevokedCertificate, problems []string) {
 	var result string
 	if isRevoked {
 		result = fmt.Sprintf(`🔴 证书已被吊销
@@ -275,30 +759,56 @@ func displayVerificationResult(outputWidget *widget.Entry, serialNumber string,
 ✅ 该证书在当前CRL中未被列为已吊销状态`,
 			serialNumber)
 	}
+	if len(problems) > 0 {
+		result += "\n\n⚠️ 该CRL存在以下问题，结果仅供参考:\n- " + strings.Join(problems, "\n- ")
+	}
 
 	outputWidget.SetText(result)
 }
 
-// parsePEMCRL 解析PEM格式CRL
-func parsePEMCRL(pemData string) ([]byte, error) {
-	// 清理输入数据，移除多余的空格和换行
-	pemData = strings.TrimSpace(pemData)
-
-	// 解析PEM块
-	rest := []byte(pemData)
-	for len(rest) > 0 {
-		block, remaining := pem.Decode(rest)
-		if block == nil {
-			break
+// displayBatchVerificationResult 显示批量序列号查询结果，已吊销的排在前面
+func displayBatchVerificationResult(outputWidget *widget.Entry, results []helper.SerialLookup, problems []string) {
+	var revoked, good, failed strings.Builder
+	revokedCount, failedCount := 0, 0
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			failedCount++
+			fmt.Fprintf(&failed, "⚠️ %s: %s\n", result.SerialNumber, result.Error)
+		case result.Revoked:
+			revokedCount++
+			fmt.Fprintf(&revoked, "🔴 %s 吊销时间: %s, 原因: %s\n", result.SerialNumber,
+				result.Entry.RevocationTime.Format(util.DateTime), result.Entry.Reason)
+		default:
+			fmt.Fprintf(&good, "🟢 %s 未吊销\n", result.SerialNumber)
 		}
+	}
+	text := fmt.Sprintf("共查询 %d 个序列号，已吊销 %d 个，未吊销 %d 个，无效 %d 个\n\n",
+		len(results), revokedCount, len(results)-revokedCount-failedCount, failedCount)
+	text += revoked.String() + good.String() + failed.String()
+	if len(problems) > 0 {
+		text += "\n⚠️ 该CRL存在以下问题，结果仅供参考:\n- " + strings.Join(problems, "\n- ")
+	}
+	outputWidget.SetText(text)
+}
 
-		// 检查PEM块类型，支持CRL格式
-		if block.Type == "X509 CRL" || block.Type == "CRL" {
-			return block.Bytes, nil
+// decodeCRLList 解析多个CRL，支持多个PEM块及Base64/Hex编码的DER
+func decodeCRLList(input string) ([]*pkix.CertificateList, error) {
+	result, err := codec.DecodeString(input)
+	if err != nil {
+		return nil, err
+	}
+	blocks := result.AllOfType("X509 CRL", "CRL")
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("未找到CRL，输入为 %s", result.Describe())
+	}
+	var crls []*pkix.CertificateList
+	for _, block := range blocks {
+		crl, err := x509.ParseCRL(block)
+		if err != nil {
+			return nil, err
 		}
-
-		rest = remaining
+		crls = append(crls, crl)
 	}
-
-	return nil, fmt.Errorf("未找到有效的CRL块")
+	return crls, nil
 }
@@ -4,13 +4,13 @@ import (
 	"HeTu/gm"
 	"HeTu/helper"
 	"HeTu/util"
-	"crypto/x509/pkix"
-	"encoding/asn1"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/big"
 	"strings"
+	"unicode/utf8"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -23,29 +23,65 @@ import (
 )
 
 var knownAlgOIDs = map[string]string{
-	"1.2.156.10197.1.104.1":  "SM4-ECB",
-	"1.2.156.10197.1.104.2":  "SM4-CBC",
-	"1.2.156.10197.1.104.3":  "SM4-OFB",
-	"1.2.156.10197.1.104.4":  "SM4-CFB",
-	"1.2.156.10197.1.104":    "SM4",
-	"1.2.156.10197.1.301":    "SM2",
-	"1.2.156.10197.1.401":    "SM3",
-	"2.16.840.113549.3.4":    "RC4",
-	"2.16.840.1.101.3.4.1.1": "AES-128-ECB",
-	"2.16.840.1.101.3.4.1.2": "AES-128-CBC",
+	"1.2.156.10197.1.104.1":   "SM4-ECB",
+	"1.2.156.10197.1.104.2":   "SM4-CBC",
+	"1.2.156.10197.1.104.3":   "SM4-OFB",
+	"1.2.156.10197.1.104.4":   "SM4-CFB",
+	"1.2.156.10197.1.104":     "SM4",
+	"1.2.156.10197.1.301":     "SM2",
+	"1.2.156.10197.1.401":     "SM3",
+	"1.2.156.10197.1.102.1":   "SM1-ECB",
+	"1.2.156.10197.1.102.2":   "SM1-CBC",
+	"2.16.840.113549.3.4":     "RC4",
+	"2.16.840.1.101.3.4.1.1":  "AES-128-ECB",
+	"2.16.840.1.101.3.4.1.2":  "AES-128-CBC",
+	"2.16.840.1.101.3.4.1.21": "AES-192-ECB",
+	"2.16.840.1.101.3.4.1.22": "AES-192-CBC",
+	"2.16.840.1.101.3.4.1.41": "AES-256-ECB",
+	"2.16.840.1.101.3.4.1.42": "AES-256-CBC",
 }
 
 var (
 	currentEnvelopedKey *gm.SM2EnvelopedKey
-	currentDecodeData   []byte
+	// currentEnvelopedKeyBlob 输入为SKF ENVELOPEDKEYBLOB时的原始结构，currentEnvelopedKey为其转换结果
+	currentEnvelopedKeyBlob *gm.EnvelopedKeyBlob
+	// currentEnvelopedData 输入为CMS EnvelopedData时的解析结果，与currentEnvelopedKey二者取其一
+	currentEnvelopedData *helper.EnvelopedDataInfo
+	currentDecodeData    []byte
 )
 
+// parseEnvelope 优先按CMS EnvelopedData解析，失败时依次按GM/T 0009 SM2EnvelopedKey、SKF ENVELOPEDKEYBLOB解析，
+// SKF信封转换为SM2EnvelopedKey后沿用同一解密流程
+func parseEnvelope(decodeEnveloped []byte) error {
+	if envelopedData, err := helper.ParseEnvelopedData(decodeEnveloped); err == nil {
+		currentEnvelopedData, currentEnvelopedKey, currentEnvelopedKeyBlob = envelopedData, nil, nil
+		currentDecodeData = decodeEnveloped
+		return nil
+	}
+	sm2EnvelopedKey, err := gm.ParseSM2EnvelopedKey(decodeEnveloped)
+	var blob *gm.EnvelopedKeyBlob
+	if err != nil {
+		var blobErr error
+		if blob, blobErr = gm.ParseEnvelopedKeyBlob(decodeEnveloped); blobErr != nil {
+			return fmt.Errorf("信封结构解析失败: %v; 按SKF ENVELOPEDKEYBLOB解析失败: %v", err, blobErr)
+		}
+		if sm2EnvelopedKey, err = gm.EnvelopedKeyBlobToSM2EnvelopedKey(blob); err != nil {
+			return err
+		}
+	}
+	currentEnvelopedData, currentEnvelopedKey, currentEnvelopedKeyBlob = nil, sm2EnvelopedKey, blob
+	currentDecodeData = decodeEnveloped
+	return nil
+}
+
 func SM2EnvelopedPfxStructure(input *widget.Entry) *fyne.Container {
 	input.Wrapping = fyne.TextWrapWord
 	structure := container.NewVBox()
 
-	keyInput := buildInputCertEntry("请输入 Base64/Hex 格式的 SM2 私钥")
+	keyInput := buildInputCertEntry("请输入 Base64/Hex 格式的 SM2 私钥，CMS信封可使用接收者 SM2/RSA 私钥")
 	keyInput.Wrapping = fyne.TextWrapWord
+	certInput := buildInputCertEntry("可选: CMS信封接收者证书，存在多个接收者时用于选择")
+	certInput.Wrapping = fyne.TextWrapWord
 
 	detail := container.NewVBox()
 
@@ -57,7 +93,7 @@ func SM2EnvelopedPfxStructure(input *widget.Entry) *fyne.Container {
 			return
 		}
 
-		decodeEnveloped, err := decodeInput(inputEnveloped)
+		decodeEnveloped, encoding, err := decodeInput(inputEnveloped, "PKCS7", "CMS")
 		if err != nil {
 			dialog.ShowError(fmt.Errorf("信封数据解码失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
 			return
@@ -68,17 +104,22 @@ func SM2EnvelopedPfxStructure(input *widget.Entry) *fyne.Container {
 			historyManager.LoadHistoryForTab("📦 信封解析")
 		}
 
-		sm2EnvelopedKey, err := ParseSM2EnvelopedKey(decodeEnveloped)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("信封结构解析失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+		if err := parseEnvelope(decodeEnveloped); err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
 
-		currentEnvelopedKey = sm2EnvelopedKey
-		currentDecodeData = decodeEnveloped
-
 		detail.RemoveAll()
-		detail.Add(buildEnvelopeStructureCard(sm2EnvelopedKey))
+		detail.Add(widget.NewLabel("输入格式: " + encoding))
+		if currentEnvelopedData != nil {
+			detail.Add(buildEnvelopedDataCard(currentEnvelopedData))
+		} else {
+			if currentEnvelopedKeyBlob != nil {
+				detail.Add(buildEnvelopedKeyBlobCard(currentEnvelopedKeyBlob))
+			}
+			detail.Add(buildEnvelopeStructureCard(currentEnvelopedKey))
+			detail.Add(buildEnvelopeConvertCard(currentEnvelopedKey, currentEnvelopedKeyBlob != nil))
+		}
 		detail.Refresh()
 	}
 
@@ -91,13 +132,13 @@ func SM2EnvelopedPfxStructure(input *widget.Entry) *fyne.Container {
 			return
 		}
 
-		if currentEnvelopedKey == nil || currentDecodeData == nil {
+		if (currentEnvelopedKey == nil && currentEnvelopedData == nil) || currentDecodeData == nil {
 			if inputEnveloped == "" {
 				dialog.ShowError(fmt.Errorf("请输入信封数据"), fyne.CurrentApp().Driver().AllWindows()[0])
 				return
 			}
 
-			decodeEnveloped, err := decodeInput(inputEnveloped)
+			decodeEnveloped, _, err := decodeInput(inputEnveloped, "PKCS7", "CMS")
 			if err != nil {
 				dialog.ShowError(fmt.Errorf("信封数据解码失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
 				return
@@ -108,43 +149,58 @@ func SM2EnvelopedPfxStructure(input *widget.Entry) *fyne.Container {
 				historyManager.LoadHistoryForTab("📦 信封解析")
 			}
 
-			sm2EnvelopedKey, err := ParseSM2EnvelopedKey(decodeEnveloped)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("信封结构解析失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			if err := parseEnvelope(decodeEnveloped); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
 				return
 			}
-
-			currentEnvelopedKey = sm2EnvelopedKey
-			currentDecodeData = decodeEnveloped
 		}
 
-		decodeKey, err := decodeInput(inputKey)
+		decodeKey, _, err := decodeInput(inputKey, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY")
 		if err != nil {
 			dialog.ShowError(fmt.Errorf("私钥解码失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
 
-		sm2SignPrivateKey, err := parsePrivateKey(decodeKey)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("私钥解析失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+		if currentEnvelopedData != nil {
+			privateKey, err := helper.ParsePrivateKey(decodeKey)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("私钥解析失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			var cert *x509.Certificate
+			if strings.TrimSpace(certInput.Text) != "" {
+				certs, err := decodeCertificateChain(certInput.Text)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("解析接收者证书失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+					return
+				}
+				cert = certs[0]
+			}
+			content, recipient, err := helper.DecryptEnvelopedData(currentEnvelopedData, privateKey, cert)
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			detail.RemoveAll()
+			detail.Add(buildEnvelopedContentCard(content, recipient))
+			detail.Refresh()
 			return
 		}
 
-		sm2CipherBytes, _ := asn1.Marshal(currentEnvelopedKey.Sm2cipher)
-		sm4Key, err := sm2SignPrivateKey.DecryptAsn1(sm2CipherBytes)
+		sm2SignPrivateKey, err := helper.ParseSM2PrivateKey(decodeKey)
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("对称密钥解密失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			dialog.ShowError(fmt.Errorf("私钥解析失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
 
-		encPrivateKey, err := gm.DecryptDataUseSm4Key(currentEnvelopedKey.Sm2EncryptedPrivateKey.Bytes, sm4Key)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("私钥解密失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+		sm4Key, encPrivateKey, err := gm.DecryptSM2EnvelopedKey(currentEnvelopedKey, sm2SignPrivateKey)
+		if err != nil && !errors.Is(err, gm.ErrPublicKeyMismatch) {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
 
 		detail.RemoveAll()
-		detail.Add(buildDecryptResultCard(sm4Key, encPrivateKey, currentEnvelopedKey.PublicKey.Bytes))
+		detail.Add(buildDecryptResultCard(sm4Key, encPrivateKey, currentEnvelopedKey.PublicKey.Bytes, err))
 		detail.Refresh()
 	}
 
@@ -155,8 +211,11 @@ func SM2EnvelopedPfxStructure(input *widget.Entry) *fyne.Container {
 		keyInput.Text = ""
 		input.Refresh()
 		keyInput.Refresh()
+		certInput.SetText("")
 		detail.RemoveAll()
 		currentEnvelopedKey = nil
+		currentEnvelopedKeyBlob = nil
+		currentEnvelopedData = nil
 		currentDecodeData = nil
 		detail.Refresh()
 	})
@@ -164,8 +223,13 @@ func SM2EnvelopedPfxStructure(input *widget.Entry) *fyne.Container {
 	buttonRow := container.New(layout.NewGridLayout(3), parseBtn, decryptBtn, clearBtn)
 
 	structure.Add(keyInput)
+	structure.Add(certInput)
 	structure.Add(buttonRow)
 	structure.Add(detail)
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildSM2EnvelopedKeyForm())
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildEnvelopedDataForm())
 
 	scrollContainer := container.NewScroll(structure)
 	return container.NewMax(scrollContainer)
@@ -198,7 +262,60 @@ func buildEnvelopeStructureCard(env *gm.SM2EnvelopedKey) *widget.Card {
 	return widget.NewCard("📋 信封结构", "SM2EnvelopedKey ASN.1 结构", form)
 }
 
-func buildDecryptResultCard(sm4Key, privateKey, publicKey []byte) *widget.Card {
+// buildEnvelopedKeyBlobCard 展示SKF ENVELOPEDKEYBLOB各字段
+func buildEnvelopedKeyBlobCard(blob *gm.EnvelopedKeyBlob) *widget.Card {
+	algID := fmt.Sprintf("0x%08X", blob.SymmAlgID)
+	if name, ok := gm.SGDAlgorithmNames[blob.SymmAlgID]; ok {
+		algID = fmt.Sprintf("%s (%s)", name, algID)
+	}
+	cipherBlob := blob.ECCCipherBlob
+	form := widget.NewForm(
+		widget.NewFormItem("Version", newSelectableLabel(fmt.Sprintf("%d", blob.Version))),
+		widget.NewFormItem("ulSymmAlgID", newSelectableLabel(algID)),
+		widget.NewFormItem("ulBits", newSelectableLabel(fmt.Sprintf("%d", blob.Bits))),
+		widget.NewFormItem("cbEncryptedPriKey", newSelectableLabel(hex.EncodeToString(blob.EncryptedPriKey[:]))),
+		widget.NewFormItem("PubKey.BitLen", newSelectableLabel(fmt.Sprintf("%d", blob.PubKey.BitLen))),
+		widget.NewFormItem("PubKey.XCoordinate", newSelectableLabel(hex.EncodeToString(blob.PubKey.XCoordinate[:]))),
+		widget.NewFormItem("PubKey.YCoordinate", newSelectableLabel(hex.EncodeToString(blob.PubKey.YCoordinate[:]))),
+		widget.NewFormItem("ECCCipherBlob.XCoordinate", newSelectableLabel(hex.EncodeToString(cipherBlob.XCoordinate[:]))),
+		widget.NewFormItem("ECCCipherBlob.YCoordinate", newSelectableLabel(hex.EncodeToString(cipherBlob.YCoordinate[:]))),
+		widget.NewFormItem("ECCCipherBlob.HASH", newSelectableLabel(hex.EncodeToString(cipherBlob.Hash[:]))),
+		widget.NewFormItem("ECCCipherBlob.CipherLen", newSelectableLabel(fmt.Sprintf("%d", cipherBlob.CipherLen))),
+		widget.NewFormItem("ECCCipherBlob.Cipher", newSelectableLabel(hex.EncodeToString(cipherBlob.Cipher))),
+	)
+	if blob.Trailing > 0 {
+		form.Append("多余数据", newSelectableLabel(fmt.Sprintf("Cipher之后 %d 字节", blob.Trailing)))
+	}
+	return widget.NewCard("📋 SKF信封结构", "GM/T 0016 ENVELOPEDKEYBLOB", form)
+}
+
+// buildEnvelopeConvertCard 给出SM2EnvelopedKey与SKF ENVELOPEDKEYBLOB的相互转换结果，fromSKF表示输入为SKF格式
+func buildEnvelopeConvertCard(env *gm.SM2EnvelopedKey, fromSKF bool) *widget.Card {
+	if fromSKF {
+		der, err := gm.MarshalSM2EnvelopedKey(env)
+		if err != nil {
+			return widget.NewCard("🔁 格式转换", "转换为 SM2EnvelopedKey 失败", newSelectableLabel(err.Error()))
+		}
+		form := widget.NewForm(
+			widget.NewFormItem("SM2EnvelopedKey (Base64)", newCopyableEntry(base64.StdEncoding.EncodeToString(der))),
+			widget.NewFormItem("SM2EnvelopedKey (Hex)", newCopyableEntry(hex.EncodeToString(der))),
+		)
+		return widget.NewCard("🔁 格式转换", "GM/T 0009 SM2EnvelopedKey", form)
+	}
+	blob, err := gm.SM2EnvelopedKeyToEnvelopedKeyBlob(env)
+	if err != nil {
+		return widget.NewCard("🔁 格式转换", "转换为 SKF ENVELOPEDKEYBLOB 失败", newSelectableLabel(err.Error()))
+	}
+	data := gm.MarshalEnvelopedKeyBlob(blob)
+	form := widget.NewForm(
+		widget.NewFormItem("ENVELOPEDKEYBLOB (Base64)", newCopyableEntry(base64.StdEncoding.EncodeToString(data))),
+		widget.NewFormItem("ENVELOPEDKEYBLOB (Hex)", newCopyableEntry(hex.EncodeToString(data))),
+	)
+	return widget.NewCard("🔁 格式转换", "GM/T 0016 SKF ENVELOPEDKEYBLOB", form)
+}
+
+// buildDecryptResultCard 展示信封解密结果，mismatch非空时提示私钥与公钥不匹配
+func buildDecryptResultCard(sm4Key, privateKey, publicKey []byte, mismatch error) *widget.Card {
 	form := widget.NewForm(
 		widget.NewFormItem("公钥 (Hex)", newCopyableEntry(hex.EncodeToString(publicKey))),
 		widget.NewFormItem("公钥 (Base64)", newCopyableEntry(base64.StdEncoding.EncodeToString(publicKey))),
@@ -207,147 +324,201 @@ func buildDecryptResultCard(sm4Key, privateKey, publicKey []byte) *widget.Card {
 		widget.NewFormItem("对称密钥 (Hex)", newCopyableEntry(hex.EncodeToString(sm4Key))),
 		widget.NewFormItem("对称密钥 (Base64)", newCopyableEntry(base64.StdEncoding.EncodeToString(sm4Key))),
 	)
+	if mismatch != nil {
+		form.Append("公钥校验", newSelectableLabel("⚠️ "+mismatch.Error()))
+		return widget.NewCard("🔓 解密结果", "信封已解密，但私钥与信封中的公钥不匹配", form)
+	}
+	form.Append("公钥校验", newSelectableLabel("✅ 一致"))
 	return widget.NewCard("🔓 解密结果", "信封解密成功", form)
 }
 
-func newSelectableLabel(text string) *widget.Entry {
-	entry := widget.NewEntry()
-	entry.SetText(text)
-	entry.Disable()
-	return entry
-}
-
-func newCopyableEntry(text string) *widget.Entry {
-	entry := widget.NewEntry()
-	entry.SetText(text)
-	return entry
-}
-
-func decodeInput(input string) ([]byte, error) {
-	if strings.Contains(input, "-----BEGIN") {
-		var b64 strings.Builder
-		for _, line := range strings.Split(input, "\n") {
-			line = strings.TrimSpace(strings.TrimRight(line, "\r"))
-			if line == "" || strings.HasPrefix(line, "-----") {
-				continue
-			}
-			b64.WriteString(line)
-		}
-		input = b64.String()
+// buildEnvelopedDataCard 展示CMS EnvelopedData的内容加密算法及各接收者
+func buildEnvelopedDataCard(info *helper.EnvelopedDataInfo) *widget.Card {
+	form := widget.NewForm(
+		widget.NewFormItem("内容类型", newSelectableLabel(info.ContentType)),
+		widget.NewFormItem("版本", newSelectableLabel(fmt.Sprintf("%d", info.Version))),
+		widget.NewFormItem("加密内容类型", newSelectableLabel(info.EncryptedContentType)),
+		widget.NewFormItem("内容加密算法", newSelectableLabel(info.ContentEncryptionAlgorithm)),
+		widget.NewFormItem("密文长度", newSelectableLabel(fmt.Sprintf("%d 字节", len(info.EncryptedContent)))),
+	)
+	if info.MAC != nil {
+		form.Append("认证码", newSelectableLabel(hex.EncodeToString(info.MAC)))
 	}
-	input = strings.TrimSpace(input)
-	input = strings.ReplaceAll(input, "\n", "")
-	input = strings.ReplaceAll(input, "\r", "")
-	decoded, err := base64.StdEncoding.DecodeString(input)
-	if err == nil {
-		return decoded, nil
+	for i, recipient := range info.Recipients {
+		text := recipient.Type
+		if recipient.KeyEncryptionAlgorithm != "" {
+			text = fmt.Sprintf("%s, %s", recipient.KeyEncryptionAlgorithm, recipient.Identifier())
+		}
+		form.Append(fmt.Sprintf("接收者 #%d", i+1), newSelectableLabel(text))
 	}
-	decoded, err = hex.DecodeString(input)
-	if err == nil {
-		return decoded, nil
+	for _, attr := range info.UnprotectedAttributes {
+		form.Append(attr.Name, newSelectableLabel(strings.Join(attr.FormatValues(), "; ")))
 	}
-	return nil, fmt.Errorf("无法识别的编码格式，请使用 Base64 或 Hex")
+	return widget.NewCard("📋 信封结构", "CMS EnvelopedData", form)
 }
 
-func ParseSM2EnvelopedKey(data []byte) (*gm.SM2EnvelopedKey, error) {
-	var sm2EnvelopedKey gm.SM2EnvelopedKey
-	_, err := asn1.Unmarshal(data, &sm2EnvelopedKey)
-	return &sm2EnvelopedKey, err
+// buildEnvelopedContentCard 展示CMS信封解密得到的明文，非UTF-8内容以Hex显示
+func buildEnvelopedContentCard(content []byte, recipient *helper.CMSRecipientInfo) *widget.Card {
+	form := widget.NewForm(
+		widget.NewFormItem("接收者", newCopyableEntry(recipient.Identifier())),
+		widget.NewFormItem("明文长度", newCopyableEntry(fmt.Sprintf("%d 字节", len(content)))),
+	)
+	if utf8.Valid(content) {
+		text := widget.NewMultiLineEntry()
+		text.Wrapping = fyne.TextWrapWord
+		text.SetText(string(content))
+		form.Append("明文", text)
+	}
+	form.Append("明文 (Hex)", newCopyableEntry(hex.EncodeToString(content)))
+	form.Append("明文 (Base64)", newCopyableEntry(base64.StdEncoding.EncodeToString(content)))
+	return widget.NewCard("🔓 解密结果", "信封解密成功", form)
 }
 
-func DecryptSM2EnvelopedKey(data []byte, signPrivateKey []byte) ([]byte, []byte, error) {
-	sm2SignPrivateKey, err := parsePrivateKey(signPrivateKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("私钥解析失败: %v", err)
-	}
+// buildSM2EnvelopedKeyForm 以接收方签名公钥封装加密私钥，生成GM/T 0009 SM2EnvelopedKey
+func buildSM2EnvelopedKeyForm() *fyne.Container {
+	title := widget.NewLabel("生成SM2信封(GM/T 0009)")
+	title.TextStyle = fyne.TextStyle{Bold: true}
 
-	sm2EnvelopedKey, err := ParseSM2EnvelopedKey(data)
-	if err != nil {
-		return nil, nil, fmt.Errorf("信封解析失败: %v", err)
-	}
+	signInput := buildInputCertEntry("请输入接收方签名证书或SM2签名公钥(PEM/Base64/Hex)")
+	signInput.Wrapping = fyne.TextWrapWord
+	encKeyInput := buildInputCertEntry("可选: 被封装的SM2加密私钥，留空时随机生成")
+	encKeyInput.Wrapping = fyne.TextWrapWord
+	result := container.NewVBox()
 
-	sm2Cipher, _ := asn1.Marshal(sm2EnvelopedKey.Sm2cipher)
-	sm4Key, err := sm2SignPrivateKey.DecryptAsn1(sm2Cipher)
-	if err != nil {
-		return nil, nil, fmt.Errorf("对称密钥解密失败: %v", err)
-	}
+	create := buildButton("生成信封", theme.ConfirmIcon(), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
 
-	encPrivateKey, err := gm.DecryptDataUseSm4Key(sm2EnvelopedKey.Sm2EncryptedPrivateKey.Bytes, sm4Key)
-	if err != nil {
-		return nil, nil, fmt.Errorf("私钥解密失败: %v", err)
-	}
+		signData, _, err := decodeInput(signInput.Text, "CERTIFICATE", "PUBLIC KEY")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("签名公钥解码失败: %v", err), window)
+			return
+		}
+		signPublicKey, err := helper.ParseSM2PublicKey(signData)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析签名公钥失败: %v", err), window)
+			return
+		}
+		var encPrivateKey *sm2.PrivateKey
+		if strings.TrimSpace(encKeyInput.Text) != "" {
+			decodeKey, _, err := decodeInput(encKeyInput.Text, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("加密私钥解码失败: %v", err), window)
+				return
+			}
+			if encPrivateKey, err = helper.ParseSM2PrivateKey(decodeKey); err != nil {
+				dialog.ShowError(fmt.Errorf("加密私钥解析失败: %v", err), window)
+				return
+			}
+		} else if encPrivateKey, err = sm2.GenerateKey(rand.Reader); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+
+		env, symKey, err := gm.CreateSM2EnvelopedKey(encPrivateKey, signPublicKey)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		der, err := gm.MarshalSM2EnvelopedKey(env)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		form := widget.NewForm(
+			widget.NewFormItem("信封 (Base64)", newCopyableEntry(base64.StdEncoding.EncodeToString(der))),
+			widget.NewFormItem("信封 (Hex)", newCopyableEntry(hex.EncodeToString(der))),
+			widget.NewFormItem("加密私钥 (Hex)", newCopyableEntry(hex.EncodeToString(encPrivateKey.D.FillBytes(make([]byte, 32))))),
+			widget.NewFormItem("加密公钥 (Hex)", newCopyableEntry(hex.EncodeToString(env.PublicKey.Bytes))),
+			widget.NewFormItem("对称密钥 (Hex)", newCopyableEntry(hex.EncodeToString(symKey))),
+		)
+		if blob, err := gm.SM2EnvelopedKeyToEnvelopedKeyBlob(env); err == nil {
+			form.Append("SKF ENVELOPEDKEYBLOB (Base64)", newCopyableEntry(base64.StdEncoding.EncodeToString(gm.MarshalEnvelopedKeyBlob(blob))))
+		}
+		result.RemoveAll()
+		result.Add(widget.NewCard("🔐 生成结果", "SM2EnvelopedKey", form))
+		result.Refresh()
+	})
+	clear := buildButton("清除", theme.CancelIcon(), func() {
+		signInput.SetText("")
+		encKeyInput.SetText("")
+		result.RemoveAll()
+		result.Refresh()
+	})
 
-	return sm2EnvelopedKey.PublicKey.Bytes, encPrivateKey, nil
+	allButton := container.New(layout.NewGridLayout(2), create, clear)
+	return container.NewVBox(title, signInput, encKeyInput, allButton, result)
 }
 
-func parsePrivateKey(data []byte) (*sm2.PrivateKey, error) {
-	if len(data) == 32 {
-		return helper.BuildPrivateKeyUseRaw(data), nil
-	}
-	if len(data) == 30 {
-		padded := make([]byte, 32)
-		copy(padded[2:], data)
-		return helper.BuildPrivateKeyUseRaw(padded), nil
-	}
+// buildEnvelopedDataForm 为一个或多个接收者证书生成CMS数字信封
+func buildEnvelopedDataForm() *fyne.Container {
+	title := widget.NewLabel("生成数字信封")
+	title.TextStyle = fyne.TextStyle{Bold: true}
 
-	privKey, err := parsePKCS8PrivateKey(data)
-	if err == nil {
-		return privKey, nil
-	}
+	content, contentRow := newContentSource("待加密的原文，输入文本或选择文件")
+	recipientsInput := buildInputCertEntry("请输入接收者证书(SM2或RSA)，多个证书对应多个接收者")
+	recipientsInput.Wrapping = fyne.TextWrapWord
+	cipherSelect := widget.NewSelect(append([]string{"自动"}, helper.EnvelopeCiphers...), nil)
+	cipherSelect.SetSelected("自动")
+	gmCheck := widget.NewCheck("GM/T 0010 OID", nil)
+	oaepCheck := widget.NewCheck("RSA使用OAEP", nil)
 
-	return x509.ParseSm2PrivateKey(data)
-}
+	form := widget.NewForm(
+		widget.NewFormItem("内容加密", container.NewHBox(cipherSelect, gmCheck, oaepCheck)),
+	)
 
-var (
-	oidPublicKeyEC       = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
-	oidSM2Curve          = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
-	oidNamedCurveP256SM2 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
-)
+	output := widget.NewMultiLineEntry()
+	output.Wrapping = fyne.TextWrapWord
+	output.Hide()
 
-type pkcs8PrivateKey struct {
-	Version    int
-	Algo       pkix.AlgorithmIdentifier
-	PrivateKey []byte
-}
+	encrypt := buildButton("生成信封", theme.ConfirmIcon(), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
 
-type pkixAlgorithmIdentifier struct {
-	Algorithm  asn1.ObjectIdentifier
-	Parameters asn1.RawValue `optional:"true"`
-}
+		data, err := content.load()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if data == nil {
+			dialog.ShowError(fmt.Errorf("请输入待加密的原文"), window)
+			return
+		}
+		recipients, err := decodeCertificateChain(recipientsInput.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析接收者证书失败: %v", err), window)
+			return
+		}
+		opts := helper.EnvelopedDataOptions{GMOIDs: gmCheck.Checked, RSAOAEP: oaepCheck.Checked}
+		if cipherSelect.Selected != "自动" {
+			opts.Cipher = cipherSelect.Selected
+		}
+		envelope, err := helper.CreateEnvelopedData(data, recipients, opts)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("生成数字信封失败: %v", err), window)
+			return
+		}
+		output.SetText(base64.StdEncoding.EncodeToString(envelope))
+		output.Show()
+	})
+	clear := buildButton("清除", theme.CancelIcon(), func() {
+		content.clear()
+		recipientsInput.SetText("")
+		output.SetText("")
+		output.Hide()
+	})
 
-type ecPrivateKey struct {
-	Version    int
-	PrivateKey []byte
-	Parameters asn1.RawValue `optional:"true"`
-	PublicKey  asn1.RawValue `optional:"true"`
+	allButton := container.New(layout.NewGridLayout(2), encrypt, clear)
+	return container.NewVBox(title, contentRow, recipientsInput, form, allButton, output)
 }
 
-type pkixPublicKeyAlgorithmIdentifier struct {
-	Algorithm  asn1.ObjectIdentifier
-	Parameters asn1.RawValue `optional:"true"`
+func newSelectableLabel(text string) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetText(text)
+	entry.Disable()
+	return entry
 }
 
-func parsePKCS8PrivateKey(data []byte) (*sm2.PrivateKey, error) {
-	var privKeyInfo pkcs8PrivateKey
-	_, err := asn1.Unmarshal(data, &privKeyInfo)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal PKCS8 failed: %v", err)
-	}
-
-	if !privKeyInfo.Algo.Algorithm.Equal(oidPublicKeyEC) {
-		return nil, fmt.Errorf("not EC key")
-	}
-
-	var ecPriv ecPrivateKey
-	_, err = asn1.Unmarshal(privKeyInfo.PrivateKey, &ecPriv)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal EC private key failed: %v", err)
-	}
-
-	privKey := &sm2.PrivateKey{}
-	privKey.Curve = sm2.P256Sm2()
-	privKey.D = new(big.Int).SetBytes(ecPriv.PrivateKey)
-	privKey.PublicKey.X, privKey.PublicKey.Y = privKey.Curve.ScalarBaseMult(ecPriv.PrivateKey)
-
-	return privKey, nil
+func newCopyableEntry(text string) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetText(text)
+	return entry
 }
@@ -1,6 +1,9 @@
 package window
 
 import (
+	"HeTu/codec"
+	"HeTu/helper"
+	"HeTu/security"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -9,7 +12,6 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"math/big"
@@ -23,6 +25,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/zaneway/cain-go/sm2"
 	"github.com/zaneway/cain-go/sm4"
+	gmx509 "github.com/zaneway/cain-go/x509"
 )
 
 func KeyStructure(input *widget.Entry) *fyne.Container {
@@ -144,6 +147,8 @@ func KeyStructure(input *widget.Entry) *fyne.Container {
 
 	buttonRow := container.New(layout.NewGridLayout(4), processBtn, decryptBtn, clearBtn, copyResultBtn)
 
+	structure.Add(buildKeyGenerateCard(keyInput, statusLabel))
+	structure.Add(widget.NewSeparator())
 	structure.Add(statusLabel)
 	structure.Add(widget.NewSeparator())
 	structure.Add(algoSelect)
@@ -156,17 +161,103 @@ func KeyStructure(input *widget.Entry) *fyne.Container {
 	return container.NewMax(scrollContainer)
 }
 
+// buildKeyGenerateCard 构造按security包规格生成密钥的区域，生成结果可一键填入下方密钥输入框
+func buildKeyGenerateCard(keyInput *widget.Entry, statusLabel *widget.Label) *fyne.Container {
+	title := widget.NewLabel("密钥生成")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	specs := append(append([]string{}, security.ALL_ASYM_KEYS...), security.ALL_SYM_KEYS...)
+	specSelect := widget.NewSelect(specs, nil)
+	specSelect.SetSelected(security.SM2_256)
+
+	output := widget.NewMultiLineEntry()
+	output.Wrapping = fyne.TextWrapWord
+	output.SetMinRowsVisible(10)
+	output.SetPlaceHolder("生成的密钥：裸密钥以Hex展示，PKCS#1/PKCS#8/SEC1/SubjectPublicKeyInfo以Base64及PEM展示")
+
+	var generated *helper.GeneratedKey
+	generateBtn := widget.NewButtonWithIcon("生成密钥", theme.ContentAddIcon(), func() {
+		spec := specSelect.Selected
+		if spec == "" {
+			dialog.ShowError(fmt.Errorf("请选择密钥规格"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		statusLabel.SetText("🔄 密钥生成中...")
+		go func() {
+			key, err := helper.GenerateKey(spec)
+			fyne.Do(func() {
+				if err != nil {
+					statusLabel.SetText(fmt.Sprintf("❌ 密钥生成失败: %v", err))
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+					return
+				}
+				generated = key
+				output.SetText(formatGeneratedKey(key))
+				statusLabel.SetText(fmt.Sprintf("✅ 已生成 %s 密钥", spec))
+			})
+		}()
+	})
+
+	// 对称密钥填入裸密钥；非对称密钥填入公钥，便于直接加密
+	fillBtn := widget.NewButtonWithIcon("填入密钥输入框", theme.ContentPasteIcon(), func() {
+		if generated == nil {
+			dialog.ShowError(fmt.Errorf("请先生成密钥"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		encodings := generated.Private
+		if len(generated.Public) > 0 {
+			encodings = generated.Public
+		}
+		last := encodings[len(encodings)-1]
+		if last.PEMType == "" {
+			keyInput.SetText(hex.EncodeToString(last.Data))
+		} else {
+			keyInput.SetText(base64.StdEncoding.EncodeToString(last.Data))
+		}
+	})
+
+	buttons := container.New(layout.NewGridLayout(2), generateBtn, fillBtn)
+	return container.NewVBox(title, specSelect, buttons, output)
+}
+
+// formatGeneratedKey 将生成的密钥按各编码形式格式化为文本
+func formatGeneratedKey(key *helper.GeneratedKey) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("算法: %s (%d bits)\n", key.Algorithm, key.Bits))
+	label := "私钥"
+	if len(key.Public) == 0 {
+		label = "密钥"
+	}
+	groups := []struct {
+		label     string
+		encodings []helper.KeyEncoding
+	}{{label, key.Private}, {"公钥", key.Public}}
+	for _, group := range groups {
+		for _, e := range group.encodings {
+			sb.WriteString("\n")
+			if e.PEMType == "" {
+				sb.WriteString(fmt.Sprintf("%s %s (Hex):\n%s\n", group.label, e.Name, hex.EncodeToString(e.Data)))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%s %s (Base64):\n%s\n", group.label, e.Name, base64.StdEncoding.EncodeToString(e.Data)))
+			sb.WriteString(e.PEM())
+		}
+	}
+	return sb.String()
+}
+
 func processData(algo, mode, keyStr, dataStr string) (string, error) {
-	keyData, err := decodeKey(keyStr, algo)
+	keyData, keyEncoding, err := decodeKey(keyStr, algo)
 	if err != nil {
 		return "", fmt.Errorf("密钥解析失败: %v", err)
 	}
 
 	var inputData []byte
+	var dataEncoding string
 	if mode == "加密" {
-		inputData, err = decodeData(dataStr, false)
+		inputData, dataEncoding, err = decodeData(dataStr, false)
 	} else {
-		inputData, err = decodeData(dataStr, true)
+		inputData, dataEncoding, err = decodeData(dataStr, true)
 	}
 	if err != nil {
 		return "", fmt.Errorf("数据解析失败: %v", err)
@@ -206,57 +297,28 @@ func processData(algo, mode, keyStr, dataStr string) (string, error) {
 		return "", err
 	}
 
-	return fmt.Sprintf("Hex: %s\nBase64: %s", hex.EncodeToString(result), base64.StdEncoding.EncodeToString(result)), nil
+	return fmt.Sprintf("密钥格式: %s\n数据格式: %s\nHex: %s\nBase64: %s", keyEncoding, dataEncoding, hex.EncodeToString(result), base64.StdEncoding.EncodeToString(result)), nil
 }
 
-func decodeKey(keyStr, algo string) ([]byte, error) {
-	cleaned := strings.TrimSpace(keyStr)
-
-	if data, err := base64.StdEncoding.DecodeString(cleaned); err == nil {
-		return data, nil
-	}
-	if data, err := hex.DecodeString(cleaned); err == nil {
-		return data, nil
-	}
-
-	if strings.Contains(cleaned, "-----BEGIN") {
-		block, _ := pem.Decode([]byte(cleaned))
-		if block != nil {
-			return block.Bytes, nil
-		}
-	}
-
-	cleaned = strings.TrimSpace(keyStr)
-	cleaned = strings.ReplaceAll(cleaned, " ", "")
-	cleaned = strings.ReplaceAll(cleaned, "\n", "")
-	cleaned = strings.ReplaceAll(cleaned, "\r", "")
-	cleaned = strings.ReplaceAll(cleaned, "\t", "")
-
-	if data, err := base64.StdEncoding.DecodeString(cleaned); err == nil {
-		return data, nil
-	}
-	if data, err := hex.DecodeString(cleaned); err == nil {
-		return data, nil
+func decodeKey(keyStr, algo string) ([]byte, string, error) {
+	result, err := codec.DecodeString(keyStr)
+	if err != nil {
+		return nil, "", fmt.Errorf("无法解析密钥格式: %v", err)
 	}
-
-	return nil, fmt.Errorf("无法解析密钥格式")
+	return result.Bytes(), result.Describe(), nil
 }
 
-func decodeData(dataStr string, isCipher bool) ([]byte, error) {
-	cleaned := strings.TrimSpace(dataStr)
-
-	if data, err := base64.StdEncoding.DecodeString(cleaned); err == nil {
-		return data, nil
-	}
-	if data, err := hex.DecodeString(cleaned); err == nil {
-		return data, nil
-	}
-
+func decodeData(dataStr string, isCipher bool) ([]byte, string, error) {
+	// 明文允许直接输入普通字符串
 	if !isCipher {
-		return []byte(cleaned), nil
+		result := codec.DecodeOrText(dataStr)
+		return result.Bytes(), result.Describe(), nil
 	}
-
-	return nil, fmt.Errorf("无法解析数据格式，请使用Base64或Hex编码")
+	result, err := codec.DecodeString(dataStr)
+	if err != nil {
+		return nil, "", fmt.Errorf("无法解析数据格式，请使用Base64或Hex编码: %v", err)
+	}
+	return result.Bytes(), result.Describe(), nil
 }
 
 func sm2Encrypt(keyData []byte, data []byte) ([]byte, error) {
@@ -333,56 +395,33 @@ func aesDecrypt(keyData []byte, data []byte) ([]byte, error) {
 }
 
 func parseSM2PublicKey(data []byte) (*sm2.PublicKey, error) {
-	if len(data) >= 64 {
+	// 裸公钥 X||Y 或 04||X||Y
+	if len(data) == 64 || (len(data) == 65 && data[0] == 0x04) {
+		data = data[len(data)-64:]
 		pubKey := &sm2.PublicKey{}
 		pubKey.Curve = sm2.P256Sm2()
 		pubKey.X = new(big.Int).SetBytes(data[:32])
 		pubKey.Y = new(big.Int).SetBytes(data[32:64])
 		return pubKey, nil
 	}
-
-	pubKeyInterface, err := x509.ParsePKIXPublicKey(data)
-	if err != nil {
-		return nil, err
-	}
-	pubKey, ok := pubKeyInterface.(*sm2.PublicKey)
-	if !ok {
-		return nil, errors.New("不是有效的SM2公钥")
-	}
-	return pubKey, nil
+	return gmx509.ParseSm2PublicKey(data)
 }
 
 func parseSM2PrivateKey(data []byte) (*sm2.PrivateKey, error) {
-	if len(data) >= 30 && len(data) <= 32 {
-		keyBytes := data
-		if len(data) == 30 {
-			padded := make([]byte, 32)
-			copy(padded[2:], data)
-			keyBytes = padded
-		}
-		privKey := &sm2.PrivateKey{}
-		privKey.Curve = sm2.P256Sm2()
-		privKey.D = new(big.Int).SetBytes(keyBytes)
-		privKey.PublicKey.X, privKey.PublicKey.Y = privKey.Curve.ScalarBaseMult(keyBytes)
-		return privKey, nil
-	}
-
-	privKeyInterface, err := x509.ParsePKCS8PrivateKey(data)
-	if err == nil {
-		if privKey, ok := privKeyInterface.(*sm2.PrivateKey); ok {
-			return privKey, nil
-		}
-	}
-
-	privKeyInterface, err = x509.ParsePKIXPublicKey(data)
-	if err == nil {
+	if _, err := gmx509.ParseSm2PublicKey(data); err == nil {
 		return nil, errors.New("输入的是公钥，请输入私钥")
 	}
-
-	return nil, fmt.Errorf("不是有效的SM2私钥: %v", err)
+	privKey, err := helper.ParseSM2PrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("不是有效的SM2私钥: %v", err)
+	}
+	return privKey, nil
 }
 
 func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	if pubKey, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		return pubKey, nil
+	}
 	pubKeyInterface, err := x509.ParsePKIXPublicKey(data)
 	if err != nil {
 		return nil, err
@@ -395,11 +434,11 @@ func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
 }
 
 func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
-	privKeyInterface, err := x509.ParsePKCS8PrivateKey(data)
+	key, err := helper.ParsePrivateKey(data)
 	if err != nil {
 		return nil, err
 	}
-	privKey, ok := privKeyInterface.(*rsa.PrivateKey)
+	privKey, ok := key.(*rsa.PrivateKey)
 	if !ok {
 		return nil, errors.New("不是有效的RSA私钥")
 	}
@@ -0,0 +1,247 @@
+package window
+
+import (
+	"HeTu/gm"
+	"HeTu/helper"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zaneway/cain-go/sm2"
+)
+
+// keyExchangePartyForm 密钥交换一方的输入项
+type keyExchangePartyForm struct {
+	name                string
+	id                  *widget.Entry
+	staticPrivateKey    *widget.Entry
+	staticPublicKey     *widget.Entry
+	ephemeralPrivateKey *widget.Entry
+	ephemeralPublicKey  *widget.Entry
+}
+
+// KeyExchangeStructure 构造GM/T 0003.3 SM2密钥交换图形模块，展示Z值、U/V、共享密钥及确认值等全部中间值
+func KeyExchangeStructure(input *widget.Entry) *fyne.Container {
+	structure := container.NewVBox()
+
+	partyA := newKeyExchangePartyForm("A")
+	partyB := newKeyExchangePartyForm("B")
+	klenEntry := widget.NewEntry()
+	klenEntry.SetText("16")
+	detail := container.NewVBox()
+
+	computeBtn := widget.NewButtonWithIcon("计算", theme.ConfirmIcon(), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+		klen, err := strconv.Atoi(strings.TrimSpace(klenEntry.Text))
+		if err != nil || klen <= 0 {
+			dialog.ShowError(fmt.Errorf("共享密钥长度必须为正整数"), window)
+			return
+		}
+		a, err := partyA.party()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		b, err := partyB.party()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		result, err := gm.SM2KeyExchange(a, b, klen)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		// 仅提供私钥时回填推导出的公钥，便于复制给对方
+		partyA.fillPublicKeys(a)
+		partyB.fillPublicKeys(b)
+
+		detail.RemoveAll()
+		detail.Add(buildKeyExchangeCommonCard(result))
+		if result.KA != nil {
+			detail.Add(buildKeyExchangeSideCard("🅰️ A方计算结果", "U = [h·tA](PB + [x̄2]RB)", []keyExchangeValue{
+				{"t_A", result.TA}, {"U", result.U}, {fmt.Sprintf("K_A (%d 字节)", klen), result.KA},
+				{"Hash(x_U‖Z_A‖Z_B‖x1‖y1‖x2‖y2)", result.HashU}, {"S1", result.S1}, {"S_A", result.SA},
+			}))
+		}
+		if result.KB != nil {
+			detail.Add(buildKeyExchangeSideCard("🅱️ B方计算结果", "V = [h·tB](PA + [x̄1]RA)", []keyExchangeValue{
+				{"t_B", result.TB}, {"V", result.V}, {fmt.Sprintf("K_B (%d 字节)", klen), result.KB},
+				{"Hash(x_V‖Z_A‖Z_B‖x1‖y1‖x2‖y2)", result.HashV}, {"S_B", result.SB}, {"S2", result.S2},
+			}))
+		}
+		if result.KA != nil && result.KB != nil {
+			detail.Add(buildKeyExchangeCheckCard(result))
+		}
+		detail.Refresh()
+	})
+	clearBtn := widget.NewButtonWithIcon("清除", theme.CancelIcon(), func() {
+		partyA.clear()
+		partyB.clear()
+		klenEntry.SetText("16")
+		detail.RemoveAll()
+		detail.Refresh()
+	})
+
+	structure.Add(container.NewGridWithColumns(2, partyA.card(), partyB.card()))
+	structure.Add(widget.NewForm(widget.NewFormItem("共享密钥长度(字节)", klenEntry)))
+	structure.Add(container.New(layout.NewGridLayout(2), computeBtn, clearBtn))
+	structure.Add(detail)
+
+	scrollContainer := container.NewScroll(structure)
+	return container.NewMax(scrollContainer)
+}
+
+func newKeyExchangePartyForm(name string) *keyExchangePartyForm {
+	form := &keyExchangePartyForm{
+		name:                name,
+		id:                  widget.NewEntry(),
+		staticPrivateKey:    widget.NewEntry(),
+		staticPublicKey:     widget.NewEntry(),
+		ephemeralPrivateKey: widget.NewEntry(),
+		ephemeralPublicKey:  widget.NewEntry(),
+	}
+	form.id.SetText(string(helper.SM2DefaultUserID))
+	form.staticPrivateKey.SetPlaceHolder("静态私钥 d_" + name + " (Hex/Base64/PEM)，仅知公钥时留空")
+	form.staticPublicKey.SetPlaceHolder("静态公钥 P_" + name + " (04||X||Y/证书)，有私钥时可留空")
+	form.ephemeralPrivateKey.SetPlaceHolder("临时私钥 r_" + name + "，仅知公钥时留空")
+	form.ephemeralPublicKey.SetPlaceHolder("临时公钥 R_" + name + "，有私钥时可留空")
+	return form
+}
+
+func (f *keyExchangePartyForm) card() *widget.Card {
+	generateBtn := buildButton("随机生成密钥", theme.ViewRefreshIcon(), func() {
+		staticKey, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		ephemeralKey, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		f.staticPrivateKey.SetText(hex.EncodeToString(staticKey.D.FillBytes(make([]byte, 32))))
+		f.staticPublicKey.SetText(hex.EncodeToString(helper.MarshalSM2Point(&staticKey.PublicKey)))
+		f.ephemeralPrivateKey.SetText(hex.EncodeToString(ephemeralKey.D.FillBytes(make([]byte, 32))))
+		f.ephemeralPublicKey.SetText(hex.EncodeToString(helper.MarshalSM2Point(&ephemeralKey.PublicKey)))
+	})
+	title, subtitle := "🅰️ 发起方 A", "ID_A、d_A/P_A、r_A/R_A"
+	if f.name == "B" {
+		title, subtitle = "🅱️ 响应方 B", "ID_B、d_B/P_B、r_B/R_B"
+	}
+	form := widget.NewForm(
+		widget.NewFormItem("ID_"+f.name, f.id),
+		widget.NewFormItem("d_"+f.name, f.staticPrivateKey),
+		widget.NewFormItem("P_"+f.name, f.staticPublicKey),
+		widget.NewFormItem("r_"+f.name, f.ephemeralPrivateKey),
+		widget.NewFormItem("R_"+f.name, f.ephemeralPublicKey),
+	)
+	return widget.NewCard(title, subtitle, container.NewVBox(form, generateBtn))
+}
+
+// party 解析输入项，空白的密钥保持为空，由gm.SM2KeyExchange判断能否计算该方视角
+func (f *keyExchangePartyForm) party() (*gm.SM2KeyExchangeParty, error) {
+	party := &gm.SM2KeyExchangeParty{ID: []byte(f.id.Text)}
+	var err error
+	if text := strings.TrimSpace(f.staticPrivateKey.Text); text != "" {
+		if party.StaticPrivateKey, err = parseKeyExchangePrivateKey(text); err != nil {
+			return nil, fmt.Errorf("%s方静态私钥解析失败: %v", f.name, err)
+		}
+	}
+	if text := strings.TrimSpace(f.staticPublicKey.Text); text != "" {
+		if party.StaticPublicKey, err = parseKeyExchangePublicKey(text); err != nil {
+			return nil, fmt.Errorf("%s方静态公钥解析失败: %v", f.name, err)
+		}
+	}
+	if text := strings.TrimSpace(f.ephemeralPrivateKey.Text); text != "" {
+		if party.EphemeralPrivateKey, err = parseKeyExchangePrivateKey(text); err != nil {
+			return nil, fmt.Errorf("%s方临时私钥解析失败: %v", f.name, err)
+		}
+	}
+	if text := strings.TrimSpace(f.ephemeralPublicKey.Text); text != "" {
+		if party.EphemeralPublicKey, err = parseKeyExchangePublicKey(text); err != nil {
+			return nil, fmt.Errorf("%s方临时公钥解析失败: %v", f.name, err)
+		}
+	}
+	return party, nil
+}
+
+func (f *keyExchangePartyForm) fillPublicKeys(party *gm.SM2KeyExchangeParty) {
+	if strings.TrimSpace(f.staticPublicKey.Text) == "" {
+		f.staticPublicKey.SetText(hex.EncodeToString(helper.MarshalSM2Point(party.StaticPublicKey)))
+	}
+	if strings.TrimSpace(f.ephemeralPublicKey.Text) == "" {
+		f.ephemeralPublicKey.SetText(hex.EncodeToString(helper.MarshalSM2Point(party.EphemeralPublicKey)))
+	}
+}
+
+func (f *keyExchangePartyForm) clear() {
+	f.id.SetText(string(helper.SM2DefaultUserID))
+	f.staticPrivateKey.SetText("")
+	f.staticPublicKey.SetText("")
+	f.ephemeralPrivateKey.SetText("")
+	f.ephemeralPublicKey.SetText("")
+}
+
+func parseKeyExchangePrivateKey(text string) (*sm2.PrivateKey, error) {
+	data, _, err := decodeInput(text, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	return helper.ParseSM2PrivateKey(data)
+}
+
+func parseKeyExchangePublicKey(text string) (*sm2.PublicKey, error) {
+	data, _, err := decodeInput(text, "PUBLIC KEY", "CERTIFICATE")
+	if err != nil {
+		return nil, err
+	}
+	return helper.ParseSM2PublicKey(data)
+}
+
+// keyExchangeValue 结果卡片中的一项
+type keyExchangeValue struct {
+	label string
+	value []byte
+}
+
+// buildKeyExchangeCommonCard 双方共同的中间值
+func buildKeyExchangeCommonCard(result *gm.SM2KeyExchangeResult) *widget.Card {
+	return buildKeyExchangeSideCard("📋 公共中间值", "Z = SM3(ENTL‖ID‖a‖b‖xG‖yG‖x‖y)，x̄ = 2^127 + (x & (2^127-1))", []keyExchangeValue{
+		{"Z_A", result.ZA}, {"Z_B", result.ZB}, {"x̄1", result.X1Bar}, {"x̄2", result.X2Bar},
+	})
+}
+
+func buildKeyExchangeSideCard(title, subtitle string, values []keyExchangeValue) *widget.Card {
+	form := widget.NewForm()
+	for _, item := range values {
+		form.Append(item.label, newCopyableEntry(hex.EncodeToString(item.value)))
+	}
+	return widget.NewCard(title, subtitle, form)
+}
+
+// buildKeyExchangeCheckCard 双方均可计算时比对共享密钥及确认值
+func buildKeyExchangeCheckCard(result *gm.SM2KeyExchangeResult) *widget.Card {
+	check := func(match bool) fyne.CanvasObject {
+		if match {
+			return newSelectableLabel("✅ 一致")
+		}
+		return newSelectableLabel("❌ 不一致")
+	}
+	form := widget.NewForm(
+		widget.NewFormItem("K_A = K_B", check(result.KeyMatch())),
+		widget.NewFormItem("S1 = S_B (A确认B)", check(bytes.Equal(result.S1, result.SB))),
+		widget.NewFormItem("S2 = S_A (B确认A)", check(bytes.Equal(result.S2, result.SA))),
+	)
+	return widget.NewCard("🔍 校验", "双方计算结果比对", form)
+}
@@ -0,0 +1,487 @@
+package window
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zaneway/cain-go/x509"
+)
+
+const (
+	// ocspQueryTimeout 发送OCSP请求的超时时间
+	ocspQueryTimeout = 15 * time.Second
+	// ocspLogLimit 本地OCSP服务日志保留的最大行数
+	ocspLogLimit = 500
+)
+
+// OcspStructure 构造OCSP请求、发送至OCSP服务，并解析验证OCSP响应
+func OcspStructure(input *widget.Entry) *fyne.Container {
+	structure := container.NewVBox()
+	input.Wrapping = fyne.TextWrapWord
+
+	certInput := buildInputCertEntry("请输入被查询的证书(PEM/Base64/Hex)，可包含多个证书")
+	issuerInput := buildInputCertEntry("请输入颁发者证书(PEM/Base64/Hex)，用于计算CertID及验证响应签名")
+	serialInput := widget.NewEntry()
+	serialInput.SetPlaceHolder("可选，证书序列号(Hex)，多个以逗号分隔，填写后无需提供证书")
+	hashSelect := widget.NewSelect([]string{helper.OCSPHashSHA1, helper.OCSPHashSM3, helper.OCSPHashSHA256}, nil)
+	hashSelect.SetSelected(helper.OCSPHashSHA1)
+	nonceCheck := widget.NewCheck("包含nonce", nil)
+	nonceCheck.SetChecked(true)
+	urlInput := widget.NewEntry()
+	urlInput.SetPlaceHolder("OCSP服务地址，留空时使用证书AIA中的OCSP地址")
+
+	form := widget.NewForm(
+		widget.NewFormItem("序列号", serialInput),
+		widget.NewFormItem("CertID摘要", container.NewHBox(hashSelect, nonceCheck)),
+		widget.NewFormItem("OCSP地址", urlInput),
+	)
+
+	statusLabel := widget.NewLabel("")
+	progressBar := widget.NewProgressBarInfinite()
+	progressBar.Hide()
+
+	requestOutput := widget.NewMultiLineEntry()
+	requestOutput.Wrapping = fyne.TextWrapWord
+	requestOutput.Hide()
+	details := widget.NewMultiLineEntry()
+	details.Wrapping = fyne.TextWrapWord
+	details.Hide()
+
+	// lastRequest 最近一次生成的请求，用于检查响应的nonce及CertID
+	var lastRequest *helper.OCSPRequestInfo
+
+	// buildRequest 按输入构造请求，返回请求DER、颁发者证书及证书AIA中的OCSP地址
+	buildRequest := func() ([]byte, *x509.Certificate, []string, error) {
+		issuer, err := decodeOCSPIssuer(issuerInput.Text)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if issuer == nil {
+			return nil, nil, nil, fmt.Errorf("请输入颁发者证书")
+		}
+		template := &helper.OCSPRequestTemplate{HashAlgorithm: hashSelect.Selected}
+		var urls []string
+		for _, serial := range helper.SplitSerialNumbers(serialInput.Text) {
+			n, err := helper.ConvertSerialNumberToBigInt(serial)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			template.SerialNumbers = append(template.SerialNumbers, n)
+		}
+		if len(template.SerialNumbers) == 0 {
+			if strings.TrimSpace(certInput.Text) == "" {
+				return nil, nil, nil, fmt.Errorf("请输入被查询的证书或证书序列号")
+			}
+			certs, err := decodeCertificateChain(certInput.Text)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("解析被查询证书失败: %v", err)
+			}
+			for _, cert := range certs {
+				template.SerialNumbers = append(template.SerialNumbers, cert.SerialNumber)
+				urls = append(urls, cert.OCSPServer...)
+			}
+		}
+		if nonceCheck.Checked {
+			if template.Nonce, err = helper.NewOCSPNonce(); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		request, err := helper.CreateOCSPRequest(template, issuer)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if lastRequest, err = helper.ParseOCSPRequest(request); err != nil {
+			return nil, nil, nil, err
+		}
+		return request, issuer, urls, nil
+	}
+
+	showResponse := func(info *helper.OCSPResponseInfo, issuer *x509.Certificate, req *helper.OCSPRequestInfo) {
+		if info.ResponseStatus == 0 {
+			// 签名验证失败记录在结果中
+			_ = helper.VerifyOCSPResponse(info, issuer)
+		}
+		details.SetText(formatOCSPResponse(info, req))
+		details.Show()
+	}
+
+	buildBtn := buildButton("生成请求", theme.ConfirmIcon(), func() {
+		request, _, urls, err := buildRequest()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		if strings.TrimSpace(urlInput.Text) == "" && len(urls) > 0 {
+			urlInput.SetText(urls[0])
+		}
+		text := base64.StdEncoding.EncodeToString(request)
+		if len(lastRequest.Nonce) > 0 {
+			text = fmt.Sprintf("Nonce: %s\n\n%s", hex.EncodeToString(lastRequest.Nonce), text)
+		}
+		requestOutput.SetText(text)
+		requestOutput.Show()
+	})
+
+	sendBtn := buildButton("发送请求", theme.MailSendIcon(), func() {
+		request, issuer, urls, err := buildRequest()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		target := strings.TrimSpace(urlInput.Text)
+		if target == "" {
+			if len(urls) == 0 {
+				dialog.ShowError(fmt.Errorf("证书未包含AIA OCSP地址，请填写OCSP服务地址"), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			target = urls[0]
+			urlInput.SetText(target)
+		}
+		req := lastRequest
+		requestOutput.SetText(base64.StdEncoding.EncodeToString(request))
+		requestOutput.Show()
+		statusLabel.SetText("正在请求 " + target + " ...")
+		progressBar.Show()
+		go func() {
+			response, err := helper.QueryOCSP(target, request, ocspQueryTimeout)
+			var info *helper.OCSPResponseInfo
+			if err == nil {
+				info, err = helper.ParseOCSPResponse(response)
+			}
+			fyne.Do(func() {
+				progressBar.Hide()
+				if err != nil {
+					statusLabel.SetText("请求失败")
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+					return
+				}
+				statusLabel.SetText("✅ 已收到OCSP响应")
+				input.SetText(base64.StdEncoding.EncodeToString(response))
+				showResponse(info, issuer, req)
+			})
+		}()
+	})
+
+	parseBtn := buildButton("解析响应", theme.ConfirmIcon(), func() {
+		inputData := strings.TrimSpace(input.Text)
+		if inputData == "" {
+			dialog.ShowError(fmt.Errorf("请输入OCSP响应数据"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		decodeData, _, err := decodeInput(inputData)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("无法解码OCSP响应: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		info, err := helper.ParseOCSPResponse(decodeData)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		issuer, err := decodeOCSPIssuer(issuerInput.Text)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		showResponse(info, issuer, lastRequest)
+	})
+
+	clear := buildButton("清除", theme.CancelIcon(), func() {
+		input.SetText("")
+		certInput.SetText("")
+		issuerInput.SetText("")
+		serialInput.SetText("")
+		urlInput.SetText("")
+		requestOutput.SetText("")
+		details.SetText("")
+		statusLabel.SetText("")
+		requestOutput.Hide()
+		details.Hide()
+		lastRequest = nil
+	})
+
+	requestButtons := container.New(layout.NewGridLayout(2), buildBtn, sendBtn)
+	responseButtons := container.New(layout.NewGridLayout(2), parseBtn, clear)
+
+	structure.Add(widget.NewLabel("被查询证书:"))
+	structure.Add(certInput)
+	structure.Add(widget.NewLabel("颁发者证书:"))
+	structure.Add(issuerInput)
+	structure.Add(form)
+	structure.Add(requestButtons)
+	structure.Add(statusLabel)
+	structure.Add(progressBar)
+	structure.Add(requestOutput)
+	structure.Add(widget.NewSeparator())
+	structure.Add(widget.NewLabel("OCSP响应(上方输入框):"))
+	structure.Add(responseButtons)
+	structure.Add(details)
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildOCSPResponderForm(issuerInput, urlInput))
+
+	scrollContainer := container.NewScroll(structure)
+	return container.NewMax(scrollContainer)
+}
+
+// buildOCSPResponderForm 本地OCSP服务，使用上方的颁发者证书，启动后将服务地址填入OCSP地址
+func buildOCSPResponderForm(issuerInput, urlInput *widget.Entry) *fyne.Container {
+	title := widget.NewLabel("本地OCSP服务")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	certInput := buildInputCertEntry("可选，委托响应者证书(PEM/Base64/Hex)，留空时由颁发者直接签名")
+	certInput.Wrapping = fyne.TextWrapWord
+	keyInput := buildInputCertEntry("请输入响应签名私钥(SM2或RSA)")
+	keyInput.Wrapping = fyne.TextWrapWord
+	crl, crlRow := newCRLSource("可选，作为状态来源的CRL(PEM/Base64/Hex)，未列入的证书返回good")
+	statusInput := widget.NewMultiLineEntry()
+	statusInput.SetPlaceHolder("可选，手工状态表，优先于CRL，每行一个: 序列号(Hex),状态[,吊销原因[,吊销时间]]\n如: 1A2B,revoked,keyCompromise,2026-01-02 10:00:00\n状态为good、revoked或unknown，均未命中时返回unknown")
+	statusInput.Wrapping = fyne.TextWrapWord
+
+	addrInput := widget.NewEntry()
+	addrInput.SetText(helper.DefaultOCSPResponderAddr)
+	validityInput := widget.NewEntry()
+	validityInput.SetText("1h")
+	validityInput.SetPlaceHolder("手工状态响应的有效期，如30m、24h，0表示不包含nextUpdate")
+	byKeyCheck := widget.NewCheck("以公钥摘要标识响应者", nil)
+
+	form := widget.NewForm(
+		widget.NewFormItem("监听地址", addrInput),
+		widget.NewFormItem("响应有效期", validityInput),
+		widget.NewFormItem("ResponderID", byKeyCheck),
+	)
+
+	statusLabel := widget.NewLabel("")
+	logOutput := widget.NewMultiLineEntry()
+	logOutput.Wrapping = fyne.TextWrapWord
+	logOutput.SetMinRowsVisible(8)
+	logOutput.Hide()
+
+	var server *http.Server
+	var logLines []string
+	appendLog := func(line string) {
+		logLines = append(logLines, line)
+		if len(logLines) > ocspLogLimit {
+			logLines = logLines[len(logLines)-ocspLogLimit:]
+		}
+		logOutput.SetText(strings.Join(logLines, "\n"))
+		logOutput.Show()
+	}
+
+	var startBtn *widget.Button
+	startBtn = buildButton("启动服务", theme.MediaPlayIcon(), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+		if server != nil {
+			server.Close()
+			server = nil
+			statusLabel.SetText("服务已停止")
+			startBtn.SetText("启动服务")
+			startBtn.SetIcon(theme.MediaPlayIcon())
+			return
+		}
+
+		config := helper.OCSPResponderConfig{ResponderIDByKey: byKeyCheck.Checked}
+		var err error
+		if config.Issuer, err = decodeOCSPIssuer(issuerInput.Text); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if config.Issuer == nil {
+			dialog.ShowError(fmt.Errorf("请在上方输入颁发者证书"), window)
+			return
+		}
+		if strings.TrimSpace(certInput.Text) != "" {
+			certs, err := decodeCertificateChain(certInput.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("解析响应者证书失败: %v", err), window)
+				return
+			}
+			config.Certificate = certs[0]
+		}
+		decodeKey, _, err := decodeInput(keyInput.Text, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("私钥解码失败: %v", err), window)
+			return
+		}
+		if config.Key, err = helper.ParsePrivateKey(decodeKey); err != nil {
+			dialog.ShowError(fmt.Errorf("解析私钥错误: %v", err), window)
+			return
+		}
+		if config.Statuses, err = helper.ParseOCSPStatusTable(statusInput.Text); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if config.Validity, err = time.ParseDuration(strings.TrimSpace(validityInput.Text)); err != nil {
+			dialog.ShowError(fmt.Errorf("响应有效期格式错误: %v", err), window)
+			return
+		}
+		useCRL := crl.uri != nil || strings.TrimSpace(crl.input.Text) != ""
+		if !useCRL && len(config.Statuses) == 0 {
+			dialog.ShowError(fmt.Errorf("请提供CRL或手工状态表作为状态来源"), window)
+			return
+		}
+
+		addr := strings.TrimSpace(addrInput.Text)
+		startBtn.Disable()
+		statusLabel.SetText("正在启动...")
+		// 大CRL加载较慢，在后台读取
+		go func() {
+			var responder *helper.OCSPResponder
+			var err error
+			if useCRL {
+				config.CRL, err = crl.load([]*x509.Certificate{config.Issuer})
+			}
+			if err == nil {
+				responder, err = helper.NewOCSPResponder(config)
+			}
+			var started *http.Server
+			var url string
+			if err == nil {
+				responder.Log = func(log helper.OCSPRequestLog) {
+					line := log.String()
+					fyne.Do(func() { appendLog(line) })
+				}
+				started, url, err = responder.Listen(addr)
+			}
+			fyne.Do(func() {
+				startBtn.Enable()
+				if err != nil {
+					statusLabel.SetText("启动失败")
+					dialog.ShowError(err, window)
+					return
+				}
+				server = started
+				for _, warning := range responder.Warnings {
+					appendLog("⚠️ " + warning)
+				}
+				statusLabel.SetText("✅ OCSP服务运行中: " + url)
+				urlInput.SetText(url)
+				startBtn.SetText("停止服务")
+				startBtn.SetIcon(theme.MediaStopIcon())
+			})
+		}()
+	})
+
+	clearLog := buildButton("清空日志", theme.DeleteIcon(), func() {
+		logLines = nil
+		logOutput.SetText("")
+		logOutput.Hide()
+	})
+
+	return container.NewVBox(
+		title,
+		widget.NewLabel("响应者证书:"),
+		certInput,
+		widget.NewLabel("签名私钥:"),
+		keyInput,
+		widget.NewLabel("CRL:"),
+		crlRow,
+		widget.NewLabel("手工状态表:"),
+		statusInput,
+		form,
+		container.New(layout.NewGridLayout(2), startBtn, clearLog),
+		statusLabel,
+		logOutput,
+	)
+}
+
+// decodeOCSPIssuer 解析颁发者证书输入，空输入返回nil
+func decodeOCSPIssuer(input string) (*x509.Certificate, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	certs, err := decodeCertificateChain(input)
+	if err != nil {
+		return nil, fmt.Errorf("解析颁发者证书失败: %v", err)
+	}
+	return certs[0], nil
+}
+
+// formatOCSPResponse 格式化OCSP响应详情，req不为空时检查响应与请求是否对应
+func formatOCSPResponse(info *helper.OCSPResponseInfo, req *helper.OCSPRequestInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "响应状态: %s\n", info.ResponseStatusText())
+	if info.ResponseStatus != 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "响应类型: %s\n", info.ResponseType)
+	fmt.Fprintf(&b, "版本: V%d\n", info.Version)
+	fmt.Fprintf(&b, "响应者: %s\n", info.ResponderID())
+	fmt.Fprintf(&b, "产生时间: %s\n", info.ProducedAt.Format(util.DateTime))
+	if len(info.Nonce) > 0 {
+		fmt.Fprintf(&b, "Nonce: %s\n", hex.EncodeToString(info.Nonce))
+	}
+	fmt.Fprintf(&b, "签名算法: %s\n", info.SignatureAlgorithm)
+	switch info.SignatureStatus {
+	case helper.OCSPSignatureValid:
+		fmt.Fprintf(&b, "签名状态: ✅ %s\n", info.SignatureStatus)
+	case helper.OCSPSignatureInvalid:
+		fmt.Fprintf(&b, "签名状态: ❌ %s\n", info.SignatureStatus)
+	default:
+		fmt.Fprintf(&b, "签名状态: ⚠️ %s\n", info.SignatureStatus)
+	}
+	if info.Signer != nil {
+		if info.Delegated {
+			fmt.Fprintf(&b, "签名证书: %s (委托响应者)\n", info.Signer.Subject.String())
+		} else {
+			fmt.Fprintf(&b, "签名证书: %s\n", info.Signer.Subject.String())
+		}
+	}
+	for _, cert := range info.Certificates {
+		fmt.Fprintf(&b, "附带证书: %s, 有效期 %s 至 %s\n", cert.Subject.String(),
+			cert.NotBefore.Format(util.DateTime), cert.NotAfter.Format(util.DateTime))
+	}
+
+	b.WriteString("\n证书状态:\n")
+	for i, response := range info.Responses {
+		icon := "🟢"
+		switch response.Status {
+		case helper.OCSPStatusRevoked:
+			icon = "🔴"
+		case helper.OCSPStatusUnknown:
+			icon = "⚪"
+		}
+		fmt.Fprintf(&b, "%d. %s 序列号: %s, 状态: %s\n", i+1, icon, formatSerial(response.CertID.SerialNumber), response.StatusText())
+		if response.Status == helper.OCSPStatusRevoked {
+			fmt.Fprintf(&b, "   吊销时间: %s", response.RevocationTime.Format(util.DateTime))
+			if response.ReasonCode != helper.CRLReasonOmitted {
+				fmt.Fprintf(&b, ", 原因: %s", helper.RevocationReasonText(response.ReasonCode))
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "   CertID摘要: %s, 颁发者名称摘要: %X, 颁发者公钥摘要: %X\n",
+			response.CertID.HashAlgorithm, response.CertID.IssuerNameHash, response.CertID.IssuerKeyHash)
+		fmt.Fprintf(&b, "   本次更新时间: %s", response.ThisUpdate.Format(util.DateTime))
+		if response.NextUpdate.IsZero() {
+			b.WriteString(", 下次更新时间: 未设置\n")
+		} else {
+			fmt.Fprintf(&b, ", 下次更新时间: %s\n", response.NextUpdate.Format(util.DateTime))
+		}
+	}
+
+	problems := info.CheckFreshness(time.Now())
+	if req != nil {
+		problems = append(problems, info.CheckRequest(req)...)
+	}
+	if len(problems) > 0 {
+		b.WriteString("\n⚠️ 注意:\n- " + strings.Join(problems, "\n- ") + "\n")
+	}
+	return b.String()
+}
+
+// formatSerial 以十六进制显示序列号
+func formatSerial(serial *big.Int) string {
+	return fmt.Sprintf("%X", serial)
+}
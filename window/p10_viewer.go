@@ -0,0 +1,304 @@
+package window
+
+import (
+	"HeTu/helper"
+	"HeTu/util"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// P10Structure 构造解析P10证书请求核心图形模块
+func P10Structure(input *widget.Entry) *fyne.Container {
+	structure := container.NewVBox()
+	detail := container.NewVBox()
+
+	//确认按钮
+	confirm := widget.NewButtonWithIcon("解析P10", theme.ConfirmIcon(), func() {
+		inputData := strings.TrimSpace(input.Text)
+		if inputData == "" {
+			dialog.ShowError(fmt.Errorf("请输入P10证书请求数据"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		// 保存到历史记录
+		util.GetHistoryDB().AddHistory(P10Tab, inputData)
+		if historyManager := GetGlobalHistoryManager(); historyManager != nil {
+			historyManager.LoadHistoryForTab(P10Tab)
+		}
+
+		decodeData, encoding, err := decodeInput(inputData, "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("无法解码输入数据，请确保输入的是有效的Base64、Hex或PEM格式P10数据\n\n%v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		csrInfo, err := helper.ParseCSR(decodeData)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		detail.RemoveAll()
+		detail.Add(widget.NewLabel("输入格式: " + encoding))
+		showCSRInfo(csrInfo, detail)
+	})
+
+	//清除按钮
+	clear := widget.NewButtonWithIcon("清除", theme.CancelIcon(), func() {
+		input.SetText("")
+		detail.RemoveAll()
+		detail.Refresh()
+	})
+
+	allButton := container.New(layout.NewGridLayout(2), confirm, clear)
+	structure.Add(allButton)
+	structure.Add(detail)
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildCSRGenerateForm(input))
+
+	// 使用带滚动条的容器包装
+	scrollContainer := container.NewScroll(structure)
+	scrollContainer.SetMinSize(fyne.NewSize(600, 400))
+	return container.NewMax(scrollContainer)
+}
+
+// showCSRInfo 展示证书请求详情、属性、请求的扩展项以及签名验证结果
+func showCSRInfo(csrInfo *helper.CSRInfo, box *fyne.Container) {
+	// 签名验证结果放在最前面
+	var verifyText string
+	if csrInfo.SignatureValid {
+		verifyText = "✅ 请求自签名验证通过"
+		if csrInfo.PublicKeyAlgorithm == "SM2" {
+			verifyText += fmt.Sprintf("（SM2-SM3，默认用户ID %s）", helper.SM2DefaultUserID)
+		}
+	} else {
+		verifyText = "❌ " + csrInfo.SignatureError
+	}
+	verifyLabel := widget.NewLabel(verifyText)
+	verifyLabel.TextStyle = fyne.TextStyle{Bold: true}
+	box.Add(verifyLabel)
+
+	keys := []string{"Version", "SubjectName", "PublicKeyAlgorithm", "PublicKey", "SignatureAlgorithm", "Signature"}
+	values := map[string]string{
+		"Version":            fmt.Sprintf("v%d", csrInfo.Version+1),
+		"SubjectName":        csrInfo.Subject,
+		"PublicKeyAlgorithm": fmt.Sprintf("%s (%d bits)", csrInfo.PublicKeyAlgorithm, csrInfo.PublicKeySize),
+		"PublicKey":          csrInfo.PublicKey,
+		"SignatureAlgorithm": csrInfo.SignatureAlgorithm,
+		"Signature":          csrInfo.Signature,
+	}
+	if csrInfo.ChallengePassword != "" {
+		keys = append(keys, "ChallengePassword")
+		values["ChallengePassword"] = csrInfo.ChallengePassword
+	}
+	if len(csrInfo.SubjectAltNames) > 0 {
+		var names []string
+		for _, name := range csrInfo.SubjectAltNames {
+			names = append(names, name.String())
+		}
+		keys = append(keys, "SubjectAltNames")
+		values["SubjectAltNames"] = strings.Join(names, "\n")
+	}
+	if len(csrInfo.KeyUsage) > 0 {
+		keys = append(keys, "KeyUsage")
+		values["KeyUsage"] = strings.Join(csrInfo.KeyUsage, "\n")
+	}
+	if len(csrInfo.ExtKeyUsage) > 0 {
+		var usages []string
+		for _, oid := range csrInfo.ExtKeyUsage {
+			if name, ok := helper.ExtKeyUsageNames[oid]; ok {
+				oid = fmt.Sprintf("%s (%s)", name, oid)
+			}
+			usages = append(usages, oid)
+		}
+		keys = append(keys, "ExtKeyUsage")
+		values["ExtKeyUsage"] = strings.Join(usages, "\n")
+	}
+	showCertificateDetail(keys, values, box)
+
+	// 展示全部属性
+	if len(csrInfo.Attributes) > 0 {
+		box.Add(widget.NewSeparator())
+		attrTitle := widget.NewLabel("Attributes:")
+		attrTitle.TextStyle = fyne.TextStyle{Bold: true}
+		box.Add(attrTitle)
+
+		var attrKeys []string
+		attrValues := make(map[string]string)
+		for _, attr := range csrInfo.Attributes {
+			name := fmt.Sprintf("%s (%s)", attr.Name, attr.OID)
+			attrKeys = append(attrKeys, name)
+			attrValues[name] = strings.Join(attr.Values, "\n")
+		}
+		showCertificateDetail(attrKeys, attrValues, box)
+	}
+
+	// 展示extensionRequest中请求的扩展项
+	if len(csrInfo.Extensions) > 0 {
+		var extKeys []string
+		extValues := make(map[string]string)
+		for i, ext := range csrInfo.Extensions {
+			oidStr := ext.Id.String()
+			name, exists := helper.ExtensionNames[oidStr]
+			if !exists {
+				name = fmt.Sprintf("Extension %d (%s)", i+1, oidStr)
+			} else {
+				name = fmt.Sprintf("%s (%s)", name, oidStr)
+			}
+			value := helper.FormatExtension(oidStr, ext.Value)
+			if ext.Critical {
+				value = "[Critical] " + value
+			}
+			extKeys = append(extKeys, name)
+			extValues[name] = value
+		}
+		showCertificateExtensions(extKeys, extValues, box)
+	}
+	box.Refresh()
+}
+
+// buildCSRGenerateForm 构造由私钥、主题及扩展项生成P10证书请求的表单
+func buildCSRGenerateForm(input *widget.Entry) *fyne.Container {
+	title := widget.NewLabel("生成P10证书请求")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	keyInput := buildInputCertEntry("请输入 Base64/Hex/PEM 格式的私钥（裸SM2私钥、PKCS#8、PKCS#1）")
+	keyInput.Wrapping = fyne.TextWrapWord
+
+	cn := widget.NewEntry()
+	o := widget.NewEntry()
+	ou := widget.NewEntry()
+	l := widget.NewEntry()
+	st := widget.NewEntry()
+	c := widget.NewEntry()
+	c.SetPlaceHolder("CN")
+	email := widget.NewEntry()
+
+	sans := widget.NewMultiLineEntry()
+	sans.SetPlaceHolder("每行一个，如 DNS:example.com、IP:127.0.0.1、email:a@b.com、URI:https://x")
+	sans.SetMinRowsVisible(3)
+
+	keyUsage := widget.NewCheckGroup(helper.KeyUsageNames, nil)
+	keyUsage.Horizontal = true
+
+	var ekuOIDs []string
+	for oid := range helper.ExtKeyUsageNames {
+		ekuOIDs = append(ekuOIDs, oid)
+	}
+	sort.Strings(ekuOIDs)
+	var ekuOptions []string
+	ekuByOption := make(map[string]string)
+	for _, oid := range ekuOIDs {
+		option := fmt.Sprintf("%s (%s)", helper.ExtKeyUsageNames[oid], oid)
+		ekuOptions = append(ekuOptions, option)
+		ekuByOption[option] = oid
+	}
+	extKeyUsage := widget.NewCheckGroup(ekuOptions, nil)
+
+	challenge := widget.NewPasswordEntry()
+
+	format := widget.NewSelect([]string{"PEM", "DER (Base64)"}, nil)
+	format.SetSelected("PEM")
+
+	output := widget.NewMultiLineEntry()
+	output.Wrapping = fyne.TextWrapWord
+	output.SetMinRowsVisible(8)
+
+	form := widget.NewForm(
+		widget.NewFormItem("CN", cn),
+		widget.NewFormItem("O", o),
+		widget.NewFormItem("OU", ou),
+		widget.NewFormItem("L", l),
+		widget.NewFormItem("ST", st),
+		widget.NewFormItem("C", c),
+		widget.NewFormItem("Email", email),
+		widget.NewFormItem("SubjectAltNames", sans),
+		widget.NewFormItem("KeyUsage", keyUsage),
+		widget.NewFormItem("ExtKeyUsage", extKeyUsage),
+		widget.NewFormItem("ChallengePassword", challenge),
+		widget.NewFormItem("输出格式", format),
+	)
+
+	generate := widget.NewButtonWithIcon("生成P10", theme.ConfirmIcon(), func() {
+		keyData := strings.TrimSpace(keyInput.Text)
+		if keyData == "" {
+			dialog.ShowError(fmt.Errorf("请输入私钥数据"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		decodeKey, _, err := decodeInput(keyData, "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("私钥解码失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		key, err := helper.ParsePrivateKey(decodeKey)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		template := &helper.CSRTemplate{
+			ChallengePassword: challenge.Text,
+		}
+		// 按表单字段拼接名称，值中的逗号需转义
+		var rdns []string
+		for _, field := range []struct {
+			key   string
+			entry *widget.Entry
+		}{{"C", c}, {"ST", st}, {"L", l}, {"O", o}, {"OU", ou}, {"CN", cn}, {"E", email}} {
+			if value := strings.TrimSpace(field.entry.Text); value != "" {
+				rdns = append(rdns, field.key+"="+strings.ReplaceAll(value, ",", "\\,"))
+			}
+		}
+		template.Subject, err = helper.ParseDistinguishedName(strings.Join(rdns, ","))
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		for _, line := range strings.Split(sans.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				template.SubjectAltNames = append(template.SubjectAltNames, line)
+			}
+		}
+		for i, name := range helper.KeyUsageNames {
+			for _, selected := range keyUsage.Selected {
+				if selected == name {
+					template.KeyUsage = append(template.KeyUsage, i)
+				}
+			}
+		}
+		for _, selected := range extKeyUsage.Selected {
+			template.ExtKeyUsage = append(template.ExtKeyUsage, ekuByOption[selected])
+		}
+
+		der, err := helper.CreateCSR(template, key)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		if format.Selected == "PEM" {
+			output.SetText(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})))
+		} else {
+			output.SetText(base64.StdEncoding.EncodeToString(der))
+		}
+	})
+
+	// 将生成结果填入上方输入框，便于直接解析校验
+	toInput := widget.NewButtonWithIcon("填入解析框", theme.ContentPasteIcon(), func() {
+		if output.Text != "" {
+			input.SetText(output.Text)
+		}
+	})
+
+	buttons := container.New(layout.NewGridLayout(2), generate, toInput)
+	return container.NewVBox(title, keyInput, form, buttons, output)
+}
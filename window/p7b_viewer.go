@@ -1,9 +1,10 @@
 package window
 
 import (
+	"HeTu/helper"
 	"HeTu/util"
+	"crypto/x509/pkix"
 	"encoding/base64"
-	"encoding/hex"
 	"fmt"
 	"strings"
 	"time"
@@ -61,30 +62,14 @@ func P7bStructure(input *widget.Entry) *fyne.Container {
 				progressBar.SetValue(0.3)
 			})
 
-			// 尝试Base64解码
-			var decodeData []byte
-			var err error
-
-			// 清理输入数据，移除空格和换行符
-			cleanedInput := strings.ReplaceAll(inputData, " ", "")
-			cleanedInput = strings.ReplaceAll(cleanedInput, "\n", "")
-			cleanedInput = strings.ReplaceAll(cleanedInput, "\r", "")
-			cleanedInput = strings.ReplaceAll(cleanedInput, "\t", "")
-			cleanedInput = strings.TrimSpace(cleanedInput)
-
-			// 尝试Base64解码
-			decodeData, err = base64.StdEncoding.DecodeString(cleanedInput)
+			decodeData, encoding, err := decodeInput(inputData, "PKCS7", "CMS")
 			if err != nil {
-				// 如果Base64失败，尝试Hex解码
-				decodeData, err = hex.DecodeString(cleanedInput)
-				if err != nil {
-					fyne.Do(func() {
-						progressBar.Hide()
-						dialog.ShowError(fmt.Errorf("无法解码输入数据，请确保输入的是有效的Base64或Hex格式P7B数据\n\n输入数据长度: %d\n清理后数据长度: %d\n\nBase64错误: %v\nHex错误: %v", len(inputData), len(cleanedInput), err, err), fyne.CurrentApp().Driver().AllWindows()[0])
-						statusLabel.SetText("数据解码失败")
-					})
-					return
-				}
+				fyne.Do(func() {
+					progressBar.Hide()
+					dialog.ShowError(fmt.Errorf("无法解码输入数据，请确保输入的是有效的Base64、Hex或PEM格式P7B数据\n\n%v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+					statusLabel.SetText("数据解码失败")
+				})
+				return
 			}
 
 			// 验证解码后的数据长度
@@ -102,8 +87,18 @@ func P7bStructure(input *widget.Entry) *fyne.Container {
 				progressBar.SetValue(0.6)
 			})
 
-			// 解析P7B证书链
+			// 签名消息另行解析签名者信息，仅含证书的P7B没有签名者
+			signedData, _ := helper.ParseSignedData(decodeData)
+
+			// 解析P7B证书链，cain-go无法解析其中的证书(如含关键名称约束)或GM/T 0010签名消息时仅提取证书
 			p7b, err := ParsePKCS7(decodeData)
+			if err != nil {
+				if certs, certErr := helper.ParseCertificates(decodeData); certErr == nil {
+					p7b, err = &PKCS7{Certificates: certs}, nil
+				} else if signedData != nil {
+					p7b, err = &PKCS7{Certificates: signedData.Certificates, Content: signedData.Content}, nil
+				}
+			}
 			if err != nil {
 				fyne.Do(func() {
 					progressBar.Hide()
@@ -125,7 +120,11 @@ func P7bStructure(input *widget.Entry) *fyne.Container {
 
 				// 显示P7B信息
 				detail.RemoveAll()
+				detail.Add(widget.NewLabel("输入格式: " + encoding))
 				showP7bInfo(p7b, detail)
+				if signedData != nil && len(signedData.Signers) > 0 {
+					showSignedDataVerification(signedData, detail)
+				}
 
 				progressBar.Hide()
 				detail.Refresh()
@@ -143,6 +142,8 @@ func P7bStructure(input *widget.Entry) *fyne.Container {
 	allButton := container.New(layout.NewGridLayout(2), confirm, clear)
 	structure.Add(allButton)
 	structure.Add(detail)
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildP7bSignForm())
 
 	// 使用带滚动条的容器包装
 	scrollContainer := container.NewScroll(structure)
@@ -196,66 +197,307 @@ func showP7bInfo(p7b *PKCS7, box *fyne.Container) {
 		}
 	}
 
-	// 验证证书链
-	showCertificateChainValidation(p7b.Certificates, box)
+	// 验证证书链，P7B中携带的CRL用于吊销检查
+	var crls []*pkix.CertificateList
+	for i := range p7b.CRLs {
+		crls = append(crls, &p7b.CRLs[i])
+	}
+	showCertificateChainValidation(p7b.Certificates, crls, box)
 
 	box.Refresh()
 }
 
-// showCertificateChainValidation 展示证书链验证结果
-func showCertificateChainValidation(certificates []*Certificate, box *fyne.Container) {
-	validationTitle := widget.NewLabel("证书链验证:")
-	validationTitle.TextStyle = fyne.TextStyle{Bold: true}
-	box.Add(validationTitle)
+// showSignedDataVerification 展示签名者信息并验证签名，分离式签名需提供原文
+func showSignedDataVerification(info *helper.SignedDataInfo, box *fyne.Container) {
+	box.Add(widget.NewSeparator())
+	signatureTitle := widget.NewLabel("签名信息:")
+	signatureTitle.TextStyle = fyne.TextStyle{Bold: true}
+	box.Add(signatureTitle)
+	box.Add(widget.NewLabel(fmt.Sprintf("版本: V%d", info.Version)))
+	box.Add(widget.NewLabel("内容类型: " + info.ContentType))
+	box.Add(widget.NewLabel("摘要算法: " + strings.Join(info.DigestAlgorithms, ", ")))
+	if info.Detached {
+		box.Add(widget.NewLabel("封装内容: 分离式签名，需提供原文后验证"))
+	} else {
+		box.Add(widget.NewLabel(fmt.Sprintf("封装内容: %d 字节", len(info.Content))))
+	}
 
-	validationResult := validateCertificateChain(certificates)
+	content, contentRow := newContentSource("分离式签名的原文，输入文本或选择文件")
+	certInput := widget.NewMultiLineEntry()
+	certInput.SetPlaceHolder("可选: 补充的签名者证书(PEM/Base64/Hex)，签名消息未附带签名者证书时使用")
+	certInput.Wrapping = fyne.TextWrapWord
+	userIDInput := widget.NewEntry()
+	userIDInput.SetPlaceHolder("可选: 验证SM2签名使用的用户ID，留空使用默认用户ID " + string(helper.SM2DefaultUserID))
 
-	validationEntry := widget.NewMultiLineEntry()
-	validationEntry.SetText(validationResult)
-	validationEntry.Wrapping = fyne.TextWrapWord
-	validationEntry.Resize(fyne.NewSize(400, 200))
+	resultEntry := widget.NewMultiLineEntry()
+	resultEntry.Wrapping = fyne.TextWrapWord
+	resultEntry.SetMinRowsVisible(12)
 
-	box.Add(validationEntry)
+	verify := func() {
+		var certs []*Certificate
+		if strings.TrimSpace(certInput.Text) != "" {
+			var err error
+			if certs, err = decodeCertificateChain(certInput.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("解析签名者证书失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+		}
+		data, err := content.load()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		if info.Detached && data == nil {
+			dialog.ShowError(fmt.Errorf("分离式签名需要提供原文"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		// 各签名者的结果记录在SignatureStatus中
+		info.SM2UserID = []byte(userIDInput.Text)
+		_ = helper.VerifySignedData(info, data, certs)
+		resultEntry.SetText(formatSignedDataSigners(info))
+	}
+	verifyButton := widget.NewButtonWithIcon("验证签名", theme.ConfirmIcon(), verify)
+
+	if info.Detached {
+		box.Add(widget.NewLabel("原文:"))
+		box.Add(contentRow)
+	}
+	box.Add(certInput)
+	box.Add(userIDInput)
+	box.Add(verifyButton)
+	box.Add(resultEntry)
+	if info.Detached {
+		resultEntry.SetText(formatSignedDataSigners(info))
+	} else {
+		verify()
+	}
 	box.Refresh()
 }
 
-// validateCertificateChain 验证证书链的有效性
-func validateCertificateChain(certificates []*Certificate) string {
-	if len(certificates) == 0 {
-		return "证书链为空"
+// formatSignedDataSigners 格式化各签名者的标识、算法、属性及验证结果
+func formatSignedDataSigners(info *helper.SignedDataInfo) string {
+	var b strings.Builder
+	for i, signer := range info.Signers {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "签名者 #%d: %s\n", i+1, formatCMSSignatureStatus(signer))
+		writeCMSSignerDetail(&b, signer, "  ")
+		for j, countersignature := range signer.Countersignatures {
+			fmt.Fprintf(&b, "  副署签名 #%d: %s\n", j+1, formatCMSSignatureStatus(countersignature))
+			writeCMSSignerDetail(&b, countersignature, "    ")
+		}
 	}
+	return b.String()
+}
 
-	if len(certificates) == 1 {
-		return "单个证书，无需验证证书链"
+func formatCMSSignatureStatus(signer *helper.CMSSignerInfo) string {
+	switch signer.SignatureStatus {
+	case helper.CMSSignatureValid:
+		return "✅ " + signer.SignatureStatus
+	case helper.CMSSignatureInvalid:
+		return "❌ " + signer.SignatureStatus + ": " + signer.SignatureError
 	}
+	return "⚠️ " + signer.SignatureStatus
+}
 
-	result := fmt.Sprintf("证书链验证结果（共 %d 个证书）:\n\n", len(certificates))
-
-	// 从根证书开始验证（假设证书按从叶到根的顺序排列）
-	for i := 0; i < len(certificates)-1; i++ {
-		childCert := certificates[i]
-		parentCert := certificates[i+1]
-
-		result += fmt.Sprintf("验证证书 #%d -> #%d:\n", i+1, i+2)
+func writeCMSSignerDetail(b *strings.Builder, signer *helper.CMSSignerInfo, indent string) {
+	fmt.Fprintf(b, "%s版本: V%d\n", indent, signer.Version)
+	fmt.Fprintf(b, "%s标识: %s\n", indent, signer.Identifier())
+	fmt.Fprintf(b, "%s摘要算法: %s, 签名算法: %s\n", indent, signer.DigestAlgorithm, signer.SignatureAlgorithm)
+	if signer.Certificate != nil {
+		fmt.Fprintf(b, "%s签名证书: %s, 有效期 %s 至 %s\n", indent, signer.Certificate.Subject.String(),
+			signer.Certificate.NotBefore.Format(util.DateTime), signer.Certificate.NotAfter.Format(util.DateTime))
+	}
+	if len(signer.SignedAttributes) > 0 {
+		fmt.Fprintf(b, "%s签名属性:\n", indent)
+		for _, attr := range signer.SignedAttributes {
+			fmt.Fprintf(b, "%s  %s: %s\n", indent, attr.Name, strings.Join(attr.FormatValues(), "; "))
+		}
+	}
+	if len(signer.UnsignedAttributes) > 0 {
+		fmt.Fprintf(b, "%s非签名属性:\n", indent)
+		for _, attr := range signer.UnsignedAttributes {
+			fmt.Fprintf(b, "%s  %s: %s\n", indent, attr.Name, strings.Join(attr.FormatValues(), "; "))
+		}
+	}
+	for _, warning := range signer.Warnings {
+		fmt.Fprintf(b, "%s⚠️ %s\n", indent, warning)
+	}
+}
 
-		// 验证签名
-		err := childCert.CheckSignatureFrom(parentCert)
+// buildP7bSignForm 以证书及SM2/RSA私钥生成PKCS#7签名消息
+func buildP7bSignForm() *fyne.Container {
+	title := widget.NewLabel("生成签名")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	content, contentRow := newContentSource("待签名的原文，输入文本或选择文件")
+	certInput := buildInputCertEntry("请输入签名证书(PEM/Base64/Hex)，可附带上级证书")
+	certInput.Wrapping = fyne.TextWrapWord
+	keyInput := buildInputCertEntry("请输入签名私钥(SM2或RSA)")
+	keyInput.Wrapping = fyne.TextWrapWord
+
+	hashSelect := widget.NewSelect(append([]string{"自动"}, helper.TSPHashAlgorithms...), nil)
+	hashSelect.SetSelected("自动")
+	detachedCheck := widget.NewCheck("分离式签名", nil)
+	attributesCheck := widget.NewCheck("包含签名属性", nil)
+	attributesCheck.SetChecked(true)
+	certsCheck := widget.NewCheck("附带证书", nil)
+	certsCheck.SetChecked(true)
+	gmCheck := widget.NewCheck("GM/T 0010 OID", nil)
+	signingTimeInput := widget.NewEntry()
+	signingTimeInput.SetPlaceHolder("留空使用当前时间，格式 " + util.DateTime)
+	userIDInput := widget.NewEntry()
+	userIDInput.SetPlaceHolder("留空使用默认用户ID " + string(helper.SM2DefaultUserID))
+
+	form := widget.NewForm(
+		widget.NewFormItem("摘要算法", container.NewHBox(hashSelect, detachedCheck, attributesCheck, certsCheck, gmCheck)),
+		widget.NewFormItem("签名时间", signingTimeInput),
+		widget.NewFormItem("SM2用户ID", userIDInput),
+	)
+
+	output := widget.NewMultiLineEntry()
+	output.Wrapping = fyne.TextWrapWord
+	output.Hide()
+
+	sign := buildButton("生成签名", theme.ConfirmIcon(), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+
+		data, err := content.load()
 		if err != nil {
-			result += fmt.Sprintf("  ❌ 签名验证失败: %v\n", err)
-		} else {
-			result += fmt.Sprintf("  ✅ 签名验证通过\n")
+			dialog.ShowError(err, window)
+			return
+		}
+		if data == nil {
+			dialog.ShowError(fmt.Errorf("请输入待签名的原文"), window)
+			return
+		}
+		certs, err := decodeCertificateChain(certInput.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析签名证书失败: %v", err), window)
+			return
+		}
+		decodeKey, _, err := decodeInput(keyInput.Text, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("私钥解码失败: %v", err), window)
+			return
+		}
+		privateKey, err := helper.ParsePrivateKey(decodeKey)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析私钥错误: %v", err), window)
+			return
 		}
 
-		// 检查有效期
-		now := time.Now()
-		if now.Before(childCert.NotBefore) || now.After(childCert.NotAfter) {
-			result += fmt.Sprintf("  ❌ 证书不在有效期内\n")
-		} else {
-			result += fmt.Sprintf("  ✅ 证书在有效期内\n")
+		opts := helper.SignedDataOptions{
+			Detached:           detachedCheck.Checked,
+			NoSignedAttributes: !attributesCheck.Checked,
+			GMOIDs:             gmCheck.Checked,
+			SM2UserID:          []byte(userIDInput.Text),
 		}
+		if hashSelect.Selected != "自动" {
+			opts.HashAlgorithm = hashSelect.Selected
+		}
+		if certsCheck.Checked {
+			opts.Certificates = certs
+		}
+		if attributesCheck.Checked {
+			if opts.SigningTime, err = util.ParseDateTime(signingTimeInput.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("签名时间格式应为 %s", util.DateTime), window)
+				return
+			}
+			if opts.SigningTime.IsZero() {
+				opts.SigningTime = time.Now()
+			}
+		}
+		signed, err := helper.CreateSignedData(privateKey, certs[0], data, opts)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("生成签名失败: %v", err), window)
+			return
+		}
+		output.SetText(base64.StdEncoding.EncodeToString(signed))
+		output.Show()
+	})
+	clear := buildButton("清除", theme.CancelIcon(), func() {
+		content.clear()
+		certInput.SetText("")
+		keyInput.SetText("")
+		signingTimeInput.SetText("")
+		userIDInput.SetText("")
+		output.SetText("")
+		output.Hide()
+	})
+
+	allButton := container.New(layout.NewGridLayout(2), sign, clear)
+	return container.NewVBox(title, contentRow, certInput, keyInput, form, allButton, output)
+}
 
-		result += "\n"
+// showCertificateChainValidation 展示证书路径构建与验证结果，可指定信任锚、验证时间及用于吊销检查的CRL
+func showCertificateChainValidation(certificates []*Certificate, embeddedCRLs []*pkix.CertificateList, box *fyne.Container) {
+	validationTitle := widget.NewLabel("证书路径验证:")
+	validationTitle.TextStyle = fyne.TextStyle{Bold: true}
+	box.Add(validationTitle)
+
+	anchorsInput := widget.NewMultiLineEntry()
+	anchorsInput.SetPlaceHolder("可选: 信任锚证书(多个PEM证书或P7B)，为空时使用P7B中的自签名证书")
+	anchorsInput.Wrapping = fyne.TextWrapWord
+	crlInput := widget.NewMultiLineEntry()
+	crlInput.SetPlaceHolder(fmt.Sprintf("可选: 用于吊销检查的CRL(多个PEM或Base64/Hex)，P7B中已包含%d个CRL", len(embeddedCRLs)))
+	crlInput.Wrapping = fyne.TextWrapWord
+	timeInput := widget.NewEntry()
+	timeInput.SetText(util.ToBeijingTime(time.Now()).Format(util.DateTime))
+	form := widget.NewForm(
+		widget.NewFormItem("信任锚", anchorsInput),
+		widget.NewFormItem("CRL", crlInput),
+		widget.NewFormItem("验证时间", timeInput),
+	)
+
+	validationEntry := widget.NewMultiLineEntry()
+	validationEntry.Wrapping = fyne.TextWrapWord
+	validationEntry.SetMinRowsVisible(12)
+
+	validate := func() {
+		opts := helper.PathOptions{Intermediates: certificates, CRLs: embeddedCRLs}
+		if strings.TrimSpace(anchorsInput.Text) != "" {
+			roots, err := decodeCertificateChain(anchorsInput.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("解析信任锚失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			opts.Roots = roots
+		}
+		if strings.TrimSpace(crlInput.Text) != "" {
+			crls, err := decodeCRLList(crlInput.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("解析CRL失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			opts.CRLs = append(append([]*pkix.CertificateList(nil), embeddedCRLs...), crls...)
+		}
+		at, err := util.ParseDateTime(timeInput.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("验证时间格式应为 %s", util.DateTime), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		opts.Time = at
+		validationEntry.SetText(validateCertificateChain(certificates, opts))
 	}
+	validateButton := widget.NewButtonWithIcon("验证证书路径", theme.ConfirmIcon(), validate)
+
+	box.Add(form)
+	box.Add(validateButton)
+	box.Add(validationEntry)
+	validate()
+	box.Refresh()
+}
 
-	return result
+// validateCertificateChain 对证书池中的每张终端证书构建并验证证书路径
+func validateCertificateChain(certificates []*Certificate, opts helper.PathOptions) string {
+	if len(certificates) == 0 {
+		return "证书链为空"
+	}
+	var results []string
+	for _, leaf := range helper.FindLeafCertificates(certificates) {
+		results = append(results, helper.FormatPathValidation(helper.ValidateCertificatePath(leaf, opts)))
+	}
+	return strings.Join(results, "\n\n")
 }
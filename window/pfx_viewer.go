@@ -1,118 +1,329 @@
 package window
 
 import (
+	"HeTu/codec"
 	"HeTu/helper"
 	"HeTu/util"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/zaneway/cain-go/pkcs12"
-	"github.com/zaneway/cain-go/sm2"
 	"github.com/zaneway/cain-go/x509"
 )
 
-// 输入证书、私钥、密码，生成pfx文件
+// SM2PfxStructure 解析PFX(含SM2)并导出私钥与证书链，下方保留由证书与私钥生成PFX的功能
 func SM2PfxStructure(input *widget.Entry) *fyne.Container {
 	// 移除占位符设置，由主界面统一管理
 	structure := container.NewVBox()
 	input.Wrapping = fyne.TextWrapWord
+
+	passwordInput := widget.NewPasswordEntry()
+	passwordInput.SetPlaceHolder("请输入PFX口令")
+
+	detail := container.NewVBox()
+	exportFormat := widget.NewSelect([]string{"PEM", "DER (Base64)"}, nil)
+	exportFormat.SetSelected("PEM")
+	exportOutput := widget.NewMultiLineEntry()
+	exportOutput.Wrapping = fyne.TextWrapWord
+	exportOutput.SetMinRowsVisible(8)
+	exportOutput.Hide()
+
+	var parsed *helper.PFXInfo
+	parse := widget.NewButtonWithIcon("解析PFX", theme.ConfirmIcon(), func() {
+		inputPfx := strings.TrimSpace(input.Text)
+		if inputPfx == "" {
+			dialog.ShowError(fmt.Errorf("请输入PFX数据"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		// 保存到历史记录
+		util.GetHistoryDB().AddHistory(P12Tab, inputPfx)
+		if historyManager := GetGlobalHistoryManager(); historyManager != nil {
+			historyManager.LoadHistoryForTab(P12Tab)
+		}
+
+		decodePfx, encoding, err := decodeInput(inputPfx, "PKCS12")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("PFX数据解码失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		pfxInfo, err := helper.ParsePFX(decodePfx, passwordInput.Text)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		parsed = pfxInfo
+
+		detail.RemoveAll()
+		exportOutput.SetText("")
+		exportOutput.Hide()
+		detail.Add(widget.NewLabel("输入格式: " + encoding))
+		showPFXInfo(pfxInfo, detail)
+	})
+
+	export := widget.NewButtonWithIcon("导出私钥与证书链", theme.DownloadIcon(), func() {
+		if parsed == nil {
+			dialog.ShowError(fmt.Errorf("请先解析PFX"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		if parsed.PrivateKeyDER == nil && len(parsed.Certificates) == 0 {
+			dialog.ShowError(fmt.Errorf("PFX中没有可导出的私钥或证书"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		if exportFormat.Selected == "PEM" {
+			exportOutput.SetText(parsed.ExportPEM())
+		} else {
+			var lines []string
+			if parsed.PrivateKeyDER != nil {
+				lines = append(lines, "PrivateKey:\n"+base64.StdEncoding.EncodeToString(parsed.PrivateKeyDER))
+			}
+			for i, cert := range parsed.Certificates {
+				lines = append(lines, fmt.Sprintf("Certificate #%d:\n%s", i+1, base64.StdEncoding.EncodeToString(cert.Raw)))
+			}
+			exportOutput.SetText(strings.Join(lines, "\n\n"))
+		}
+		exportOutput.Show()
+	})
+
+	//清除按钮
+	clear := widget.NewButtonWithIcon("清除", theme.CancelIcon(), func() {
+		input.SetText("")
+		passwordInput.SetText("")
+		parsed = nil
+		detail.RemoveAll()
+		exportOutput.SetText("")
+		exportOutput.Hide()
+	})
+
+	structure.Add(passwordInput)
+	structure.Add(container.New(layout.NewGridLayout(2), parse, clear))
+	structure.Add(detail)
+	structure.Add(container.New(layout.NewGridLayout(2), exportFormat, export))
+	structure.Add(exportOutput)
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildPfxGenerateForm())
+
+	// 使用滚动容器支持长内容
+	scrollContainer := container.NewScroll(structure)
+	return container.NewMax(scrollContainer)
+}
+
+// showPFXInfo 展示MAC信息及每个ContentInfo中的SafeBag
+func showPFXInfo(info *helper.PFXInfo, box *fyne.Container) {
+	summaryKeys := []string{"Version", "MAC"}
+	summary := map[string]string{
+		"Version": fmt.Sprintf("%d", info.Version),
+		"MAC":     "无",
+	}
+	if info.MACAlgorithm != "" {
+		summary["MAC"] = fmt.Sprintf("%s, %d次迭代, Salt %s", info.MACAlgorithm, info.MACIterations, info.MACSalt)
+	}
+	summaryKeys = append(summaryKeys, "私钥", "证书数量")
+	summary["私钥"] = "无"
+	if info.PrivateKey != nil {
+		algorithm, size := "", 0
+		for _, bag := range info.Bags() {
+			if bag.PrivateKey == info.PrivateKey {
+				algorithm, size = bag.KeyAlgorithm, bag.KeySize
+			}
+		}
+		summary["私钥"] = fmt.Sprintf("%s (%d bits)", algorithm, size)
+	}
+	summary["证书数量"] = fmt.Sprintf("%d", len(info.Certificates))
+	showCertificateDetail(summaryKeys, summary, box)
+
+	for i, safe := range info.Safes {
+		box.Add(widget.NewSeparator())
+		title := fmt.Sprintf("ContentInfo #%d: %s", i+1, safe.ContentType)
+		if safe.EncryptionAlgorithm != "" {
+			title += "，加密算法 " + safe.EncryptionAlgorithm
+		}
+		titleLabel := widget.NewLabel(title)
+		titleLabel.TextStyle = fyne.TextStyle{Bold: true}
+		titleLabel.Wrapping = fyne.TextWrapWord
+		box.Add(titleLabel)
+		if safe.Error != "" {
+			box.Add(widget.NewLabel("❌ " + safe.Error))
+		}
+		for j, bag := range safe.Bags {
+			bagLabel := widget.NewLabel(fmt.Sprintf("SafeBag #%d: %s", j+1, bag.TypeName))
+			bagLabel.TextStyle = fyne.TextStyle{Italic: true}
+			box.Add(bagLabel)
+			showPFXBag(bag, box)
+		}
+	}
+	box.Refresh()
+}
+
+// showPFXBag 以表格展示单个SafeBag
+func showPFXBag(bag *helper.PFXBag, box *fyne.Container) {
+	var keys []string
+	values := make(map[string]string)
+	add := func(key, value string) {
+		if value != "" {
+			keys = append(keys, key)
+			values[key] = value
+		}
+	}
+	add("EncryptionAlgorithm", bag.EncryptionAlgorithm)
+	if bag.KeyAlgorithm != "" {
+		add("KeyAlgorithm", fmt.Sprintf("%s (%d bits)", bag.KeyAlgorithm, bag.KeySize))
+	}
+	add("ValueType", bag.ValueType)
+	add("SubjectName", bag.Subject)
+	add("IssueName", bag.Issuer)
+	add("SerialNumber", bag.SerialNumber)
+	for _, attr := range bag.Attributes {
+		add(attr.Name, strings.Join(attr.Values, "\n"))
+	}
+	if bag.Error != "" {
+		add("Error", "❌ "+bag.Error)
+	}
+	showCertificateDetail(keys, values, box)
+}
+
+// buildPfxGenerateForm 由证书、私钥、证书链和口令生成PFX，可选择加密方案与迭代次数
+func buildPfxGenerateForm() *fyne.Container {
+	title := widget.NewLabel("生成PFX")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	certInput := buildInputCertEntry("Please input base64/hex certificate")
 	KeyInput := buildInputCertEntry("Please input base64/hex private key")
 	KeyInput.Wrapping = fyne.TextWrapWord
+	chainInput := buildInputCertEntry("可选: CA证书链，支持多个PEM证书或P7B")
+	chainInput.Wrapping = fyne.TextWrapWord
+
+	passwordInput := widget.NewPasswordEntry()
+	passwordInput.SetPlaceHolder("Please input password")
+	friendlyNameInput := widget.NewEntry()
+	friendlyNameInput.SetPlaceHolder("可选")
+	localKeyIDInput := widget.NewEntry()
+	localKeyIDInput.SetPlaceHolder("可选，Hex格式，默认为证书SHA-1指纹")
+	encryptionSelect := widget.NewSelect(helper.PFXEncryptions, nil)
+	encryptionSelect.SetSelected(helper.PFXEncryptionAES256)
+	iterationsInput := widget.NewEntry()
+	iterationsInput.SetText("2048")
+	macIterationsInput := widget.NewEntry()
+	macIterationsInput.SetText("2048")
 
-	passwordInput := buildInputCertEntry("Please input password")
-	passwordInput.Wrapping = fyne.TextWrapWord
+	form := widget.NewForm(
+		widget.NewFormItem("口令", passwordInput),
+		widget.NewFormItem("FriendlyName", friendlyNameInput),
+		widget.NewFormItem("LocalKeyID", localKeyIDInput),
+		widget.NewFormItem("加密方案", encryptionSelect),
+		widget.NewFormItem("加密迭代次数", iterationsInput),
+		widget.NewFormItem("MAC迭代次数", macIterationsInput),
+	)
 
 	// 创建输出框，供用户输入数据
 	output := widget.NewMultiLineEntry()
+	output.Wrapping = fyne.TextWrapWord
 	output.Hide()
 
-	//inputCertEntry.Text = "MIICETCCAbWgAwIBAgINKl81oFaaablKOp0YTjAMBggqgRzPVQGDdQUAMGExCzAJBgNVBAYMAkNOMQ0wCwYDVQQKDARCSkNBMSUwIwYDVQQLDBxCSkNBIEFueXdyaXRlIFRydXN0IFNlcnZpY2VzMRwwGgYDVQQDDBNUcnVzdC1TaWduIFNNMiBDQS0xMB4XDTIwMDgxMzIwMTkzNFoXDTIwMTAyNDE1NTk1OVowHjELMAkGA1UEBgwCQ04xDzANBgNVBAMMBuWGr+i9rDBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABAIF97Sqq0Rv616L2PjFP3xt16QGJLmi+W8Ht+NLHiXntgUey0Nz+ZVnSUKUMzkKuGTikY3h2v7la20b6lpKo8WjgZIwgY8wCwYDVR0PBAQDAgbAMB0GA1UdDgQWBBSxiaS6z4Uguz3MepS2zblkuAF/LTAfBgNVHSMEGDAWgBTMZyRCGsP4rSes0vLlhIEf6cUvrjBABgNVHSAEOTA3MDUGCSqBHIbvMgICAjAoMCYGCCsGAQUFBwIBFhpodHRwOi8vd3d3LmJqY2Eub3JnLmNuL2NwczAMBggqgRzPVQGDdQUAA0gAMEUCIG6n6PG0BOK1EdFcvetQlC+9QhpsTuTui2wkeqWiPKYWAiEAvqR8Z+tSiYR5DIs7SyHJPWZ+sa8brtQL/1jURvHGxU8="
 	//确认按钮
-	confirm := buildButton("确认", theme.ConfirmIcon(), func() {
-		inputCert := input.Text
-		inputKey := KeyInput.Text
-		inputPassword := passwordInput.Text
-
-		// 保存到历史记录
-		if inputCert != "" {
-			util.GetHistoryDB().AddHistory("🎫 P12证书", inputCert)
+	confirm := buildButton("生成PFX", theme.ConfirmIcon(), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
 
-			// 刷新历史记录下拉框
-			if historyManager := GetGlobalHistoryManager(); historyManager != nil {
-				historyManager.LoadHistoryForTab("🎫 P12证书")
-			}
+		decodeCert, _, err := decodeInput(certInput.Text, "CERTIFICATE")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("证书解码失败: %v", err), window)
+			return
+		}
+		certificate, err := x509.ParseCertificate(decodeCert)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析证书错误: %v", err), window)
+			return
 		}
 
-		decodeCert, err := base64.StdEncoding.DecodeString(inputCert)
+		decodeKey, _, err := decodeInput(KeyInput.Text, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY")
 		if err != nil {
-			decodeCert, err = hex.DecodeString(inputCert)
-			if err != nil {
-				fyne.LogError("解析Cert请求错误", err)
+			dialog.ShowError(fmt.Errorf("私钥解码失败: %v", err), window)
+			return
+		}
+		privateKey, err := helper.ParsePrivateKey(decodeKey)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("解析私钥错误: %v", err), window)
+			return
+		}
+
+		var chain []*x509.Certificate
+		if strings.TrimSpace(chainInput.Text) != "" {
+			if chain, err = decodeCertificateChain(chainInput.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("解析证书链错误: %v", err), window)
 				return
 			}
 		}
 
-		decodeKey, err := base64.StdEncoding.DecodeString(inputKey)
-		if err != nil {
-			decodeKey, err = hex.DecodeString(inputKey)
-			if err != nil {
-				fyne.LogError("解析Key请求错误", err)
+		opts := helper.PFXOptions{
+			Password:     passwordInput.Text,
+			FriendlyName: strings.TrimSpace(friendlyNameInput.Text),
+			Encryption:   encryptionSelect.Selected,
+		}
+		if keyID := strings.TrimSpace(localKeyIDInput.Text); keyID != "" {
+			if opts.LocalKeyID, err = hex.DecodeString(keyID); err != nil {
+				dialog.ShowError(fmt.Errorf("LocalKeyID不是有效的Hex: %v", err), window)
 				return
 			}
 		}
-
-		//MIICETCCAbWgAwIBAgINKl81oFaaablKOp0YTjAMBggqgRzPVQGDdQUAMGExCzAJBgNVBAYMAkNOMQ0wCwYDVQQKDARCSkNBMSUwIwYDVQQLDBxCSkNBIEFueXdyaXRlIFRydXN0IFNlcnZpY2VzMRwwGgYDVQQDDBNUcnVzdC1TaWduIFNNMiBDQS0xMB4XDTIwMDgxMzIwMTkzNFoXDTIwMTAyNDE1NTk1OVowHjELMAkGA1UEBgwCQ04xDzANBgNVBAMMBuWGr+i9rDBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABAIF97Sqq0Rv616L2PjFP3xt16QGJLmi+W8Ht+NLHiXntgUey0Nz+ZVnSUKUMzkKuGTikY3h2v7la20b6lpKo8WjgZIwgY8wCwYDVR0PBAQDAgbAMB0GA1UdDgQWBBSxiaS6z4Uguz3MepS2zblkuAF/LTAfBgNVHSMEGDAWgBTMZyRCGsP4rSes0vLlhIEf6cUvrjBABgNVHSAEOTA3MDUGCSqBHIbvMgICAjAoMCYGCCsGAQUFBwIBFhpodHRwOi8vd3d3LmJqY2Eub3JnLmNuL2NwczAMBggqgRzPVQGDdQUAA0gAMEUCIG6n6PG0BOK1EdFcvetQlC+9QhpsTuTui2wkeqWiPKYWAiEAvqR8Z+tSiYR5DIs7SyHJPWZ+sa8brtQL/1jURvHGxU8=
-		//MIIEfjCCA2agAwIBAgIQefIDuSADkosPySFwsKcsjDANBgkqhkiG9w0BAQsFADBQMQswCQYDVQQGEwJDTjEmMCQGA1UECgwdQkVJSklORyBDRVJUSUZJQ0FURSBBVVRIT1JJVFkxGTAXBgNVBAMMEEJKQ0EgRG9jU2lnbiBDQTMwHhcNMjAxMjA3MDc1MDAwWhcNMjExMjA3MDc1MDAwWjBIMQswCQYDVQQGEwJDTjElMCMGA1UECwwcYmI1Tndlbk5kYVg2ZkhNd1VKUlkvQTFOVDcwPTESMBAGA1UEAwwJ5p2O5Li96ZyeMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAzeiCgLXKDzzBsLLHedJKG11m6SotdlynexHe8cI1TmWa3ODerwBHukr5ZkJft3seIQqFHi6xVlNgfOHO5WNgCKpvg/HxRoQshwLDYgeH5KcpH67dv1dl6urqwvwzSE5gmJo1+OGqAl9yeG9X76zkueZUd4v3RrVOoofbTlSBkWoigXH/0mpu/vgxhDRzmksNQvZ+Ay2jisdshpZovH6a+ABYMYMYo4U1o6BfvHBKEPo20TDJ/t0KlVRoHkgiMvtO8NOI5d0cxea5RaOCDT10CGHheqieMibUQnCkB6Yi01aoDQxtG8TshO7uGWoMzqPPs+u44Ym1s2LH51fvTS6bHQIDAQABo4IBWjCCAVYwcQYIKwYBBQUHAQEEZTBjMEAGCCsGAQUFBzAChjRodHRwOi8vcmVwby5iamNhLmNuL2dsb2JhbC9jZXJ0L0JKQ0FfRG9jU2lnbl9DQTMuY3J0MB8GCCsGAQUFBzABhhNodHRwOi8vb2NzcC5iamNhLmNuMB0GA1UdDgQWBBQh7RHFVos8ievEiiAvASMjEmqw+zAMBgNVHRMBAf8EAjAAMB8GA1UdIwQYMBaAFCA6epfxEmaXv3PW5YXPR9M0GLwyMD0GA1UdIAQ2MDQwMgYJKoEchu8yAgIWMCUwIwYIKwYBBQUHAgEWF2h0dHBzOi8vd3d3LmJqY2EuY24vQ1BTMEQGA1UdHwQ9MDswOaA3oDWGM2h0dHA6Ly9yZXBvLmJqY2EuY24vZ2xvYmFsL2NybC9CSkNBX0RvY1NpZ25fQ0EzLmNybDAOBgNVHQ8BAf8EBAMCBsAwDQYJKoZIhvcNAQELBQADggEBAF5apKpbT9EG+gJP82LKKwbW9/jUJ/9tZEzPKfX4Uqs7YB3DCnM78qLBKvHByP9bUv2L7Yd6ncv9FORJqw6KEJiNz6/wXcNsNN/MYj8tZNonMyTW+tGkoRR0AqPWHZ1Cq+M0LFYuL8uwkMXDPZiHrrwtwNrr5cSsrYiamDyoZAe6MRzBiU9WgpzGWbMPu+IRoYye04Cq/yEVBsHLnUR24wehUVgPJb68tR7j3M3Yc3gSbTb9ymFFfETxaf2qDUelnr7CqhM/Ddj77dnZ86ZUGi95l7SDeEQW56EL9Og4TnLuL7A0tOPZhADwY5mgiQbLiMziO7szirh8wK8R5njJ9gI=
-		certificate, err := x509.ParseCertificate(decodeCert)
-		if err != nil {
-			fyne.LogError("解析证书错误", err)
+		if opts.Iterations, err = strconv.Atoi(strings.TrimSpace(iterationsInput.Text)); err != nil || opts.Iterations <= 0 {
+			dialog.ShowError(fmt.Errorf("加密迭代次数必须为正整数"), window)
 			return
 		}
-		var sm2Key *sm2.PrivateKey
-		//裸私钥长度为32
-		if len(decodeKey) <= 32 {
-			sm2Key = helper.BuildPrivateKeyUseRaw(decodeKey)
-		} else {
-			sm2Key, err = helper.BuildPrivateKey(decodeKey)
+		if opts.MACIterations, err = strconv.Atoi(strings.TrimSpace(macIterationsInput.Text)); err != nil || opts.MACIterations <= 0 {
+			dialog.ShowError(fmt.Errorf("MAC迭代次数必须为正整数"), window)
+			return
 		}
-		pfx, err := buildPfx(certificate, sm2Key, inputPassword)
+
+		pfx, err := helper.CreatePFX(privateKey, certificate, chain, opts)
 		if err != nil {
+			dialog.ShowError(fmt.Errorf("生成PFX失败: %v", err), window)
 			return
 		}
-		output.Text = base64.StdEncoding.EncodeToString(pfx)
+		output.SetText(base64.StdEncoding.EncodeToString(pfx))
 		output.Show()
 	})
 	//清除按钮
 	clear := buildButton("清除", theme.CancelIcon(), func() {
-		input.Text = ""
-		output.Text = ""
-		input.Refresh()
-		output.Refresh()
+		certInput.SetText("")
+		KeyInput.SetText("")
+		chainInput.SetText("")
+		passwordInput.SetText("")
+		friendlyNameInput.SetText("")
+		localKeyIDInput.SetText("")
+		output.SetText("")
+		output.Hide()
 	})
 
 	//对所有按钮进行表格化
 	allButton := container.New(layout.NewGridLayout(2), confirm, clear)
-	// 不添加全局输入框，它已经在主界面的固定位置
-	// structure.Add(input)
-	structure.Add(KeyInput)
-	structure.Add(passwordInput)
-	structure.Add(allButton)
-	structure.Add(output)
-	// 使用滚动容器支持长内容
-	scrollContainer := container.NewScroll(structure)
-	return container.NewMax(scrollContainer)
+	return container.NewVBox(title, certInput, KeyInput, chainInput, form, allButton, output)
 }
 
-func buildPfx(cert *x509.Certificate, privateKey interface{}, password string) ([]byte, error) {
-	// 创建 PFX（PKCS#12）文件
-	pfxData, err := pkcs12.Encode(privateKey, cert, nil, password)
+// decodeCertificateChain 解析证书链输入，支持多个PEM证书、PKCS7块及Base64/Hex编码的DER或P7B
+func decodeCertificateChain(input string) ([]*x509.Certificate, error) {
+	result, err := codec.DecodeString(input)
 	if err != nil {
 		return nil, err
 	}
-	return pfxData, nil
+	blocks := result.AllOfType("CERTIFICATE", "PKCS7")
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("未找到证书，输入为 %s", result.Describe())
+	}
+	var certs []*x509.Certificate
+	for _, block := range blocks {
+		parsed, err := helper.ParseCertificates(block)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, parsed...)
+	}
+	return certs, nil
 }
@@ -2,95 +2,529 @@ package window
 
 import (
 	"HeTu/helper"
+	"HeTu/util"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/zaneway/cain-go/sm2"
 	"github.com/zaneway/cain-go/x509"
 )
 
-// 输入证书、私钥、密码，生成pfx文件
+const (
+	// tspQueryTimeout 发送时间戳请求的超时时间
+	tspQueryTimeout = 15 * time.Second
+	// tsaLogLimit 本地时间戳服务日志保留的最大行数
+	tsaLogLimit = 500
+)
+
+// TimestampStructure 构造RFC 3161时间戳请求、发送至TSA，并解析验证时间戳响应
 func TimestampStructure(input *widget.Entry) *fyne.Container {
-	// 移除占位符设置，由主界面统一管理
 	structure := container.NewVBox()
 	input.Wrapping = fyne.TextWrapWord
-	KeyInput := buildInputCertEntry("Please input base64/hex private key")
-	KeyInput.Wrapping = fyne.TextWrapWord
 
-	passwordInput := buildInputCertEntry("Please input password")
-	passwordInput.Wrapping = fyne.TextWrapWord
+	data, dataRow := newContentSource("请输入待加盖时间戳的文本，或选择文件；解析响应时用于验证消息摘要")
+	hashSelect := widget.NewSelect(helper.TSPHashAlgorithms, nil)
+	hashSelect.SetSelected(helper.TSPHashSHA256)
+	nonceCheck := widget.NewCheck("包含nonce", nil)
+	nonceCheck.SetChecked(true)
+	certReqCheck := widget.NewCheck("要求附带TSA证书", nil)
+	certReqCheck.SetChecked(true)
+	policyInput := widget.NewEntry()
+	policyInput.SetPlaceHolder("可选，请求的TSA策略OID")
+	urlInput := widget.NewEntry()
+	urlInput.SetPlaceHolder("时间戳服务地址，如 http://timestamp.example.com/tsa")
+	tsaCertInput := buildInputCertEntry("可选，TSA证书(PEM/Base64/Hex)，令牌未附带证书时用于验证签名")
+
+	form := widget.NewForm(
+		widget.NewFormItem("摘要算法", container.NewHBox(hashSelect, nonceCheck, certReqCheck)),
+		widget.NewFormItem("策略", policyInput),
+		widget.NewFormItem("TSA地址", urlInput),
+	)
+
+	statusLabel := widget.NewLabel("")
+	progressBar := widget.NewProgressBarInfinite()
+	progressBar.Hide()
 
-	// 创建输出框，供用户输入数据
-	output := widget.NewMultiLineEntry()
-	output.Hide()
+	requestOutput := widget.NewMultiLineEntry()
+	requestOutput.Wrapping = fyne.TextWrapWord
+	requestOutput.Hide()
+	details := widget.NewMultiLineEntry()
+	details.Wrapping = fyne.TextWrapWord
+	details.Hide()
 
-	//inputCertEntry.Text = "MIICETCCAbWgAwIBAgINKl81oFaaablKOp0YTjAMBggqgRzPVQGDdQUAMGExCzAJBgNVBAYMAkNOMQ0wCwYDVQQKDARCSkNBMSUwIwYDVQQLDBxCSkNBIEFueXdyaXRlIFRydXN0IFNlcnZpY2VzMRwwGgYDVQQDDBNUcnVzdC1TaWduIFNNMiBDQS0xMB4XDTIwMDgxMzIwMTkzNFoXDTIwMTAyNDE1NTk1OVowHjELMAkGA1UEBgwCQ04xDzANBgNVBAMMBuWGr+i9rDBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABAIF97Sqq0Rv616L2PjFP3xt16QGJLmi+W8Ht+NLHiXntgUey0Nz+ZVnSUKUMzkKuGTikY3h2v7la20b6lpKo8WjgZIwgY8wCwYDVR0PBAQDAgbAMB0GA1UdDgQWBBSxiaS6z4Uguz3MepS2zblkuAF/LTAfBgNVHSMEGDAWgBTMZyRCGsP4rSes0vLlhIEf6cUvrjBABgNVHSAEOTA3MDUGCSqBHIbvMgICAjAoMCYGCCsGAQUFBwIBFhpodHRwOi8vd3d3LmJqY2Eub3JnLmNuL2NwczAMBggqgRzPVQGDdQUAA0gAMEUCIG6n6PG0BOK1EdFcvetQlC+9QhpsTuTui2wkeqWiPKYWAiEAvqR8Z+tSiYR5DIs7SyHJPWZ+sa8brtQL/1jURvHGxU8="
-	//确认按钮
-	confirm := buildButton("确认", theme.ConfirmIcon(), func() {
-		inputCert := input.Text
-		inputKey := KeyInput.Text
-		inputPassword := passwordInput.Text
-		decodeCert, err := base64.StdEncoding.DecodeString(inputCert)
+	// lastRequest 最近一次生成的请求，用于检查响应的摘要、nonce及策略
+	var lastRequest *helper.TimeStampRequestInfo
+
+	buildRequest := func() ([]byte, error) {
+		content, err := data.load()
 		if err != nil {
-			decodeCert, err = hex.DecodeString(inputCert)
-			if err != nil {
-				fyne.LogError("解析Cert请求错误", err)
-				return
+			return nil, err
+		}
+		if content == nil {
+			return nil, fmt.Errorf("请输入待加盖时间戳的数据或选择文件")
+		}
+		template := &helper.TimeStampRequestTemplate{
+			HashAlgorithm: hashSelect.Selected,
+			Policy:        strings.TrimSpace(policyInput.Text),
+			CertReq:       certReqCheck.Checked,
+		}
+		if template.HashedMessage, err = helper.ComputeMessageImprint(template.HashAlgorithm, content); err != nil {
+			return nil, err
+		}
+		if nonceCheck.Checked {
+			if template.Nonce, err = helper.NewTimeStampNonce(); err != nil {
+				return nil, err
 			}
 		}
+		request, err := helper.CreateTimeStampRequest(template)
+		if err != nil {
+			return nil, err
+		}
+		if lastRequest, err = helper.ParseTimeStampRequest(request); err != nil {
+			return nil, err
+		}
+		return request, nil
+	}
 
-		decodeKey, err := base64.StdEncoding.DecodeString(inputKey)
+	// showResponse 验证令牌签名，并在提供数据时验证消息摘要
+	showResponse := func(info *helper.TimeStampResponseInfo, req *helper.TimeStampRequestInfo) {
+		content, err := data.load()
 		if err != nil {
-			decodeKey, err = hex.DecodeString(inputKey)
-			if err != nil {
-				fyne.LogError("解析Key请求错误", err)
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		var certs []*x509.Certificate
+		if strings.TrimSpace(tsaCertInput.Text) != "" {
+			if certs, err = decodeCertificateChain(tsaCertInput.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("解析TSA证书失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
 				return
 			}
 		}
+		if info.TSTInfo != nil {
+			// 签名验证失败记录在结果中
+			_ = helper.VerifyTimeStampResponse(info, certs)
+		}
+		details.SetText(formatTimeStampResponse(info, content, req))
+		details.Show()
+	}
 
-		//MIICETCCAbWgAwIBAgINKl81oFaaablKOp0YTjAMBggqgRzPVQGDdQUAMGExCzAJBgNVBAYMAkNOMQ0wCwYDVQQKDARCSkNBMSUwIwYDVQQLDBxCSkNBIEFueXdyaXRlIFRydXN0IFNlcnZpY2VzMRwwGgYDVQQDDBNUcnVzdC1TaWduIFNNMiBDQS0xMB4XDTIwMDgxMzIwMTkzNFoXDTIwMTAyNDE1NTk1OVowHjELMAkGA1UEBgwCQ04xDzANBgNVBAMMBuWGr+i9rDBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABAIF97Sqq0Rv616L2PjFP3xt16QGJLmi+W8Ht+NLHiXntgUey0Nz+ZVnSUKUMzkKuGTikY3h2v7la20b6lpKo8WjgZIwgY8wCwYDVR0PBAQDAgbAMB0GA1UdDgQWBBSxiaS6z4Uguz3MepS2zblkuAF/LTAfBgNVHSMEGDAWgBTMZyRCGsP4rSes0vLlhIEf6cUvrjBABgNVHSAEOTA3MDUGCSqBHIbvMgICAjAoMCYGCCsGAQUFBwIBFhpodHRwOi8vd3d3LmJqY2Eub3JnLmNuL2NwczAMBggqgRzPVQGDdQUAA0gAMEUCIG6n6PG0BOK1EdFcvetQlC+9QhpsTuTui2wkeqWiPKYWAiEAvqR8Z+tSiYR5DIs7SyHJPWZ+sa8brtQL/1jURvHGxU8=
-		//MIIEfjCCA2agAwIBAgIQefIDuSADkosPySFwsKcsjDANBgkqhkiG9w0BAQsFADBQMQswCQYDVQQGEwJDTjEmMCQGA1UECgwdQkVJSklORyBDRVJUSUZJQ0FURSBBVVRIT1JJVFkxGTAXBgNVBAMMEEJKQ0EgRG9jU2lnbiBDQTMwHhcNMjAxMjA3MDc1MDAwWhcNMjExMjA3MDc1MDAwWjBIMQswCQYDVQQGEwJDTjElMCMGA1UECwwcYmI1Tndlbk5kYVg2ZkhNd1VKUlkvQTFOVDcwPTESMBAGA1UEAwwJ5p2O5Li96ZyeMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAzeiCgLXKDzzBsLLHedJKG11m6SotdlynexHe8cI1TmWa3ODerwBHukr5ZkJft3seIQqFHi6xVlNgfOHO5WNgCKpvg/HxRoQshwLDYgeH5KcpH67dv1dl6urqwvwzSE5gmJo1+OGqAl9yeG9X76zkueZUd4v3RrVOoofbTlSBkWoigXH/0mpu/vgxhDRzmksNQvZ+Ay2jisdshpZovH6a+ABYMYMYo4U1o6BfvHBKEPo20TDJ/t0KlVRoHkgiMvtO8NOI5d0cxea5RaOCDT10CGHheqieMibUQnCkB6Yi01aoDQxtG8TshO7uGWoMzqPPs+u44Ym1s2LH51fvTS6bHQIDAQABo4IBWjCCAVYwcQYIKwYBBQUHAQEEZTBjMEAGCCsGAQUFBzAChjRodHRwOi8vcmVwby5iamNhLmNuL2dsb2JhbC9jZXJ0L0JKQ0FfRG9jU2lnbl9DQTMuY3J0MB8GCCsGAQUFBzABhhNodHRwOi8vb2NzcC5iamNhLmNuMB0GA1UdDgQWBBQh7RHFVos8ievEiiAvASMjEmqw+zAMBgNVHRMBAf8EAjAAMB8GA1UdIwQYMBaAFCA6epfxEmaXv3PW5YXPR9M0GLwyMD0GA1UdIAQ2MDQwMgYJKoEchu8yAgIWMCUwIwYIKwYBBQUHAgEWF2h0dHBzOi8vd3d3LmJqY2EuY24vQ1BTMEQGA1UdHwQ9MDswOaA3oDWGM2h0dHA6Ly9yZXBvLmJqY2EuY24vZ2xvYmFsL2NybC9CSkNBX0RvY1NpZ25fQ0EzLmNybDAOBgNVHQ8BAf8EBAMCBsAwDQYJKoZIhvcNAQELBQADggEBAF5apKpbT9EG+gJP82LKKwbW9/jUJ/9tZEzPKfX4Uqs7YB3DCnM78qLBKvHByP9bUv2L7Yd6ncv9FORJqw6KEJiNz6/wXcNsNN/MYj8tZNonMyTW+tGkoRR0AqPWHZ1Cq+M0LFYuL8uwkMXDPZiHrrwtwNrr5cSsrYiamDyoZAe6MRzBiU9WgpzGWbMPu+IRoYye04Cq/yEVBsHLnUR24wehUVgPJb68tR7j3M3Yc3gSbTb9ymFFfETxaf2qDUelnr7CqhM/Ddj77dnZ86ZUGi95l7SDeEQW56EL9Og4TnLuL7A0tOPZhADwY5mgiQbLiMziO7szirh8wK8R5njJ9gI=
-		certificate, err := x509.ParseCertificate(decodeCert)
+	buildBtn := buildButton("生成请求", theme.ConfirmIcon(), func() {
+		request, err := buildRequest()
 		if err != nil {
-			fyne.LogError("解析证书错误", err)
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
-		var sm2Key *sm2.PrivateKey
-		//裸私钥长度为32
-		if len(decodeKey) <= 32 {
-			sm2Key = helper.BuildPrivateKeyUseRaw(decodeKey)
-		} else {
-			sm2Key, err = helper.BuildPrivateKey(decodeKey)
+		requestOutput.SetText(formatTimeStampRequest(lastRequest, request))
+		requestOutput.Show()
+	})
+
+	sendBtn := buildButton("发送请求", theme.MailSendIcon(), func() {
+		target := strings.TrimSpace(urlInput.Text)
+		if target == "" {
+			dialog.ShowError(fmt.Errorf("请填写时间戳服务地址"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		request, err := buildRequest()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		req := lastRequest
+		requestOutput.SetText(formatTimeStampRequest(req, request))
+		requestOutput.Show()
+		statusLabel.SetText("正在请求 " + target + " ...")
+		progressBar.Show()
+		go func() {
+			response, err := helper.QueryTSA(target, request, tspQueryTimeout)
+			var info *helper.TimeStampResponseInfo
+			if err == nil {
+				info, err = helper.ParseTimeStampResponse(response)
+			}
+			fyne.Do(func() {
+				progressBar.Hide()
+				if err != nil {
+					statusLabel.SetText("请求失败")
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+					return
+				}
+				statusLabel.SetText("✅ 已收到时间戳响应")
+				input.SetText(base64.StdEncoding.EncodeToString(response))
+				showResponse(info, req)
+			})
+		}()
+	})
+
+	parseBtn := buildButton("解析响应", theme.ConfirmIcon(), func() {
+		inputData := strings.TrimSpace(input.Text)
+		if inputData == "" {
+			dialog.ShowError(fmt.Errorf("请输入时间戳响应或令牌数据"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
 		}
-		pfx, err := buildPfx(certificate, sm2Key, inputPassword)
+		decodeData, _, err := decodeInput(inputData, "PKCS7", "CMS")
 		if err != nil {
+			dialog.ShowError(fmt.Errorf("无法解码时间戳响应: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
-		output.Text = base64.StdEncoding.EncodeToString(pfx)
-		output.Show()
+		info, err := helper.ParseTimeStampResponse(decodeData)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		// 保存到历史记录
+		util.GetHistoryDB().AddHistory(TimestampTab, inputData)
+		if historyManager := GetGlobalHistoryManager(); historyManager != nil {
+			historyManager.LoadHistoryForTab(TimestampTab)
+		}
+		showResponse(info, lastRequest)
 	})
-	//清除按钮
+
 	clear := buildButton("清除", theme.CancelIcon(), func() {
-		input.Text = ""
-		output.Text = ""
-		input.Refresh()
-		output.Refresh()
+		input.SetText("")
+		data.clear()
+		policyInput.SetText("")
+		urlInput.SetText("")
+		tsaCertInput.SetText("")
+		requestOutput.SetText("")
+		details.SetText("")
+		statusLabel.SetText("")
+		requestOutput.Hide()
+		details.Hide()
+		lastRequest = nil
 	})
 
-	//对所有按钮进行表格化
-	allButton := container.New(layout.NewGridLayout(2), confirm, clear)
-	// 不添加全局输入框，它已经在主界面的固定位置
-	// structure.Add(input)
-	structure.Add(KeyInput)
-	structure.Add(passwordInput)
-	structure.Add(allButton)
-	structure.Add(output)
-	// 使用滚动容器支持长内容
+	// issueLocal 以本地TSA直接签发当前数据的令牌，结果填入上方输入框
+	issueLocal := func(tsa *helper.TimeStampAuthority) {
+		request, err := buildRequest()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		req := lastRequest
+		requestOutput.SetText(formatTimeStampRequest(req, request))
+		requestOutput.Show()
+		response, _ := tsa.Issue(request)
+		info, err := helper.ParseTimeStampResponse(response)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		input.SetText(base64.StdEncoding.EncodeToString(response))
+		if strings.TrimSpace(tsaCertInput.Text) == "" && !certReqCheck.Checked {
+			// 未要求附带证书时以TSA证书验证签名
+			tsaCertInput.SetText(base64.StdEncoding.EncodeToString(tsa.Certificate().Raw))
+		}
+		showResponse(info, req)
+	}
+
+	requestButtons := container.New(layout.NewGridLayout(2), buildBtn, sendBtn)
+	responseButtons := container.New(layout.NewGridLayout(2), parseBtn, clear)
+
+	structure.Add(widget.NewLabel("待加盖时间戳的数据:"))
+	structure.Add(dataRow)
+	structure.Add(form)
+	structure.Add(requestButtons)
+	structure.Add(statusLabel)
+	structure.Add(progressBar)
+	structure.Add(requestOutput)
+	structure.Add(widget.NewSeparator())
+	structure.Add(widget.NewLabel("时间戳响应或令牌(上方输入框):"))
+	structure.Add(tsaCertInput)
+	structure.Add(responseButtons)
+	structure.Add(details)
+	structure.Add(widget.NewSeparator())
+	structure.Add(buildTimeStampAuthorityForm(urlInput, issueLocal))
+
 	scrollContainer := container.NewScroll(structure)
 	return container.NewMax(scrollContainer)
 }
+
+// buildTimeStampAuthorityForm 本地时间戳服务，可直接为上方数据签发令牌，或启动HTTP服务并将地址填入TSA地址
+func buildTimeStampAuthorityForm(urlInput *widget.Entry, issueLocal func(*helper.TimeStampAuthority)) *fyne.Container {
+	title := widget.NewLabel("本地时间戳服务")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	certInput := buildInputCertEntry("请输入TSA证书(PEM/Base64/Hex)，可附带上级证书")
+	certInput.Wrapping = fyne.TextWrapWord
+	keyInput := buildInputCertEntry("请输入TSA签名私钥(SM2或RSA)")
+	keyInput.Wrapping = fyne.TextWrapWord
+
+	policyInput := widget.NewEntry()
+	policyInput.SetPlaceHolder("TSA策略OID，如 1.2.3.4.1，请求指定其他策略时拒绝")
+	hashSelect := widget.NewSelect(append([]string{"自动"}, helper.TSPHashAlgorithms...), nil)
+	hashSelect.SetSelected("自动")
+	accuracyInput := widget.NewEntry()
+	accuracyInput.SetText("1s")
+	accuracyInput.SetPlaceHolder("genTime精度，如 1s、500ms，0表示不包含")
+	genTimeInput := widget.NewEntry()
+	genTimeInput.SetPlaceHolder("留空使用当前时间，可填写过去的时间生成回溯令牌，格式 " + util.DateTime)
+	orderingCheck := widget.NewCheck("ordering", nil)
+	tsaNameCheck := widget.NewCheck("包含TSA名称", nil)
+	addrInput := widget.NewEntry()
+	addrInput.SetText(helper.DefaultTSAAddr)
+
+	form := widget.NewForm(
+		widget.NewFormItem("策略", policyInput),
+		widget.NewFormItem("签名摘要", container.NewHBox(hashSelect, orderingCheck, tsaNameCheck)),
+		widget.NewFormItem("精度", accuracyInput),
+		widget.NewFormItem("签发时间", genTimeInput),
+		widget.NewFormItem("监听地址", addrInput),
+	)
+
+	statusLabel := widget.NewLabel("")
+	logOutput := widget.NewMultiLineEntry()
+	logOutput.Wrapping = fyne.TextWrapWord
+	logOutput.SetMinRowsVisible(8)
+	logOutput.Hide()
+
+	var logLines []string
+	appendLog := func(line string) {
+		logLines = append(logLines, line)
+		if len(logLines) > tsaLogLimit {
+			logLines = logLines[len(logLines)-tsaLogLimit:]
+		}
+		logOutput.SetText(strings.Join(logLines, "\n"))
+		logOutput.Show()
+	}
+
+	// newAuthority 按输入创建TSA，配置问题记入日志
+	newAuthority := func() (*helper.TimeStampAuthority, error) {
+		certs, err := decodeCertificateChain(certInput.Text)
+		if err != nil {
+			return nil, fmt.Errorf("解析TSA证书失败: %v", err)
+		}
+		config := helper.TSAConfig{
+			Certificate:    certs[0],
+			Chain:          certs[1:],
+			Policy:         strings.TrimSpace(policyInput.Text),
+			Ordering:       orderingCheck.Checked,
+			IncludeTSAName: tsaNameCheck.Checked,
+		}
+		decodeKey, _, err := decodeInput(keyInput.Text, "PRIVATE KEY", "EC PRIVATE KEY", "SM2 PRIVATE KEY", "RSA PRIVATE KEY")
+		if err != nil {
+			return nil, fmt.Errorf("私钥解码失败: %v", err)
+		}
+		if config.Key, err = helper.ParsePrivateKey(decodeKey); err != nil {
+			return nil, fmt.Errorf("解析私钥错误: %v", err)
+		}
+		if hashSelect.Selected != "自动" {
+			config.HashAlgorithm = hashSelect.Selected
+		}
+		accuracy, err := time.ParseDuration(strings.TrimSpace(accuracyInput.Text))
+		if err != nil {
+			return nil, fmt.Errorf("精度格式错误: %v", err)
+		}
+		config.Accuracy = helper.NewTimeStampAccuracy(accuracy)
+		if config.GenTime, err = util.ParseDateTime(genTimeInput.Text); err != nil {
+			return nil, fmt.Errorf("签发时间格式应为 %s", util.DateTime)
+		}
+		tsa, err := helper.NewTimeStampAuthority(config)
+		if err != nil {
+			return nil, err
+		}
+		for _, warning := range tsa.Warnings {
+			appendLog("⚠️ " + warning)
+		}
+		return tsa, nil
+	}
+
+	issueBtn := buildButton("签发令牌", theme.DocumentCreateIcon(), func() {
+		tsa, err := newAuthority()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		issueLocal(tsa)
+	})
+
+	var server *http.Server
+	var startBtn *widget.Button
+	startBtn = buildButton("启动服务", theme.MediaPlayIcon(), func() {
+		if server != nil {
+			server.Close()
+			server = nil
+			statusLabel.SetText("服务已停止")
+			startBtn.SetText("启动服务")
+			startBtn.SetIcon(theme.MediaPlayIcon())
+			return
+		}
+		tsa, err := newAuthority()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		tsa.Log = func(log helper.TSARequestLog) {
+			line := log.String()
+			fyne.Do(func() { appendLog(line) })
+		}
+		started, url, err := tsa.Listen(strings.TrimSpace(addrInput.Text))
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		server = started
+		statusLabel.SetText("✅ 时间戳服务运行中: " + url)
+		urlInput.SetText(url)
+		startBtn.SetText("停止服务")
+		startBtn.SetIcon(theme.MediaStopIcon())
+	})
+
+	clearLog := buildButton("清空日志", theme.DeleteIcon(), func() {
+		logLines = nil
+		logOutput.SetText("")
+		logOutput.Hide()
+	})
+
+	return container.NewVBox(
+		title,
+		widget.NewLabel("TSA证书:"),
+		certInput,
+		widget.NewLabel("签名私钥:"),
+		keyInput,
+		form,
+		container.New(layout.NewGridLayout(3), issueBtn, startBtn, clearLog),
+		statusLabel,
+		logOutput,
+	)
+}
+
+// contentSource 待签名或待验证的原文，可直接输入文本或选择文件
+type contentSource struct {
+	input *widget.Entry
+	uri   fyne.URI
+}
+
+// newContentSource 创建原文输入框及文件选择按钮，选择文件后输入框显示文件路径
+func newContentSource(placeholder string) (*contentSource, *fyne.Container) {
+	source := &contentSource{input: buildInputCertEntry(placeholder)}
+	source.input.OnChanged = func(string) { source.uri = nil }
+	selectBtn := buildButton("选择文件", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("打开文件失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			if reader == nil {
+				return
+			}
+			reader.Close()
+			source.input.SetText(fmt.Sprintf("已选择文件: %s", reader.URI().Path()))
+			source.uri = reader.URI()
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+	})
+	return source, container.NewBorder(nil, nil, nil, selectBtn, source.input)
+}
+
+// load 读取数据，未输入时返回nil
+func (source *contentSource) load() ([]byte, error) {
+	if source.uri != nil {
+		reader, err := storage.Reader(source.uri)
+		if err != nil {
+			return nil, fmt.Errorf("打开文件失败: %v", err)
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	if source.input.Text == "" {
+		return nil, nil
+	}
+	return []byte(source.input.Text), nil
+}
+
+func (source *contentSource) clear() {
+	source.input.SetText("")
+	source.uri = nil
+}
+
+// formatTimeStampRequest 显示请求摘要及Base64编码
+func formatTimeStampRequest(req *helper.TimeStampRequestInfo, request []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "消息摘要: %s %s\n", req.HashAlgorithm, hex.EncodeToString(req.HashedMessage))
+	if req.Nonce != nil {
+		fmt.Fprintf(&b, "Nonce: %X\n", req.Nonce)
+	}
+	if req.Policy != "" {
+		fmt.Fprintf(&b, "策略: %s\n", req.Policy)
+	}
+	b.WriteString("\n" + base64.StdEncoding.EncodeToString(request))
+	return b.String()
+}
+
+// formatTimeStampResponse 格式化时间戳响应详情，data不为nil时验证消息摘要，req不为空时检查响应与请求是否对应
+func formatTimeStampResponse(info *helper.TimeStampResponseInfo, data []byte, req *helper.TimeStampRequestInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "响应状态: %s\n", info.StatusText())
+	for _, text := range info.StatusStrings {
+		fmt.Fprintf(&b, "状态说明: %s\n", text)
+	}
+	if len(info.FailureInfo) > 0 {
+		fmt.Fprintf(&b, "失败原因: %s\n", strings.Join(info.FailureInfo, ", "))
+	}
+	tst := info.TSTInfo
+	if tst == nil {
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "\n版本: V%d\n", tst.Version)
+	fmt.Fprintf(&b, "策略: %s\n", tst.Policy)
+	fmt.Fprintf(&b, "序列号: %s\n", formatSerial(tst.SerialNumber))
+	fmt.Fprintf(&b, "时间: %s\n", tst.GenTime.Format(util.DateTime))
+	fmt.Fprintf(&b, "精度: %s\n", tst.Accuracy.String())
+	fmt.Fprintf(&b, "排序: %v\n", tst.Ordering)
+	if tst.Nonce != nil {
+		fmt.Fprintf(&b, "Nonce: %X\n", tst.Nonce)
+	}
+	if tst.TSAName != "" {
+		fmt.Fprintf(&b, "TSA名称: %s\n", tst.TSAName)
+	}
+	fmt.Fprintf(&b, "消息摘要: %s %s\n", tst.HashAlgorithm, hex.EncodeToString(tst.HashedMessage))
+	if len(tst.Extensions) > 0 {
+		fmt.Fprintf(&b, "扩展: %d 项\n", len(tst.Extensions))
+	}
+
+	if len(info.SignedData.Signers) > 0 {
+		signer := info.SignedData.Signers[0]
+		fmt.Fprintf(&b, "\n签名算法: %s (%s)\n", signer.SignatureAlgorithm, signer.DigestAlgorithm)
+	}
+	switch info.SignatureStatus {
+	case helper.CMSSignatureValid:
+		fmt.Fprintf(&b, "签名状态: ✅ %s\n", info.SignatureStatus)
+	case helper.CMSSignatureInvalid:
+		fmt.Fprintf(&b, "签名状态: ❌ %s: %s\n", info.SignatureStatus, info.SignatureError)
+	default:
+		fmt.Fprintf(&b, "签名状态: ⚠️ %s\n", info.SignatureStatus)
+	}
+	if info.Signer != nil {
+		fmt.Fprintf(&b, "TSA证书: %s, 有效期 %s 至 %s\n", info.Signer.Subject.String(),
+			info.Signer.NotBefore.Format(util.DateTime), info.Signer.NotAfter.Format(util.DateTime))
+	}
+	for _, cert := range info.SignedData.Certificates {
+		fmt.Fprintf(&b, "附带证书: %s\n", cert.Subject.String())
+	}
+	if data != nil {
+		if err := info.VerifyMessageImprint(data); err != nil {
+			fmt.Fprintf(&b, "数据摘要: ❌ %v\n", err)
+		} else {
+			b.WriteString("数据摘要: ✅ 与令牌一致\n")
+		}
+	}
+
+	problems := append([]string{}, info.Warnings...)
+	if req != nil {
+		problems = append(problems, info.CheckRequest(req)...)
+	}
+	if len(problems) > 0 {
+		b.WriteString("\n⚠️ 注意:\n- " + strings.Join(problems, "\n- ") + "\n")
+	}
+	return b.String()
+}
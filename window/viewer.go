@@ -27,9 +27,12 @@ const (
 	P12Tab         = "🎫 P12证书"
 	P7bTab         = "🔗 P7B证书链"
 	CrlTab         = "📜 CRL列表"
+	OcspTab        = "📡 OCSP"
+	TimestampTab   = "⏱️ 时间戳"
 	FormatTab      = "📄 JSON/XML"
 	TOTP           = "📄 TOTP"
 	ShamirTab      = "🧩 Shamir"
+	KeyExchangeTab = "🤝 密钥交换"
 )
 
 // 全局历史记录管理器引用
@@ -187,14 +190,17 @@ func createMainContent(sharedInput *widget.Entry) *fyne.Container {
 		CoderTab:       "📝 请输入 Base64/Hex 格式的数据进行编码转换，或拖拽文件到此处...",
 		CertificateTab: "📝 请输入 Base64/Hex 格式的证书数据进行解析，或拖拽证书文件到此处...",
 		Asn1Tab:        "📝 请输入 Base64/Hex 格式的 ASN.1 数据进行解析，或拖拽文件到此处...",
-		// KeyTab:         "📝 密钥生成工具 - 请在下方选择算法并生成密钥，或拖拽密钥文件到此处...",
-		EnvelopTab: "📝 请输入 Base64/Hex 格式的信封数据 (GMT-0009)，或拖拽文件到此处...",
-		P10Tab:     "📝 请输入 Base64/Hex 格式的 P10 证书签名请求数据，或拖拽P10文件到此处...",
-		P12Tab:     "📝 请输入 Base64/Hex 格式的证书数据生成 PFX 文件，或拖拽证书文件到此处...",
-		P7bTab:     "📝 请输入 Base64/Hex 格式的 P7B 证书链数据，或拖拽P7B文件到此处...",
-		CrlTab:     "📝 请输入 Base64/Hex 格式的 CRL 数据，或拖拽CRL文件到此处...",
-		FormatTab:  "📝 请输入 JSON 或 XML 数据进行格式化，或拖拽文件到此处...",
-		ShamirTab:  "📝 请输入要拆分的秘密数据...",
+		KeyTab:         "📝 密钥生成工具 - 请在下方选择算法并生成密钥，或拖拽密钥文件到此处...",
+		EnvelopTab:     "📝 请输入 Base64/Hex 格式的信封数据 (GMT-0009)，或拖拽文件到此处...",
+		P10Tab:         "📝 请输入 Base64/Hex 格式的 P10 证书签名请求数据，或拖拽P10文件到此处...",
+		P12Tab:         "📝 请输入 Base64/Hex 格式的 PFX/P12 数据并填写口令进行解析，或拖拽PFX文件到此处...",
+		P7bTab:         "📝 请输入 Base64/Hex 格式的 P7B 证书链数据，或拖拽P7B文件到此处...",
+		CrlTab:         "📝 请输入 Base64/Hex 格式的 CRL 数据，或拖拽CRL文件到此处...",
+		OcspTab:        "📝 请输入 Base64/Hex 格式的 OCSP 响应数据，或拖拽OCSP响应文件到此处...",
+		TimestampTab:   "📝 请输入 Base64/Hex 格式的时间戳响应或令牌，或拖拽TSR文件到此处...",
+		FormatTab:      "📝 请输入 JSON 或 XML 数据进行格式化，或拖拽文件到此处...",
+		ShamirTab:      "📝 请输入要拆分的秘密数据...",
+		KeyExchangeTab: "📝 SM2密钥交换 - 请在下方填写或随机生成双方的静态及临时密钥...",
 	}
 
 	// 创建历史记录下拉框
@@ -305,15 +311,18 @@ func createMultiRowTabs(sharedInput *widget.Entry, placeholders map[string]strin
 		{CoderTab, theme.ZoomInIcon(), func() *fyne.Container { return CoderStructure(sharedInput) }},
 		{CertificateTab, theme.InfoIcon(), func() *fyne.Container { return CertificateStructure(sharedInput) }},
 		{Asn1Tab, theme.ZoomInIcon(), func() *fyne.Container { return Asn1Structure(sharedInput) }},
-		// {KeyTab, theme.ColorChromaticIcon(), func() *fyne.Container { return KeyStructure(sharedInput) }},
+		{KeyTab, theme.ColorChromaticIcon(), func() *fyne.Container { return KeyStructure(sharedInput) }},
 		{EnvelopTab, theme.FolderIcon(), func() *fyne.Container { return SM2EnvelopedPfxStructure(sharedInput) }},
-		//{P10Tab, theme.DocumentIcon(), func() *fyne.Container { return P10Structure(sharedInput) }},
+		{P10Tab, theme.DocumentIcon(), func() *fyne.Container { return P10Structure(sharedInput) }},
 		{P12Tab, theme.AccountIcon(), func() *fyne.Container { return SM2PfxStructure(sharedInput) }},
 		{P7bTab, theme.InfoIcon(), func() *fyne.Container { return P7bStructure(sharedInput) }},
 		{CrlTab, theme.AccountIcon(), func() *fyne.Container { return CrlStructure(sharedInput) }},
+		{OcspTab, theme.SearchIcon(), func() *fyne.Container { return OcspStructure(sharedInput) }},
+		{TimestampTab, theme.HistoryIcon(), func() *fyne.Container { return TimestampStructure(sharedInput) }},
 		{FormatTab, theme.DocumentIcon(), func() *fyne.Container { return FormatStructure(sharedInput) }},
 		{TOTP, theme.DocumentIcon(), func() *fyne.Container { return OTPStructure(sharedInput) }},
 		{ShamirTab, theme.VisibilityIcon(), func() *fyne.Container { return ShamirStructure(sharedInput) }},
+		{KeyExchangeTab, theme.MailForwardIcon(), func() *fyne.Container { return KeyExchangeStructure(sharedInput) }},
 	}
 
 	// 创建内容容器